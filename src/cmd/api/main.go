@@ -6,19 +6,32 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/PabloPavan/sniply_api/internal"
+	"github.com/PabloPavan/sniply_api/internal/accounts"
+	"github.com/PabloPavan/sniply_api/internal/analytics"
 	"github.com/PabloPavan/sniply_api/internal/apikeys"
 	"github.com/PabloPavan/sniply_api/internal/auth"
+	"github.com/PabloPavan/sniply_api/internal/comments"
 	"github.com/PabloPavan/sniply_api/internal/db"
+	"github.com/PabloPavan/sniply_api/internal/fulltext"
 	"github.com/PabloPavan/sniply_api/internal/httpapi"
+	"github.com/PabloPavan/sniply_api/internal/languages"
+	"github.com/PabloPavan/sniply_api/internal/mail"
+	"github.com/PabloPavan/sniply_api/internal/notifications"
+	"github.com/PabloPavan/sniply_api/internal/passkeys"
 	"github.com/PabloPavan/sniply_api/internal/ratelimit"
+	"github.com/PabloPavan/sniply_api/internal/savedsearches"
+	"github.com/PabloPavan/sniply_api/internal/secrets"
 	"github.com/PabloPavan/sniply_api/internal/session"
 	"github.com/PabloPavan/sniply_api/internal/snippets"
 	"github.com/PabloPavan/sniply_api/internal/telemetry"
+	"github.com/PabloPavan/sniply_api/internal/twofactor"
 	"github.com/PabloPavan/sniply_api/internal/users"
+	"github.com/go-webauthn/webauthn/webauthn"
 	"github.com/redis/go-redis/v9"
 )
 
@@ -53,8 +66,18 @@ func main() {
 
 	dbBase := db.NewBase(d.Pool, 3*time.Second)
 	snRepo := snippets.NewRepository(dbBase)
+	snRepo.RegexTimeout = internal.ParseDurationEnv("SEARCH_REGEX_TIMEOUT", 2*time.Second)
 	usrRepo := users.NewRepository(dbBase)
 	apiKeysRepo := apikeys.NewRepository(dbBase)
+	findingsRepo := secrets.NewRepository(dbBase)
+	commentsRepo := comments.NewRepository(dbBase)
+	analyticsRepo := analytics.NewRepository(dbBase)
+	savedSearchesRepo := savedsearches.NewRepository(dbBase)
+	notificationsRepo := notifications.NewRepository(dbBase)
+	twoFactorRepo := twofactor.NewRepository(dbBase)
+	passkeysRepo := passkeys.NewRepository(dbBase)
+	accountsRepo := accounts.NewRepository(dbBase)
+	outboxRepo := mail.NewOutboxRepository(dbBase)
 
 	sessionPrefix := internal.Env("SESSION_REDIS_PREFIX", "sniply:session:")
 	sessionTTL := internal.ParseDurationEnv("SESSION_TTL", 7*24*time.Hour)
@@ -66,6 +89,8 @@ func main() {
 		MaxAge:        sessionMaxAge,
 		RefreshBefore: sessionRefreshBefore,
 		IDBytes:       32,
+		PendingTTL:    internal.ParseDurationEnv("SESSION_PENDING_TTL", session.DefaultPendingTTL),
+		TouchEvery:    internal.ParseDurationEnv("SESSION_TOUCH_INTERVAL", session.DefaultTouchEvery),
 	}
 
 	cookieSecure := internal.ParseBoolEnv("SESSION_COOKIE_SECURE", true)
@@ -96,38 +121,172 @@ func main() {
 
 	cacheTTL := internal.ParseDurationEnv("SNIPPETS_CACHE_TTL", 2*time.Minute)
 	listCacheTTL := internal.ParseDurationEnv("SNIPPETS_LIST_CACHE_TTL", 30*time.Second)
+	renderCacheTTL := internal.ParseDurationEnv("SNIPPETS_RENDER_CACHE_TTL", 10*time.Minute)
+	relatedCacheTTL := internal.ParseDurationEnv("SNIPPETS_RELATED_CACHE_TTL", 5*time.Minute)
 	snippetsCache := snippets.NewRedisCache(redisClient, "sniply:cache:")
+	secretPolicy, err := secrets.ParsePolicy(internal.Env("SECRET_SCAN_POLICY", "block"))
+	if err != nil {
+		log.Fatalf("config error: %v", err)
+	}
+	var searchIndex snippets.SearchIndex
+	var searchRefresh time.Duration
+	embeddedSearch := false
+	switch backend := internal.Env("SEARCH_BACKEND", "postgres"); backend {
+	case "postgres":
+		searchIndex = snippets.NewPostgresIndex(snRepo)
+	case "embedded":
+		searchIndex = fulltext.New()
+		embeddedSearch = true
+		searchRefresh = internal.ParseDurationEnv("SEARCH_INDEX_REFRESH_INTERVAL", 10*time.Minute)
+	default:
+		log.Fatalf("config error: unknown SEARCH_BACKEND %q", backend)
+	}
 	telemetry.InitAppMetrics("sniply-api", d.Pool, redisClient, sessionPrefix)
 
-	usersService := &users.Service{Store: usrRepo}
+	accountsService := &accounts.Service{
+		Store:     accountsRepo,
+		Users:     usrRepo,
+		Limiter:   loginLimiter,
+		Sessions:  sessionManager,
+		ResetURL:  internal.Env("PASSWORD_RESET_URL", "http://localhost:"+port+"/reset-password"),
+		VerifyURL: internal.Env("EMAIL_VERIFY_URL", "http://localhost:"+port+"/verify-email"),
+		ResetTTL:  internal.ParseDurationEnv("PASSWORD_RESET_TTL", accounts.DefaultResetTTL),
+		VerifyTTL: internal.ParseDurationEnv("EMAIL_VERIFY_TTL", accounts.DefaultVerifyTTL),
+	}
+	mailWorker := &mail.Worker{
+		Store:       outboxRepo,
+		Mailer:      mailer(),
+		Interval:    internal.ParseDurationEnv("MAIL_OUTBOX_INTERVAL", 5*time.Second),
+		MaxAttempts: internal.ParseIntEnv("MAIL_MAX_ATTEMPTS", mail.DefaultMaxAttempts),
+	}
+	usersService := &users.Service{Store: usrRepo, Verifier: accountsService, Sessions: sessionManager}
 	snippetsService := &snippets.Service{
-		Store:        snRepo,
-		Users:        usrRepo,
-		Cache:        snippetsCache,
-		CacheTTL:     cacheTTL,
-		ListCacheTTL: listCacheTTL,
+		Store:           snRepo,
+		Users:           usrRepo,
+		Languages:       languages.NewDetector(nil),
+		Cache:           snippetsCache,
+		CacheTTL:        cacheTTL,
+		ListCacheTTL:    listCacheTTL,
+		RenderCache:     snippetsCache,
+		RenderCacheTTL:  renderCacheTTL,
+		RelatedCache:    snippetsCache,
+		RelatedCacheTTL: relatedCacheTTL,
+		FacetCache:      snippetsCache,
+		Secrets:         secrets.NewScanner(),
+		SecretPolicy:    secretPolicy,
+		Findings:        findingsRepo,
+		Search:          searchIndex,
+	}
+	if embeddedSearch {
+		// The embedded index lives in memory and starts empty.
+		n, err := snippetsService.RebuildIndex(ctx)
+		if err != nil {
+			log.Fatalf("search index error: %v", err)
+		}
+		log.Printf("search index built with %d snippets", n)
+	}
+	indexRefresher := &snippets.IndexRefresher{Service: snippetsService, Interval: searchRefresh}
+	commentsService := &comments.Service{
+		Store:    commentsRepo,
+		Snippets: snippetsService,
+	}
+	viewBuffer := analytics.NewRedisBuffer(redisClient, internal.Env("VIEWS_REDIS_PREFIX", "sniply:views:"))
+	analyticsService := &analytics.Service{
+		Store:    analyticsRepo,
+		Buffer:   viewBuffer,
+		Snippets: snippetsService,
+	}
+	viewFlusher := &analytics.Flusher{
+		Buffer:   viewBuffer,
+		Store:    analyticsRepo,
+		Interval: internal.ParseDurationEnv("VIEWS_FLUSH_INTERVAL", 30*time.Second),
+	}
+	notificationsService := &notifications.Service{Store: notificationsRepo}
+	savedSearchesService := &savedsearches.Service{
+		Store:    savedSearchesRepo,
+		Searcher: snippetsService,
+	}
+	savedSearchWorker := &savedsearches.Worker{
+		Store:    savedSearchesRepo,
+		Searcher: snippetsService,
+		Users:    usrRepo,
+		Inbox:    notificationsService,
+		Webhooks: savedsearches.NewHTTPWebhook(internal.ParseBoolEnv("SAVED_SEARCH_WEBHOOK_ALLOW_PRIVATE", false)),
+		Interval: internal.ParseDurationEnv("SAVED_SEARCH_CHECK_INTERVAL", time.Minute),
+		Every:    internal.ParseDurationEnv("SAVED_SEARCH_NOTIFY_INTERVAL", 15*time.Minute),
+	}
+	apiKeysService := &apikeys.Service{
+		Store:         apiKeysRepo,
+		RotationGrace: internal.ParseDurationEnv("API_KEY_ROTATION_GRACE", apikeys.DefaultRotationGrace),
+	}
+	apiKeyUsage := &apikeys.UsageTracker{
+		Store:    apiKeysRepo,
+		Interval: internal.ParseDurationEnv("API_KEY_USAGE_FLUSH_INTERVAL", time.Minute),
+	}
+	twoFactorService := &twofactor.Service{
+		Store:  twoFactorRepo,
+		Users:  usrRepo,
+		Issuer: internal.Env("TOTP_ISSUER", twofactor.DefaultIssuer),
+	}
+	authStates := session.NewRedisStateStore(redisClient, internal.Env("AUTH_STATE_REDIS_PREFIX", "sniply:authstate:"))
+	webAuthn, err := webauthn.New(&webauthn.Config{
+		RPID:          internal.Env("WEBAUTHN_RP_ID", "localhost"),
+		RPDisplayName: internal.Env("WEBAUTHN_RP_NAME", "Sniply"),
+		RPOrigins:     strings.Fields(strings.ReplaceAll(internal.Env("WEBAUTHN_RP_ORIGINS", "http://localhost:"+port), ",", " ")),
+	})
+	if err != nil {
+		log.Fatalf("config error: WEBAUTHN_RP_ID, WEBAUTHN_RP_NAME and WEBAUTHN_RP_ORIGINS: %v", err)
+	}
+	passkeysService := &passkeys.Service{
+		Store:       passkeysRepo,
+		Users:       usrRepo,
+		WebAuthn:    webAuthn,
+		States:      authStates,
+		CeremonyTTL: internal.ParseDurationEnv("WEBAUTHN_CEREMONY_TTL", passkeys.DefaultCeremonyTTL),
 	}
-	apiKeysService := &apikeys.Service{Store: apiKeysRepo}
 	authService := &auth.Service{
 		Users:        usrRepo,
 		Sessions:     sessionManager,
 		APIKeys:      apiKeysRepo,
+		APIKeyUsage:  apiKeyUsage,
 		LoginLimiter: loginLimiter,
+		TwoFactor:    twoFactorService,
+		Passkeys:     passkeysService,
+		OIDC:         oidcProviders(),
+		Identities:   usrRepo,
+		States:       authStates,
+		StateTTL:     internal.ParseDurationEnv("OIDC_STATE_TTL", auth.DefaultStateTTL),
 	}
 
 	app := &httpapi.App{
 		Health: &httpapi.HealthHandler{DB: d.Pool},
 		Snippets: &httpapi.SnippetsHandler{
 			Service: snippetsService,
+			Views:   analyticsService,
 		},
-		Users: &httpapi.UsersHandler{Service: usersService},
+		Languages: &httpapi.LanguagesHandler{Service: snippetsService},
+		Tags:      &httpapi.TagsHandler{Service: snippetsService},
+		Comments:  &httpapi.CommentsHandler{Service: commentsService},
+		Analytics: &httpapi.AnalyticsHandler{Service: analyticsService},
+		Users:     &httpapi.UsersHandler{Service: usersService},
 		Auth: &httpapi.AuthHandler{
 			Service:       authService,
 			Authenticator: authService,
 			Cookie:        cookie,
 			CSRFCookie:    csrfCookie,
+			PostLoginURL:  internal.Env("OIDC_POST_LOGIN_URL", ""),
+		},
+		Sessions: &httpapi.SessionsHandler{
+			Service:    authService,
+			Cookie:     cookie,
+			CSRFCookie: csrfCookie,
 		},
 		APIKeys:       &httpapi.APIKeysHandler{Service: apiKeysService},
+		TwoFactor:     &httpapi.TwoFactorHandler{Service: twoFactorService},
+		Passkeys:      &httpapi.PasskeysHandler{Service: passkeysService},
+		Accounts:      &httpapi.AccountsHandler{Service: accountsService},
+		SavedSearches: &httpapi.SavedSearchesHandler{Service: savedSearchesService},
+		Notifications: &httpapi.NotificationsHandler{Service: notificationsService},
 		Authenticator: authService,
 	}
 
@@ -137,6 +296,49 @@ func main() {
 		ReadHeaderTimeout: 5 * time.Second,
 	}
 
+	flushDone := make(chan struct{})
+	go func() {
+		viewFlusher.Run(ctx)
+		close(flushDone)
+	}()
+	refreshDone := make(chan struct{})
+	go func() {
+		indexRefresher.Run(ctx)
+		close(refreshDone)
+	}()
+	searchesDone := make(chan struct{})
+	go func() {
+		savedSearchWorker.Run(ctx)
+		close(searchesDone)
+	}()
+	mailDone := make(chan struct{})
+	go func() {
+		mailWorker.Run(ctx)
+		close(mailDone)
+	}()
+	go func() {
+		n, err := snRepo.BackfillSymbols(ctx)
+		if err != nil {
+			telemetry.LogError(ctx, "symbol backfill failed",
+				telemetry.LogString("event", "snippets.symbols.backfill"),
+				telemetry.LogInt("snippets", n),
+				telemetry.LogString("error", err.Error()),
+			)
+			return
+		}
+		if n > 0 {
+			telemetry.LogInfo(ctx, "symbol backfill done",
+				telemetry.LogString("event", "snippets.symbols.backfill"),
+				telemetry.LogInt("snippets", n),
+			)
+		}
+	}()
+	usageDone := make(chan struct{})
+	go func() {
+		apiKeyUsage.Run(ctx)
+		close(usageDone)
+	}()
+
 	log.Printf("api listening on :%s", port)
 	errCh := make(chan error, 1)
 	go func() {
@@ -163,4 +365,71 @@ func main() {
 			log.Printf("server shutdown timeout")
 		}
 	}
+
+	// Stop the background workers and wait for the final view and key
+	// usage flushes.
+	stop()
+	<-flushDone
+	<-usageDone
+	<-searchesDone
+	<-refreshDone
+	<-mailDone
+}
+
+// mailer reads MAIL_BACKEND: "log" writes mail to stderr, "file" appends it
+// to MAIL_FILE and "smtp" sends it through SMTP_ADDR.
+func mailer() mail.Mailer {
+	switch backend := internal.Env("MAIL_BACKEND", "log"); backend {
+	case "log":
+		return &mail.LogMailer{W: os.Stderr}
+	case "file":
+		m, err := mail.NewFileMailer(internal.MustEnv("MAIL_FILE"))
+		if err != nil {
+			log.Fatalf("config error: MAIL_FILE: %v", err)
+		}
+		return m
+	case "smtp":
+		return &mail.SMTPMailer{
+			Addr:     internal.MustEnv("SMTP_ADDR"),
+			From:     internal.Env("MAIL_FROM", "no-reply@localhost"),
+			Username: internal.Env("SMTP_USERNAME", ""),
+			Password: internal.Env("SMTP_PASSWORD", ""),
+		}
+	default:
+		log.Fatalf("config error: unknown MAIL_BACKEND %q", backend)
+		return nil
+	}
+}
+
+// oidcProviders reads the identity providers named in OIDC_PROVIDERS, e.g.
+// "corp", from OIDC_CORP_ISSUER, OIDC_CORP_CLIENT_ID and so on.
+func oidcProviders() map[string]*auth.OIDCProvider {
+	providers := make(map[string]*auth.OIDCProvider)
+	for _, name := range strings.Split(internal.Env("OIDC_PROVIDERS", ""), ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		prefix := "OIDC_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+		p := &auth.OIDCProvider{
+			Name:         name,
+			Issuer:       internal.Env(prefix+"ISSUER", ""),
+			ClientID:     internal.Env(prefix+"CLIENT_ID", ""),
+			ClientSecret: internal.Env(prefix+"CLIENT_SECRET", ""),
+			RedirectURL:  internal.Env(prefix+"REDIRECT_URL", ""),
+			Scopes:       strings.Fields(strings.ReplaceAll(internal.Env(prefix+"SCOPES", ""), ",", " ")),
+			AllowSignup:  internal.ParseBoolEnv(prefix+"ALLOW_SIGNUP", true),
+			RoleClaim:    internal.Env(prefix+"ROLE_CLAIM", ""),
+		}
+		for _, v := range strings.Split(internal.Env(prefix+"ADMIN_VALUES", ""), ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				p.AdminValues = append(p.AdminValues, v)
+			}
+		}
+		if p.Issuer == "" || p.ClientID == "" || p.RedirectURL == "" {
+			log.Fatalf("config error: %sISSUER, %sCLIENT_ID and %sREDIRECT_URL are required", prefix, prefix, prefix)
+		}
+		providers[name] = p
+	}
+	return providers
 }
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/PabloPavan/sniply_api/internal/searchquery"
+)
+
+const usage = `usage:
+  sniply search [-url URL] [-key API_KEY] [-mode fts|phrase|regex] [-limit N] [-json] QUERY...
+  sniply parse QUERY...
+  sniply reindex [-url URL] [-key API_KEY]
+
+QUERY uses the same syntax as the q parameter of GET /v1/snippets, e.g.
+  sniply search 'lang:go tag:http -tag:deprecated created:>2025-01-01 "exact phrase" router'
+
+Qualifiers: %s.
+reindex rebuilds the server's search index; it needs an admin API key
+with write scope.
+The URL and API key default to $SNIPLY_URL and $SNIPLY_API_KEY.
+`
+
+type snippet struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Language  string    `json:"language"`
+	Tags      []string  `json:"tags"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, usage, strings.Join(searchquery.Qualifiers(), ", "))
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	var err error
+	switch flag.Arg(0) {
+	case "search":
+		err = search(flag.Args()[1:])
+	case "parse":
+		err = parse(flag.Args()[1:])
+	case "reindex":
+		err = reindex(flag.Args()[1:])
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "sniply:", err)
+		os.Exit(1)
+	}
+}
+
+func parse(args []string) error {
+	q, err := searchquery.Parse(strings.Join(args, " "))
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(q)
+}
+
+func search(args []string) error {
+	fs := flag.NewFlagSet("search", flag.ExitOnError)
+	baseURL := fs.String("url", envOr("SNIPLY_URL", "http://localhost:8080"), "API base URL")
+	apiKey := fs.String("key", os.Getenv("SNIPLY_API_KEY"), "API key")
+	mode := fs.String("mode", "fts", "search mode: fts, phrase or regex")
+	limit := fs.Int("limit", 20, "maximum results")
+	raw := fs.Bool("json", false, "print the JSON response")
+	_ = fs.Parse(args)
+
+	query := strings.Join(fs.Args(), " ")
+	// Check the query here first so mistakes are reported without a round
+	// trip; the server parses it again with the same package.
+	if *mode == "fts" {
+		if _, err := searchquery.Parse(query); err != nil {
+			return err
+		}
+	}
+
+	params := url.Values{}
+	params.Set("q", query)
+	params.Set("mode", *mode)
+	params.Set("limit", strconv.Itoa(*limit))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimRight(*baseURL, "/")+"/v1/snippets?"+params.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	if *apiKey != "" {
+		req.Header.Set("X-API-Key", *apiKey)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 32<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		fmt.Println("no snippets found")
+		return nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return errors.New(strings.TrimSpace(resp.Status + ": " + string(body)))
+	}
+	if *raw {
+		_, err := os.Stdout.Write(body)
+		return err
+	}
+
+	var list []snippet
+	if err := json.Unmarshal(body, &list); err != nil {
+		return err
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tNAME\tLANGUAGE\tTAGS\tUPDATED")
+	for _, s := range list {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", s.ID, s.Name, s.Language, strings.Join(s.Tags, ","), s.UpdatedAt.Format(time.DateOnly))
+	}
+	return tw.Flush()
+}
+
+func reindex(args []string) error {
+	fs := flag.NewFlagSet("reindex", flag.ExitOnError)
+	baseURL := fs.String("url", envOr("SNIPLY_URL", "http://localhost:8080"), "API base URL")
+	apiKey := fs.String("key", os.Getenv("SNIPLY_API_KEY"), "API key")
+	_ = fs.Parse(args)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimRight(*baseURL, "/")+"/v1/snippets/reindex", nil)
+	if err != nil {
+		return err
+	}
+	if *apiKey != "" {
+		req.Header.Set("X-API-Key", *apiKey)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return errors.New(strings.TrimSpace(resp.Status + ": " + string(body)))
+	}
+
+	var out struct {
+		Indexed int `json:"indexed"`
+	}
+	if err := json.Unmarshal(body, &out); err != nil {
+		return err
+	}
+	fmt.Printf("indexed %d snippets\n", out.Indexed)
+	return nil
+}
+
+func envOr(key, fallback string) string {
+	if v := strings.TrimSpace(os.Getenv(key)); v != "" {
+		return v
+	}
+	return fallback
+}
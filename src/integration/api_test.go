@@ -10,17 +10,31 @@ import (
 	"net/http/httptest"
 	"net/url"
 	"os"
+	"regexp"
 	"testing"
 	"time"
 
 	"github.com/PabloPavan/sniply_api/internal"
+	"github.com/PabloPavan/sniply_api/internal/accounts"
+	"github.com/PabloPavan/sniply_api/internal/analytics"
 	"github.com/PabloPavan/sniply_api/internal/apikeys"
 	"github.com/PabloPavan/sniply_api/internal/auth"
+	"github.com/PabloPavan/sniply_api/internal/comments"
 	"github.com/PabloPavan/sniply_api/internal/db"
 	"github.com/PabloPavan/sniply_api/internal/httpapi"
+	"github.com/PabloPavan/sniply_api/internal/languages"
+	"github.com/PabloPavan/sniply_api/internal/mail"
+	"github.com/PabloPavan/sniply_api/internal/notifications"
+	"github.com/PabloPavan/sniply_api/internal/oidctest"
+	"github.com/PabloPavan/sniply_api/internal/passkeys"
+	"github.com/PabloPavan/sniply_api/internal/passkeytest"
+	"github.com/PabloPavan/sniply_api/internal/savedsearches"
+	"github.com/PabloPavan/sniply_api/internal/secrets"
 	"github.com/PabloPavan/sniply_api/internal/session"
 	"github.com/PabloPavan/sniply_api/internal/snippets"
+	"github.com/PabloPavan/sniply_api/internal/twofactor"
 	"github.com/PabloPavan/sniply_api/internal/users"
+	"github.com/go-webauthn/webauthn/webauthn"
 )
 
 type testEnv struct {
@@ -29,6 +43,10 @@ type testEnv struct {
 	users    *users.Repository
 	snippets *snippets.Repository
 	apiKeys  *apikeys.Repository
+	keyUsage *apikeys.UsageTracker
+	idp      *oidctest.IdP
+	mailer   *mail.MemoryMailer
+	outbox   *mail.Worker
 }
 
 func newTestEnv(t *testing.T) *testEnv {
@@ -68,31 +86,105 @@ func newTestEnv(t *testing.T) *testEnv {
 		SameSite: cookieCfg.SameSite,
 	}
 
-	usersService := &users.Service{Store: usrRepo}
-	snippetsService := &snippets.Service{Store: snRepo, Users: usrRepo}
+	accountsService := &accounts.Service{Store: accounts.NewRepository(base), Users: usrRepo, Sessions: sessionManager}
+	mailer := &mail.MemoryMailer{}
+	outbox := &mail.Worker{Store: mail.NewOutboxRepository(base), Mailer: mailer, BatchSize: 1000}
+	usersService := &users.Service{Store: usrRepo, Verifier: accountsService, Sessions: sessionManager}
+	snippetsService := &snippets.Service{
+		Store:        snRepo,
+		Users:        usrRepo,
+		Languages:    languages.NewDetector(nil),
+		Secrets:      secrets.NewScanner(),
+		SecretPolicy: secrets.PolicyBlock,
+		Findings:     secrets.NewRepository(base),
+		Search:       snippets.NewPostgresIndex(snRepo),
+	}
+	commentsService := &comments.Service{
+		Store:    comments.NewRepository(base),
+		Snippets: snippetsService,
+	}
+	analyticsService := &analytics.Service{
+		Store:    analytics.NewRepository(base),
+		Buffer:   analytics.NewMemoryBuffer(),
+		Snippets: snippetsService,
+	}
+	savedSearchesService := &savedsearches.Service{
+		Store:    savedsearches.NewRepository(base),
+		Searcher: snippetsService,
+	}
+	notificationsService := &notifications.Service{Store: notifications.NewRepository(base)}
 	apiKeysService := &apikeys.Service{Store: apiKeyRepo}
-	authService := &auth.Service{
+	keyUsage := &apikeys.UsageTracker{Store: apiKeyRepo}
+	twoFactorService := &twofactor.Service{Store: twofactor.NewRepository(base), Users: usrRepo}
+	idp := oidctest.New("sniply", "secret")
+	t.Cleanup(idp.Close)
+	provider := &auth.OIDCProvider{
+		Name:         "mock",
+		Issuer:       idp.Issuer(),
+		ClientID:     idp.ClientID,
+		ClientSecret: idp.ClientSecret,
+		AllowSignup:  true,
+		RoleClaim:    "groups",
+		AdminValues:  []string{"sniply-admins"},
+	}
+	webAuthn, err := webauthn.New(&webauthn.Config{
+		RPID:          "localhost",
+		RPDisplayName: "Sniply",
+		RPOrigins:     []string{"http://localhost"},
+	})
+	if err != nil {
+		t.Fatalf("webauthn config: %v", err)
+	}
+	authStates := session.NewMemoryStateStore()
+	passkeysService := &passkeys.Service{
+		Store:    passkeys.NewRepository(base),
 		Users:    usrRepo,
-		Sessions: sessionManager,
-		APIKeys:  apiKeyRepo,
+		WebAuthn: webAuthn,
+		States:   authStates,
+	}
+	authService := &auth.Service{
+		Users:       usrRepo,
+		Sessions:    sessionManager,
+		APIKeys:     apiKeyRepo,
+		APIKeyUsage: keyUsage,
+		TwoFactor:   twoFactorService,
+		Passkeys:    passkeysService,
+		OIDC:        map[string]*auth.OIDCProvider{"mock": provider},
+		Identities:  usrRepo,
+		States:      authStates,
 	}
 
 	app := &httpapi.App{
-		Health:   &httpapi.HealthHandler{DB: pool.Pool},
-		Snippets: &httpapi.SnippetsHandler{Service: snippetsService},
-		Users:    &httpapi.UsersHandler{Service: usersService},
+		Health:    &httpapi.HealthHandler{DB: pool.Pool},
+		Snippets:  &httpapi.SnippetsHandler{Service: snippetsService, Views: analyticsService},
+		Languages: &httpapi.LanguagesHandler{Service: snippetsService},
+		Tags:      &httpapi.TagsHandler{Service: snippetsService},
+		Comments:  &httpapi.CommentsHandler{Service: commentsService},
+		Analytics: &httpapi.AnalyticsHandler{Service: analyticsService},
+		Users:     &httpapi.UsersHandler{Service: usersService},
 		Auth: &httpapi.AuthHandler{
 			Service:       authService,
 			Authenticator: authService,
 			Cookie:        cookieCfg,
 			CSRFCookie:    csfrCfg,
 		},
+		Sessions: &httpapi.SessionsHandler{
+			Service:    authService,
+			Cookie:     cookieCfg,
+			CSRFCookie: csfrCfg,
+		},
 		APIKeys:       &httpapi.APIKeysHandler{Service: apiKeysService},
+		TwoFactor:     &httpapi.TwoFactorHandler{Service: twoFactorService},
+		Passkeys:      &httpapi.PasskeysHandler{Service: passkeysService},
+		Accounts:      &httpapi.AccountsHandler{Service: accountsService},
+		SavedSearches: &httpapi.SavedSearchesHandler{Service: savedSearchesService},
+		Notifications: &httpapi.NotificationsHandler{Service: notificationsService},
 		Authenticator: authService,
 	}
 
 	srv := httptest.NewServer(httpapi.NewRouter(app))
 	t.Cleanup(srv.Close)
+	provider.RedirectURL = srv.URL + "/v1/auth/oidc/mock/callback"
 
 	return &testEnv{
 		baseURL:  srv.URL,
@@ -100,6 +192,10 @@ func newTestEnv(t *testing.T) *testEnv {
 		users:    usrRepo,
 		snippets: snRepo,
 		apiKeys:  apiKeyRepo,
+		keyUsage: keyUsage,
+		idp:      idp,
+		mailer:   mailer,
+		outbox:   outbox,
 	}
 }
 
@@ -183,12 +279,13 @@ func logout(t *testing.T, client *http.Client, baseURL string) {
 }
 
 type apiKeyCreateResponse struct {
-	ID          string    `json:"id"`
-	Name        string    `json:"name"`
-	Scope       string    `json:"scope"`
-	Token       string    `json:"token"`
-	TokenPrefix string    `json:"token_prefix"`
-	CreatedAt   time.Time `json:"created_at"`
+	ID          string     `json:"id"`
+	Name        string     `json:"name"`
+	Scope       string     `json:"scope"`
+	Token       string     `json:"token"`
+	TokenPrefix string     `json:"token_prefix"`
+	CreatedAt   time.Time  `json:"created_at"`
+	ExpiresAt   *time.Time `json:"expires_at"`
 }
 
 func createAPIKey(t *testing.T, client *http.Client, baseURL, csrfToken, name, scope string) apiKeyCreateResponse {
@@ -216,7 +313,7 @@ func createAPIKey(t *testing.T, client *http.Client, baseURL, csrfToken, name, s
 	return out
 }
 
-func createAdminUser(t *testing.T, env *testEnv, email, password string) {
+func createAdminUser(t *testing.T, env *testEnv, email, password string) string {
 	t.Helper()
 
 	hash, err := internal.DefaultPasswordHasher(password)
@@ -239,6 +336,7 @@ func createAdminUser(t *testing.T, env *testEnv, email, password string) {
 	if err := env.users.Update(context.Background(), update); err != nil {
 		t.Fatalf("set admin role: %v", err)
 	}
+	return u.ID
 }
 
 func doJSON(t *testing.T, client *http.Client, method, url string, body any) *http.Response {
@@ -316,6 +414,389 @@ func TestAuthLoginLogout(t *testing.T) {
 	}
 }
 
+func TestOIDCLogin(t *testing.T) {
+	env := newTestEnv(t)
+	client := newClient(t)
+
+	email := fmt.Sprintf("sso_%s@local", internal.RandomHex(6))
+	subject := "sub_" + internal.RandomHex(6)
+	env.idp.SetUser(subject, map[string]any{"email": email, "email_verified": true, "groups": []string{"sniply-admins"}})
+
+	oidcLogin := func() *users.User {
+		t.Helper()
+		res, err := client.Get(env.baseURL + "/v1/auth/oidc/mock/start")
+		if err != nil {
+			t.Fatalf("oidc login: %v", err)
+		}
+		var login httpapi.LoginResponse
+		err = json.NewDecoder(res.Body).Decode(&login)
+		_ = res.Body.Close()
+		if res.StatusCode != http.StatusOK || err != nil || login.CSRFToken == "" {
+			t.Fatalf("oidc callback status: %d (%v)", res.StatusCode, err)
+		}
+
+		res = doJSON(t, client, http.MethodGet, env.baseURL+"/v1/users/me", nil)
+		defer res.Body.Close()
+		var me users.UserResponse
+		if res.StatusCode != http.StatusOK {
+			t.Fatalf("me status after oidc login: %d", res.StatusCode)
+		}
+		if err := json.NewDecoder(res.Body).Decode(&me); err != nil {
+			t.Fatalf("decode me: %v", err)
+		}
+		u, err := env.users.GetByID(context.Background(), me.ID)
+		if err != nil {
+			t.Fatalf("get user: %v", err)
+		}
+		return u
+	}
+
+	me := oidcLogin()
+	t.Cleanup(func() { _ = env.users.Delete(context.Background(), me.ID) })
+	if me.Email != email || me.Role != users.RoleAdmin {
+		t.Fatalf("unexpected provisioned user: %+v", me)
+	}
+
+	env.idp.SetUser(subject, map[string]any{"email": email, "groups": []string{"developers"}})
+	again := oidcLogin()
+	if again.ID != me.ID || again.Role != users.RoleUser {
+		t.Fatalf("expected the same user demoted, got %+v", again)
+	}
+
+	res, err := client.Get(env.baseURL + "/v1/auth/oidc/mock/callback?state=forged&code=x")
+	if err != nil {
+		t.Fatalf("forged callback: %v", err)
+	}
+	_ = res.Body.Close()
+	if res.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("forged callback status: %d", res.StatusCode)
+	}
+}
+
+func TestTwoFactorLogin(t *testing.T) {
+	env := newTestEnv(t)
+	client := newClient(t)
+
+	email := fmt.Sprintf("tfa_%s@local", internal.RandomHex(6))
+	password := "secret123"
+	created := createUser(t, client, env.baseURL, email, password)
+	t.Cleanup(func() { _ = env.users.Delete(context.Background(), created.ID) })
+	headers := map[string]string{"X-CSRF-Token": login(t, client, env.baseURL, email, password)}
+
+	res := doJSONWithHeaders(t, client, http.MethodPost, env.baseURL+"/v1/auth/2fa/totp", nil, headers)
+	var enrollment httpapi.TOTPEnrollmentResponse
+	err := json.NewDecoder(res.Body).Decode(&enrollment)
+	_ = res.Body.Close()
+	if res.StatusCode != http.StatusOK || err != nil || enrollment.Secret == "" || len(enrollment.QRPNG) == 0 {
+		t.Fatalf("enroll status: %d (%v)", res.StatusCode, err)
+	}
+
+	code, err := twofactor.GenerateCode(enrollment.Secret, time.Now())
+	if err != nil {
+		t.Fatalf("generate code: %v", err)
+	}
+	res = doJSONWithHeaders(t, client, http.MethodPost, env.baseURL+"/v1/auth/2fa/totp/confirm", httpapi.TwoFactorCodeDTO{Code: code}, headers)
+	var recovery httpapi.RecoveryCodesResponse
+	err = json.NewDecoder(res.Body).Decode(&recovery)
+	_ = res.Body.Close()
+	if res.StatusCode != http.StatusOK || err != nil || len(recovery.RecoveryCodes) == 0 {
+		t.Fatalf("confirm status: %d (%v)", res.StatusCode, err)
+	}
+
+	logout(t, client, env.baseURL)
+
+	res = doJSON(t, client, http.MethodPost, env.baseURL+"/v1/auth/login", httpapi.LoginRequest{Email: email, Password: password})
+	var pending httpapi.LoginResponse
+	err = json.NewDecoder(res.Body).Decode(&pending)
+	_ = res.Body.Close()
+	if res.StatusCode != http.StatusOK || err != nil || !pending.TwoFactorRequired || pending.CSRFToken != "" {
+		t.Fatalf("expected a pending login, got %d %+v (%v)", res.StatusCode, pending, err)
+	}
+
+	res = doJSON(t, client, http.MethodGet, env.baseURL+"/v1/users/me", nil)
+	_ = res.Body.Close()
+	if res.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("me status before the second factor: %d", res.StatusCode)
+	}
+
+	res = doJSON(t, client, http.MethodPost, env.baseURL+"/v1/auth/2fa", httpapi.TwoFactorCodeDTO{Code: recovery.RecoveryCodes[0]})
+	var done httpapi.LoginResponse
+	err = json.NewDecoder(res.Body).Decode(&done)
+	_ = res.Body.Close()
+	if res.StatusCode != http.StatusOK || err != nil || done.CSRFToken == "" {
+		t.Fatalf("2fa status: %d (%v)", res.StatusCode, err)
+	}
+
+	res = doJSON(t, client, http.MethodGet, env.baseURL+"/v1/users/me", nil)
+	_ = res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("me status after the second factor: %d", res.StatusCode)
+	}
+}
+
+// passkeyCeremony posts to a begin endpoint and answers the ceremony with
+// the authenticator.
+func passkeyCeremony(t *testing.T, client *http.Client, url string, headers map[string]string, answer func([]byte) ([]byte, error)) httpapi.WebAuthnFinishDTO {
+	t.Helper()
+	res := doJSONWithHeaders(t, client, http.MethodPost, url, nil, headers)
+	var c httpapi.WebAuthnCeremonyResponse
+	err := json.NewDecoder(res.Body).Decode(&c)
+	_ = res.Body.Close()
+	if res.StatusCode != http.StatusOK || err != nil || c.Ceremony == "" {
+		t.Fatalf("begin %s status: %d (%v)", url, res.StatusCode, err)
+	}
+	credential, err := answer(c.Options)
+	if err != nil {
+		t.Fatalf("authenticator: %v", err)
+	}
+	return httpapi.WebAuthnFinishDTO{Ceremony: c.Ceremony, Credential: credential}
+}
+
+func TestPasskeyLogin(t *testing.T) {
+	env := newTestEnv(t)
+	client := newClient(t)
+	authenticator := passkeytest.New("localhost", "http://localhost")
+
+	email := fmt.Sprintf("passkey_%s@local", internal.RandomHex(6))
+	password := "secret123"
+	created := createUser(t, client, env.baseURL, email, password)
+	t.Cleanup(func() { _ = env.users.Delete(context.Background(), created.ID) })
+	headers := map[string]string{"X-CSRF-Token": login(t, client, env.baseURL, email, password)}
+
+	finish := passkeyCeremony(t, client, env.baseURL+"/v1/auth/webauthn/register/begin", headers, authenticator.Create)
+	finish.Name = "laptop"
+	res := doJSONWithHeaders(t, client, http.MethodPost, env.baseURL+"/v1/auth/webauthn/register/finish", finish, headers)
+	var cred passkeys.Credential
+	err := json.NewDecoder(res.Body).Decode(&cred)
+	_ = res.Body.Close()
+	if res.StatusCode != http.StatusCreated || err != nil || cred.Name != "laptop" {
+		t.Fatalf("register status: %d %+v (%v)", res.StatusCode, cred, err)
+	}
+	logout(t, client, env.baseURL)
+
+	// Passwordless.
+	finish = passkeyCeremony(t, client, env.baseURL+"/v1/auth/webauthn/login/begin", nil, authenticator.Get)
+	res = doJSON(t, client, http.MethodPost, env.baseURL+"/v1/auth/webauthn/login/finish", finish)
+	var done httpapi.LoginResponse
+	err = json.NewDecoder(res.Body).Decode(&done)
+	_ = res.Body.Close()
+	if res.StatusCode != http.StatusOK || err != nil || done.CSRFToken == "" {
+		t.Fatalf("passkey login status: %d (%v)", res.StatusCode, err)
+	}
+
+	res = doJSON(t, client, http.MethodGet, env.baseURL+"/v1/auth/webauthn/credentials", nil)
+	var list []passkeys.Credential
+	err = json.NewDecoder(res.Body).Decode(&list)
+	_ = res.Body.Close()
+	if res.StatusCode != http.StatusOK || err != nil || len(list) != 1 || list[0].LastUsedAt == nil {
+		t.Fatalf("list status: %d %+v (%v)", res.StatusCode, list, err)
+	}
+	logout(t, client, env.baseURL)
+
+	// A password login now waits for the passkey as its second factor.
+	res = doJSON(t, client, http.MethodPost, env.baseURL+"/v1/auth/login", httpapi.LoginRequest{Email: email, Password: password})
+	var pending httpapi.LoginResponse
+	err = json.NewDecoder(res.Body).Decode(&pending)
+	_ = res.Body.Close()
+	if res.StatusCode != http.StatusOK || err != nil || !pending.TwoFactorRequired {
+		t.Fatalf("expected a pending login, got %d %+v (%v)", res.StatusCode, pending, err)
+	}
+	finish = passkeyCeremony(t, client, env.baseURL+"/v1/auth/webauthn/2fa/begin", nil, authenticator.Get)
+	res = doJSON(t, client, http.MethodPost, env.baseURL+"/v1/auth/webauthn/2fa/finish", finish)
+	err = json.NewDecoder(res.Body).Decode(&done)
+	_ = res.Body.Close()
+	if res.StatusCode != http.StatusOK || err != nil || done.CSRFToken == "" {
+		t.Fatalf("passkey second factor status: %d (%v)", res.StatusCode, err)
+	}
+
+	headers = map[string]string{"X-CSRF-Token": done.CSRFToken}
+	res = doJSONWithHeaders(t, client, http.MethodDelete, env.baseURL+"/v1/auth/webauthn/credentials/"+cred.ID, nil, headers)
+	_ = res.Body.Close()
+	if res.StatusCode != http.StatusNoContent {
+		t.Fatalf("delete status: %d", res.StatusCode)
+	}
+}
+
+var mailedToken = regexp.MustCompile(`token=([0-9a-f]{64})`)
+
+// lastMailedToken delivers the outbox and returns the token in the last mail
+// sent to email with subject.
+func lastMailedToken(t *testing.T, env *testEnv, email, subject string) string {
+	t.Helper()
+	if err := env.outbox.Check(context.Background()); err != nil {
+		t.Fatalf("outbox: %v", err)
+	}
+	token := ""
+	for _, m := range env.mailer.Sent() {
+		if m.To == email && m.Subject == subject {
+			if match := mailedToken.FindStringSubmatch(m.Body); match != nil {
+				token = match[1]
+			}
+		}
+	}
+	if token == "" {
+		t.Fatalf("no %q mail for %s", subject, email)
+	}
+	return token
+}
+
+func TestEmailVerification(t *testing.T) {
+	env := newTestEnv(t)
+	client := newClient(t)
+
+	email := fmt.Sprintf("verify_%s@local", internal.RandomHex(6))
+	created := createUser(t, client, env.baseURL, email, "secret123")
+	t.Cleanup(func() { _ = env.users.Delete(context.Background(), created.ID) })
+	token := lastMailedToken(t, env, email, "Confirm your email for Sniply")
+
+	for _, want := range []int{http.StatusNoContent, http.StatusBadRequest} {
+		res := doJSON(t, client, http.MethodPost, env.baseURL+"/v1/users/verify-email", httpapi.VerifyEmailDTO{Token: token})
+		_ = res.Body.Close()
+		if res.StatusCode != want {
+			t.Fatalf("verify status: %d, want %d", res.StatusCode, want)
+		}
+	}
+
+	headers := map[string]string{"X-CSRF-Token": login(t, client, env.baseURL, email, "secret123")}
+	res := doJSON(t, client, http.MethodGet, env.baseURL+"/v1/users/me", nil)
+	var me users.UserResponse
+	err := json.NewDecoder(res.Body).Decode(&me)
+	_ = res.Body.Close()
+	if err != nil || me.EmailVerifiedAt == nil {
+		t.Fatalf("expected a verified email: %+v (%v)", me, err)
+	}
+	res = doJSONWithHeaders(t, client, http.MethodPost, env.baseURL+"/v1/users/me/verify-email", nil, headers)
+	_ = res.Body.Close()
+	if res.StatusCode != http.StatusConflict {
+		t.Fatalf("resend status: %d", res.StatusCode)
+	}
+}
+
+func TestPasswordReset(t *testing.T) {
+	env := newTestEnv(t)
+	client := newClient(t)
+
+	email := fmt.Sprintf("reset_%s@local", internal.RandomHex(6))
+	created := createUser(t, client, env.baseURL, email, "secret123")
+	t.Cleanup(func() { _ = env.users.Delete(context.Background(), created.ID) })
+
+	for _, addr := range []string{email, "nobody_" + email} {
+		res := doJSON(t, client, http.MethodPost, env.baseURL+"/v1/auth/password/forgot", httpapi.ForgotPasswordDTO{Email: addr})
+		_ = res.Body.Close()
+		if res.StatusCode != http.StatusAccepted {
+			t.Fatalf("forgot status for %s: %d", addr, res.StatusCode)
+		}
+	}
+	token := lastMailedToken(t, env, email, "Reset your Sniply password")
+
+	for _, want := range []int{http.StatusNoContent, http.StatusBadRequest} {
+		res := doJSON(t, client, http.MethodPost, env.baseURL+"/v1/auth/password/reset", httpapi.ResetPasswordDTO{Token: token, Password: "newsecret123"})
+		_ = res.Body.Close()
+		if res.StatusCode != want {
+			t.Fatalf("reset status: %d, want %d", res.StatusCode, want)
+		}
+	}
+	login(t, client, env.baseURL, email, "newsecret123")
+}
+
+func TestSessionManagement(t *testing.T) {
+	env := newTestEnv(t)
+	laptop, phone := newClient(t), newClient(t)
+
+	email := fmt.Sprintf("sessions_%s@local", internal.RandomHex(6))
+	created := createUser(t, laptop, env.baseURL, email, "secret123")
+	t.Cleanup(func() { _ = env.users.Delete(context.Background(), created.ID) })
+	headers := map[string]string{"X-CSRF-Token": login(t, laptop, env.baseURL, email, "secret123")}
+	login(t, phone, env.baseURL, email, "secret123")
+
+	status := func(client *http.Client) int {
+		res := doJSON(t, client, http.MethodGet, env.baseURL+"/v1/users/me", nil)
+		_ = res.Body.Close()
+		return res.StatusCode
+	}
+
+	res := doJSON(t, laptop, http.MethodGet, env.baseURL+"/v1/auth/sessions", nil)
+	var list []auth.ActiveSession
+	err := json.NewDecoder(res.Body).Decode(&list)
+	_ = res.Body.Close()
+	if res.StatusCode != http.StatusOK || err != nil || len(list) != 2 || list[0].Current || !list[1].Current {
+		t.Fatalf("list status: %d %+v (%v)", res.StatusCode, list, err)
+	}
+	if list[0].IP == "" || list[0].LastSeenAt.IsZero() {
+		t.Fatalf("expected client details: %+v", list[0])
+	}
+
+	// Revoke the phone from the laptop.
+	res = doJSONWithHeaders(t, laptop, http.MethodDelete, env.baseURL+"/v1/auth/sessions/"+list[0].ID, nil, headers)
+	_ = res.Body.Close()
+	if res.StatusCode != http.StatusNoContent || status(phone) != http.StatusUnauthorized || status(laptop) != http.StatusOK {
+		t.Fatalf("revoke status: %d", res.StatusCode)
+	}
+
+	// A password change ends the other sessions only.
+	login(t, phone, env.baseURL, email, "secret123")
+	password := "newsecret123"
+	res = doJSONWithHeaders(t, laptop, http.MethodPut, env.baseURL+"/v1/users/me", httpapi.UserUpdateDTO{Password: &password}, headers)
+	_ = res.Body.Close()
+	if res.StatusCode != http.StatusNoContent || status(phone) != http.StatusUnauthorized || status(laptop) != http.StatusOK {
+		t.Fatalf("password change status: %d", res.StatusCode)
+	}
+
+	// Log out everywhere.
+	login(t, phone, env.baseURL, email, password)
+	res = doJSONWithHeaders(t, laptop, http.MethodDelete, env.baseURL+"/v1/auth/sessions", nil, headers)
+	_ = res.Body.Close()
+	if res.StatusCode != http.StatusNoContent || status(phone) != http.StatusUnauthorized || status(laptop) != http.StatusUnauthorized {
+		t.Fatalf("log out everywhere status: %d", res.StatusCode)
+	}
+}
+
+func TestRoleChangeReachesSessions(t *testing.T) {
+	env := newTestEnv(t)
+	adminClient, otherClient := newClient(t), newClient(t)
+
+	adminEmail := fmt.Sprintf("admin_%s@local", internal.RandomHex(6))
+	createAdminUser(t, env, adminEmail, "adminpass")
+	headers := map[string]string{"X-CSRF-Token": login(t, adminClient, env.baseURL, adminEmail, "adminpass")}
+
+	otherEmail := fmt.Sprintf("admin_%s@local", internal.RandomHex(6))
+	otherID := createAdminUser(t, env, otherEmail, "otherpass")
+	login(t, otherClient, env.baseURL, otherEmail, "otherpass")
+
+	listStatus := func() int {
+		res := doJSON(t, otherClient, http.MethodGet, env.baseURL+"/v1/users", nil)
+		_ = res.Body.Close()
+		return res.StatusCode
+	}
+	setRole := func(role string) {
+		res := doJSONWithHeaders(t, adminClient, http.MethodPut, env.baseURL+"/v1/users/"+otherID, map[string]string{"role": role}, headers)
+		_ = res.Body.Close()
+		if res.StatusCode != http.StatusNoContent {
+			t.Fatalf("set role status: %d", res.StatusCode)
+		}
+	}
+
+	if status := listStatus(); status != http.StatusOK {
+		t.Fatalf("users list status (admin): %d", status)
+	}
+
+	// Demoted mid-session: the same session loses admin access.
+	setRole("user")
+	if status := listStatus(); status != http.StatusForbidden {
+		t.Fatalf("users list status (demoted): %d", status)
+	}
+	res := doJSON(t, otherClient, http.MethodGet, env.baseURL+"/v1/users/me", nil)
+	_ = res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("expected the session kept: %d", res.StatusCode)
+	}
+
+	setRole("admin")
+	if status := listStatus(); status != http.StatusOK {
+		t.Fatalf("users list status (promoted): %d", status)
+	}
+}
+
 func TestUsersEndpoints(t *testing.T) {
 	env := newTestEnv(t)
 	client := newClient(t)
@@ -522,6 +1003,20 @@ func TestSnippetsEndpoints(t *testing.T) {
 		t.Fatal("expected snippets list")
 	}
 
+	res = doJSON(t, client, http.MethodGet, env.baseURL+"/v1/snippets/facets?facets=language,tags&creator="+me.ID, nil)
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("snippet facets status: %d", res.StatusCode)
+	}
+	var facets snippets.Facets
+	if err := json.NewDecoder(res.Body).Decode(&facets); err != nil {
+		t.Fatalf("decode snippet facets: %v", err)
+	}
+	if facets.Total != 1 || len(facets.Language) != 1 || facets.Language[0] != (snippets.FacetCount{Value: "python", Count: 1}) ||
+		len(facets.Tags) != 1 || facets.Creator != nil {
+		t.Fatalf("unexpected facets: %+v", facets)
+	}
+
 	updateReq := httpapi.SnippetCreateDTO{
 		Name:       "Updated",
 		Content:    "print('updated')",
@@ -570,6 +1065,35 @@ func TestSnippetsEndpoints(t *testing.T) {
 		t.Fatalf("private snippets list status: %d", res.StatusCode)
 	}
 
+	// The owner reads a private snippet the same way with or without a
+	// selection; other users never do.
+	stranger := newClient(t)
+	strangerEmail := fmt.Sprintf("ci_%s@local", internal.RandomHex(6))
+	strangerUser := createUser(t, stranger, env.baseURL, strangerEmail, "secret123")
+	t.Cleanup(func() { _ = env.users.Delete(context.Background(), strangerUser.ID) })
+	strangerCSRF := login(t, stranger, env.baseURL, strangerEmail, "secret123")
+	for _, suffix := range []string{"", "?lines=1"} {
+		res = doJSON(t, client, http.MethodGet, env.baseURL+"/v1/snippets/"+privateSnippet.ID+suffix, nil)
+		_ = res.Body.Close()
+		if res.StatusCode != http.StatusOK {
+			t.Fatalf("owner private get%s status: %d", suffix, res.StatusCode)
+		}
+		res = doJSON(t, stranger, http.MethodGet, env.baseURL+"/v1/snippets/"+privateSnippet.ID+suffix, nil)
+		_ = res.Body.Close()
+		if res.StatusCode != http.StatusNotFound {
+			t.Fatalf("stranger private get%s status: %d", suffix, res.StatusCode)
+		}
+	}
+
+	// Nor do they update someone else's snippet, even a public one.
+	res = doJSONWithHeaders(t, stranger, http.MethodPut, env.baseURL+"/v1/snippets/"+newSnippet.ID, updateReq, map[string]string{
+		"X-CSRF-Token": strangerCSRF,
+	})
+	_ = res.Body.Close()
+	if res.StatusCode != http.StatusNotFound {
+		t.Fatalf("stranger update status: %d", res.StatusCode)
+	}
+
 	res = doJSONWithHeaders(t, client, http.MethodDelete, env.baseURL+"/v1/snippets/"+newSnippet.ID, nil, map[string]string{
 		"X-CSRF-Token": csrf,
 	})
@@ -666,3 +1190,83 @@ func TestAPIKeysAuth(t *testing.T) {
 		t.Fatalf("revoked api key status: %d", res.StatusCode)
 	}
 }
+
+func TestAPIKeyExpiryUsageAndRotation(t *testing.T) {
+	env := newTestEnv(t)
+	client := newClient(t)
+
+	email := fmt.Sprintf("ci_%s@local", internal.RandomHex(6))
+	created := createUser(t, client, env.baseURL, email, "secret123")
+	t.Cleanup(func() { _ = env.users.Delete(context.Background(), created.ID) })
+	headers := map[string]string{"X-CSRF-Token": login(t, client, env.baseURL, email, "secret123")}
+
+	status := func(token string) int {
+		res := doJSONWithHeaders(t, client, http.MethodGet, env.baseURL+"/v1/users/me", nil, map[string]string{"X-API-Key": token})
+		_ = res.Body.Close()
+		return res.StatusCode
+	}
+	rotate := func(id string, body any) apiKeyCreateResponse {
+		res := doJSONWithHeaders(t, client, http.MethodPost, env.baseURL+"/v1/auth/api-keys/"+id+"/rotate", body, headers)
+		defer res.Body.Close()
+		var out apiKeyCreateResponse
+		if err := json.NewDecoder(res.Body).Decode(&out); err != nil || res.StatusCode != http.StatusCreated || out.Token == "" {
+			t.Fatalf("rotate status: %d (%v)", res.StatusCode, err)
+		}
+		return out
+	}
+
+	past := time.Now().Add(-time.Hour)
+	res := doJSONWithHeaders(t, client, http.MethodPost, env.baseURL+"/v1/auth/api-keys", httpapi.APIKeyCreateDTO{Name: "old", ExpiresAt: &past}, headers)
+	_ = res.Body.Close()
+	if res.StatusCode != http.StatusBadRequest {
+		t.Fatalf("past expiry status: %d", res.StatusCode)
+	}
+
+	expiresAt := time.Now().Add(48 * time.Hour).UTC().Truncate(time.Second)
+	res = doJSONWithHeaders(t, client, http.MethodPost, env.baseURL+"/v1/auth/api-keys", httpapi.APIKeyCreateDTO{Name: "ci", ExpiresAt: &expiresAt}, headers)
+	var key apiKeyCreateResponse
+	err := json.NewDecoder(res.Body).Decode(&key)
+	_ = res.Body.Close()
+	if res.StatusCode != http.StatusCreated || err != nil || key.ExpiresAt == nil || !key.ExpiresAt.Equal(expiresAt) {
+		t.Fatalf("create status: %d %+v (%v)", res.StatusCode, key, err)
+	}
+
+	// Usage is written in batches, not by the request.
+	if status(key.Token) != http.StatusOK {
+		t.Fatal("expected the key to work")
+	}
+	if err := env.keyUsage.Flush(context.Background()); err != nil {
+		t.Fatalf("flush usage: %v", err)
+	}
+	stored, err := env.apiKeys.GetByID(context.Background(), key.ID)
+	if err != nil || stored.LastUsedAt == nil || stored.LastUsedIP == "" {
+		t.Fatalf("expected usage recorded: %+v (%v)", stored, err)
+	}
+
+	// The old token keeps working through the default grace period, and the
+	// new one keeps the lifetime of the old.
+	next := rotate(key.ID, nil)
+	if status(key.Token) != http.StatusOK || status(next.Token) != http.StatusOK {
+		t.Fatal("expected both tokens to work during the grace period")
+	}
+	if next.Name != "ci" || next.ExpiresAt == nil || next.ExpiresAt.Before(expiresAt) {
+		t.Fatalf("unexpected rotated key: %+v", next)
+	}
+	// A rotated key has its successor and cannot be rotated again.
+	res = doJSONWithHeaders(t, client, http.MethodPost, env.baseURL+"/v1/auth/api-keys/"+key.ID+"/rotate", nil, headers)
+	_ = res.Body.Close()
+	if res.StatusCode != http.StatusConflict {
+		t.Fatalf("rotate twice status: %d", res.StatusCode)
+	}
+
+	// Without a grace period the old token stops at once.
+	last := rotate(next.ID, map[string]int64{"grace_period_seconds": 0})
+	if status(next.Token) != http.StatusUnauthorized || status(last.Token) != http.StatusOK {
+		t.Fatal("expected only the new token to work")
+	}
+	res = doJSONWithHeaders(t, client, http.MethodPost, env.baseURL+"/v1/auth/api-keys/"+next.ID+"/rotate", nil, headers)
+	_ = res.Body.Close()
+	if res.StatusCode != http.StatusConflict {
+		t.Fatalf("rotate expired status: %d", res.StatusCode)
+	}
+}
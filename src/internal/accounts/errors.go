@@ -0,0 +1,15 @@
+package accounts
+
+import (
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrNotFound means a token is unknown, used, expired or no longer matches
+// the user's email.
+var ErrNotFound = errors.New("token not found")
+
+func IsNotFound(err error) bool {
+	return errors.Is(err, pgx.ErrNoRows) || errors.Is(err, ErrNotFound)
+}
@@ -0,0 +1,19 @@
+package accounts
+
+import "time"
+
+type Purpose string
+
+const (
+	PurposePasswordReset     Purpose = "password_reset"
+	PurposeEmailVerification Purpose = "email_verification"
+)
+
+// Token is a single-use token mailed to a user. Only its hash is stored.
+type Token struct {
+	Hash      string
+	UserID    string
+	Purpose   Purpose
+	Email     string
+	ExpiresAt time.Time
+}
@@ -0,0 +1,115 @@
+package accounts
+
+import (
+	"context"
+	"time"
+
+	"github.com/PabloPavan/sniply_api/internal/db"
+	"github.com/PabloPavan/sniply_api/internal/mail"
+	"github.com/jackc/pgx/v5"
+)
+
+type Repository struct {
+	base *db.Base
+}
+
+func NewRepository(base *db.Base) *Repository {
+	return &Repository{base: base}
+}
+
+const (
+	sqlTokensDeleteUnused = `DELETE FROM user_tokens
+		WHERE user_id = $1 AND purpose = $2 AND used_at IS NULL`
+
+	sqlTokenInsert = `INSERT INTO user_tokens (token_hash, user_id, purpose, email, expires_at)
+		VALUES ($1, $2, $3, $4, $5)`
+
+	sqlTokenUse = `UPDATE user_tokens
+		SET used_at = now()
+		WHERE token_hash = $1 AND purpose = $2 AND used_at IS NULL AND expires_at > $3
+		RETURNING user_id, email`
+
+	// A reset link also proves the user reads the address it was sent to.
+	sqlUserResetPassword = `UPDATE users
+		SET password_hash = $3, email_verified_at = coalesce(email_verified_at, now())
+		WHERE id = $1 AND email = $2`
+
+	sqlUserVerifyEmail = `UPDATE users
+		SET email_verified_at = now()
+		WHERE id = $1 AND email = $2`
+)
+
+// Issue stores t in place of the user's unused tokens for the same purpose
+// and queues m in the same transaction.
+func (r *Repository) Issue(ctx context.Context, t *Token, m mail.Message) error {
+	return r.base.WithTx(ctx, func(ctx context.Context, tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, sqlTokensDeleteUnused, t.UserID, t.Purpose); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(ctx, sqlTokenInsert, t.Hash, t.UserID, t.Purpose, t.Email, t.ExpiresAt); err != nil {
+			return err
+		}
+		return mail.Enqueue(ctx, tx, m)
+	})
+}
+
+// ResetPassword uses a reset token and sets the password of its user,
+// returning the user ID.
+func (r *Repository) ResetPassword(ctx context.Context, hash string, now time.Time, passwordHash string) (string, error) {
+	var userID string
+	err := r.base.WithTx(ctx, func(ctx context.Context, tx pgx.Tx) error {
+		id, email, err := useToken(ctx, tx, hash, PurposePasswordReset, now)
+		if err != nil {
+			return err
+		}
+		if err := updateUser(ctx, tx, sqlUserResetPassword, id, email, passwordHash); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(ctx, sqlTokensDeleteUnused, id, PurposePasswordReset); err != nil {
+			return err
+		}
+		userID = id
+		return nil
+	})
+	return userID, err
+}
+
+// VerifyEmail uses a verification token and marks its email verified,
+// returning the user ID.
+func (r *Repository) VerifyEmail(ctx context.Context, hash string, now time.Time) (string, error) {
+	var userID string
+	err := r.base.WithTx(ctx, func(ctx context.Context, tx pgx.Tx) error {
+		id, email, err := useToken(ctx, tx, hash, PurposeEmailVerification, now)
+		if err != nil {
+			return err
+		}
+		if err := updateUser(ctx, tx, sqlUserVerifyEmail, id, email); err != nil {
+			return err
+		}
+		userID = id
+		return nil
+	})
+	return userID, err
+}
+
+func useToken(ctx context.Context, tx pgx.Tx, hash string, purpose Purpose, now time.Time) (string, string, error) {
+	var userID, email string
+	err := tx.QueryRow(ctx, sqlTokenUse, hash, purpose, now).Scan(&userID, &email)
+	if IsNotFound(err) {
+		return "", "", ErrNotFound
+	}
+	return userID, email, err
+}
+
+// updateUser fails with ErrNotFound, rolling the token back, when the user
+// changed email after the token was sent.
+func updateUser(ctx context.Context, tx pgx.Tx, query string, args ...any) error {
+	tag, err := tx.Exec(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
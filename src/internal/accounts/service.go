@@ -0,0 +1,284 @@
+package accounts
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/PabloPavan/sniply_api/internal"
+	"github.com/PabloPavan/sniply_api/internal/apperrors"
+	"github.com/PabloPavan/sniply_api/internal/identity"
+	"github.com/PabloPavan/sniply_api/internal/mail"
+	"github.com/PabloPavan/sniply_api/internal/users"
+)
+
+const (
+	DefaultResetTTL  = time.Hour
+	DefaultVerifyTTL = 48 * time.Hour
+)
+
+type Store interface {
+	Issue(ctx context.Context, t *Token, m mail.Message) error
+	ResetPassword(ctx context.Context, hash string, now time.Time, passwordHash string) (string, error)
+	VerifyEmail(ctx context.Context, hash string, now time.Time) (string, error)
+}
+
+type UserLookup interface {
+	GetByEmail(ctx context.Context, email string) (users.User, error)
+	GetByID(ctx context.Context, id string) (*users.User, error)
+}
+
+type SessionRevoker interface {
+	DeleteUser(ctx context.Context, userID, keepID string) error
+}
+
+type RateLimiter interface {
+	Allow(ctx context.Context, key string) (bool, time.Duration, error)
+}
+
+type Service struct {
+	Store          Store
+	Users          UserLookup
+	PasswordHasher func(plain string) (string, error)
+	Limiter        RateLimiter
+	// Sessions, when set, are all revoked by a password reset.
+	Sessions SessionRevoker
+	// ResetURL and VerifyURL are the pages the mailed links open; the token
+	// is added as the "token" query parameter.
+	ResetURL  string
+	VerifyURL string
+	ResetTTL  time.Duration
+	VerifyTTL time.Duration
+	Now       func() time.Time
+}
+
+// ForgotPassword mails a password reset link. It succeeds for unknown
+// emails too, so it cannot be used to find accounts.
+func (s *Service) ForgotPassword(ctx context.Context, email, clientIP string) error {
+	if s.Store == nil || s.Users == nil {
+		return apperrors.New(apperrors.KindInternal, "accounts not configured")
+	}
+	email = strings.TrimSpace(strings.ToLower(email))
+	if email == "" {
+		return apperrors.New(apperrors.KindInvalidInput, "email is required")
+	}
+	if strings.TrimSpace(clientIP) != "" {
+		if err := s.allow(ctx, "forgot:ip:"+clientIP); err != nil {
+			return err
+		}
+	}
+	if err := s.allow(ctx, "forgot:email:"+email); err != nil {
+		return err
+	}
+
+	u, err := s.Users.GetByEmail(ctx, email)
+	if users.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return apperrors.New(apperrors.KindInternal, "failed to load user")
+	}
+
+	ttl := s.ResetTTL
+	if ttl <= 0 {
+		ttl = DefaultResetTTL
+	}
+	return s.issue(ctx, u.ID, u.Email, PurposePasswordReset, ttl, func(link string) mail.Message {
+		return mail.Message{
+			To:      u.Email,
+			Subject: "Reset your Sniply password",
+			Body: fmt.Sprintf("Someone asked to reset the password of your Sniply account.\n\n"+
+				"Open this link within %s to choose a new one:\n\n%s\n\n"+
+				"If it was not you, ignore this message; your password is unchanged.", duration(ttl), link),
+		}
+	})
+}
+
+// ResetPassword sets a new password with a token from ForgotPassword.
+func (s *Service) ResetPassword(ctx context.Context, token, password string) error {
+	if s.Store == nil {
+		return apperrors.New(apperrors.KindInternal, "accounts not configured")
+	}
+	token = strings.TrimSpace(token)
+	password = strings.TrimSpace(password)
+	if token == "" || password == "" {
+		return apperrors.New(apperrors.KindInvalidInput, "token and password are required")
+	}
+
+	hasher := s.PasswordHasher
+	if hasher == nil {
+		hasher = internal.DefaultPasswordHasher
+	}
+	hash, err := hasher(password)
+	if err != nil {
+		return apperrors.New(apperrors.KindInternal, "failed to process password")
+	}
+
+	userID, err := s.Store.ResetPassword(ctx, hashToken(token), s.now(), hash)
+	if err != nil {
+		if IsNotFound(err) {
+			return apperrors.New(apperrors.KindInvalidInput, "invalid or expired token")
+		}
+		return apperrors.New(apperrors.KindInternal, "failed to reset password")
+	}
+	if s.Sessions != nil {
+		if err := s.Sessions.DeleteUser(ctx, userID, ""); err != nil {
+			return apperrors.New(apperrors.KindInternal, "failed to revoke sessions")
+		}
+	}
+	return nil
+}
+
+// RequestVerification mails the current user a new verification link.
+func (s *Service) RequestVerification(ctx context.Context) error {
+	if s.Store == nil || s.Users == nil {
+		return apperrors.New(apperrors.KindInternal, "accounts not configured")
+	}
+	userID, ok := identity.UserID(ctx)
+	if !ok || strings.TrimSpace(userID) == "" {
+		return apperrors.New(apperrors.KindUnauthorized, "unauthorized")
+	}
+
+	u, err := s.Users.GetByID(ctx, userID)
+	if err != nil {
+		if users.IsNotFound(err) {
+			return apperrors.New(apperrors.KindNotFound, "user not found")
+		}
+		return apperrors.New(apperrors.KindInternal, "failed to load user")
+	}
+	if u.EmailVerifiedAt != nil {
+		return apperrors.New(apperrors.KindConflict, "email already verified")
+	}
+	if err := s.allow(ctx, "verify:user:"+u.ID); err != nil {
+		return err
+	}
+	return s.sendVerification(ctx, u.ID, u.Email)
+}
+
+// SendVerification mails a verification link for email, unless the user
+// has since moved to another address or already verified this one.
+func (s *Service) SendVerification(ctx context.Context, userID, email string) error {
+	if s.Store == nil || s.Users == nil {
+		return apperrors.New(apperrors.KindInternal, "accounts not configured")
+	}
+	u, err := s.Users.GetByID(ctx, userID)
+	if err != nil {
+		if users.IsNotFound(err) {
+			return nil
+		}
+		return apperrors.New(apperrors.KindInternal, "failed to load user")
+	}
+	if u.Email != email || u.EmailVerifiedAt != nil {
+		return nil
+	}
+	return s.sendVerification(ctx, u.ID, u.Email)
+}
+
+func (s *Service) sendVerification(ctx context.Context, userID, email string) error {
+	ttl := s.VerifyTTL
+	if ttl <= 0 {
+		ttl = DefaultVerifyTTL
+	}
+	return s.issue(ctx, userID, email, PurposeEmailVerification, ttl, func(link string) mail.Message {
+		return mail.Message{
+			To:      email,
+			Subject: "Confirm your email for Sniply",
+			Body: fmt.Sprintf("Open this link within %s to confirm this address for your Sniply account:\n\n%s\n\n"+
+				"If you did not sign up, ignore this message.", duration(ttl), link),
+		}
+	})
+}
+
+// VerifyEmail marks an email verified with a token from a verification
+// link. The token alone identifies the user.
+func (s *Service) VerifyEmail(ctx context.Context, token string) error {
+	if s.Store == nil {
+		return apperrors.New(apperrors.KindInternal, "accounts not configured")
+	}
+	token = strings.TrimSpace(token)
+	if token == "" {
+		return apperrors.New(apperrors.KindInvalidInput, "token is required")
+	}
+
+	if _, err := s.Store.VerifyEmail(ctx, hashToken(token), s.now()); err != nil {
+		if IsNotFound(err) {
+			return apperrors.New(apperrors.KindInvalidInput, "invalid or expired token")
+		}
+		return apperrors.New(apperrors.KindInternal, "failed to verify email")
+	}
+	return nil
+}
+
+func (s *Service) issue(ctx context.Context, userID, email string, purpose Purpose, ttl time.Duration, message func(link string) mail.Message) error {
+	token := internal.RandomHex(32)
+	base := s.VerifyURL
+	if purpose == PurposePasswordReset {
+		base = s.ResetURL
+	}
+
+	t := &Token{
+		Hash:      hashToken(token),
+		UserID:    userID,
+		Purpose:   purpose,
+		Email:     email,
+		ExpiresAt: s.now().Add(ttl),
+	}
+	if err := s.Store.Issue(ctx, t, message(link(base, token))); err != nil {
+		return apperrors.New(apperrors.KindInternal, "failed to send email")
+	}
+	return nil
+}
+
+func (s *Service) allow(ctx context.Context, key string) error {
+	if s.Limiter == nil {
+		return nil
+	}
+	allowed, retryAfter, err := s.Limiter.Allow(ctx, key)
+	if err != nil {
+		return apperrors.New(apperrors.KindInternal, "rate limit error")
+	}
+	if !allowed {
+		return apperrors.RateLimit("too many requests", retryAfter)
+	}
+	return nil
+}
+
+func (s *Service) now() time.Time {
+	if s.Now != nil {
+		return s.Now().UTC()
+	}
+	return time.Now().UTC()
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// duration writes d for people, e.g. "1 hour" or "30 minutes".
+func duration(d time.Duration) string {
+	n, unit := int(d/time.Minute), "minute"
+	if d >= time.Hour && d%time.Hour == 0 {
+		n, unit = int(d/time.Hour), "hour"
+	}
+	if n == 1 {
+		return "1 " + unit
+	}
+	return fmt.Sprintf("%d %ss", n, unit)
+}
+
+// link adds token to base as the "token" query parameter.
+func link(base, token string) string {
+	u, err := url.Parse(base)
+	if err != nil {
+		return base + "?token=" + url.QueryEscape(token)
+	}
+	q := u.Query()
+	q.Set("token", token)
+	u.RawQuery = q.Encode()
+	return u.String()
+}
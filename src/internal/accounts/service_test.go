@@ -0,0 +1,264 @@
+package accounts
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/PabloPavan/sniply_api/internal/apperrors"
+	"github.com/PabloPavan/sniply_api/internal/identity"
+	"github.com/PabloPavan/sniply_api/internal/mail"
+	"github.com/PabloPavan/sniply_api/internal/users"
+)
+
+type issued struct {
+	token Token
+	used  bool
+}
+
+// storeStub keeps tokens and users in memory, and mail in a MemoryMailer
+// instead of the outbox.
+type storeStub struct {
+	tokens map[string]*issued
+	users  map[string]*users.User
+	mailer *mail.MemoryMailer
+}
+
+func newStoreStub(list ...users.User) *storeStub {
+	s := &storeStub{tokens: map[string]*issued{}, users: map[string]*users.User{}, mailer: &mail.MemoryMailer{}}
+	for i := range list {
+		s.users[list[i].ID] = &list[i]
+	}
+	return s
+}
+
+func (s *storeStub) Issue(ctx context.Context, t *Token, m mail.Message) error {
+	for hash, it := range s.tokens {
+		if it.token.UserID == t.UserID && it.token.Purpose == t.Purpose && !it.used {
+			delete(s.tokens, hash)
+		}
+	}
+	s.tokens[t.Hash] = &issued{token: *t}
+	return s.mailer.Send(ctx, m)
+}
+
+func (s *storeStub) use(hash string, purpose Purpose, now time.Time) (*users.User, error) {
+	it, ok := s.tokens[hash]
+	if !ok || it.used || it.token.Purpose != purpose || !it.token.ExpiresAt.After(now) {
+		return nil, ErrNotFound
+	}
+	u, ok := s.users[it.token.UserID]
+	if !ok || u.Email != it.token.Email {
+		return nil, ErrNotFound
+	}
+	it.used = true
+	return u, nil
+}
+
+func (s *storeStub) ResetPassword(ctx context.Context, hash string, now time.Time, passwordHash string) (string, error) {
+	u, err := s.use(hash, PurposePasswordReset, now)
+	if err != nil {
+		return "", err
+	}
+	u.PasswordHash = passwordHash
+	return u.ID, nil
+}
+
+func (s *storeStub) VerifyEmail(ctx context.Context, hash string, now time.Time) (string, error) {
+	u, err := s.use(hash, PurposeEmailVerification, now)
+	if err != nil {
+		return "", err
+	}
+	u.EmailVerifiedAt = &now
+	return u.ID, nil
+}
+
+func (s *storeStub) GetByEmail(ctx context.Context, email string) (users.User, error) {
+	for _, u := range s.users {
+		if u.Email == email {
+			return *u, nil
+		}
+	}
+	return users.User{}, users.ErrNotFound
+}
+
+func (s *storeStub) GetByID(ctx context.Context, id string) (*users.User, error) {
+	if u, ok := s.users[id]; ok {
+		cp := *u
+		return &cp, nil
+	}
+	return nil, users.ErrNotFound
+}
+
+type revokerStub struct {
+	revoked []string
+}
+
+func (r *revokerStub) DeleteUser(ctx context.Context, userID, keepID string) error {
+	r.revoked = append(r.revoked, userID+" keep:"+keepID)
+	return nil
+}
+
+var tokenInLink = regexp.MustCompile(`token=([0-9a-f]{64})`)
+
+func lastToken(t *testing.T, m *mail.MemoryMailer) string {
+	t.Helper()
+	sent := m.Sent()
+	if len(sent) == 0 {
+		t.Fatal("expected a mail")
+	}
+	match := tokenInLink.FindStringSubmatch(sent[len(sent)-1].Body)
+	if match == nil {
+		t.Fatalf("no token in mail: %q", sent[len(sent)-1].Body)
+	}
+	return match[1]
+}
+
+func newTestService(store *storeStub, now *time.Time) *Service {
+	return &Service{
+		Store:          store,
+		Users:          store,
+		PasswordHasher: func(plain string) (string, error) { return "hash:" + plain, nil },
+		ResetURL:       "https://sniply.test/reset?lang=en",
+		VerifyURL:      "https://sniply.test/verify",
+		Now:            func() time.Time { return *now },
+	}
+}
+
+func TestServicePasswordReset(t *testing.T) {
+	store := newStoreStub(users.User{ID: "usr_1", Email: "ana@local", PasswordHash: "old"})
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	svc := newTestService(store, &now)
+	revoker := &revokerStub{}
+	svc.Sessions = revoker
+	ctx := context.Background()
+
+	// Unknown emails look the same to the caller but send nothing.
+	if err := svc.ForgotPassword(ctx, "nobody@local", ""); err != nil {
+		t.Fatalf("forgot unknown: %v", err)
+	}
+	if len(store.mailer.Sent()) != 0 {
+		t.Fatal("expected no mail for an unknown email")
+	}
+
+	if err := svc.ForgotPassword(ctx, " Ana@Local ", ""); err != nil {
+		t.Fatalf("forgot: %v", err)
+	}
+	sent := store.mailer.Sent()
+	if len(sent) != 1 || sent[0].To != "ana@local" || !regexp.MustCompile(`https://sniply\.test/reset\?lang=en&token=`).MatchString(sent[0].Body) {
+		t.Fatalf("unexpected mail: %+v", sent)
+	}
+	first := lastToken(t, store.mailer)
+	for hash := range store.tokens {
+		if hash == first {
+			t.Fatal("expected the token to be stored hashed")
+		}
+	}
+
+	// A new request replaces the unused token.
+	if err := svc.ForgotPassword(ctx, "ana@local", ""); err != nil {
+		t.Fatalf("forgot again: %v", err)
+	}
+	token := lastToken(t, store.mailer)
+	assertKind(t, svc.ResetPassword(ctx, first, "new-pass"), apperrors.KindInvalidInput)
+
+	if err := svc.ResetPassword(ctx, token, " new-pass "); err != nil {
+		t.Fatalf("reset: %v", err)
+	}
+	if store.users["usr_1"].PasswordHash != "hash:new-pass" {
+		t.Fatalf("password not changed: %q", store.users["usr_1"].PasswordHash)
+	}
+	if len(revoker.revoked) != 1 || revoker.revoked[0] != "usr_1 keep:" {
+		t.Fatalf("expected every session revoked: %v", revoker.revoked)
+	}
+	// Single use.
+	assertKind(t, svc.ResetPassword(ctx, token, "other"), apperrors.KindInvalidInput)
+	assertKind(t, svc.ResetPassword(ctx, "", "other"), apperrors.KindInvalidInput)
+
+	// Expired.
+	if err := svc.ForgotPassword(ctx, "ana@local", ""); err != nil {
+		t.Fatalf("forgot: %v", err)
+	}
+	token = lastToken(t, store.mailer)
+	now = now.Add(DefaultResetTTL)
+	assertKind(t, svc.ResetPassword(ctx, token, "late"), apperrors.KindInvalidInput)
+}
+
+func TestServiceEmailVerification(t *testing.T) {
+	store := newStoreStub(users.User{ID: "usr_1", Email: "ana@local"})
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	svc := newTestService(store, &now)
+	ctx := identity.WithUser(context.Background(), "usr_1", string(users.RoleUser))
+
+	// Stale requests, for an address the user left, are dropped.
+	if err := svc.SendVerification(ctx, "usr_1", "old@local"); err != nil {
+		t.Fatalf("send stale: %v", err)
+	}
+	if len(store.mailer.Sent()) != 0 {
+		t.Fatal("expected no mail for a stale address")
+	}
+
+	if err := svc.SendVerification(ctx, "usr_1", "ana@local"); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	token := lastToken(t, store.mailer)
+
+	// The token no longer applies once the email changes.
+	store.users["usr_1"].Email = "ana@work"
+	assertKind(t, svc.VerifyEmail(ctx, token), apperrors.KindInvalidInput)
+
+	if err := svc.RequestVerification(ctx); err != nil {
+		t.Fatalf("request: %v", err)
+	}
+	if sent := store.mailer.Sent(); sent[len(sent)-1].To != "ana@work" {
+		t.Fatalf("unexpected recipient: %+v", sent[len(sent)-1])
+	}
+	token = lastToken(t, store.mailer)
+	if err := svc.VerifyEmail(context.Background(), token); err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+	if store.users["usr_1"].EmailVerifiedAt == nil {
+		t.Fatal("expected the email to be verified")
+	}
+	assertKind(t, svc.VerifyEmail(ctx, token), apperrors.KindInvalidInput)
+	assertKind(t, svc.RequestVerification(ctx), apperrors.KindConflict)
+	assertKind(t, svc.RequestVerification(context.Background()), apperrors.KindUnauthorized)
+}
+
+type limiterStub struct{ allowed bool }
+
+func (l limiterStub) Allow(ctx context.Context, key string) (bool, time.Duration, error) {
+	return l.allowed, time.Minute, nil
+}
+
+func TestServiceRateLimitsAndFailures(t *testing.T) {
+	store := newStoreStub(users.User{ID: "usr_1", Email: "ana@local"})
+	now := time.Now()
+	svc := newTestService(store, &now)
+	svc.Limiter = limiterStub{allowed: false}
+
+	err := svc.ForgotPassword(context.Background(), "ana@local", "10.0.0.1")
+	assertKind(t, err, apperrors.KindRateLimited)
+
+	svc.Limiter = limiterStub{allowed: true}
+	store.mailer.Err = errors.New("outbox down")
+	assertKind(t, svc.ForgotPassword(context.Background(), "ana@local", "10.0.0.1"), apperrors.KindInternal)
+
+	assertKind(t, (&Service{}).ResetPassword(context.Background(), "t", "p"), apperrors.KindInternal)
+}
+
+func assertKind(t *testing.T, err error, kind apperrors.Kind) {
+	t.Helper()
+	if err == nil {
+		t.Fatalf("expected error kind %s", kind)
+	}
+	var appErr *apperrors.Error
+	if !errors.As(err, &appErr) {
+		t.Fatalf("expected app error, got: %v", err)
+	}
+	if appErr.Kind != kind {
+		t.Fatalf("unexpected kind: %s", appErr.Kind)
+	}
+}
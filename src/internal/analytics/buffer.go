@@ -0,0 +1,64 @@
+package analytics
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrBusy is returned by Drain when another process is flushing.
+var ErrBusy = errors.New("buffer is being flushed")
+
+// Buffer accumulates counts between flushes. Drain returns everything
+// pending; the counts stay claimed until Ack, so a failed flush is retried
+// on the next Drain instead of being lost.
+type Buffer interface {
+	Add(ctx context.Context, c Count) error
+	Drain(ctx context.Context) ([]Count, error)
+	Ack(ctx context.Context) error
+}
+
+type MemoryBuffer struct {
+	mu       sync.Mutex
+	pending  map[countKey]int64
+	draining map[countKey]int64
+}
+
+type countKey struct {
+	snippetID string
+	kind      Kind
+	bucket    int64
+}
+
+func NewMemoryBuffer() *MemoryBuffer {
+	return &MemoryBuffer{pending: make(map[countKey]int64)}
+}
+
+func (b *MemoryBuffer) Add(_ context.Context, c Count) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.pending[countKey{c.SnippetID, c.Kind, c.Bucket.Unix()}] += c.N
+	return nil
+}
+
+func (b *MemoryBuffer) Drain(_ context.Context) ([]Count, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.draining == nil {
+		b.draining = b.pending
+		b.pending = make(map[countKey]int64)
+	}
+	out := make([]Count, 0, len(b.draining))
+	for k, n := range b.draining {
+		out = append(out, Count{SnippetID: k.snippetID, Kind: k.kind, Bucket: time.Unix(k.bucket, 0).UTC(), N: n})
+	}
+	return out, nil
+}
+
+func (b *MemoryBuffer) Ack(_ context.Context) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.draining = nil
+	return nil
+}
@@ -0,0 +1,187 @@
+package analytics
+
+import (
+	"context"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PabloPavan/sniply_api/internal"
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisBuffer keeps pending counts in a hash shared by every API instance.
+// Drain renames the hash to a batch key of its own, so new events keep
+// flowing while the batch is written and no other instance can read it;
+// a lock keeps flushes from running side by side.
+type RedisBuffer struct {
+	Client  *redis.Client
+	Prefix  string
+	LockTTL time.Duration
+	// OrphanTTL is how long a batch may stay unacknowledged before another
+	// instance takes it over, assuming its owner is gone.
+	OrphanTTL time.Duration
+
+	mu      sync.Mutex
+	token   string
+	claimed []string
+}
+
+func NewRedisBuffer(client *redis.Client, prefix string) *RedisBuffer {
+	p := strings.TrimSpace(prefix)
+	if p == "" {
+		p = "sniply:views:"
+	}
+	return &RedisBuffer{Client: client, Prefix: p, LockTTL: time.Minute, OrphanTTL: 10 * time.Minute}
+}
+
+func (b *RedisBuffer) pendingKey() string           { return b.Prefix + "pending" }
+func (b *RedisBuffer) batchesKey() string           { return b.Prefix + "batches" }
+func (b *RedisBuffer) batchKey(token string) string { return b.Prefix + "batch:" + token }
+func (b *RedisBuffer) lockKey() string              { return b.Prefix + "lock" }
+
+// claimScript moves the pending hash to a new batch and registers it with
+// the time of the claim. Batches given in ARGV, still held by the caller,
+// are touched so nobody mistakes them for orphans; batches nobody touched
+// since the cutoff are taken over. It returns whether a batch was created,
+// followed by the orphans.
+var claimScript = redis.NewScript(`
+local created = 0
+if redis.call("EXISTS", KEYS[1]) == 1 then
+  redis.call("RENAME", KEYS[1], KEYS[2])
+  redis.call("ZADD", KEYS[3], ARGV[1], KEYS[2])
+  created = 1
+end
+for i = 3, #ARGV do
+  redis.call("ZADD", KEYS[3], "XX", ARGV[1], ARGV[i])
+end
+local orphans = redis.call("ZRANGEBYSCORE", KEYS[3], "-inf", ARGV[2])
+for _, key in ipairs(orphans) do
+  redis.call("ZADD", KEYS[3], ARGV[1], key)
+end
+table.insert(orphans, 1, created)
+return orphans
+`)
+
+// ackScript drops the written batches and releases the lock if the caller
+// still holds it.
+var ackScript = redis.NewScript(`
+for i = 3, #KEYS do
+  redis.call("DEL", KEYS[i])
+  redis.call("ZREM", KEYS[2], KEYS[i])
+end
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+  redis.call("DEL", KEYS[1])
+end
+return 1
+`)
+
+// unlockScript releases the lock if the caller still holds it.
+var unlockScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+  return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+func (b *RedisBuffer) Add(ctx context.Context, c Count) error {
+	field := c.SnippetID + "|" + string(c.Kind) + "|" + strconv.FormatInt(c.Bucket.Unix(), 10)
+	return b.Client.HIncrBy(ctx, b.pendingKey(), field, c.N).Err()
+}
+
+// Drain returns the counts of a new batch plus the batches of a failed
+// flush, which stay with this instance until Ack.
+func (b *RedisBuffer) Drain(ctx context.Context) ([]Count, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	// A failed flush never reached Ack; let go of its lock first.
+	if b.token != "" {
+		if err := unlockScript.Run(ctx, b.Client, []string{b.lockKey()}, b.token).Err(); err != nil {
+			return nil, err
+		}
+		b.token = ""
+	}
+
+	token := internal.RandomHex(16)
+	ok, err := b.Client.SetNX(ctx, b.lockKey(), token, b.LockTTL).Result()
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrBusy
+	}
+	b.token = token
+
+	now := time.Now()
+	batch := b.batchKey(token)
+	args := []any{now.UnixMilli(), now.Add(-b.OrphanTTL).UnixMilli()}
+	for _, key := range b.claimed {
+		args = append(args, key)
+	}
+	res, err := claimScript.Run(ctx, b.Client, []string{b.pendingKey(), batch, b.batchesKey()}, args...).Slice()
+	if err != nil {
+		return nil, err
+	}
+	if len(res) > 0 {
+		if created, _ := res[0].(int64); created == 1 {
+			b.claimed = append(b.claimed, batch)
+		}
+		for _, v := range res[1:] {
+			if key, ok := v.(string); ok && !slices.Contains(b.claimed, key) {
+				b.claimed = append(b.claimed, key)
+			}
+		}
+	}
+
+	var out []Count
+	for _, key := range b.claimed {
+		fields, err := b.Client.HGetAll(ctx, key).Result()
+		if err != nil {
+			return nil, err
+		}
+		for field, value := range fields {
+			c, ok := parseField(field)
+			if !ok {
+				continue
+			}
+			c.N, err = strconv.ParseInt(value, 10, 64)
+			if err != nil || c.N <= 0 {
+				continue
+			}
+			out = append(out, c)
+		}
+	}
+	return out, nil
+}
+
+func (b *RedisBuffer) Ack(ctx context.Context) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	keys := append([]string{b.lockKey(), b.batchesKey()}, b.claimed...)
+	if err := ackScript.Run(ctx, b.Client, keys, b.token).Err(); err != nil {
+		return err
+	}
+	b.token = ""
+	b.claimed = nil
+	return nil
+}
+
+func parseField(field string) (Count, bool) {
+	parts := strings.Split(field, "|")
+	if len(parts) != 3 || parts[0] == "" {
+		return Count{}, false
+	}
+	unix, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		return Count{}, false
+	}
+	kind := Kind(parts[1])
+	if kind != KindView && kind != KindRaw {
+		return Count{}, false
+	}
+	return Count{SnippetID: parts[0], Kind: kind, Bucket: time.Unix(unix, 0).UTC()}, true
+}
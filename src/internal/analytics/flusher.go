@@ -0,0 +1,66 @@
+package analytics
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/PabloPavan/sniply_api/internal/telemetry"
+)
+
+type Flusher struct {
+	Buffer   Buffer
+	Store    Store
+	Interval time.Duration
+}
+
+// Run flushes on every tick until ctx is done, then flushes once more so a
+// clean shutdown does not drop buffered counts.
+func (f *Flusher) Run(ctx context.Context) {
+	interval := f.Interval
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			if err := f.Flush(flushCtx); err != nil {
+				logFlushError(flushCtx, err)
+			}
+			cancel()
+			return
+		case <-ticker.C:
+			if err := f.Flush(ctx); err != nil {
+				logFlushError(ctx, err)
+			}
+		}
+	}
+}
+
+// Flush writes the buffered counts to the store in one batch.
+func (f *Flusher) Flush(ctx context.Context) error {
+	counts, err := f.Buffer.Drain(ctx)
+	if errors.Is(err, ErrBusy) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if len(counts) > 0 {
+		if err := f.Store.AddCounts(ctx, counts); err != nil {
+			return err
+		}
+	}
+	return f.Buffer.Ack(ctx)
+}
+
+func logFlushError(ctx context.Context, err error) {
+	telemetry.LogError(ctx, "analytics flush failed",
+		telemetry.LogString("event", "analytics.flush"),
+		telemetry.LogString("error", err.Error()),
+	)
+}
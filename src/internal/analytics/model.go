@@ -0,0 +1,70 @@
+package analytics
+
+import "time"
+
+type Kind string
+
+const (
+	KindView Kind = "view"
+	KindRaw  Kind = "raw"
+)
+
+// Count is a number of events of one kind for a snippet within the hour
+// starting at Bucket.
+type Count struct {
+	SnippetID string
+	Kind      Kind
+	Bucket    time.Time
+	N         int64
+}
+
+type Window string
+
+const (
+	Window24h Window = "24h"
+	Window7d  Window = "7d"
+)
+
+// Span is how far back the window looks and HalfLife how quickly older
+// views stop counting towards the trending score.
+func (w Window) Span() (span, halfLife time.Duration, ok bool) {
+	switch w {
+	case Window24h:
+		return 24 * time.Hour, 6 * time.Hour, true
+	case Window7d:
+		return 7 * 24 * time.Hour, 48 * time.Hour, true
+	default:
+		return 0, 0, false
+	}
+}
+
+type TrendingSnippet struct {
+	ID           string    `json:"id"`
+	Name         string    `json:"name"`
+	Language     string    `json:"language"`
+	Tags         []string  `json:"tags"`
+	CreatorID    string    `json:"creator_id"`
+	UpdatedAt    time.Time `json:"updated_at"`
+	Views        int64     `json:"views"`
+	RawDownloads int64     `json:"raw_downloads"`
+	Score        float64   `json:"score"`
+}
+
+type TrendingFilter struct {
+	Since    time.Time
+	HalfLife time.Duration
+	Limit    int
+}
+
+type DailyStat struct {
+	Day          string `json:"day"`
+	Views        int64  `json:"views"`
+	RawDownloads int64  `json:"raw_downloads"`
+}
+
+type SnippetStats struct {
+	SnippetID    string      `json:"snippet_id"`
+	Views        int64       `json:"views"`
+	RawDownloads int64       `json:"raw_downloads"`
+	Daily        []DailyStat `json:"daily"`
+}
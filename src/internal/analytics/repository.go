@@ -0,0 +1,148 @@
+package analytics
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/PabloPavan/sniply_api/internal/db"
+)
+
+type Repository struct {
+	base *db.Base
+}
+
+func NewRepository(base *db.Base) *Repository {
+	return &Repository{base: base}
+}
+
+const (
+	// Counts for snippets deleted since the event are dropped.
+	sqlStatsUpsert = `INSERT INTO snippet_view_stats (snippet_id, bucket, views, raw_downloads)
+		SELECT u.snippet_id, u.bucket, u.views, u.raw_downloads
+		FROM unnest($1::text[], $2::timestamptz[], $3::bigint[], $4::bigint[]) AS u(snippet_id, bucket, views, raw_downloads)
+		WHERE EXISTS (SELECT 1 FROM snippets s WHERE s.id = u.snippet_id)
+		ON CONFLICT (snippet_id, bucket) DO UPDATE
+		SET views = snippet_view_stats.views + EXCLUDED.views,
+			raw_downloads = snippet_view_stats.raw_downloads + EXCLUDED.raw_downloads`
+
+	sqlTrending = `SELECT s.id, s.name, s.language, s.tags, s.creator_id, s.updated_at,
+			sum(v.views)::bigint, sum(v.raw_downloads)::bigint,
+			sum((v.views + v.raw_downloads) * exp(-ln(2) * extract(epoch FROM now() - v.bucket) / $2))::float8 AS score
+		FROM snippet_view_stats v
+		JOIN snippets s ON s.id = v.snippet_id
+		WHERE v.bucket >= $1 AND s.visibility = 'public'
+		GROUP BY s.id
+		ORDER BY score DESC, s.id
+		LIMIT $3`
+
+	sqlDaily = `SELECT to_char(date_trunc('day', bucket AT TIME ZONE 'UTC'), 'YYYY-MM-DD') AS day,
+			sum(views)::bigint, sum(raw_downloads)::bigint
+		FROM snippet_view_stats
+		WHERE snippet_id = $1 AND bucket >= $2
+		GROUP BY 1
+		ORDER BY 1`
+
+	sqlTotals = `SELECT coalesce(sum(views), 0)::bigint, coalesce(sum(raw_downloads), 0)::bigint
+		FROM snippet_view_stats
+		WHERE snippet_id = $1`
+)
+
+type statKey struct {
+	snippetID string
+	bucket    time.Time
+}
+
+// AddCounts merges counts into the hourly stats with a single statement.
+func (r *Repository) AddCounts(ctx context.Context, counts []Count) error {
+	merged := make(map[statKey][2]int64, len(counts))
+	for _, c := range counts {
+		k := statKey{c.SnippetID, c.Bucket.UTC().Truncate(time.Hour)}
+		v := merged[k]
+		if c.Kind == KindRaw {
+			v[1] += c.N
+		} else {
+			v[0] += c.N
+		}
+		merged[k] = v
+	}
+
+	keys := make([]statKey, 0, len(merged))
+	for k := range merged {
+		keys = append(keys, k)
+	}
+	// A stable order keeps concurrent upserts from deadlocking.
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].snippetID != keys[j].snippetID {
+			return keys[i].snippetID < keys[j].snippetID
+		}
+		return keys[i].bucket.Before(keys[j].bucket)
+	})
+
+	ids := make([]string, len(keys))
+	buckets := make([]time.Time, len(keys))
+	views := make([]int64, len(keys))
+	raws := make([]int64, len(keys))
+	for i, k := range keys {
+		ids[i], buckets[i] = k.snippetID, k.bucket
+		views[i], raws[i] = merged[k][0], merged[k][1]
+	}
+
+	ctx, cancel := r.base.WithTimeout(ctx)
+	defer cancel()
+
+	_, err := r.base.Q().Exec(ctx, sqlStatsUpsert, ids, buckets, views, raws)
+	return err
+}
+
+func (r *Repository) Trending(ctx context.Context, f TrendingFilter) ([]TrendingSnippet, error) {
+	ctx, cancel := r.base.WithTimeout(ctx)
+	defer cancel()
+
+	rows, err := r.base.Q().Query(ctx, sqlTrending, f.Since, f.HalfLife.Seconds(), f.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]TrendingSnippet, 0, f.Limit)
+	for rows.Next() {
+		var t TrendingSnippet
+		if err := rows.Scan(&t.ID, &t.Name, &t.Language, &t.Tags, &t.CreatorID, &t.UpdatedAt, &t.Views, &t.RawDownloads, &t.Score); err != nil {
+			return nil, err
+		}
+		out = append(out, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *Repository) Stats(ctx context.Context, snippetID string, since time.Time) (*SnippetStats, error) {
+	ctx, cancel := r.base.WithTimeout(ctx)
+	defer cancel()
+
+	stats := &SnippetStats{SnippetID: snippetID, Daily: []DailyStat{}}
+	if err := r.base.Q().QueryRow(ctx, sqlTotals, snippetID).Scan(&stats.Views, &stats.RawDownloads); err != nil {
+		return nil, err
+	}
+
+	rows, err := r.base.Q().Query(ctx, sqlDaily, snippetID, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var d DailyStat
+		if err := rows.Scan(&d.Day, &d.Views, &d.RawDownloads); err != nil {
+			return nil, err
+		}
+		stats.Daily = append(stats.Daily, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
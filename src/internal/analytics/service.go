@@ -0,0 +1,116 @@
+package analytics
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/PabloPavan/sniply_api/internal/apperrors"
+	"github.com/PabloPavan/sniply_api/internal/identity"
+	"github.com/PabloPavan/sniply_api/internal/snippets"
+)
+
+type Store interface {
+	AddCounts(ctx context.Context, counts []Count) error
+	Trending(ctx context.Context, f TrendingFilter) ([]TrendingSnippet, error)
+	Stats(ctx context.Context, snippetID string, since time.Time) (*SnippetStats, error)
+}
+
+type SnippetReader interface {
+	View(ctx context.Context, id string, sel snippets.Selection) (*snippets.Snippet, error)
+}
+
+type Service struct {
+	Store    Store
+	Buffer   Buffer
+	Snippets SnippetReader
+	Now      func() time.Time
+}
+
+func (s *Service) now() time.Time {
+	if s.Now != nil {
+		return s.Now().UTC()
+	}
+	return time.Now().UTC()
+}
+
+// Record counts a view or raw download in the buffer. Owners reading their
+// own snippets are not counted.
+func (s *Service) Record(ctx context.Context, snippet *snippets.Snippet, kind Kind) error {
+	if s.Buffer == nil || snippet == nil {
+		return nil
+	}
+	if requesterID, ok := identity.UserID(ctx); ok && requesterID == snippet.CreatorID {
+		return nil
+	}
+	return s.Buffer.Add(ctx, Count{
+		SnippetID: snippet.ID,
+		Kind:      kind,
+		Bucket:    s.now().Truncate(time.Hour),
+		N:         1,
+	})
+}
+
+// Trending ranks public snippets by views and raw downloads in the window,
+// each weighted down by its age.
+func (s *Service) Trending(ctx context.Context, window string, limit int) ([]TrendingSnippet, error) {
+	if s.Store == nil {
+		return nil, apperrors.New(apperrors.KindInternal, "analytics store not configured")
+	}
+
+	w := Window(strings.TrimSpace(window))
+	if w == "" {
+		w = Window24h
+	}
+	span, halfLife, ok := w.Span()
+	if !ok {
+		return nil, apperrors.New(apperrors.KindInvalidInput, "window must be 24h or 7d")
+	}
+
+	if limit <= 0 {
+		limit = 20
+	}
+	limit = min(limit, 100)
+
+	list, err := s.Store.Trending(ctx, TrendingFilter{
+		Since:    s.now().Add(-span).Truncate(time.Hour),
+		HalfLife: halfLife,
+		Limit:    limit,
+	})
+	if err != nil {
+		return nil, apperrors.New(apperrors.KindInternal, "failed to load trending snippets")
+	}
+	return list, nil
+}
+
+// Stats returns total and daily counts for the last days. Only the owner and
+// admins can see them.
+func (s *Service) Stats(ctx context.Context, snippetID string, days int) (*SnippetStats, error) {
+	if s.Store == nil || s.Snippets == nil {
+		return nil, apperrors.New(apperrors.KindInternal, "analytics store not configured")
+	}
+	requesterID, ok := identity.UserID(ctx)
+	if !ok || strings.TrimSpace(requesterID) == "" {
+		return nil, apperrors.New(apperrors.KindUnauthorized, "unauthorized")
+	}
+
+	snippet, err := s.Snippets.View(ctx, strings.TrimSpace(snippetID), snippets.Selection{})
+	if err != nil {
+		return nil, err
+	}
+	if snippet.CreatorID != requesterID && !identity.IsAdmin(ctx) {
+		return nil, apperrors.New(apperrors.KindForbidden, "forbidden")
+	}
+
+	if days <= 0 {
+		days = 30
+	}
+	days = min(days, 365)
+	today := s.now().Truncate(24 * time.Hour)
+
+	stats, err := s.Store.Stats(ctx, snippet.ID, today.AddDate(0, 0, -(days-1)))
+	if err != nil {
+		return nil, apperrors.New(apperrors.KindInternal, "failed to load analytics")
+	}
+	return stats, nil
+}
@@ -0,0 +1,147 @@
+package analytics
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/PabloPavan/sniply_api/internal/apperrors"
+	"github.com/PabloPavan/sniply_api/internal/identity"
+	"github.com/PabloPavan/sniply_api/internal/snippets"
+)
+
+type storeStub struct {
+	addErr   error
+	added    []Count
+	trending TrendingFilter
+	since    time.Time
+}
+
+func (s *storeStub) AddCounts(ctx context.Context, counts []Count) error {
+	if s.addErr != nil {
+		return s.addErr
+	}
+	s.added = append(s.added, counts...)
+	return nil
+}
+
+func (s *storeStub) Trending(ctx context.Context, f TrendingFilter) ([]TrendingSnippet, error) {
+	s.trending = f
+	return []TrendingSnippet{}, nil
+}
+
+func (s *storeStub) Stats(ctx context.Context, snippetID string, since time.Time) (*SnippetStats, error) {
+	s.since = since
+	return &SnippetStats{SnippetID: snippetID}, nil
+}
+
+type snippetStub struct{}
+
+func (snippetStub) View(ctx context.Context, id string, sel snippets.Selection) (*snippets.Snippet, error) {
+	return &snippets.Snippet{ID: id, CreatorID: "usr_owner", Visibility: snippets.VisibilityPublic}, nil
+}
+
+var fixedNow = time.Date(2026, 10, 18, 13, 45, 0, 0, time.UTC)
+
+func TestRecordAndFlush(t *testing.T) {
+	buffer := NewMemoryBuffer()
+	store := &storeStub{}
+	svc := &Service{Buffer: buffer, Store: store, Now: func() time.Time { return fixedNow }}
+	flusher := &Flusher{Buffer: buffer, Store: store}
+
+	snippet := &snippets.Snippet{ID: "snp_1", CreatorID: "usr_owner"}
+	reader := identity.WithUser(context.Background(), "usr_reader", "member")
+	owner := identity.WithUser(context.Background(), "usr_owner", "member")
+
+	for range 3 {
+		_ = svc.Record(reader, snippet, KindView)
+	}
+	_ = svc.Record(owner, snippet, KindView)
+	_ = svc.Record(reader, snippet, KindRaw)
+
+	store.addErr = errors.New("db down")
+	if err := flusher.Flush(context.Background()); err == nil {
+		t.Fatalf("expected flush error")
+	}
+	_ = svc.Record(reader, snippet, KindView)
+
+	store.addErr = nil
+	if err := flusher.Flush(context.Background()); err != nil {
+		t.Fatalf("flush error: %v", err)
+	}
+	totals := map[Kind]int64{}
+	for _, c := range store.added {
+		if !c.Bucket.Equal(fixedNow.Truncate(time.Hour)) {
+			t.Fatalf("unexpected bucket %v", c.Bucket)
+		}
+		totals[c.Kind] += c.N
+	}
+	if totals[KindView] != 3 || totals[KindRaw] != 1 {
+		t.Fatalf("expected failed batch to be retried alone, got %v", totals)
+	}
+
+	if err := flusher.Flush(context.Background()); err != nil {
+		t.Fatalf("flush error: %v", err)
+	}
+	if len(store.added) != 3 || store.added[2].N != 1 {
+		t.Fatalf("expected the view recorded during the failed flush, got %+v", store.added)
+	}
+}
+
+func TestParseField(t *testing.T) {
+	c, ok := parseField("snp_1|raw|1791813600")
+	if !ok || c.SnippetID != "snp_1" || c.Kind != KindRaw || c.Bucket.Unix() != 1791813600 {
+		t.Fatalf("unexpected count: %+v", c)
+	}
+	for _, field := range []string{"snp_1|view", "|view|1", "snp_1|like|1", "snp_1|view|x"} {
+		if _, ok := parseField(field); ok {
+			t.Fatalf("%q: expected parse failure", field)
+		}
+	}
+}
+
+func TestTrendingWindow(t *testing.T) {
+	store := &storeStub{}
+	svc := &Service{Store: store, Now: func() time.Time { return fixedNow }}
+
+	if _, err := svc.Trending(context.Background(), "7d", 1000); err != nil {
+		t.Fatalf("trending error: %v", err)
+	}
+	if store.trending.Limit != 100 || store.trending.HalfLife != 48*time.Hour ||
+		!store.trending.Since.Equal(time.Date(2026, 10, 11, 13, 0, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected filter: %+v", store.trending)
+	}
+
+	_, err := svc.Trending(context.Background(), "1y", 0)
+	assertKind(t, err, apperrors.KindInvalidInput)
+}
+
+func TestStatsOwnerOnly(t *testing.T) {
+	store := &storeStub{}
+	svc := &Service{Store: store, Snippets: snippetStub{}, Now: func() time.Time { return fixedNow }}
+
+	_, err := svc.Stats(identity.WithUser(context.Background(), "usr_reader", "member"), "snp_1", 0)
+	assertKind(t, err, apperrors.KindForbidden)
+
+	if _, err := svc.Stats(identity.WithUser(context.Background(), "usr_owner", "member"), "snp_1", 7); err != nil {
+		t.Fatalf("stats error: %v", err)
+	}
+	if !store.since.Equal(time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected since: %v", store.since)
+	}
+}
+
+func assertKind(t *testing.T, err error, kind apperrors.Kind) {
+	t.Helper()
+	if err == nil {
+		t.Fatalf("expected error kind %s", kind)
+	}
+	var appErr *apperrors.Error
+	if !errors.As(err, &appErr) {
+		t.Fatalf("expected app error, got: %v", err)
+	}
+	if appErr.Kind != kind {
+		t.Fatalf("unexpected kind: %s", appErr.Kind)
+	}
+}
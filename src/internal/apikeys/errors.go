@@ -8,6 +8,9 @@ import (
 
 var ErrNotFound = errors.New("api key not found")
 
+// ErrRotated is returned when rotating a key that was already rotated.
+var ErrRotated = errors.New("api key already rotated")
+
 func IsNotFound(err error) bool {
 	return errors.Is(err, pgx.ErrNoRows) || errors.Is(err, ErrNotFound)
 }
@@ -38,4 +38,23 @@ type Key struct {
 	TokenPrefix string     `json:"token_prefix"`
 	CreatedAt   time.Time  `json:"created_at"`
 	RevokedAt   *time.Time `json:"revoked_at,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt  *time.Time `json:"last_used_at,omitempty"`
+	LastUsedIP  string     `json:"last_used_ip,omitempty"`
+	// ReplacedBy is the key this one was rotated to.
+	ReplacedBy *string `json:"replaced_by,omitempty"`
+	// Lifetime is the lifetime the key was created with, 0 for none.
+	Lifetime time.Duration `json:"-"`
+}
+
+// Active reports whether the key still authenticates at now.
+func (k *Key) Active(now time.Time) bool {
+	return k.RevokedAt == nil && (k.ExpiresAt == nil || k.ExpiresAt.After(now))
+}
+
+// Usage is the latest use of a key.
+type Usage struct {
+	KeyID string
+	At    time.Time
+	IP    string
 }
@@ -2,9 +2,12 @@ package apikeys
 
 import (
 	"context"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/PabloPavan/sniply_api/internal/db"
+	"github.com/jackc/pgx/v5"
 )
 
 type Repository struct {
@@ -16,19 +19,19 @@ func NewRepository(base *db.Base) *Repository {
 }
 
 const (
-	sqlKeyInsert = `INSERT INTO api_keys (id, user_id, name, scope, token_hash, token_prefix)
-		VALUES ($1, $2, $3, $4, $5, $6)`
+	sqlKeyInsert = `INSERT INTO api_keys (id, user_id, name, scope, token_hash, token_prefix, expires_at, lifetime_seconds)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
 
-	sqlKeyListByUser = `SELECT id, user_id, name, scope, token_prefix, created_at, revoked_at
+	sqlKeyListByUser = `SELECT id, user_id, name, scope, token_prefix, created_at, revoked_at, expires_at, last_used_at, last_used_ip, replaced_by, lifetime_seconds
 		FROM api_keys
 		WHERE user_id = $1
 		ORDER BY created_at DESC`
 
-	sqlKeyGetByID = `SELECT id, user_id, name, scope, token_prefix, created_at, revoked_at
+	sqlKeyGetByID = `SELECT id, user_id, name, scope, token_prefix, created_at, revoked_at, expires_at, last_used_at, last_used_ip, replaced_by, lifetime_seconds
 		FROM api_keys
 		WHERE id = $1`
 
-	sqlKeyGetByHash = `SELECT k.id, k.user_id, k.name, k.scope, k.token_prefix, k.created_at, k.revoked_at, u.role
+	sqlKeyGetByHash = `SELECT k.id, k.user_id, k.name, k.scope, k.token_prefix, k.created_at, k.revoked_at, k.expires_at, k.last_used_at, k.last_used_ip, k.replaced_by, k.lifetime_seconds, u.role
 		FROM api_keys k
 		JOIN users u ON u.id = k.user_id
 		WHERE k.token_hash = $1`
@@ -36,13 +39,37 @@ const (
 	sqlKeyRevoke = `UPDATE api_keys
 		SET revoked_at = now()
 		WHERE id = $1`
+
+	// The old key keeps an earlier expiry of its own.
+	sqlKeyExpireRotated = `UPDATE api_keys
+		SET expires_at = LEAST(COALESCE(expires_at, $2), $2), replaced_by = $3
+		WHERE id = $1 AND revoked_at IS NULL AND replaced_by IS NULL`
+
+	sqlKeyRotated = `SELECT replaced_by IS NOT NULL
+		FROM api_keys
+		WHERE id = $1 AND revoked_at IS NULL`
+
+	// Older uses, flushed late by another instance, do not win.
+	sqlKeyRecordUsage = `UPDATE api_keys k
+		SET last_used_at = u.at, last_used_ip = u.ip
+		FROM unnest($1::text[], $2::timestamptz[], $3::text[]) AS u(id, at, ip)
+		WHERE k.id = u.id AND (k.last_used_at IS NULL OR k.last_used_at < u.at)`
 )
 
 func (r *Repository) Create(ctx context.Context, k *Key) error {
 	ctx, cancel := r.base.WithTimeout(ctx)
 	defer cancel()
 
-	row := r.base.Q().QueryRow(ctx, sqlKeyInsert+" RETURNING created_at", k.ID, k.UserID, k.Name, k.Scope, k.TokenHash, k.TokenPrefix)
+	return insertKey(ctx, r.base.Q(), k)
+}
+
+func insertKey(ctx context.Context, q db.Queryer, k *Key) error {
+	var lifetime *int64
+	if k.Lifetime > 0 {
+		secs := int64(k.Lifetime / time.Second)
+		lifetime = &secs
+	}
+	row := q.QueryRow(ctx, sqlKeyInsert+" RETURNING created_at", k.ID, k.UserID, k.Name, k.Scope, k.TokenHash, k.TokenPrefix, k.ExpiresAt, lifetime)
 	if err := row.Scan(&k.CreatedAt); err != nil {
 		return err
 	}
@@ -62,9 +89,11 @@ func (r *Repository) ListByUser(ctx context.Context, userID string) ([]*Key, err
 	var out []*Key
 	for rows.Next() {
 		var k Key
-		if err := rows.Scan(&k.ID, &k.UserID, &k.Name, &k.Scope, &k.TokenPrefix, &k.CreatedAt, &k.RevokedAt); err != nil {
+		var lifetime *int64
+		if err := rows.Scan(&k.ID, &k.UserID, &k.Name, &k.Scope, &k.TokenPrefix, &k.CreatedAt, &k.RevokedAt, &k.ExpiresAt, &k.LastUsedAt, &k.LastUsedIP, &k.ReplacedBy, &lifetime); err != nil {
 			return nil, err
 		}
+		k.Lifetime = secondsToDuration(lifetime)
 		out = append(out, &k)
 	}
 	if err := rows.Err(); err != nil {
@@ -78,6 +107,7 @@ func (r *Repository) GetByID(ctx context.Context, id string) (*Key, error) {
 	defer cancel()
 
 	var k Key
+	var lifetime *int64
 	err := r.base.Q().QueryRow(ctx, sqlKeyGetByID, id).Scan(
 		&k.ID,
 		&k.UserID,
@@ -86,6 +116,11 @@ func (r *Repository) GetByID(ctx context.Context, id string) (*Key, error) {
 		&k.TokenPrefix,
 		&k.CreatedAt,
 		&k.RevokedAt,
+		&k.ExpiresAt,
+		&k.LastUsedAt,
+		&k.LastUsedIP,
+		&k.ReplacedBy,
+		&lifetime,
 	)
 	if IsNotFound(err) {
 		return nil, ErrNotFound
@@ -93,6 +128,7 @@ func (r *Repository) GetByID(ctx context.Context, id string) (*Key, error) {
 	if err != nil {
 		return nil, err
 	}
+	k.Lifetime = secondsToDuration(lifetime)
 	return &k, nil
 }
 
@@ -101,6 +137,7 @@ func (r *Repository) GetByTokenHash(ctx context.Context, hash string) (*Key, err
 	defer cancel()
 
 	var k Key
+	var lifetime *int64
 	err := r.base.Q().QueryRow(ctx, sqlKeyGetByHash, strings.TrimSpace(hash)).Scan(
 		&k.ID,
 		&k.UserID,
@@ -109,6 +146,11 @@ func (r *Repository) GetByTokenHash(ctx context.Context, hash string) (*Key, err
 		&k.TokenPrefix,
 		&k.CreatedAt,
 		&k.RevokedAt,
+		&k.ExpiresAt,
+		&k.LastUsedAt,
+		&k.LastUsedIP,
+		&k.ReplacedBy,
+		&lifetime,
 		&k.UserRole,
 	)
 	if IsNotFound(err) {
@@ -117,6 +159,7 @@ func (r *Repository) GetByTokenHash(ctx context.Context, hash string) (*Key, err
 	if err != nil {
 		return nil, err
 	}
+	k.Lifetime = secondsToDuration(lifetime)
 	return &k, nil
 }
 
@@ -130,3 +173,59 @@ func (r *Repository) Revoke(ctx context.Context, id string) (bool, error) {
 	}
 	return tag.RowsAffected() > 0, nil
 }
+
+// Rotate creates next and makes the key oldID expire at oldExpiresAt, or
+// earlier if it already did. It returns ErrNotFound when oldID is revoked
+// and ErrRotated when it was rotated already.
+func (r *Repository) Rotate(ctx context.Context, oldID string, next *Key, oldExpiresAt time.Time) error {
+	return r.base.WithTx(ctx, func(ctx context.Context, tx pgx.Tx) error {
+		if err := insertKey(ctx, tx, next); err != nil {
+			return err
+		}
+		tag, err := tx.Exec(ctx, sqlKeyExpireRotated, oldID, oldExpiresAt, next.ID)
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() > 0 {
+			return nil
+		}
+		var rotated bool
+		if err := tx.QueryRow(ctx, sqlKeyRotated, oldID).Scan(&rotated); err != nil {
+			if IsNotFound(err) {
+				return ErrNotFound
+			}
+			return err
+		}
+		if rotated {
+			return ErrRotated
+		}
+		return ErrNotFound
+	})
+}
+
+func secondsToDuration(secs *int64) time.Duration {
+	if secs == nil {
+		return 0
+	}
+	return time.Duration(*secs) * time.Second
+}
+
+// RecordUsage stores the latest use of each key with a single statement.
+func (r *Repository) RecordUsage(ctx context.Context, usage []Usage) error {
+	sorted := append([]Usage(nil), usage...)
+	// A stable order keeps concurrent flushes from deadlocking.
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].KeyID < sorted[j].KeyID })
+
+	ids := make([]string, len(sorted))
+	ats := make([]time.Time, len(sorted))
+	ips := make([]string, len(sorted))
+	for i, u := range sorted {
+		ids[i], ats[i], ips[i] = u.KeyID, u.At, u.IP
+	}
+
+	ctx, cancel := r.base.WithTimeout(ctx)
+	defer cancel()
+
+	_, err := r.base.Q().Exec(ctx, sqlKeyRecordUsage, ids, ats, ips)
+	return err
+}
@@ -2,7 +2,9 @@ package apikeys
 
 import (
 	"context"
+	"errors"
 	"strings"
+	"time"
 
 	"github.com/PabloPavan/sniply_api/internal"
 	"github.com/PabloPavan/sniply_api/internal/apperrors"
@@ -15,19 +17,34 @@ type Store interface {
 	GetByID(ctx context.Context, id string) (*Key, error)
 	Revoke(ctx context.Context, id string) (bool, error)
 	GetByTokenHash(ctx context.Context, hash string) (*Key, error)
+	Rotate(ctx context.Context, oldID string, next *Key, oldExpiresAt time.Time) error
 }
 
+const (
+	DefaultRotationGrace = 24 * time.Hour
+	MaxRotationGrace     = 30 * 24 * time.Hour
+)
+
 type Service struct {
 	Store          Store
 	IDGenerator    func() string
 	TokenGenerator func() string
 	TokenHasher    func(token string) string
 	TokenPrefixer  func(token string) string
+	// RotationGrace is how long a rotated key keeps working by default.
+	RotationGrace time.Duration
+	Now           func() time.Time
 }
 
 type CreateInput struct {
-	Name  string
-	Scope string
+	Name      string
+	Scope     string
+	ExpiresAt *time.Time
+}
+
+type RotateInput struct {
+	// GracePeriod overrides RotationGrace; 0 ends the old key at once.
+	GracePeriod *time.Duration
 }
 
 func (s *Service) Create(ctx context.Context, input CreateInput) (*Key, string, error) {
@@ -47,7 +64,86 @@ func (s *Service) Create(ctx context.Context, input CreateInput) (*Key, string,
 	if !scope.Valid() {
 		return nil, "", apperrors.New(apperrors.KindInvalidInput, "invalid scope")
 	}
+	if input.ExpiresAt != nil && !input.ExpiresAt.After(s.now()) {
+		return nil, "", apperrors.New(apperrors.KindInvalidInput, "expires_at must be in the future")
+	}
+
+	var lifetime time.Duration
+	if input.ExpiresAt != nil {
+		lifetime = input.ExpiresAt.Sub(s.now())
+	}
+	key, token := s.newKey(userID, name, scope, input.ExpiresAt, lifetime)
+	if err := s.Store.Create(ctx, key); err != nil {
+		return nil, "", apperrors.New(apperrors.KindInternal, "failed to create api key")
+	}
+	return key, token, nil
+}
+
+// Rotate issues a new token for the key id, with the same name and scope,
+// and lets the old one work for a grace period. A key that expires keeps
+// its lifetime: the new one lasts as long as the old one was created for.
+// A key is rotated once; its successor is the one to rotate next.
+func (s *Service) Rotate(ctx context.Context, id string, input RotateInput) (*Key, string, error) {
+	if s.Store == nil {
+		return nil, "", apperrors.New(apperrors.KindInternal, "api keys store not configured")
+	}
+	userID, ok := identity.UserID(ctx)
+	if !ok || strings.TrimSpace(userID) == "" {
+		return nil, "", apperrors.New(apperrors.KindUnauthorized, "unauthorized")
+	}
+	id = strings.TrimSpace(id)
+	if id == "" {
+		return nil, "", apperrors.New(apperrors.KindInvalidInput, "invalid id")
+	}
+
+	grace := s.RotationGrace
+	if grace <= 0 {
+		grace = DefaultRotationGrace
+	}
+	if input.GracePeriod != nil {
+		grace = *input.GracePeriod
+	}
+	if grace < 0 || grace > MaxRotationGrace {
+		return nil, "", apperrors.New(apperrors.KindInvalidInput, "invalid grace period")
+	}
+
+	old, err := s.Store.GetByID(ctx, id)
+	if err != nil {
+		if IsNotFound(err) {
+			return nil, "", apperrors.New(apperrors.KindNotFound, "api key not found")
+		}
+		return nil, "", apperrors.New(apperrors.KindInternal, "failed to load api key")
+	}
+	if old.UserID != userID || old.RevokedAt != nil {
+		return nil, "", apperrors.New(apperrors.KindNotFound, "api key not found")
+	}
+	now := s.now()
+	if !old.Active(now) {
+		return nil, "", apperrors.New(apperrors.KindConflict, "api key expired")
+	}
+	if old.ReplacedBy != nil {
+		return nil, "", apperrors.New(apperrors.KindConflict, "api key already rotated")
+	}
+
+	var expiresAt *time.Time
+	if old.Lifetime > 0 {
+		at := now.Add(old.Lifetime)
+		expiresAt = &at
+	}
+	key, token := s.newKey(userID, old.Name, old.Scope, expiresAt, old.Lifetime)
+	if err := s.Store.Rotate(ctx, old.ID, key, now.Add(grace)); err != nil {
+		if IsNotFound(err) {
+			return nil, "", apperrors.New(apperrors.KindNotFound, "api key not found")
+		}
+		if errors.Is(err, ErrRotated) {
+			return nil, "", apperrors.New(apperrors.KindConflict, "api key already rotated")
+		}
+		return nil, "", apperrors.New(apperrors.KindInternal, "failed to rotate api key")
+	}
+	return key, token, nil
+}
 
+func (s *Service) newKey(userID, name string, scope Scope, expiresAt *time.Time, lifetime time.Duration) (*Key, string) {
 	idGen := s.IDGenerator
 	if idGen == nil {
 		idGen = func() string {
@@ -68,19 +164,16 @@ func (s *Service) Create(ctx context.Context, input CreateInput) (*Key, string,
 	}
 
 	token := tokenGen()
-	key := &Key{
+	return &Key{
 		ID:          idGen(),
 		UserID:      userID,
 		Name:        name,
 		Scope:       scope,
 		TokenHash:   hashToken(token),
 		TokenPrefix: prefixer(token),
-	}
-
-	if err := s.Store.Create(ctx, key); err != nil {
-		return nil, "", apperrors.New(apperrors.KindInternal, "failed to create api key")
-	}
-	return key, token, nil
+		ExpiresAt:   expiresAt,
+		Lifetime:    lifetime,
+	}, token
 }
 
 func (s *Service) List(ctx context.Context) ([]*Key, error) {
@@ -132,3 +225,10 @@ func (s *Service) Revoke(ctx context.Context, id string) error {
 	}
 	return nil
 }
+
+func (s *Service) now() time.Time {
+	if s.Now != nil {
+		return s.Now().UTC()
+	}
+	return time.Now().UTC()
+}
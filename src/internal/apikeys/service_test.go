@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/PabloPavan/sniply_api/internal/apperrors"
 	"github.com/PabloPavan/sniply_api/internal/identity"
@@ -15,6 +16,7 @@ type storeStub struct {
 	getIDFn  func(ctx context.Context, id string) (*Key, error)
 	revokeFn func(ctx context.Context, id string) (bool, error)
 	getFn    func(ctx context.Context, hash string) (*Key, error)
+	rotateFn func(ctx context.Context, oldID string, next *Key, oldExpiresAt time.Time) error
 }
 
 func (s *storeStub) Create(ctx context.Context, k *Key) error {
@@ -52,6 +54,13 @@ func (s *storeStub) GetByTokenHash(ctx context.Context, hash string) (*Key, erro
 	return nil, ErrNotFound
 }
 
+func (s *storeStub) Rotate(ctx context.Context, oldID string, next *Key, oldExpiresAt time.Time) error {
+	if s.rotateFn != nil {
+		return s.rotateFn(ctx, oldID, next, oldExpiresAt)
+	}
+	return nil
+}
+
 func TestServiceCreateDefaults(t *testing.T) {
 	store := &storeStub{}
 	svc := &Service{
@@ -93,6 +102,133 @@ func TestServiceCreateInvalidScope(t *testing.T) {
 	assertKind(t, err, apperrors.KindInvalidInput)
 }
 
+func TestServiceCreateExpiry(t *testing.T) {
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	svc := &Service{Store: &storeStub{}, Now: func() time.Time { return now }}
+	ctx := identity.WithUser(context.Background(), "usr_1", "member")
+
+	past := now.Add(-time.Minute)
+	_, _, err := svc.Create(ctx, CreateInput{ExpiresAt: &past})
+	assertKind(t, err, apperrors.KindInvalidInput)
+
+	later := now.Add(time.Hour)
+	key, _, err := svc.Create(ctx, CreateInput{ExpiresAt: &later})
+	if err != nil {
+		t.Fatalf("create error: %v", err)
+	}
+	if !key.Active(now) || key.Active(later) {
+		t.Fatalf("unexpected expiry: %+v", key)
+	}
+}
+
+func TestServiceRotate(t *testing.T) {
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	expires := now.Add(-24 * time.Hour).Add(90 * 24 * time.Hour)
+	old := &Key{ID: "key_old", UserID: "usr_1", Name: "ci", Scope: ScopeRead, CreatedAt: now.Add(-24 * time.Hour), ExpiresAt: &expires, Lifetime: 90 * 24 * time.Hour}
+	store := &storeStub{}
+	store.getIDFn = func(ctx context.Context, id string) (*Key, error) {
+		if id != old.ID {
+			return nil, ErrNotFound
+		}
+		return old, nil
+	}
+	var rotated *Key
+	var oldUntil time.Time
+	store.rotateFn = func(ctx context.Context, oldID string, next *Key, oldExpiresAt time.Time) error {
+		rotated, oldUntil = next, oldExpiresAt
+		return nil
+	}
+	svc := &Service{
+		Store:          store,
+		IDGenerator:    func() string { return "key_new" },
+		TokenGenerator: func() string { return "sk_new" },
+		RotationGrace:  time.Hour,
+		Now:            func() time.Time { return now },
+	}
+	ctx := identity.WithUser(context.Background(), "usr_1", "member")
+
+	key, token, err := svc.Rotate(ctx, "key_old", RotateInput{})
+	if err != nil {
+		t.Fatalf("rotate error: %v", err)
+	}
+	if token != "sk_new" || key != rotated || key.Name != "ci" || key.Scope != ScopeRead || key.TokenHash != HashToken("sk_new") {
+		t.Fatalf("unexpected key: %+v", key)
+	}
+	// The new key lasts as long as the old one was created for.
+	if key.ExpiresAt == nil || !key.ExpiresAt.Equal(now.Add(90*24*time.Hour)) || key.Lifetime != old.Lifetime {
+		t.Fatalf("unexpected expiry: %v", key.ExpiresAt)
+	}
+	if !oldUntil.Equal(now.Add(time.Hour)) {
+		t.Fatalf("unexpected grace: %v", oldUntil)
+	}
+
+	zero := time.Duration(0)
+	if _, _, err := svc.Rotate(ctx, "key_old", RotateInput{GracePeriod: &zero}); err != nil || !oldUntil.Equal(now) {
+		t.Fatalf("expected no grace: %v %v", oldUntil, err)
+	}
+	tooLong := MaxRotationGrace + time.Second
+	_, _, err = svc.Rotate(ctx, "key_old", RotateInput{GracePeriod: &tooLong})
+	assertKind(t, err, apperrors.KindInvalidInput)
+
+	_, _, err = svc.Rotate(identity.WithUser(context.Background(), "usr_2", "member"), "key_old", RotateInput{})
+	assertKind(t, err, apperrors.KindNotFound)
+
+	now = expires
+	_, _, err = svc.Rotate(ctx, "key_old", RotateInput{})
+	assertKind(t, err, apperrors.KindConflict)
+
+	// Revoked concurrently.
+	now = expires.Add(-time.Hour)
+	store.rotateFn = func(ctx context.Context, oldID string, next *Key, oldExpiresAt time.Time) error {
+		return ErrNotFound
+	}
+	_, _, err = svc.Rotate(ctx, "key_old", RotateInput{})
+	assertKind(t, err, apperrors.KindNotFound)
+
+	// Rotated concurrently.
+	store.rotateFn = func(ctx context.Context, oldID string, next *Key, oldExpiresAt time.Time) error {
+		return ErrRotated
+	}
+	_, _, err = svc.Rotate(ctx, "key_old", RotateInput{})
+	assertKind(t, err, apperrors.KindConflict)
+}
+
+func TestServiceRotateOnce(t *testing.T) {
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	graceEnd := now.Add(time.Hour)
+	successor := "key_new"
+	// A key without expiry, rotated and now in its grace period.
+	old := &Key{ID: "key_old", UserID: "usr_1", Name: "ci", Scope: ScopeRead, CreatedAt: now.Add(-24 * time.Hour), ExpiresAt: &graceEnd, ReplacedBy: &successor}
+	store := &storeStub{}
+	store.getIDFn = func(ctx context.Context, id string) (*Key, error) {
+		return old, nil
+	}
+	var rotated *Key
+	store.rotateFn = func(ctx context.Context, oldID string, next *Key, oldExpiresAt time.Time) error {
+		rotated = next
+		return nil
+	}
+	svc := &Service{Store: store, Now: func() time.Time { return now }}
+	ctx := identity.WithUser(context.Background(), "usr_1", "member")
+
+	_, _, err := svc.Rotate(ctx, "key_old", RotateInput{})
+	assertKind(t, err, apperrors.KindConflict)
+	if rotated != nil {
+		t.Fatal("expected no second successor")
+	}
+
+	// Its successor rotates, and still never expires: the grace period of
+	// the old key is not its lifetime.
+	old.ReplacedBy = nil
+	key, _, err := svc.Rotate(ctx, "key_old", RotateInput{})
+	if err != nil {
+		t.Fatalf("rotate error: %v", err)
+	}
+	if key.ExpiresAt != nil {
+		t.Fatalf("expected no expiry, got %v", key.ExpiresAt)
+	}
+}
+
 func TestServiceRevokeNotFound(t *testing.T) {
 	store := &storeStub{}
 	svc := &Service{Store: store}
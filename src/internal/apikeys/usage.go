@@ -0,0 +1,100 @@
+package apikeys
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/PabloPavan/sniply_api/internal/telemetry"
+)
+
+type UsageStore interface {
+	RecordUsage(ctx context.Context, usage []Usage) error
+}
+
+// UsageTracker keeps the latest use of each key in memory and writes them
+// in batches, so authenticating a request never waits on the database.
+type UsageTracker struct {
+	Store    UsageStore
+	Interval time.Duration
+
+	mu      sync.Mutex
+	pending map[string]Usage
+}
+
+// Record notes a use of a key; only the latest per key is kept.
+func (t *UsageTracker) Record(u Usage) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.merge(u)
+}
+
+func (t *UsageTracker) merge(u Usage) {
+	if t.pending == nil {
+		t.pending = make(map[string]Usage)
+	}
+	if prev, ok := t.pending[u.KeyID]; ok && !u.At.After(prev.At) {
+		return
+	}
+	t.pending[u.KeyID] = u
+}
+
+// Run flushes on every tick until ctx is done, then flushes once more so a
+// clean shutdown does not drop recorded uses.
+func (t *UsageTracker) Run(ctx context.Context) {
+	interval := t.Interval
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			if err := t.Flush(flushCtx); err != nil {
+				logFlushError(flushCtx, err)
+			}
+			cancel()
+			return
+		case <-ticker.C:
+			if err := t.Flush(ctx); err != nil {
+				logFlushError(ctx, err)
+			}
+		}
+	}
+}
+
+// Flush writes the recorded uses to the store. On failure they are kept
+// for the next flush, unless a newer use replaced them meanwhile.
+func (t *UsageTracker) Flush(ctx context.Context) error {
+	t.mu.Lock()
+	pending := t.pending
+	t.pending = nil
+	t.mu.Unlock()
+	if len(pending) == 0 {
+		return nil
+	}
+
+	usage := make([]Usage, 0, len(pending))
+	for _, u := range pending {
+		usage = append(usage, u)
+	}
+	if err := t.Store.RecordUsage(ctx, usage); err != nil {
+		t.mu.Lock()
+		for _, u := range usage {
+			t.merge(u)
+		}
+		t.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+func logFlushError(ctx context.Context, err error) {
+	telemetry.LogError(ctx, "api key usage flush failed",
+		telemetry.LogString("event", "api_keys.usage.flush"),
+		telemetry.LogString("error", err.Error()),
+	)
+}
@@ -0,0 +1,69 @@
+package apikeys
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+type usageStoreStub struct {
+	batches [][]Usage
+	err     error
+}
+
+func (s *usageStoreStub) RecordUsage(ctx context.Context, usage []Usage) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.batches = append(s.batches, usage)
+	return nil
+}
+
+func TestUsageTrackerBatchesLatestUse(t *testing.T) {
+	store := &usageStoreStub{}
+	tracker := &UsageTracker{Store: store}
+	at := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	tracker.Record(Usage{KeyID: "key_1", At: at, IP: "10.0.0.1"})
+	tracker.Record(Usage{KeyID: "key_1", At: at.Add(time.Second), IP: "10.0.0.2"})
+	// Out of order: an older use does not replace a newer one.
+	tracker.Record(Usage{KeyID: "key_1", At: at, IP: "10.0.0.3"})
+	tracker.Record(Usage{KeyID: "key_2", At: at, IP: "10.0.0.4"})
+
+	if err := tracker.Flush(context.Background()); err != nil {
+		t.Fatalf("flush: %v", err)
+	}
+	if len(store.batches) != 1 || len(store.batches[0]) != 2 {
+		t.Fatalf("expected one batch of two: %+v", store.batches)
+	}
+	for _, u := range store.batches[0] {
+		if u.KeyID == "key_1" && u.IP != "10.0.0.2" {
+			t.Fatalf("expected the latest use: %+v", u)
+		}
+	}
+
+	// Nothing left to write.
+	if err := tracker.Flush(context.Background()); err != nil || len(store.batches) != 1 {
+		t.Fatalf("expected an empty flush: %v", err)
+	}
+}
+
+func TestUsageTrackerKeepsFailedBatches(t *testing.T) {
+	store := &usageStoreStub{err: errors.New("db down")}
+	tracker := &UsageTracker{Store: store}
+	at := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	tracker.Record(Usage{KeyID: "key_1", At: at, IP: "10.0.0.1"})
+	if err := tracker.Flush(context.Background()); err == nil {
+		t.Fatal("expected the store error")
+	}
+
+	store.err = nil
+	if err := tracker.Flush(context.Background()); err != nil {
+		t.Fatalf("flush: %v", err)
+	}
+	if len(store.batches) != 1 || store.batches[0][0].IP != "10.0.0.1" {
+		t.Fatalf("expected the use retried: %+v", store.batches)
+	}
+}
@@ -14,11 +14,18 @@ const (
 	KindInternal     Kind = "internal"
 )
 
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
 type Error struct {
 	Kind       Kind
 	Message    string
 	Err        error
 	RetryAfter time.Duration
+	Fields     []FieldError
 }
 
 func (e *Error) Error() string {
@@ -52,3 +59,7 @@ func Wrap(kind Kind, msg string, err error) *Error {
 func RateLimit(msg string, retryAfter time.Duration) *Error {
 	return &Error{Kind: KindRateLimited, Message: msg, RetryAfter: retryAfter}
 }
+
+func Validation(msg string, fields []FieldError) *Error {
+	return &Error{Kind: KindInvalidInput, Message: msg, Fields: fields}
+}
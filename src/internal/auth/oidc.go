@@ -0,0 +1,328 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PabloPavan/sniply_api/internal"
+	"github.com/PabloPavan/sniply_api/internal/apperrors"
+	"github.com/PabloPavan/sniply_api/internal/users"
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+)
+
+// DefaultStateTTL bounds how long a user may take at the identity provider.
+const DefaultStateTTL = 10 * time.Minute
+
+type IdentityStore interface {
+	GetByIdentity(ctx context.Context, provider, subject string) (users.User, error)
+	LinkIdentity(ctx context.Context, id users.Identity) error
+	CreateWithIdentity(ctx context.Context, u *users.User, id users.Identity) error
+	Update(ctx context.Context, u *users.UpdateUserRequest) error
+}
+
+// OIDCProvider is an OpenID Connect identity provider users can log in
+// with. Its discovery document is fetched on first use.
+type OIDCProvider struct {
+	Name         string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+	// AllowSignup provisions a user on the first login of an unknown account.
+	AllowSignup bool
+	// RoleClaim is the claim, or a dotted path to a nested claim, listing the
+	// user's groups or roles. On every login, users with one of AdminValues
+	// become admins and everyone else a plain user. Without a RoleClaim,
+	// roles are managed in sniply only.
+	RoleClaim   string
+	AdminValues []string
+
+	mu       sync.Mutex
+	provider *oidc.Provider
+}
+
+func (p *OIDCProvider) discover(ctx context.Context) (*oidc.Provider, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.provider != nil {
+		return p.provider, nil
+	}
+	prov, err := oidc.NewProvider(ctx, p.Issuer)
+	if err != nil {
+		return nil, err
+	}
+	p.provider = prov
+	return prov, nil
+}
+
+func (p *OIDCProvider) config(prov *oidc.Provider) *oauth2.Config {
+	scopes := p.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{oidc.ScopeOpenID, "email", "profile"}
+	}
+	return &oauth2.Config{
+		ClientID:     p.ClientID,
+		ClientSecret: p.ClientSecret,
+		RedirectURL:  p.RedirectURL,
+		Endpoint:     prov.Endpoint(),
+		Scopes:       scopes,
+	}
+}
+
+// role maps the role claim to a user role; ok is false without a RoleClaim.
+func (p *OIDCProvider) role(claims map[string]any) (users.UserRole, bool) {
+	if p.RoleClaim == "" {
+		return "", false
+	}
+	for _, v := range claimValues(claims, p.RoleClaim) {
+		if slices.Contains(p.AdminValues, v) {
+			return users.RoleAdmin, true
+		}
+	}
+	return users.RoleUser, true
+}
+
+type OIDCStart struct {
+	AuthURL   string
+	State     string
+	ExpiresAt time.Time
+}
+
+type OIDCCallbackInput struct {
+	Provider string
+	State    string
+	Code     string
+	Error    string
+}
+
+// oidcFlow is what the callback needs from the start of a login.
+type oidcFlow struct {
+	Provider string `json:"provider"`
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"verifier"`
+}
+
+func (s *Service) oidcProvider(name string) (*OIDCProvider, error) {
+	if s.States == nil || s.Identities == nil || s.Users == nil || s.Sessions == nil {
+		return nil, apperrors.New(apperrors.KindInternal, "auth not configured")
+	}
+	p := s.OIDC[name]
+	if p == nil {
+		return nil, apperrors.New(apperrors.KindNotFound, "unknown identity provider")
+	}
+	return p, nil
+}
+
+// StartOIDC begins an authorization code login with PKCE at the named
+// provider. The caller redirects the user to AuthURL and should bind State
+// to the browser, e.g. in a cookie, to check it on the callback.
+func (s *Service) StartOIDC(ctx context.Context, name string) (OIDCStart, error) {
+	p, err := s.oidcProvider(name)
+	if err != nil {
+		return OIDCStart{}, err
+	}
+	prov, err := p.discover(ctx)
+	if err != nil {
+		return OIDCStart{}, apperrors.Wrap(apperrors.KindInternal, "identity provider unavailable", err)
+	}
+
+	flow := oidcFlow{
+		Provider: p.Name,
+		Nonce:    internal.RandomHex(16),
+		Verifier: oauth2.GenerateVerifier(),
+	}
+	payload, err := json.Marshal(flow)
+	if err != nil {
+		return OIDCStart{}, apperrors.New(apperrors.KindInternal, "failed to start login")
+	}
+	ttl := s.StateTTL
+	if ttl <= 0 {
+		ttl = DefaultStateTTL
+	}
+	state := internal.RandomHex(24)
+	if err := s.States.Put(ctx, "oidc:"+state, payload, ttl); err != nil {
+		return OIDCStart{}, apperrors.New(apperrors.KindInternal, "failed to start login")
+	}
+
+	authURL := p.config(prov).AuthCodeURL(state, oidc.Nonce(flow.Nonce), oauth2.S256ChallengeOption(flow.Verifier))
+	return OIDCStart{AuthURL: authURL, State: state, ExpiresAt: time.Now().Add(ttl)}, nil
+}
+
+// FinishOIDC completes a login started by StartOIDC. The user is found by
+// the provider account, linked by verified email to an existing user or
+// provisioned, and has to pass the same second factor as a password login.
+func (s *Service) FinishOIDC(ctx context.Context, input OIDCCallbackInput) (LoginResult, error) {
+	p, err := s.oidcProvider(input.Provider)
+	if err != nil {
+		return LoginResult{}, err
+	}
+	if input.State == "" {
+		return LoginResult{}, apperrors.New(apperrors.KindUnauthorized, "invalid or expired login state")
+	}
+	// The state is single use, whatever the outcome.
+	payload, err := s.States.Take(ctx, "oidc:"+input.State)
+	if err != nil {
+		return LoginResult{}, apperrors.New(apperrors.KindUnauthorized, "invalid or expired login state")
+	}
+	var flow oidcFlow
+	if err := json.Unmarshal(payload, &flow); err != nil || flow.Provider != p.Name {
+		return LoginResult{}, apperrors.New(apperrors.KindUnauthorized, "invalid or expired login state")
+	}
+	if input.Error != "" {
+		return LoginResult{}, apperrors.New(apperrors.KindUnauthorized, "identity provider denied the login: "+input.Error)
+	}
+	if input.Code == "" {
+		return LoginResult{}, apperrors.New(apperrors.KindInvalidInput, "code is required")
+	}
+
+	prov, err := p.discover(ctx)
+	if err != nil {
+		return LoginResult{}, apperrors.Wrap(apperrors.KindInternal, "identity provider unavailable", err)
+	}
+	token, err := p.config(prov).Exchange(ctx, input.Code, oauth2.VerifierOption(flow.Verifier))
+	if err != nil {
+		return LoginResult{}, apperrors.Wrap(apperrors.KindUnauthorized, "failed to exchange code", err)
+	}
+	rawIDToken, _ := token.Extra("id_token").(string)
+	if rawIDToken == "" {
+		return LoginResult{}, apperrors.New(apperrors.KindUnauthorized, "identity provider returned no id token")
+	}
+	idToken, err := prov.Verifier(&oidc.Config{ClientID: p.ClientID}).Verify(ctx, rawIDToken)
+	if err != nil {
+		return LoginResult{}, apperrors.Wrap(apperrors.KindUnauthorized, "invalid id token", err)
+	}
+	if idToken.Nonce != flow.Nonce {
+		return LoginResult{}, apperrors.New(apperrors.KindUnauthorized, "invalid id token")
+	}
+	var claims map[string]any
+	if err := idToken.Claims(&claims); err != nil {
+		return LoginResult{}, apperrors.Wrap(apperrors.KindUnauthorized, "invalid id token", err)
+	}
+
+	u, err := s.oidcUser(ctx, p, idToken.Subject, claims)
+	if err != nil {
+		return LoginResult{}, err
+	}
+	return s.loginWithFactors(ctx, u)
+}
+
+// oidcUser resolves the user of a provider account and applies the role
+// mapping.
+func (s *Service) oidcUser(ctx context.Context, p *OIDCProvider, subject string, claims map[string]any) (users.User, error) {
+	role, mapped := p.role(claims)
+
+	u, err := s.Identities.GetByIdentity(ctx, p.Name, subject)
+	if err != nil && !users.IsNotFound(err) {
+		return users.User{}, apperrors.New(apperrors.KindInternal, "failed to load user")
+	}
+	if err != nil {
+		email, verified := emailClaim(claims)
+		if email == "" {
+			return users.User{}, apperrors.New(apperrors.KindUnauthorized, "identity provider returned no email")
+		}
+		identity := users.Identity{Provider: p.Name, Subject: subject, Email: email}
+
+		u, err = s.Users.GetByEmail(ctx, email)
+		switch {
+		case err == nil:
+			// Linking on an email the provider does not vouch for would hand
+			// the account to whoever registered that address there.
+			if !verified {
+				return users.User{}, apperrors.New(apperrors.KindConflict, "email already registered; the identity provider has not verified it")
+			}
+			// Nor would an account whose owner never proved the address:
+			// someone may have registered it here before the real owner.
+			if u.EmailVerifiedAt == nil {
+				return users.User{}, apperrors.New(apperrors.KindConflict, "email already registered; verify it before signing in with this provider")
+			}
+			identity.UserID = u.ID
+			if err := s.Identities.LinkIdentity(ctx, identity); err != nil {
+				return users.User{}, apperrors.New(apperrors.KindInternal, "failed to link identity")
+			}
+		case users.IsNotFound(err):
+			if !p.AllowSignup {
+				return users.User{}, apperrors.New(apperrors.KindForbidden, "no account for this identity")
+			}
+			u = users.User{ID: "usr_" + internal.RandomHex(12), Email: email, Role: users.RoleUser}
+			if mapped {
+				u.Role = role
+			}
+			if verified {
+				now := time.Now().UTC()
+				u.EmailVerifiedAt = &now
+			}
+			identity.UserID = u.ID
+			if err := s.Identities.CreateWithIdentity(ctx, &u, identity); err != nil {
+				if users.IsUniqueViolationEmail(err) {
+					return users.User{}, apperrors.New(apperrors.KindConflict, "email already exists")
+				}
+				return users.User{}, apperrors.New(apperrors.KindInternal, "failed to create user")
+			}
+		default:
+			return users.User{}, apperrors.New(apperrors.KindInternal, "failed to load user")
+		}
+	}
+
+	if mapped && u.Role != role {
+		if err := s.Identities.Update(ctx, &users.UpdateUserRequest{ID: u.ID, Role: role}); err != nil {
+			return users.User{}, apperrors.New(apperrors.KindInternal, "failed to update role")
+		}
+		u.Role = role
+		// Other sessions of the user pick up the new role.
+		if err := s.Sessions.BumpEpoch(ctx, u.ID); err != nil {
+			return users.User{}, apperrors.New(apperrors.KindInternal, "failed to update sessions")
+		}
+	}
+	return u, nil
+}
+
+// emailClaim returns the lowercased email claim and whether the provider
+// verified it. Some providers send email_verified as a string.
+func emailClaim(claims map[string]any) (string, bool) {
+	email, _ := claims["email"].(string)
+	email = strings.TrimSpace(strings.ToLower(email))
+	if !strings.Contains(email, "@") {
+		return "", false
+	}
+	switch v := claims["email_verified"].(type) {
+	case bool:
+		return email, v
+	case string:
+		return email, v == "true"
+	default:
+		return email, false
+	}
+}
+
+// claimValues returns the strings at a dotted claim path, e.g.
+// "realm_access.roles"; the claim may be a string or a list.
+func claimValues(claims map[string]any, path string) []string {
+	var v any = claims
+	for _, part := range strings.Split(path, ".") {
+		m, ok := v.(map[string]any)
+		if !ok {
+			return nil
+		}
+		v = m[part]
+	}
+	switch v := v.(type) {
+	case string:
+		return []string{v}
+	case []any:
+		out := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,70 @@
+package auth
+
+import (
+	"context"
+
+	"github.com/PabloPavan/sniply_api/internal/apperrors"
+	"github.com/PabloPavan/sniply_api/internal/passkeys"
+)
+
+type PasskeyVerifier interface {
+	Has(ctx context.Context, userID string) (bool, error)
+	BeginLogin(ctx context.Context, userID string) (*passkeys.Ceremony, error)
+	FinishLogin(ctx context.Context, ceremonyID, userID string, response []byte) (string, error)
+}
+
+// BeginPasskeyLogin starts a passwordless login, where the authenticator
+// picks the account.
+func (s *Service) BeginPasskeyLogin(ctx context.Context) (*passkeys.Ceremony, error) {
+	if s.Passkeys == nil {
+		return nil, apperrors.New(apperrors.KindInternal, "auth not configured")
+	}
+	return s.Passkeys.BeginLogin(ctx, "")
+}
+
+// FinishPasskeyLogin creates a session for the owner of the passkey. The
+// authenticator verified the user, so no second factor is asked for.
+func (s *Service) FinishPasskeyLogin(ctx context.Context, ceremonyID string, response []byte) (LoginResult, error) {
+	if s.Passkeys == nil || s.Users == nil || s.Sessions == nil {
+		return LoginResult{}, apperrors.New(apperrors.KindInternal, "auth not configured")
+	}
+	userID, err := s.Passkeys.FinishLogin(ctx, ceremonyID, "", response)
+	if err != nil {
+		return LoginResult{}, err
+	}
+	u, err := s.Users.GetByID(ctx, userID)
+	if err != nil {
+		return LoginResult{}, apperrors.New(apperrors.KindUnauthorized, "unauthorized")
+	}
+	return s.startSession(ctx, *u)
+}
+
+// BeginPasskeyTwoFactor starts an assertion with the passkeys of the user of
+// a pending login.
+func (s *Service) BeginPasskeyTwoFactor(ctx context.Context, pendingID string) (*passkeys.Ceremony, error) {
+	if s.Passkeys == nil {
+		return nil, apperrors.New(apperrors.KindInternal, "auth not configured")
+	}
+	sess, err := s.pending(ctx, pendingID)
+	if err != nil {
+		return nil, err
+	}
+	return s.Passkeys.BeginLogin(ctx, sess.UserID)
+}
+
+// CompletePasskeyTwoFactor finishes a pending login with a passkey.
+func (s *Service) CompletePasskeyTwoFactor(ctx context.Context, pendingID, ceremonyID string, response []byte) (LoginResult, error) {
+	if s.Passkeys == nil {
+		return LoginResult{}, apperrors.New(apperrors.KindInternal, "auth not configured")
+	}
+	sess, err := s.pending(ctx, pendingID)
+	if err != nil {
+		return LoginResult{}, err
+	}
+	attempt, err := s.attempt(ctx, sess)
+	if err != nil {
+		return LoginResult{}, err
+	}
+	_, err = s.Passkeys.FinishLogin(ctx, ceremonyID, sess.UserID, response)
+	return s.finishPending(ctx, sess, attempt, err)
+}
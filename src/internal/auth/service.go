@@ -8,6 +8,7 @@ import (
 
 	"github.com/PabloPavan/sniply_api/internal/apikeys"
 	"github.com/PabloPavan/sniply_api/internal/apperrors"
+	"github.com/PabloPavan/sniply_api/internal/identity"
 	"github.com/PabloPavan/sniply_api/internal/session"
 	"github.com/PabloPavan/sniply_api/internal/users"
 	"golang.org/x/crypto/bcrypt"
@@ -15,19 +16,32 @@ import (
 
 type UserStore interface {
 	GetByEmail(ctx context.Context, email string) (users.User, error)
+	GetByID(ctx context.Context, id string) (*users.User, error)
 }
 
 type SessionManager interface {
 	Create(ctx context.Context, userID, role string) (*session.Session, error)
+	CreatePending(ctx context.Context, userID, role string, enroll bool) (*session.Session, error)
+	Save(ctx context.Context, sess *session.Session) error
 	Get(ctx context.Context, id string) (*session.Session, error)
 	Refresh(ctx context.Context, sess *session.Session) (*session.Session, bool, error)
 	Delete(ctx context.Context, id string) error
+	List(ctx context.Context, userID string) ([]session.Session, error)
+	DeleteUser(ctx context.Context, userID, keepID string) error
+	Epoch(ctx context.Context, userID string) (int64, error)
+	BumpEpoch(ctx context.Context, userID string) error
+	CountAttempt(ctx context.Context, sess *session.Session) (int, error)
 }
 
 type APIKeyStore interface {
 	GetByTokenHash(ctx context.Context, hash string) (*apikeys.Key, error)
 }
 
+// APIKeyUsage notes key uses without blocking the request.
+type APIKeyUsage interface {
+	Record(u apikeys.Usage)
+}
+
 type RateLimiter interface {
 	Allow(ctx context.Context, key string) (bool, time.Duration, error)
 }
@@ -36,8 +50,22 @@ type Service struct {
 	Users            UserStore
 	Sessions         SessionManager
 	APIKeys          APIKeyStore
+	APIKeyUsage      APIKeyUsage
 	LoginLimiter     RateLimiter
 	PasswordVerifier func(hashed, plain string) error
+	// TwoFactor, when set, makes password logins of users with a second
+	// factor, or whose role requires one, wait for it.
+	TwoFactor SecondFactor
+	// Passkeys log users in without a password, and count as a second
+	// factor for password logins.
+	Passkeys PasskeyVerifier
+
+	// Single sign-on: providers by name, the users linked to them and the
+	// state kept between the redirect and the callback.
+	OIDC       map[string]*OIDCProvider
+	Identities IdentityStore
+	States     session.StateStore
+	StateTTL   time.Duration
 }
 
 type LoginInput struct {
@@ -59,6 +87,12 @@ type LoginResult struct {
 	UserEmail string
 	UserRole  string
 	Session   SessionInfo
+	// Pending is set instead of Session while the login waits for a second
+	// factor.
+	Pending *PendingLogin
+	// RecoveryCodes are set when the login enabled two-factor
+	// authentication.
+	RecoveryCodes []string
 }
 
 type Principal struct {
@@ -80,25 +114,14 @@ func (s *Service) Login(ctx context.Context, input LoginInput) (LoginResult, err
 		return LoginResult{}, apperrors.New(apperrors.KindInvalidInput, "invalid email")
 	}
 
-	if s.LoginLimiter != nil {
-		if strings.TrimSpace(input.ClientIP) != "" {
-			allowed, retryAfter, err := s.LoginLimiter.Allow(ctx, "login:ip:"+input.ClientIP)
-			if err != nil {
-				return LoginResult{}, apperrors.New(apperrors.KindInternal, "rate limit error")
-			}
-			if !allowed {
-				return LoginResult{}, apperrors.RateLimit("too many requests", retryAfter)
-			}
-		}
-
-		allowed, retryAfter, err := s.LoginLimiter.Allow(ctx, "login:email:"+email)
-		if err != nil {
-			return LoginResult{}, apperrors.New(apperrors.KindInternal, "rate limit error")
-		}
-		if !allowed {
-			return LoginResult{}, apperrors.RateLimit("too many requests", retryAfter)
+	if strings.TrimSpace(input.ClientIP) != "" {
+		if err := s.limit(ctx, "login:ip:"+input.ClientIP); err != nil {
+			return LoginResult{}, err
 		}
 	}
+	if err := s.limit(ctx, "login:email:"+email); err != nil {
+		return LoginResult{}, err
+	}
 
 	u, err := s.Users.GetByEmail(ctx, email)
 	if err != nil {
@@ -115,7 +138,26 @@ func (s *Service) Login(ctx context.Context, input LoginInput) (LoginResult, err
 	if err := verifier(u.PasswordHash, password); err != nil {
 		return LoginResult{}, apperrors.New(apperrors.KindUnauthorized, "invalid credentials")
 	}
+	return s.loginWithFactors(ctx, u)
+}
+
+// loginWithFactors starts the session of a user who passed the first
+// factor, or a pending login when the user has a second factor or their
+// role requires one.
+func (s *Service) loginWithFactors(ctx context.Context, u users.User) (LoginResult, error) {
+	if s.TwoFactor != nil || s.Passkeys != nil {
+		enabled, required, err := s.factors(ctx, u)
+		if err != nil {
+			return LoginResult{}, err
+		}
+		if enabled || required {
+			return s.startPending(ctx, u, !enabled)
+		}
+	}
+	return s.startSession(ctx, u)
+}
 
+func (s *Service) startSession(ctx context.Context, u users.User) (LoginResult, error) {
 	sess, err := s.Sessions.Create(ctx, u.ID, string(u.Role))
 	if err != nil {
 		return LoginResult{}, apperrors.New(apperrors.KindInternal, "failed to create session")
@@ -163,7 +205,8 @@ func (s *Service) AuthenticateAPIKey(ctx context.Context, token string, method s
 		}
 		return Principal{}, apperrors.New(apperrors.KindInternal, "failed to authenticate")
 	}
-	if key.RevokedAt != nil {
+	now := time.Now().UTC()
+	if !key.Active(now) {
 		return Principal{}, apperrors.New(apperrors.KindUnauthorized, "unauthorized")
 	}
 
@@ -171,6 +214,11 @@ func (s *Service) AuthenticateAPIKey(ctx context.Context, token string, method s
 		return Principal{}, apperrors.New(apperrors.KindForbidden, "forbidden")
 	}
 
+	if s.APIKeyUsage != nil {
+		_, ip := identity.Client(ctx)
+		s.APIKeyUsage.Record(apikeys.Usage{KeyID: key.ID, At: now, IP: ip})
+	}
+
 	return Principal{UserID: key.UserID, Role: key.UserRole}, nil
 }
 
@@ -183,7 +231,7 @@ func (s *Service) AuthenticateSession(ctx context.Context, sessionID, csrfToken,
 	}
 
 	sess, err := s.Sessions.Get(ctx, sessionID)
-	if err != nil {
+	if err != nil || sess.Pending {
 		return SessionInfo{}, false, apperrors.New(apperrors.KindUnauthorized, "unauthorized")
 	}
 
@@ -193,6 +241,10 @@ func (s *Service) AuthenticateSession(ctx context.Context, sessionID, csrfToken,
 		}
 	}
 
+	if err := s.syncUser(ctx, sess); err != nil {
+		return SessionInfo{}, false, err
+	}
+
 	refreshed := false
 	sess, refreshed, err = s.Sessions.Refresh(ctx, sess)
 	if err != nil {
@@ -212,6 +264,55 @@ func (s *Service) AuthenticateSession(ctx context.Context, sessionID, csrfToken,
 	return info, refreshed, nil
 }
 
+// syncUser reloads the role of a session when the user changed since the
+// session last read it. The epoch is read before the user, so a change in
+// between leaves the session stale again rather than wrongly current.
+func (s *Service) syncUser(ctx context.Context, sess *session.Session) error {
+	if s.Users == nil {
+		return nil
+	}
+	epoch, err := s.Sessions.Epoch(ctx, sess.UserID)
+	if err != nil {
+		return apperrors.New(apperrors.KindInternal, "failed to load session")
+	}
+	if epoch == sess.Epoch {
+		return nil
+	}
+
+	u, err := s.Users.GetByID(ctx, sess.UserID)
+	if err != nil {
+		if users.IsNotFound(err) {
+			_ = s.Sessions.Delete(ctx, sess.ID)
+			return apperrors.New(apperrors.KindUnauthorized, "unauthorized")
+		}
+		return apperrors.New(apperrors.KindInternal, "failed to load user")
+	}
+	sess.Role = string(u.Role)
+	sess.Epoch = epoch
+	if err := s.Sessions.Save(ctx, sess); err != nil {
+		if errors.Is(err, session.ErrNotFound) {
+			return apperrors.New(apperrors.KindUnauthorized, "unauthorized")
+		}
+		return apperrors.New(apperrors.KindInternal, "failed to save session")
+	}
+	return nil
+}
+
+// limit applies the login limiter to key, when one is configured.
+func (s *Service) limit(ctx context.Context, key string) error {
+	if s.LoginLimiter == nil {
+		return nil
+	}
+	allowed, retryAfter, err := s.LoginLimiter.Allow(ctx, key)
+	if err != nil {
+		return apperrors.New(apperrors.KindInternal, "rate limit error")
+	}
+	if !allowed {
+		return apperrors.RateLimit("too many requests", retryAfter)
+	}
+	return nil
+}
+
 func requiresCSRFToken(method string) bool {
 	switch method {
 	case "GET", "HEAD", "OPTIONS":
@@ -3,12 +3,23 @@ package auth
 import (
 	"context"
 	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/PabloPavan/sniply_api/internal/apikeys"
 	"github.com/PabloPavan/sniply_api/internal/apperrors"
+	"github.com/PabloPavan/sniply_api/internal/identity"
+	"github.com/PabloPavan/sniply_api/internal/oidctest"
+	"github.com/PabloPavan/sniply_api/internal/passkeys"
+	"github.com/PabloPavan/sniply_api/internal/passkeytest"
 	"github.com/PabloPavan/sniply_api/internal/session"
+	"github.com/PabloPavan/sniply_api/internal/twofactor"
 	"github.com/PabloPavan/sniply_api/internal/users"
+	"github.com/go-webauthn/webauthn/webauthn"
 )
 
 type userStoreStub struct {
@@ -22,6 +33,10 @@ func (u *userStoreStub) GetByEmail(ctx context.Context, email string) (users.Use
 	return users.User{}, users.ErrNotFound
 }
 
+func (u *userStoreStub) GetByID(ctx context.Context, id string) (*users.User, error) {
+	return nil, users.ErrNotFound
+}
+
 type sessionStub struct {
 	createFn  func(ctx context.Context, userID, role string) (*session.Session, error)
 	getFn     func(ctx context.Context, id string) (*session.Session, error)
@@ -36,6 +51,14 @@ func (s *sessionStub) Create(ctx context.Context, userID, role string) (*session
 	return nil, errors.New("not implemented")
 }
 
+func (s *sessionStub) CreatePending(ctx context.Context, userID, role string, enroll bool) (*session.Session, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (s *sessionStub) Save(ctx context.Context, sess *session.Session) error {
+	return nil
+}
+
 func (s *sessionStub) Get(ctx context.Context, id string) (*session.Session, error) {
 	if s.getFn != nil {
 		return s.getFn(ctx, id)
@@ -57,6 +80,26 @@ func (s *sessionStub) Delete(ctx context.Context, id string) error {
 	return nil
 }
 
+func (s *sessionStub) List(ctx context.Context, userID string) ([]session.Session, error) {
+	return nil, nil
+}
+
+func (s *sessionStub) DeleteUser(ctx context.Context, userID, keepID string) error {
+	return nil
+}
+
+func (s *sessionStub) Epoch(ctx context.Context, userID string) (int64, error) {
+	return 0, nil
+}
+
+func (s *sessionStub) BumpEpoch(ctx context.Context, userID string) error {
+	return nil
+}
+
+func (s *sessionStub) CountAttempt(ctx context.Context, sess *session.Session) (int, error) {
+	return 1, nil
+}
+
 func TestServiceLoginInvalidEmail(t *testing.T) {
 	store := &userStoreStub{}
 	sessions := &sessionStub{}
@@ -123,6 +166,642 @@ func TestServiceAuthenticateSessionForbidden(t *testing.T) {
 	assertKind(t, err, apperrors.KindForbidden)
 }
 
+func TestServiceSessionManagement(t *testing.T) {
+	dir := newDirectoryStub(
+		users.User{ID: "usr_1", Email: "user@local", PasswordHash: "pass", Role: users.RoleUser},
+		users.User{ID: "usr_2", Email: "other@local", PasswordHash: "pass", Role: users.RoleUser},
+	)
+	manager := &session.Manager{Store: session.NewMemoryStore(), TTL: time.Hour}
+	svc := &Service{Users: dir, Sessions: manager, PasswordVerifier: func(hashed, plain string) error { return nil }}
+
+	laptop := identity.WithClient(context.Background(), "Firefox", "10.0.0.1")
+	first, err := svc.Login(laptop, LoginInput{Email: "user@local", Password: "pass"})
+	if err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	second, err := svc.Login(identity.WithClient(context.Background(), "Phone", "10.0.0.2"), LoginInput{Email: "user@local", Password: "pass"})
+	if err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	if _, err := svc.Login(context.Background(), LoginInput{Email: "other@local", Password: "pass"}); err != nil {
+		t.Fatalf("login: %v", err)
+	}
+
+	ctx := identity.WithSession(identity.WithUser(laptop, "usr_1", string(users.RoleUser)), first.Session.ID)
+	list, err := svc.ListSessions(ctx)
+	if err != nil || len(list) != 2 {
+		t.Fatalf("list: %+v %v", list, err)
+	}
+	if list[0].UserAgent != "Phone" || list[0].IP != "10.0.0.2" || list[0].Current || !list[1].Current {
+		t.Fatalf("unexpected sessions: %+v", list)
+	}
+	if list[0].ID == second.Session.ID || list[0].ID != session.PublicID(second.Session.ID) {
+		t.Fatalf("expected the public ID, got %q", list[0].ID)
+	}
+
+	// A request from a new address is recorded.
+	moved := identity.WithClient(context.Background(), "Firefox", "10.0.0.9")
+	if _, _, err := svc.AuthenticateSession(moved, first.Session.ID, "", "GET"); err != nil {
+		t.Fatalf("authenticate: %v", err)
+	}
+	if sess, _ := manager.Get(ctx, first.Session.ID); sess.IP != "10.0.0.9" || sess.UserAgent != "Firefox" {
+		t.Fatalf("expected the new address: %+v", sess)
+	}
+
+	current, err := svc.RevokeSession(ctx, list[0].ID)
+	if err != nil || current {
+		t.Fatalf("revoke: %v %v", current, err)
+	}
+	_, _, err = svc.AuthenticateSession(ctx, second.Session.ID, "", "GET")
+	assertKind(t, err, apperrors.KindUnauthorized)
+	_, err = svc.RevokeSession(ctx, list[0].ID)
+	assertKind(t, err, apperrors.KindNotFound)
+
+	if err := svc.RevokeAllSessions(ctx); err != nil {
+		t.Fatalf("revoke all: %v", err)
+	}
+	_, _, err = svc.AuthenticateSession(ctx, first.Session.ID, "", "GET")
+	assertKind(t, err, apperrors.KindUnauthorized)
+	// Other users keep their sessions.
+	if others, _ := manager.List(ctx, "usr_2"); len(others) != 1 {
+		t.Fatalf("expected the other user's session kept: %+v", others)
+	}
+}
+
+type apiKeyStoreStub map[string]*apikeys.Key
+
+func (s apiKeyStoreStub) GetByTokenHash(ctx context.Context, hash string) (*apikeys.Key, error) {
+	if k, ok := s[hash]; ok {
+		return k, nil
+	}
+	return nil, apikeys.ErrNotFound
+}
+
+type usageStub struct {
+	uses []apikeys.Usage
+}
+
+func (u *usageStub) Record(use apikeys.Usage) {
+	u.uses = append(u.uses, use)
+}
+
+func TestServiceAuthenticateAPIKey(t *testing.T) {
+	expired := time.Now().Add(-time.Minute)
+	later := time.Now().Add(time.Hour)
+	keys := apiKeyStoreStub{
+		apikeys.HashToken("sk_live"):    {ID: "key_1", UserID: "usr_1", UserRole: "user", Scope: apikeys.ScopeRead, ExpiresAt: &later},
+		apikeys.HashToken("sk_expired"): {ID: "key_2", UserID: "usr_1", UserRole: "user", Scope: apikeys.ScopeRead, ExpiresAt: &expired},
+	}
+	usage := &usageStub{}
+	svc := &Service{APIKeys: keys, APIKeyUsage: usage}
+	ctx := identity.WithClient(context.Background(), "curl", "10.0.0.1")
+
+	p, err := svc.AuthenticateAPIKey(ctx, "sk_live", "GET")
+	if err != nil || p.UserID != "usr_1" {
+		t.Fatalf("authenticate: %+v %v", p, err)
+	}
+	if len(usage.uses) != 1 || usage.uses[0].KeyID != "key_1" || usage.uses[0].IP != "10.0.0.1" {
+		t.Fatalf("expected the use recorded: %+v", usage.uses)
+	}
+
+	_, err = svc.AuthenticateAPIKey(ctx, "sk_expired", "GET")
+	assertKind(t, err, apperrors.KindUnauthorized)
+	_, err = svc.AuthenticateAPIKey(ctx, "sk_live", "POST")
+	assertKind(t, err, apperrors.KindForbidden)
+	if len(usage.uses) != 1 {
+		t.Fatalf("expected only allowed requests recorded: %+v", usage.uses)
+	}
+}
+
+func TestServiceSessionFollowsRoleChanges(t *testing.T) {
+	dir := newDirectoryStub(users.User{ID: "usr_1", Email: "admin@local", PasswordHash: "pass", Role: users.RoleAdmin})
+	manager := &session.Manager{Store: session.NewMemoryStore(), TTL: time.Hour}
+	svc := &Service{Users: dir, Sessions: manager, PasswordVerifier: func(hashed, plain string) error { return nil }}
+	ctx := context.Background()
+
+	res, err := svc.Login(ctx, LoginInput{Email: "admin@local", Password: "pass"})
+	if err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	info, _, err := svc.AuthenticateSession(ctx, res.Session.ID, "", "GET")
+	if err != nil || info.Role != string(users.RoleAdmin) {
+		t.Fatalf("expected admin: %+v %v", info, err)
+	}
+
+	// Demoted mid-session.
+	u := dir.users["usr_1"]
+	u.Role = users.RoleUser
+	dir.users["usr_1"] = u
+	if err := manager.BumpEpoch(ctx, "usr_1"); err != nil {
+		t.Fatalf("bump: %v", err)
+	}
+	info, _, err = svc.AuthenticateSession(ctx, res.Session.ID, "", "GET")
+	if err != nil || info.Role != string(users.RoleUser) {
+		t.Fatalf("expected the demotion applied: %+v %v", info, err)
+	}
+	if sess, _ := manager.Get(ctx, res.Session.ID); sess.Role != string(users.RoleUser) || sess.Epoch != 1 {
+		t.Fatalf("expected the session rewritten: %+v", sess)
+	}
+
+	// A new session starts at the current epoch and needs no reload.
+	again, err := svc.Login(ctx, LoginInput{Email: "admin@local", Password: "pass"})
+	if err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	if sess, _ := manager.Get(ctx, again.Session.ID); sess.Epoch != 1 {
+		t.Fatalf("expected the session at epoch 1: %+v", sess)
+	}
+
+	// A deleted user loses the session.
+	delete(dir.users, "usr_1")
+	if err := manager.BumpEpoch(ctx, "usr_1"); err != nil {
+		t.Fatalf("bump: %v", err)
+	}
+	_, _, err = svc.AuthenticateSession(ctx, res.Session.ID, "", "GET")
+	assertKind(t, err, apperrors.KindUnauthorized)
+	if _, err := manager.Get(ctx, res.Session.ID); !errors.Is(err, session.ErrNotFound) {
+		t.Fatalf("expected the session deleted: %v", err)
+	}
+}
+
+// directoryStub keeps users and their provider links in memory.
+type directoryStub struct {
+	users map[string]users.User
+	links map[string]string
+}
+
+func newDirectoryStub(list ...users.User) *directoryStub {
+	d := &directoryStub{users: make(map[string]users.User), links: make(map[string]string)}
+	for _, u := range list {
+		d.users[u.ID] = u
+	}
+	return d
+}
+
+func (d *directoryStub) GetByEmail(ctx context.Context, email string) (users.User, error) {
+	for _, u := range d.users {
+		if u.Email == email {
+			return u, nil
+		}
+	}
+	return users.User{}, users.ErrNotFound
+}
+
+func (d *directoryStub) GetByID(ctx context.Context, id string) (*users.User, error) {
+	u, ok := d.users[id]
+	if !ok {
+		return nil, users.ErrNotFound
+	}
+	return &u, nil
+}
+
+func (d *directoryStub) GetByIdentity(ctx context.Context, provider, subject string) (users.User, error) {
+	id, ok := d.links[provider+"|"+subject]
+	if !ok {
+		return users.User{}, users.ErrNotFound
+	}
+	return d.users[id], nil
+}
+
+func (d *directoryStub) LinkIdentity(ctx context.Context, id users.Identity) error {
+	d.links[id.Provider+"|"+id.Subject] = id.UserID
+	return nil
+}
+
+func (d *directoryStub) CreateWithIdentity(ctx context.Context, u *users.User, id users.Identity) error {
+	u.CreatedAt = time.Now()
+	d.users[u.ID] = *u
+	return d.LinkIdentity(ctx, id)
+}
+
+func (d *directoryStub) Update(ctx context.Context, req *users.UpdateUserRequest) error {
+	u, ok := d.users[req.ID]
+	if !ok {
+		return users.ErrNotFound
+	}
+	u.Role = req.Role
+	d.users[req.ID] = u
+	return nil
+}
+
+func newOIDCService(t *testing.T, idp *oidctest.IdP, dir *directoryStub) *Service {
+	t.Helper()
+	sessions := &sessionStub{}
+	sessions.createFn = func(ctx context.Context, userID, role string) (*session.Session, error) {
+		return &session.Session{ID: "ses_" + userID, UserID: userID, Role: role, CSRFToken: "csrf", ExpiresAt: time.Now().Add(time.Hour)}, nil
+	}
+	return &Service{
+		Users:      dir,
+		Sessions:   sessions,
+		Identities: dir,
+		States:     session.NewMemoryStateStore(),
+		OIDC: map[string]*OIDCProvider{
+			"corp": {
+				Name:         "corp",
+				Issuer:       idp.Issuer(),
+				ClientID:     idp.ClientID,
+				ClientSecret: idp.ClientSecret,
+				RedirectURL:  "http://sniply.local/v1/auth/oidc/corp/callback",
+				AllowSignup:  true,
+				RoleClaim:    "realm_access.roles",
+				AdminValues:  []string{"sniply-admin"},
+			},
+		},
+	}
+}
+
+// oidcLogin goes through the provider's authorization endpoint like a
+// browser would and returns the callback input.
+func oidcLogin(t *testing.T, svc *Service) OIDCCallbackInput {
+	t.Helper()
+	start, err := svc.StartOIDC(context.Background(), "corp")
+	if err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	res, err := client.Get(start.AuthURL)
+	if err != nil {
+		t.Fatalf("authorize: %v", err)
+	}
+	res.Body.Close()
+	loc, err := res.Location()
+	if err != nil {
+		t.Fatalf("authorize redirect: %v", err)
+	}
+	q := loc.Query()
+	if q.Get("state") != start.State {
+		t.Fatalf("expected state %q, got %q", start.State, q.Get("state"))
+	}
+	return OIDCCallbackInput{Provider: "corp", State: q.Get("state"), Code: q.Get("code"), Error: q.Get("error")}
+}
+
+func roles(names ...string) map[string]any {
+	list := make([]any, 0, len(names))
+	for _, n := range names {
+		list = append(list, n)
+	}
+	return map[string]any{"roles": list}
+}
+
+func TestServiceOIDCProvisionsAndMapsRoles(t *testing.T) {
+	idp := oidctest.New("sniply", "secret")
+	defer idp.Close()
+	dir := newDirectoryStub()
+	svc := newOIDCService(t, idp, dir)
+	ctx := context.Background()
+
+	idp.SetUser("sub-1", map[string]any{"email": "Dev@Corp.example", "email_verified": true, "realm_access": roles("sniply-admin")})
+	res, err := svc.FinishOIDC(ctx, oidcLogin(t, svc))
+	if err != nil {
+		t.Fatalf("first login: %v", err)
+	}
+	if res.UserEmail != "dev@corp.example" || res.UserRole != string(users.RoleAdmin) || res.Session.ID == "" {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+	if len(dir.users) != 1 {
+		t.Fatalf("expected a provisioned user, got %d", len(dir.users))
+	}
+
+	// The next login finds the same user and follows the claims down.
+	idp.SetUser("sub-1", map[string]any{"email": "dev@corp.example", "realm_access": roles("developers")})
+	input := oidcLogin(t, svc)
+	again, err := svc.FinishOIDC(ctx, input)
+	if err != nil {
+		t.Fatalf("second login: %v", err)
+	}
+	if again.UserID != res.UserID || again.UserRole != string(users.RoleUser) || dir.users[res.UserID].Role != users.RoleUser {
+		t.Fatalf("expected the user demoted, got %+v", again)
+	}
+
+	_, err = svc.FinishOIDC(ctx, input)
+	assertKind(t, err, apperrors.KindUnauthorized)
+
+	_, err = svc.StartOIDC(ctx, "other")
+	assertKind(t, err, apperrors.KindNotFound)
+
+	idp.SetUser("", nil)
+	_, err = svc.FinishOIDC(ctx, oidcLogin(t, svc))
+	assertKind(t, err, apperrors.KindUnauthorized)
+}
+
+func TestServiceOIDCLinksVerifiedEmail(t *testing.T) {
+	idp := oidctest.New("sniply", "secret")
+	defer idp.Close()
+	verifiedAt := time.Now()
+	existing := users.User{ID: "usr_1", Email: "ana@corp.example", PasswordHash: "hash", Role: users.RoleUser, EmailVerifiedAt: &verifiedAt}
+	unverified := users.User{ID: "usr_2", Email: "bob@corp.example", PasswordHash: "hash", Role: users.RoleUser}
+	dir := newDirectoryStub(existing, unverified)
+	svc := newOIDCService(t, idp, dir)
+	svc.OIDC["corp"].RoleClaim = ""
+	ctx := context.Background()
+
+	idp.SetUser("sub-2", map[string]any{"email": "ana@corp.example", "email_verified": false})
+	_, err := svc.FinishOIDC(ctx, oidcLogin(t, svc))
+	assertKind(t, err, apperrors.KindConflict)
+
+	// A local account nobody verified is not handed to the provider's user.
+	idp.SetUser("sub-4", map[string]any{"email": "bob@corp.example", "email_verified": true})
+	_, err = svc.FinishOIDC(ctx, oidcLogin(t, svc))
+	assertKind(t, err, apperrors.KindConflict)
+	if _, ok := dir.links["corp|sub-4"]; ok {
+		t.Fatal("expected no link to an unverified account")
+	}
+
+	idp.SetUser("sub-2", map[string]any{"email": "ana@corp.example", "email_verified": "true"})
+	res, err := svc.FinishOIDC(ctx, oidcLogin(t, svc))
+	if err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	if res.UserID != "usr_1" || dir.links["corp|sub-2"] != "usr_1" {
+		t.Fatalf("expected the identity linked to usr_1, got %+v", res)
+	}
+
+	svc.OIDC["corp"].AllowSignup = false
+	idp.SetUser("sub-3", map[string]any{"email": "new@corp.example", "email_verified": true})
+	_, err = svc.FinishOIDC(ctx, oidcLogin(t, svc))
+	assertKind(t, err, apperrors.KindForbidden)
+}
+
+func TestServiceOIDCRequiresSecondFactor(t *testing.T) {
+	idp := oidctest.New("sniply", "secret")
+	defer idp.Close()
+	existing := users.User{ID: "usr_1", Email: "ana@corp.example", Role: users.RoleUser}
+	dir := newDirectoryStub(existing)
+	svc := newOIDCService(t, idp, dir)
+	svc.OIDC["corp"].RoleClaim = ""
+	svc.Sessions = &session.Manager{Store: session.NewMemoryStore(), TTL: time.Hour}
+	svc.TwoFactor = &secondFactorStub{enabled: map[string]bool{"usr_1": true}}
+	dir.links["corp|sub-1"] = "usr_1"
+
+	idp.SetUser("sub-1", map[string]any{"email": "ana@corp.example", "email_verified": true})
+	res, err := svc.FinishOIDC(context.Background(), oidcLogin(t, svc))
+	if err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	if res.Pending == nil || res.Session.ID != "" {
+		t.Fatalf("expected a pending login, got %+v", res)
+	}
+	done, err := svc.CompleteTwoFactor(context.Background(), res.Pending.ID, "123456")
+	if err != nil || done.Session.ID == "" || done.UserID != "usr_1" {
+		t.Fatalf("expected a session after the code, got %+v, %v", done, err)
+	}
+}
+
+// secondFactorStub accepts the code "123456" for enabled users.
+type secondFactorStub struct {
+	enabled  map[string]bool
+	required bool
+}
+
+func (f *secondFactorStub) Requirement(ctx context.Context, userID string, role users.UserRole) (bool, bool, error) {
+	return f.enabled[userID], f.required && role == users.RoleAdmin, nil
+}
+
+func (f *secondFactorStub) EnrollUser(ctx context.Context, userID string) (*twofactor.Enrollment, error) {
+	return &twofactor.Enrollment{Secret: "SECRET", URI: "otpauth://totp/x"}, nil
+}
+
+func (f *secondFactorStub) ConfirmUser(ctx context.Context, userID, code string) ([]string, error) {
+	if code != "123456" {
+		return nil, apperrors.New(apperrors.KindUnauthorized, "invalid code")
+	}
+	f.enabled[userID] = true
+	return []string{"aaaaa-bbbbb"}, nil
+}
+
+func (f *secondFactorStub) VerifyUser(ctx context.Context, userID, code string) error {
+	if !f.enabled[userID] || code != "123456" {
+		return apperrors.New(apperrors.KindUnauthorized, "invalid code")
+	}
+	return nil
+}
+
+func TestServiceLoginTwoFactor(t *testing.T) {
+	dir := newDirectoryStub(
+		users.User{ID: "usr_1", Email: "user@local", PasswordHash: "pass", Role: users.RoleUser},
+		users.User{ID: "usr_2", Email: "admin@local", PasswordHash: "pass", Role: users.RoleAdmin},
+	)
+	factors := &secondFactorStub{enabled: map[string]bool{"usr_1": true}}
+	svc := &Service{
+		Users:            dir,
+		Sessions:         &session.Manager{Store: session.NewMemoryStore(), TTL: time.Hour},
+		TwoFactor:        factors,
+		PasswordVerifier: func(hashed, plain string) error { return nil },
+	}
+	ctx := context.Background()
+
+	res, err := svc.Login(ctx, LoginInput{Email: "user@local", Password: "pass"})
+	if err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	if res.Pending == nil || res.Pending.Enroll || res.Session.ID != "" {
+		t.Fatalf("expected a pending login, got %+v", res)
+	}
+	_, _, err = svc.AuthenticateSession(ctx, res.Pending.ID, "", "GET")
+	assertKind(t, err, apperrors.KindUnauthorized)
+	_, err = svc.EnrollPending(ctx, res.Pending.ID)
+	assertKind(t, err, apperrors.KindConflict)
+
+	_, err = svc.CompleteTwoFactor(ctx, res.Pending.ID, "000000")
+	assertKind(t, err, apperrors.KindUnauthorized)
+	done, err := svc.CompleteTwoFactor(ctx, res.Pending.ID, "123456")
+	if err != nil {
+		t.Fatalf("complete: %v", err)
+	}
+	if _, _, err := svc.AuthenticateSession(ctx, done.Session.ID, "", "GET"); err != nil {
+		t.Fatalf("expected a usable session: %v", err)
+	}
+	_, err = svc.CompleteTwoFactor(ctx, res.Pending.ID, "123456")
+	assertKind(t, err, apperrors.KindUnauthorized)
+
+	// Too many wrong codes end the pending login.
+	res, _ = svc.Login(ctx, LoginInput{Email: "user@local", Password: "pass"})
+	for range maxPendingAttempts {
+		_, err = svc.CompleteTwoFactor(ctx, res.Pending.ID, "000000")
+		assertKind(t, err, apperrors.KindUnauthorized)
+	}
+	_, err = svc.CompleteTwoFactor(ctx, res.Pending.ID, "123456")
+	assertKind(t, err, apperrors.KindUnauthorized)
+
+	// A role requiring a second factor makes the user enroll one first.
+	factors.required = true
+	res, err = svc.Login(ctx, LoginInput{Email: "admin@local", Password: "pass"})
+	if err != nil || res.Pending == nil || !res.Pending.Enroll {
+		t.Fatalf("expected a pending enrollment, got %+v (%v)", res, err)
+	}
+	if _, err := svc.EnrollPending(ctx, res.Pending.ID); err != nil {
+		t.Fatalf("enroll: %v", err)
+	}
+	done, err = svc.CompleteTwoFactor(ctx, res.Pending.ID, "123456")
+	if err != nil {
+		t.Fatalf("complete enrollment: %v", err)
+	}
+	if done.Session.ID == "" || len(done.RecoveryCodes) != 1 || done.UserRole != string(users.RoleAdmin) {
+		t.Fatalf("unexpected result: %+v", done)
+	}
+}
+
+// countingFactor counts the codes checked.
+type countingFactor struct {
+	*secondFactorStub
+	checked atomic.Int32
+}
+
+func (f *countingFactor) VerifyUser(ctx context.Context, userID, code string) error {
+	f.checked.Add(1)
+	return f.secondFactorStub.VerifyUser(ctx, userID, code)
+}
+
+// keyLimiter denies keys with the given prefix.
+type keyLimiter struct {
+	deny string
+}
+
+func (l keyLimiter) Allow(ctx context.Context, key string) (bool, time.Duration, error) {
+	return !strings.HasPrefix(key, l.deny), time.Minute, nil
+}
+
+func TestServiceTwoFactorAttemptsAreCapped(t *testing.T) {
+	dir := newDirectoryStub(users.User{ID: "usr_1", Email: "user@local", PasswordHash: "pass", Role: users.RoleUser})
+	factors := &countingFactor{secondFactorStub: &secondFactorStub{enabled: map[string]bool{"usr_1": true}}}
+	svc := &Service{
+		Users:            dir,
+		Sessions:         &session.Manager{Store: session.NewMemoryStore(), TTL: time.Hour},
+		TwoFactor:        factors,
+		PasswordVerifier: func(hashed, plain string) error { return nil },
+	}
+	ctx := context.Background()
+
+	// Parallel guesses against one pending login share its attempts.
+	res, err := svc.Login(ctx, LoginInput{Email: "user@local", Password: "pass"})
+	if err != nil || res.Pending == nil {
+		t.Fatalf("login: %+v %v", res, err)
+	}
+	var wg sync.WaitGroup
+	for range 4 * maxPendingAttempts {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _ = svc.CompleteTwoFactor(ctx, res.Pending.ID, "000000")
+		}()
+	}
+	wg.Wait()
+	if n := factors.checked.Load(); n != maxPendingAttempts {
+		t.Fatalf("expected %d codes checked, got %d", maxPendingAttempts, n)
+	}
+	_, err = svc.CompleteTwoFactor(ctx, res.Pending.ID, "123456")
+	assertKind(t, err, apperrors.KindUnauthorized)
+
+	// The login limiter applies per user and per IP.
+	res, _ = svc.Login(ctx, LoginInput{Email: "user@local", Password: "pass"})
+	svc.LoginLimiter = keyLimiter{deny: "2fa:user:usr_1"}
+	_, err = svc.CompleteTwoFactor(ctx, res.Pending.ID, "123456")
+	assertKind(t, err, apperrors.KindRateLimited)
+	svc.LoginLimiter = keyLimiter{deny: "2fa:ip:10.0.0.1"}
+	_, err = svc.CompleteTwoFactor(identity.WithClient(ctx, "curl", "10.0.0.1"), res.Pending.ID, "123456")
+	assertKind(t, err, apperrors.KindRateLimited)
+	if _, err := svc.CompleteTwoFactor(identity.WithClient(ctx, "curl", "10.0.0.2"), res.Pending.ID, "123456"); err != nil {
+		t.Fatalf("complete from another address: %v", err)
+	}
+}
+
+func TestServicePasskeyLogin(t *testing.T) {
+	dir := newDirectoryStub(users.User{ID: "usr_1", Email: "user@local", PasswordHash: "pass", Role: users.RoleUser})
+	wa, err := webauthn.New(&webauthn.Config{RPID: "localhost", RPDisplayName: "Sniply", RPOrigins: []string{"http://localhost"}})
+	if err != nil {
+		t.Fatalf("webauthn config: %v", err)
+	}
+	keys := &passkeys.Service{Store: &credentialStoreStub{}, Users: dir, WebAuthn: wa, States: session.NewMemoryStateStore()}
+	svc := &Service{
+		Users:            dir,
+		Sessions:         &session.Manager{Store: session.NewMemoryStore(), TTL: time.Hour},
+		Passkeys:         keys,
+		PasswordVerifier: func(hashed, plain string) error { return nil },
+	}
+	ctx := context.Background()
+	authenticator := passkeytest.New("localhost", "http://localhost")
+
+	// Without a passkey, a password is enough.
+	res, err := svc.Login(ctx, LoginInput{Email: "user@local", Password: "pass"})
+	if err != nil || res.Pending != nil || res.Session.ID == "" {
+		t.Fatalf("expected a session, got %+v (%v)", res, err)
+	}
+
+	userCtx := identity.WithUser(ctx, "usr_1", string(users.RoleUser))
+	reg, err := keys.BeginRegistration(userCtx)
+	if err != nil {
+		t.Fatalf("begin registration: %v", err)
+	}
+	resp, _ := authenticator.Create(reg.Options)
+	if _, err := keys.FinishRegistration(userCtx, reg.ID, "laptop", resp); err != nil {
+		t.Fatalf("finish registration: %v", err)
+	}
+
+	// Passwordless.
+	c, err := svc.BeginPasskeyLogin(ctx)
+	if err != nil {
+		t.Fatalf("begin passkey login: %v", err)
+	}
+	resp, _ = authenticator.Get(c.Options)
+	res, err = svc.FinishPasskeyLogin(ctx, c.ID, resp)
+	if err != nil || res.UserID != "usr_1" || res.Session.ID == "" {
+		t.Fatalf("expected a session, got %+v (%v)", res, err)
+	}
+
+	// As the second factor of a password login.
+	res, err = svc.Login(ctx, LoginInput{Email: "user@local", Password: "pass"})
+	if err != nil || res.Pending == nil || res.Pending.Enroll {
+		t.Fatalf("expected a pending login, got %+v (%v)", res, err)
+	}
+	c, err = svc.BeginPasskeyTwoFactor(ctx, res.Pending.ID)
+	if err != nil {
+		t.Fatalf("begin passkey second factor: %v", err)
+	}
+	resp, _ = authenticator.Get(c.Options)
+	done, err := svc.CompletePasskeyTwoFactor(ctx, res.Pending.ID, c.ID, resp)
+	if err != nil {
+		t.Fatalf("complete: %v", err)
+	}
+	if _, _, err := svc.AuthenticateSession(ctx, done.Session.ID, "", "GET"); err != nil {
+		t.Fatalf("expected a usable session: %v", err)
+	}
+	_, err = svc.BeginPasskeyTwoFactor(ctx, res.Pending.ID)
+	assertKind(t, err, apperrors.KindUnauthorized)
+}
+
+type credentialStoreStub struct {
+	creds []passkeys.Credential
+}
+
+func (s *credentialStoreStub) List(ctx context.Context, userID string) ([]passkeys.Credential, error) {
+	return s.creds, nil
+}
+
+func (s *credentialStoreStub) Count(ctx context.Context, userID string) (int, error) {
+	return len(s.creds), nil
+}
+
+func (s *credentialStoreStub) Create(ctx context.Context, c *passkeys.Credential) (bool, error) {
+	s.creds = append(s.creds, *c)
+	return true, nil
+}
+
+func (s *credentialStoreStub) Use(ctx context.Context, id string, signCount uint32, backupState bool) (bool, error) {
+	for i := range s.creds {
+		if s.creds[i].ID == id && s.creds[i].SignCount < signCount {
+			s.creds[i].SignCount = signCount
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *credentialStoreStub) Delete(ctx context.Context, userID, id string) error {
+	return nil
+}
+
 func assertKind(t *testing.T, err error, kind apperrors.Kind) {
 	t.Helper()
 	if err == nil {
@@ -0,0 +1,106 @@
+package auth
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/PabloPavan/sniply_api/internal/apperrors"
+	"github.com/PabloPavan/sniply_api/internal/identity"
+	"github.com/PabloPavan/sniply_api/internal/session"
+)
+
+// ActiveSession describes one of the caller's logins. ID is the public ID
+// of the session, not its secret.
+type ActiveSession struct {
+	ID         string    `json:"id"`
+	UserAgent  string    `json:"user_agent"`
+	IP         string    `json:"ip"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	Current    bool      `json:"current"`
+}
+
+// ListSessions returns the logins of the current user.
+func (s *Service) ListSessions(ctx context.Context) ([]ActiveSession, error) {
+	list, err := s.userSessions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	current, _ := identity.SessionID(ctx)
+	out := make([]ActiveSession, 0, len(list))
+	for _, sess := range list {
+		out = append(out, ActiveSession{
+			ID:         session.PublicID(sess.ID),
+			UserAgent:  sess.UserAgent,
+			IP:         sess.IP,
+			CreatedAt:  sess.CreatedAt,
+			LastSeenAt: sess.LastSeenAt,
+			ExpiresAt:  sess.ExpiresAt,
+			Current:    sess.ID == current,
+		})
+	}
+	return out, nil
+}
+
+// RevokeSession ends one of the current user's logins by its public ID and
+// reports whether it was the calling session.
+func (s *Service) RevokeSession(ctx context.Context, publicID string) (bool, error) {
+	list, err := s.userSessions(ctx)
+	if err != nil {
+		return false, err
+	}
+	current, _ := identity.SessionID(ctx)
+	publicID = strings.TrimSpace(publicID)
+	for _, sess := range list {
+		if session.PublicID(sess.ID) != publicID {
+			continue
+		}
+		if err := s.Sessions.Delete(ctx, sess.ID); err != nil {
+			return false, apperrors.New(apperrors.KindInternal, "failed to revoke session")
+		}
+		return sess.ID == current, nil
+	}
+	return false, apperrors.New(apperrors.KindNotFound, "session not found")
+}
+
+// RevokeAllSessions logs the current user out everywhere, this session
+// included.
+func (s *Service) RevokeAllSessions(ctx context.Context) error {
+	if s.Sessions == nil {
+		return apperrors.New(apperrors.KindInternal, "auth not configured")
+	}
+	userID, ok := identity.UserID(ctx)
+	if !ok || strings.TrimSpace(userID) == "" {
+		return apperrors.New(apperrors.KindUnauthorized, "unauthorized")
+	}
+	if err := s.Sessions.DeleteUser(ctx, userID, ""); err != nil {
+		return apperrors.New(apperrors.KindInternal, "failed to revoke sessions")
+	}
+	return nil
+}
+
+func (s *Service) userSessions(ctx context.Context) ([]session.Session, error) {
+	if s.Sessions == nil {
+		return nil, apperrors.New(apperrors.KindInternal, "auth not configured")
+	}
+	userID, ok := identity.UserID(ctx)
+	if !ok || strings.TrimSpace(userID) == "" {
+		return nil, apperrors.New(apperrors.KindUnauthorized, "unauthorized")
+	}
+
+	list, err := s.Sessions.List(ctx, userID)
+	if err != nil {
+		return nil, apperrors.New(apperrors.KindInternal, "failed to list sessions")
+	}
+	out := list[:0]
+	for _, sess := range list {
+		// Pending logins are not sessions yet.
+		if !sess.Pending {
+			out = append(out, sess)
+		}
+	}
+	return out, nil
+}
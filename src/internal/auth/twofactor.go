@@ -0,0 +1,173 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/PabloPavan/sniply_api/internal/apperrors"
+	"github.com/PabloPavan/sniply_api/internal/identity"
+	"github.com/PabloPavan/sniply_api/internal/session"
+	"github.com/PabloPavan/sniply_api/internal/twofactor"
+	"github.com/PabloPavan/sniply_api/internal/users"
+)
+
+// maxPendingAttempts is how many wrong codes end a pending login.
+const maxPendingAttempts = 5
+
+type SecondFactor interface {
+	Requirement(ctx context.Context, userID string, role users.UserRole) (enabled, required bool, err error)
+	EnrollUser(ctx context.Context, userID string) (*twofactor.Enrollment, error)
+	ConfirmUser(ctx context.Context, userID, code string) ([]string, error)
+	VerifyUser(ctx context.Context, userID, code string) error
+}
+
+type PendingLogin struct {
+	ID        string
+	ExpiresAt time.Time
+	// Enroll is set when the user's role requires a second factor the user
+	// has not set up yet.
+	Enroll bool
+}
+
+// factors reports whether the user has a second factor, TOTP or a passkey,
+// and whether their role requires one.
+func (s *Service) factors(ctx context.Context, u users.User) (bool, bool, error) {
+	var enabled, required bool
+	if s.TwoFactor != nil {
+		var err error
+		if enabled, required, err = s.TwoFactor.Requirement(ctx, u.ID, u.Role); err != nil {
+			return false, false, err
+		}
+	}
+	if !enabled && s.Passkeys != nil {
+		has, err := s.Passkeys.Has(ctx, u.ID)
+		if err != nil {
+			return false, false, err
+		}
+		enabled = has
+	}
+	return enabled, required, nil
+}
+
+func (s *Service) startPending(ctx context.Context, u users.User, enroll bool) (LoginResult, error) {
+	sess, err := s.Sessions.CreatePending(ctx, u.ID, string(u.Role), enroll)
+	if err != nil {
+		return LoginResult{}, apperrors.New(apperrors.KindInternal, "failed to create session")
+	}
+	return LoginResult{
+		UserID:    u.ID,
+		UserEmail: u.Email,
+		UserRole:  string(u.Role),
+		Pending:   &PendingLogin{ID: sess.ID, ExpiresAt: sess.ExpiresAt, Enroll: sess.Enroll},
+	}, nil
+}
+
+func (s *Service) pending(ctx context.Context, pendingID string) (*session.Session, error) {
+	if s.Sessions == nil || s.Users == nil {
+		return nil, apperrors.New(apperrors.KindInternal, "auth not configured")
+	}
+	if strings.TrimSpace(pendingID) == "" {
+		return nil, apperrors.New(apperrors.KindUnauthorized, "no pending login")
+	}
+	sess, err := s.Sessions.Get(ctx, pendingID)
+	if err != nil || !sess.Pending {
+		return nil, apperrors.New(apperrors.KindUnauthorized, "no pending login")
+	}
+	return sess, nil
+}
+
+// EnrollPending starts the TOTP enrollment a pending login needs before it
+// can be completed.
+func (s *Service) EnrollPending(ctx context.Context, pendingID string) (*twofactor.Enrollment, error) {
+	if s.TwoFactor == nil {
+		return nil, apperrors.New(apperrors.KindInternal, "auth not configured")
+	}
+	sess, err := s.pending(ctx, pendingID)
+	if err != nil {
+		return nil, err
+	}
+	if !sess.Enroll {
+		return nil, apperrors.New(apperrors.KindConflict, "two-factor authentication is already enabled")
+	}
+	return s.TwoFactor.EnrollUser(ctx, sess.UserID)
+}
+
+// CompleteTwoFactor finishes a pending login with a TOTP or recovery code,
+// or with the first code of the enrollment it required, and creates the
+// session.
+func (s *Service) CompleteTwoFactor(ctx context.Context, pendingID, code string) (LoginResult, error) {
+	if s.TwoFactor == nil {
+		return LoginResult{}, apperrors.New(apperrors.KindInternal, "auth not configured")
+	}
+	sess, err := s.pending(ctx, pendingID)
+	if err != nil {
+		return LoginResult{}, err
+	}
+	attempt, err := s.attempt(ctx, sess)
+	if err != nil {
+		return LoginResult{}, err
+	}
+
+	var recoveryCodes []string
+	if sess.Enroll {
+		recoveryCodes, err = s.TwoFactor.ConfirmUser(ctx, sess.UserID, code)
+	} else {
+		err = s.TwoFactor.VerifyUser(ctx, sess.UserID, code)
+	}
+	result, err := s.finishPending(ctx, sess, attempt, err)
+	if err != nil {
+		return LoginResult{}, err
+	}
+	result.RecoveryCodes = recoveryCodes
+	return result, nil
+}
+
+// attempt rate limits second factor guesses per user and per IP, and counts
+// this one against the pending login before the factor is checked, so
+// parallel requests cannot make more than maxPendingAttempts.
+func (s *Service) attempt(ctx context.Context, sess *session.Session) (int, error) {
+	if _, ip := identity.Client(ctx); ip != "" {
+		if err := s.limit(ctx, "2fa:ip:"+ip); err != nil {
+			return 0, err
+		}
+	}
+	if err := s.limit(ctx, "2fa:user:"+sess.UserID); err != nil {
+		return 0, err
+	}
+
+	n, err := s.Sessions.CountAttempt(ctx, sess)
+	if err != nil {
+		if errors.Is(err, session.ErrNotFound) {
+			return 0, apperrors.New(apperrors.KindUnauthorized, "no pending login")
+		}
+		return 0, apperrors.New(apperrors.KindInternal, "failed to count attempt")
+	}
+	if n > maxPendingAttempts {
+		_ = s.Sessions.Delete(ctx, sess.ID)
+		return 0, apperrors.New(apperrors.KindUnauthorized, "no pending login")
+	}
+	return n, nil
+}
+
+// finishPending creates the session of a pending login whose second factor
+// was verified. A failed last attempt ends the pending login.
+func (s *Service) finishPending(ctx context.Context, sess *session.Session, attempt int, verifyErr error) (LoginResult, error) {
+	if verifyErr != nil {
+		var appErr *apperrors.Error
+		if errors.As(verifyErr, &appErr) && appErr.Kind == apperrors.KindUnauthorized && attempt >= maxPendingAttempts {
+			_ = s.Sessions.Delete(ctx, sess.ID)
+		}
+		return LoginResult{}, verifyErr
+	}
+
+	if err := s.Sessions.Delete(ctx, sess.ID); err != nil {
+		return LoginResult{}, apperrors.New(apperrors.KindInternal, "failed to create session")
+	}
+	u, err := s.Users.GetByID(ctx, sess.UserID)
+	if err != nil {
+		return LoginResult{}, apperrors.New(apperrors.KindUnauthorized, "unauthorized")
+	}
+	return s.startSession(ctx, *u)
+}
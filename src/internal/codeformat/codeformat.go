@@ -0,0 +1,164 @@
+package codeformat
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/format"
+	"go/scanner"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+var ErrUnsupported = errors.New("language not supported")
+
+// Error is a parse error located in the snippet content. Line and Column are
+// 1-based; zero means unknown.
+type Error struct {
+	Line    int    `json:"line"`
+	Column  int    `json:"column"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	if e.Line == 0 {
+		return e.Message
+	}
+	return fmt.Sprintf("%d:%d: %s", e.Line, e.Column, e.Message)
+}
+
+type formatter func(content string) (string, error)
+
+var formatters = map[string]formatter{
+	"go":   formatGo,
+	"json": formatJSON,
+	"yaml": formatYAML,
+	"sql":  formatSQL,
+}
+
+// validators covers languages that are checked but not formatted: no
+// TOML encoder keeps comments and key order.
+var validators = map[string]func(content string) error{
+	"toml": validateTOML,
+}
+
+// Supported reports whether language has a validator.
+func Supported(language string) bool {
+	_, ok := formatters[language]
+	_, validated := validators[language]
+	return ok || validated
+}
+
+// CanFormat reports whether Format handles language.
+func CanFormat(language string) bool {
+	_, ok := formatters[language]
+	return ok
+}
+
+// Validate parses content and returns an *Error when it is not valid.
+func Validate(language, content string) error {
+	if v, ok := validators[language]; ok {
+		return v(content)
+	}
+	_, err := Format(language, content)
+	return err
+}
+
+// Format returns content in the canonical layout for language.
+func Format(language, content string) (string, error) {
+	f, ok := formatters[language]
+	if !ok {
+		return "", ErrUnsupported
+	}
+	return f(content)
+}
+
+func formatGo(content string) (string, error) {
+	out, err := format.Source([]byte(content))
+	if err != nil {
+		var list scanner.ErrorList
+		if errors.As(err, &list) && len(list) > 0 {
+			return "", &Error{Line: list[0].Pos.Line, Column: list[0].Pos.Column, Message: list[0].Msg}
+		}
+		return "", &Error{Message: err.Error()}
+	}
+	return string(out), nil
+}
+
+func formatJSON(content string) (string, error) {
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, []byte(content), "", "  "); err != nil {
+		var syntaxErr *json.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			// Offset counts the offending byte as already read.
+			line, col := position(content, max(int(syntaxErr.Offset)-1, 0))
+			return "", &Error{Line: line, Column: col, Message: strings.TrimPrefix(err.Error(), "json: ")}
+		}
+		return "", &Error{Message: err.Error()}
+	}
+	buf.WriteByte('\n')
+	return buf.String(), nil
+}
+
+var yamlLineRe = regexp.MustCompile(`^yaml: line (\d+): (.*)$`)
+
+func formatYAML(content string) (string, error) {
+	dec := yaml.NewDecoder(strings.NewReader(content))
+	var docs []*yaml.Node
+	for {
+		var node yaml.Node
+		if err := dec.Decode(&node); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if m := yamlLineRe.FindStringSubmatch(err.Error()); m != nil {
+				line, _ := strconv.Atoi(m[1])
+				return "", &Error{Line: line, Column: 1, Message: m[2]}
+			}
+			return "", &Error{Message: strings.TrimPrefix(err.Error(), "yaml: ")}
+		}
+		docs = append(docs, &node)
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	for _, doc := range docs {
+		if err := enc.Encode(doc); err != nil {
+			return "", &Error{Message: err.Error()}
+		}
+	}
+	if err := enc.Close(); err != nil {
+		return "", &Error{Message: err.Error()}
+	}
+	return buf.String(), nil
+}
+
+func validateTOML(content string) error {
+	var doc map[string]any
+	if err := toml.Unmarshal([]byte(content), &doc); err != nil {
+		var decodeErr *toml.DecodeError
+		if errors.As(err, &decodeErr) {
+			line, col := decodeErr.Position()
+			return &Error{Line: line, Column: col, Message: decodeErr.Error()}
+		}
+		return &Error{Message: err.Error()}
+	}
+	return nil
+}
+
+func position(content string, offset int) (int, int) {
+	if offset > len(content) {
+		offset = len(content)
+	}
+	before := content[:offset]
+	line := strings.Count(before, "\n") + 1
+	col := offset - strings.LastIndex(before, "\n")
+	return line, col
+}
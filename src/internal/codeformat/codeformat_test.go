@@ -0,0 +1,77 @@
+package codeformat
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestFormat(t *testing.T) {
+	cases := []struct {
+		lang string
+		in   string
+		want string
+	}{
+		{"go", "package main\nfunc main(){x:=1\n_=x}\n", "package main\n\nfunc main() {\n\tx := 1\n\t_ = x\n}\n"},
+		{"json", `{"a":[1,2],"b":{"c":true}}`, "{\n  \"a\": [\n    1,\n    2\n  ],\n  \"b\": {\n    \"c\": true\n  }\n}\n"},
+		{"yaml", "a:   1\nb:\n    - x   # keep\n    - y\n", "a: 1\nb:\n  - x # keep\n  - y\n"},
+		{"sql", "select id, count(*) from users u left join orders o on o.user_id = u.id where u.active = true group by id;", "SELECT id, count(*)\nFROM users u\nLEFT JOIN orders o ON o.user_id = u.id\nWHERE u.active = true\nGROUP BY id;\n"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.lang, func(t *testing.T) {
+			got, err := Format(tc.lang, tc.in)
+			if err != nil {
+				t.Fatalf("format error: %v", err)
+			}
+			if got != tc.want {
+				t.Fatalf("unexpected output:\n%q\nwant:\n%q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestValidateErrors(t *testing.T) {
+	cases := []struct {
+		lang string
+		in   string
+		line int
+	}{
+		{"go", "package main\n\nfunc main() {\n\tx := \n}\n", 5},
+		{"json", "{\n  \"a\": 1,\n  \"b\": \n}", 4},
+		{"yaml", "a: 1\nb: x\n  c: 2\n", 3},
+		{"toml", "a = 1\nb = \n", 2},
+		{"sql", "select 'abc from t;\n", 1},
+		{"sql", "select (1 + 2 from t;\n", 1},
+	}
+	for _, tc := range cases {
+		t.Run(tc.lang, func(t *testing.T) {
+			err := Validate(tc.lang, tc.in)
+			var fmtErr *Error
+			if !errors.As(err, &fmtErr) {
+				t.Fatalf("expected *Error, got %v", err)
+			}
+			if fmtErr.Line != tc.line || fmtErr.Column == 0 {
+				t.Fatalf("unexpected position: %d:%d (%s)", fmtErr.Line, fmtErr.Column, fmtErr.Message)
+			}
+		})
+	}
+}
+
+func TestUnsupported(t *testing.T) {
+	if Supported("python") {
+		t.Fatalf("python should not be supported")
+	}
+	if _, err := Format("python", "x = 1"); !errors.Is(err, ErrUnsupported) {
+		t.Fatalf("expected ErrUnsupported, got %v", err)
+	}
+
+	// TOML is validated but never rewritten: re-encoding drops comments.
+	if !Supported("toml") || CanFormat("toml") {
+		t.Fatalf("toml should be validated only")
+	}
+	if _, err := Format("toml", "# keep\na = 1\n"); !errors.Is(err, ErrUnsupported) {
+		t.Fatalf("expected ErrUnsupported, got %v", err)
+	}
+	if err := Validate("toml", "# keep\na = 1\n"); err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+}
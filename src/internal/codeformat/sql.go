@@ -0,0 +1,284 @@
+package codeformat
+
+import (
+	"strings"
+	"unicode"
+)
+
+type sqlTokenKind int
+
+const (
+	sqlWord sqlTokenKind = iota
+	sqlString
+	sqlQuoted
+	sqlNumber
+	sqlComment
+	sqlPunct
+)
+
+type sqlToken struct {
+	kind sqlTokenKind
+	text string
+	line int
+	col  int
+}
+
+var sqlKeywords = func() map[string]bool {
+	words := strings.Fields(`
+		add all alter and any as asc begin between by case cascade check
+		column commit constraint create cross default delete desc distinct
+		drop else end exists foreign from full group having if in index
+		inner insert into is join key left like limit not null offset on
+		or order outer primary references returning right rollback select
+		set table then to union unique update using values view when where
+		with`)
+	out := make(map[string]bool, len(words))
+	for _, w := range words {
+		out[w] = true
+	}
+	return out
+}()
+
+// Clauses that start on a new line, longest first so "group by" wins over
+// "group".
+var sqlClauses = [][]string{
+	{"insert", "into"}, {"delete", "from"}, {"group", "by"}, {"order", "by"},
+	{"left", "outer", "join"}, {"right", "outer", "join"}, {"full", "outer", "join"},
+	{"left", "join"}, {"right", "join"}, {"full", "join"}, {"inner", "join"}, {"cross", "join"},
+	{"union", "all"},
+	{"select"}, {"from"}, {"where"}, {"having"}, {"limit"}, {"offset"}, {"join"},
+	{"union"}, {"values"}, {"set"}, {"returning"}, {"update"}, {"with"},
+}
+
+func tokenizeSQL(content string) ([]sqlToken, error) {
+	var tokens []sqlToken
+	runes := []rune(content)
+	line, col := 1, 1
+	i := 0
+
+	advance := func(n int) {
+		for k := 0; k < n && i < len(runes); k++ {
+			if runes[i] == '\n' {
+				line++
+				col = 1
+			} else {
+				col++
+			}
+			i++
+		}
+	}
+
+	for i < len(runes) {
+		r := runes[i]
+		startLine, startCol, start := line, col, i
+
+		switch {
+		case unicode.IsSpace(r):
+			advance(1)
+			continue
+		case r == '-' && i+1 < len(runes) && runes[i+1] == '-':
+			for i < len(runes) && runes[i] != '\n' {
+				advance(1)
+			}
+			tokens = append(tokens, sqlToken{sqlComment, string(runes[start:i]), startLine, startCol})
+			continue
+		case r == '/' && i+1 < len(runes) && runes[i+1] == '*':
+			advance(2)
+			for i < len(runes) && !(runes[i] == '*' && i+1 < len(runes) && runes[i+1] == '/') {
+				advance(1)
+			}
+			if i >= len(runes) {
+				return nil, &Error{Line: startLine, Column: startCol, Message: "unterminated comment"}
+			}
+			advance(2)
+			tokens = append(tokens, sqlToken{sqlComment, string(runes[start:i]), startLine, startCol})
+			continue
+		case r == '\'' || r == '"':
+			kind := sqlString
+			if r == '"' {
+				kind = sqlQuoted
+			}
+			advance(1)
+			for {
+				if i >= len(runes) {
+					return nil, &Error{Line: startLine, Column: startCol, Message: "unterminated quoted string"}
+				}
+				if runes[i] == r {
+					if i+1 < len(runes) && runes[i+1] == r {
+						advance(2)
+						continue
+					}
+					advance(1)
+					break
+				}
+				advance(1)
+			}
+			tokens = append(tokens, sqlToken{kind, string(runes[start:i]), startLine, startCol})
+			continue
+		case unicode.IsDigit(r):
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.') {
+				advance(1)
+			}
+			tokens = append(tokens, sqlToken{sqlNumber, string(runes[start:i]), startLine, startCol})
+			continue
+		case unicode.IsLetter(r) || r == '_' || r == '$' || r == '@' || r == ':':
+			advance(1)
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_' || runes[i] == '$') {
+				advance(1)
+			}
+			tokens = append(tokens, sqlToken{sqlWord, string(runes[start:i]), startLine, startCol})
+			continue
+		}
+
+		// Multi-character operators.
+		width := 1
+		if i+1 < len(runes) {
+			switch string(runes[i : i+2]) {
+			case "<=", ">=", "<>", "!=", "::", "||", "->":
+				width = 2
+			}
+		}
+		advance(width)
+		tokens = append(tokens, sqlToken{sqlPunct, string(runes[start:i]), startLine, startCol})
+	}
+
+	depth := 0
+	var open []sqlToken
+	for _, t := range tokens {
+		if t.kind != sqlPunct {
+			continue
+		}
+		switch t.text {
+		case "(":
+			depth++
+			open = append(open, t)
+		case ")":
+			if depth == 0 {
+				return nil, &Error{Line: t.line, Column: t.col, Message: "unexpected )"}
+			}
+			depth--
+			open = open[:len(open)-1]
+		}
+	}
+	if depth > 0 {
+		t := open[len(open)-1]
+		return nil, &Error{Line: t.line, Column: t.col, Message: "unclosed ("}
+	}
+	return tokens, nil
+}
+
+// formatSQL upper-cases keywords, puts each top-level clause on its own line
+// and normalizes spacing. It checks quoting and parentheses but does not
+// parse the statement grammar.
+func formatSQL(content string) (string, error) {
+	tokens, err := tokenizeSQL(content)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	depth := 0
+	atLineStart := true
+	prev := sqlToken{kind: -1}
+
+	newline := func() {
+		if b.Len() > 0 && !atLineStart {
+			b.WriteByte('\n')
+		}
+		atLineStart = true
+	}
+
+	for i := 0; i < len(tokens); i++ {
+		t := tokens[i]
+
+		if t.kind == sqlWord && depth == 0 {
+			if n := matchClause(tokens[i:]); n > 0 {
+				newline()
+				for k := 0; k < n; k++ {
+					if k > 0 {
+						b.WriteByte(' ')
+					}
+					b.WriteString(strings.ToUpper(tokens[i+k].text))
+				}
+				atLineStart = false
+				prev = tokens[i+n-1]
+				i += n - 1
+				continue
+			}
+		}
+
+		text := t.text
+		if t.kind == sqlWord && sqlKeywords[strings.ToLower(text)] {
+			text = strings.ToUpper(text)
+		}
+
+		if t.kind == sqlComment && strings.HasPrefix(text, "--") {
+			if !atLineStart {
+				b.WriteByte(' ')
+			}
+			b.WriteString(text)
+			b.WriteByte('\n')
+			atLineStart = true
+			prev = t
+			continue
+		}
+
+		if !atLineStart && needsSpace(prev, t) {
+			b.WriteByte(' ')
+		}
+		b.WriteString(text)
+		atLineStart = false
+
+		if t.kind == sqlPunct {
+			switch t.text {
+			case "(":
+				depth++
+			case ")":
+				depth--
+			case ";":
+				b.WriteString("\n\n")
+				atLineStart = true
+			}
+		}
+		prev = t
+	}
+
+	return strings.TrimRight(b.String(), "\n") + "\n", nil
+}
+
+func matchClause(tokens []sqlToken) int {
+	for _, clause := range sqlClauses {
+		if len(tokens) < len(clause) {
+			continue
+		}
+		ok := true
+		for k, word := range clause {
+			if tokens[k].kind != sqlWord || !strings.EqualFold(tokens[k].text, word) {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			return len(clause)
+		}
+	}
+	return 0
+}
+
+func needsSpace(prev, cur sqlToken) bool {
+	if cur.kind == sqlPunct {
+		switch cur.text {
+		case ",", ")", ";", ".", "::", "[", "]":
+			return false
+		case "(":
+			return prev.kind != sqlWord || sqlKeywords[strings.ToLower(prev.text)]
+		}
+	}
+	if prev.kind == sqlPunct {
+		switch prev.text {
+		case "(", ".", "::", "[":
+			return false
+		}
+	}
+	return true
+}
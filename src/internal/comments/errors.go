@@ -0,0 +1,13 @@
+package comments
+
+import (
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var ErrNotFound = errors.New("comment not found")
+
+func IsNotFound(err error) bool {
+	return errors.Is(err, pgx.ErrNoRows) || errors.Is(err, ErrNotFound)
+}
@@ -0,0 +1,30 @@
+package comments
+
+import "time"
+
+type Comment struct {
+	ID        string `json:"id"`
+	SnippetID string `json:"snippet_id"`
+	ParentID  string `json:"parent_id,omitempty"`
+	AuthorID  string `json:"author_id"`
+	Body      string `json:"body"`
+
+	// LineStart and LineEnd anchor the comment to lines of the given snippet
+	// revision. All three are nil for comments on the whole snippet.
+	LineStart *int `json:"line_start,omitempty"`
+	LineEnd   *int `json:"line_end,omitempty"`
+	Revision  *int `json:"revision,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	Replies []*Comment `json:"replies,omitempty"`
+}
+
+type CreateInput struct {
+	ParentID  string
+	Body      string
+	LineStart *int
+	LineEnd   *int
+	Revision  *int
+}
@@ -0,0 +1,126 @@
+package comments
+
+import (
+	"context"
+
+	"github.com/PabloPavan/sniply_api/internal/db"
+)
+
+type Repository struct {
+	base *db.Base
+}
+
+func NewRepository(base *db.Base) *Repository {
+	return &Repository{base: base}
+}
+
+const (
+	sqlCommentInsert = `INSERT INTO snippet_comments (id, snippet_id, parent_id, author_id, body, line_start, line_end, revision)
+		VALUES ($1, $2, NULLIF($3, ''), $4, $5, $6, $7, $8)
+		RETURNING created_at, updated_at`
+
+	sqlCommentGetByID = `SELECT id, snippet_id, COALESCE(parent_id, ''), author_id, body, line_start, line_end, revision, created_at, updated_at
+		FROM snippet_comments
+		WHERE id = $1`
+
+	sqlCommentListBySnippet = `SELECT id, snippet_id, COALESCE(parent_id, ''), author_id, body, line_start, line_end, revision, created_at, updated_at
+		FROM snippet_comments
+		WHERE snippet_id = $1
+		ORDER BY created_at ASC, id ASC`
+
+	sqlCommentUpdateBody = `UPDATE snippet_comments
+		SET body = $1, updated_at = now()
+		WHERE id = $2
+		RETURNING updated_at`
+
+	sqlCommentDelete = `DELETE FROM snippet_comments
+		WHERE id = $1`
+)
+
+func (r *Repository) Create(ctx context.Context, c *Comment) error {
+	ctx, cancel := r.base.WithTimeout(ctx)
+	defer cancel()
+
+	row := r.base.Q().QueryRow(ctx, sqlCommentInsert,
+		c.ID,
+		c.SnippetID,
+		c.ParentID,
+		c.AuthorID,
+		c.Body,
+		c.LineStart,
+		c.LineEnd,
+		c.Revision,
+	)
+	return row.Scan(&c.CreatedAt, &c.UpdatedAt)
+}
+
+func (r *Repository) GetByID(ctx context.Context, id string) (*Comment, error) {
+	ctx, cancel := r.base.WithTimeout(ctx)
+	defer cancel()
+
+	var c Comment
+	err := r.base.Q().QueryRow(ctx, sqlCommentGetByID, id).Scan(
+		&c.ID, &c.SnippetID, &c.ParentID, &c.AuthorID, &c.Body,
+		&c.LineStart, &c.LineEnd, &c.Revision, &c.CreatedAt, &c.UpdatedAt,
+	)
+	if IsNotFound(err) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (r *Repository) ListBySnippet(ctx context.Context, snippetID string) ([]*Comment, error) {
+	ctx, cancel := r.base.WithTimeout(ctx)
+	defer cancel()
+
+	rows, err := r.base.Q().Query(ctx, sqlCommentListBySnippet, snippetID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []*Comment
+	for rows.Next() {
+		var c Comment
+		if err := rows.Scan(
+			&c.ID, &c.SnippetID, &c.ParentID, &c.AuthorID, &c.Body,
+			&c.LineStart, &c.LineEnd, &c.Revision, &c.CreatedAt, &c.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		out = append(out, &c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *Repository) UpdateBody(ctx context.Context, c *Comment) error {
+	ctx, cancel := r.base.WithTimeout(ctx)
+	defer cancel()
+
+	err := r.base.Q().QueryRow(ctx, sqlCommentUpdateBody, c.Body, c.ID).Scan(&c.UpdatedAt)
+	if IsNotFound(err) {
+		return ErrNotFound
+	}
+	return err
+}
+
+// Delete removes the comment and, through the foreign key, its replies.
+func (r *Repository) Delete(ctx context.Context, id string) error {
+	ctx, cancel := r.base.WithTimeout(ctx)
+	defer cancel()
+
+	tag, err := r.base.Q().Exec(ctx, sqlCommentDelete, id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
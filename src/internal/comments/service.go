@@ -0,0 +1,268 @@
+package comments
+
+import (
+	"context"
+	"strings"
+
+	"github.com/PabloPavan/sniply_api/internal"
+	"github.com/PabloPavan/sniply_api/internal/apperrors"
+	"github.com/PabloPavan/sniply_api/internal/highlight"
+	"github.com/PabloPavan/sniply_api/internal/identity"
+	"github.com/PabloPavan/sniply_api/internal/snippets"
+)
+
+const MaxBodyLength = 10000
+
+type Store interface {
+	Create(ctx context.Context, c *Comment) error
+	GetByID(ctx context.Context, id string) (*Comment, error)
+	ListBySnippet(ctx context.Context, snippetID string) ([]*Comment, error)
+	UpdateBody(ctx context.Context, c *Comment) error
+	Delete(ctx context.Context, id string) error
+}
+
+// SnippetReader loads a snippet the requester can read. Comments inherit the
+// visibility of their snippet through it.
+type SnippetReader interface {
+	View(ctx context.Context, id string, sel snippets.Selection) (*snippets.Snippet, error)
+}
+
+type Service struct {
+	Store       Store
+	Snippets    SnippetReader
+	IDGenerator func() string
+}
+
+// List returns the comments of a snippet as threads, oldest first.
+func (s *Service) List(ctx context.Context, snippetID string) ([]*Comment, error) {
+	if s.Store == nil || s.Snippets == nil {
+		return nil, apperrors.New(apperrors.KindInternal, "comments store not configured")
+	}
+	snippet, err := s.Snippets.View(ctx, strings.TrimSpace(snippetID), snippets.Selection{})
+	if err != nil {
+		return nil, err
+	}
+
+	list, err := s.Store.ListBySnippet(ctx, snippet.ID)
+	if err != nil {
+		return nil, apperrors.New(apperrors.KindInternal, "failed to list comments")
+	}
+	return Thread(list), nil
+}
+
+func (s *Service) Get(ctx context.Context, snippetID, id string) (*Comment, error) {
+	if s.Store == nil || s.Snippets == nil {
+		return nil, apperrors.New(apperrors.KindInternal, "comments store not configured")
+	}
+	_, comment, err := s.load(ctx, snippetID, id)
+	if err != nil {
+		return nil, err
+	}
+	return comment, nil
+}
+
+func (s *Service) Create(ctx context.Context, snippetID string, input CreateInput) (*Comment, error) {
+	if s.Store == nil || s.Snippets == nil {
+		return nil, apperrors.New(apperrors.KindInternal, "comments store not configured")
+	}
+	authorID, ok := identity.UserID(ctx)
+	if !ok || strings.TrimSpace(authorID) == "" {
+		return nil, apperrors.New(apperrors.KindUnauthorized, "unauthorized")
+	}
+
+	body, err := normalizeBody(input.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	snippet, err := s.Snippets.View(ctx, strings.TrimSpace(snippetID), snippets.Selection{})
+	if err != nil {
+		return nil, err
+	}
+
+	comment := &Comment{
+		SnippetID: snippet.ID,
+		ParentID:  strings.TrimSpace(input.ParentID),
+		AuthorID:  authorID,
+		Body:      body,
+	}
+
+	if comment.ParentID != "" {
+		parent, err := s.Store.GetByID(ctx, comment.ParentID)
+		if err != nil && !IsNotFound(err) {
+			return nil, apperrors.New(apperrors.KindInternal, "failed to load parent comment")
+		}
+		if err != nil || parent.SnippetID != snippet.ID {
+			return nil, apperrors.New(apperrors.KindInvalidInput, "parent comment not found")
+		}
+	}
+
+	if err := s.anchor(ctx, snippet, input, comment); err != nil {
+		return nil, err
+	}
+
+	idGen := s.IDGenerator
+	if idGen == nil {
+		idGen = func() string {
+			return "cmt_" + internal.RandomHex(12)
+		}
+	}
+	comment.ID = idGen()
+
+	if err := s.Store.Create(ctx, comment); err != nil {
+		return nil, apperrors.New(apperrors.KindInternal, "failed to create comment")
+	}
+	return comment, nil
+}
+
+// Update changes the body of a comment. Only its author can edit it.
+func (s *Service) Update(ctx context.Context, snippetID, id, body string) (*Comment, error) {
+	if s.Store == nil || s.Snippets == nil {
+		return nil, apperrors.New(apperrors.KindInternal, "comments store not configured")
+	}
+	requesterID, ok := identity.UserID(ctx)
+	if !ok || strings.TrimSpace(requesterID) == "" {
+		return nil, apperrors.New(apperrors.KindUnauthorized, "unauthorized")
+	}
+
+	body, err := normalizeBody(body)
+	if err != nil {
+		return nil, err
+	}
+
+	_, comment, err := s.load(ctx, snippetID, id)
+	if err != nil {
+		return nil, err
+	}
+	if comment.AuthorID != requesterID {
+		return nil, apperrors.New(apperrors.KindForbidden, "forbidden")
+	}
+
+	comment.Body = body
+	if err := s.Store.UpdateBody(ctx, comment); err != nil {
+		if IsNotFound(err) {
+			return nil, apperrors.New(apperrors.KindNotFound, "not found")
+		}
+		return nil, apperrors.New(apperrors.KindInternal, "failed to update comment")
+	}
+	return comment, nil
+}
+
+// Delete removes a comment and its replies. The comment author, the snippet
+// owner and admins can delete it.
+func (s *Service) Delete(ctx context.Context, snippetID, id string) error {
+	if s.Store == nil || s.Snippets == nil {
+		return apperrors.New(apperrors.KindInternal, "comments store not configured")
+	}
+	requesterID, ok := identity.UserID(ctx)
+	if !ok || strings.TrimSpace(requesterID) == "" {
+		return apperrors.New(apperrors.KindUnauthorized, "unauthorized")
+	}
+
+	snippet, comment, err := s.load(ctx, snippetID, id)
+	if err != nil {
+		return err
+	}
+	if comment.AuthorID != requesterID && snippet.CreatorID != requesterID && !identity.IsAdmin(ctx) {
+		return apperrors.New(apperrors.KindForbidden, "forbidden")
+	}
+
+	if err := s.Store.Delete(ctx, comment.ID); err != nil {
+		if IsNotFound(err) {
+			return apperrors.New(apperrors.KindNotFound, "not found")
+		}
+		return apperrors.New(apperrors.KindInternal, "failed to delete comment")
+	}
+	return nil
+}
+
+func (s *Service) load(ctx context.Context, snippetID, id string) (*snippets.Snippet, *Comment, error) {
+	snippet, err := s.Snippets.View(ctx, strings.TrimSpace(snippetID), snippets.Selection{})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	comment, err := s.Store.GetByID(ctx, strings.TrimSpace(id))
+	if err != nil {
+		if IsNotFound(err) {
+			return nil, nil, apperrors.New(apperrors.KindNotFound, "not found")
+		}
+		return nil, nil, apperrors.New(apperrors.KindInternal, "failed to load comment")
+	}
+	if comment.SnippetID != snippet.ID {
+		return nil, nil, apperrors.New(apperrors.KindNotFound, "not found")
+	}
+	return snippet, comment, nil
+}
+
+// anchor validates the line anchor against the snippet revision it refers
+// to, defaulting to the current revision.
+func (s *Service) anchor(ctx context.Context, snippet *snippets.Snippet, input CreateInput, c *Comment) error {
+	if input.LineStart == nil {
+		if input.LineEnd != nil || input.Revision != nil {
+			return apperrors.New(apperrors.KindInvalidInput, "line_start is required for line anchors")
+		}
+		return nil
+	}
+
+	start := *input.LineStart
+	end := start
+	if input.LineEnd != nil {
+		end = *input.LineEnd
+	}
+	if start < 1 || end < start {
+		return apperrors.New(apperrors.KindInvalidInput, "invalid line range")
+	}
+	revision := snippet.Revision
+	if input.Revision != nil {
+		revision = *input.Revision
+	}
+	if revision < 1 {
+		return apperrors.New(apperrors.KindInvalidInput, "invalid revision")
+	}
+
+	excerpt, err := s.Snippets.View(ctx, snippet.ID, snippets.Selection{
+		Lines:    highlight.Range{From: start, To: end},
+		Revision: revision,
+	})
+	if err != nil {
+		return err
+	}
+	if excerpt.Excerpt == nil || excerpt.Excerpt.To != end {
+		return apperrors.New(apperrors.KindInvalidInput, "invalid line range")
+	}
+
+	c.LineStart = &start
+	c.LineEnd = &end
+	c.Revision = &revision
+	return nil
+}
+
+func normalizeBody(body string) (string, error) {
+	body = strings.TrimSpace(body)
+	if body == "" {
+		return "", apperrors.New(apperrors.KindInvalidInput, "body is required")
+	}
+	if len(body) > MaxBodyLength {
+		return "", apperrors.New(apperrors.KindInvalidInput, "body is too long")
+	}
+	return body, nil
+}
+
+// Thread nests replies under their parents. list must be ordered oldest
+// first; replies whose parent is missing are kept at the top level.
+func Thread(list []*Comment) []*Comment {
+	byID := make(map[string]*Comment, len(list))
+	for _, c := range list {
+		byID[c.ID] = c
+	}
+	roots := make([]*Comment, 0, len(list))
+	for _, c := range list {
+		if parent, ok := byID[c.ParentID]; ok && c.ParentID != "" {
+			parent.Replies = append(parent.Replies, c)
+			continue
+		}
+		roots = append(roots, c)
+	}
+	return roots
+}
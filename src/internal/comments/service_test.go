@@ -0,0 +1,181 @@
+package comments
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/PabloPavan/sniply_api/internal/apperrors"
+	"github.com/PabloPavan/sniply_api/internal/highlight"
+	"github.com/PabloPavan/sniply_api/internal/identity"
+	"github.com/PabloPavan/sniply_api/internal/snippets"
+)
+
+type storeStub struct {
+	comments map[string]*Comment
+	deleted  []string
+}
+
+func (s *storeStub) Create(ctx context.Context, c *Comment) error {
+	s.comments[c.ID] = c
+	return nil
+}
+
+func (s *storeStub) GetByID(ctx context.Context, id string) (*Comment, error) {
+	c, ok := s.comments[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	cp := *c
+	return &cp, nil
+}
+
+func (s *storeStub) ListBySnippet(ctx context.Context, snippetID string) ([]*Comment, error) {
+	return nil, nil
+}
+
+func (s *storeStub) UpdateBody(ctx context.Context, c *Comment) error {
+	s.comments[c.ID] = c
+	return nil
+}
+
+func (s *storeStub) Delete(ctx context.Context, id string) error {
+	s.deleted = append(s.deleted, id)
+	return nil
+}
+
+// snippetStub serves one private snippet owned by usr_owner with two
+// revisions.
+type snippetStub struct{}
+
+func (snippetStub) View(ctx context.Context, id string, sel snippets.Selection) (*snippets.Snippet, error) {
+	requesterID, _ := identity.UserID(ctx)
+	if id != "snp_1" || (requesterID != "usr_owner" && requesterID != "usr_friend" && !identity.IsAdmin(ctx)) {
+		return nil, apperrors.New(apperrors.KindNotFound, "not found")
+	}
+	s := &snippets.Snippet{ID: id, CreatorID: "usr_owner", Revision: 2, Content: "a\nb\nc\nd"}
+	if sel.Revision == 1 {
+		s.Revision, s.Content = 1, "a\nb"
+	} else if sel.Revision > 2 {
+		return nil, apperrors.New(apperrors.KindNotFound, "revision not found")
+	}
+	if !sel.Lines.IsZero() {
+		content, lines, err := highlight.SliceLines(s.Content, sel.Lines)
+		if err != nil {
+			return nil, apperrors.New(apperrors.KindInvalidInput, err.Error())
+		}
+		s.Content = content
+		s.Excerpt = &snippets.Excerpt{From: lines.From, To: lines.To}
+	}
+	return s, nil
+}
+
+func newService() (*Service, *storeStub) {
+	store := &storeStub{comments: map[string]*Comment{}}
+	n := 0
+	return &Service{
+		Store:    store,
+		Snippets: snippetStub{},
+		IDGenerator: func() string {
+			n++
+			return "cmt_" + string(rune('0'+n))
+		},
+	}, store
+}
+
+func intPtr(v int) *int { return &v }
+
+func TestCreateAnchorsToCurrentRevision(t *testing.T) {
+	svc, _ := newService()
+	ctx := identity.WithUser(context.Background(), "usr_friend", "member")
+
+	c, err := svc.Create(ctx, "snp_1", CreateInput{Body: " looks off ", LineStart: intPtr(2), LineEnd: intPtr(3)})
+	if err != nil {
+		t.Fatalf("create error: %v", err)
+	}
+	if c.Body != "looks off" || *c.Revision != 2 || *c.LineStart != 2 || *c.LineEnd != 3 {
+		t.Fatalf("unexpected comment: %+v", c)
+	}
+
+	_, err = svc.Create(ctx, "snp_1", CreateInput{Body: "x", LineStart: intPtr(2), LineEnd: intPtr(3), Revision: intPtr(1)})
+	assertKind(t, err, apperrors.KindInvalidInput)
+
+	_, err = svc.Create(ctx, "snp_1", CreateInput{Body: "x", Revision: intPtr(1)})
+	assertKind(t, err, apperrors.KindInvalidInput)
+
+	_, err = svc.Create(identity.WithUser(context.Background(), "usr_other", "member"), "snp_1", CreateInput{Body: "x"})
+	assertKind(t, err, apperrors.KindNotFound)
+}
+
+func TestCreateReplyMustShareSnippet(t *testing.T) {
+	svc, store := newService()
+	store.comments["cmt_x"] = &Comment{ID: "cmt_x", SnippetID: "snp_other"}
+	ctx := identity.WithUser(context.Background(), "usr_friend", "member")
+
+	_, err := svc.Create(ctx, "snp_1", CreateInput{Body: "reply", ParentID: "cmt_x"})
+	assertKind(t, err, apperrors.KindInvalidInput)
+
+	root, err := svc.Create(ctx, "snp_1", CreateInput{Body: "root"})
+	if err != nil {
+		t.Fatalf("create error: %v", err)
+	}
+	reply, err := svc.Create(ctx, "snp_1", CreateInput{Body: "reply", ParentID: root.ID})
+	if err != nil || reply.ParentID != root.ID {
+		t.Fatalf("expected reply, got %+v %v", reply, err)
+	}
+}
+
+func TestDeletePermissions(t *testing.T) {
+	svc, store := newService()
+	store.comments["cmt_a"] = &Comment{ID: "cmt_a", SnippetID: "snp_1", AuthorID: "usr_friend"}
+
+	err := svc.Delete(identity.WithUser(context.Background(), "usr_other", "member"), "snp_1", "cmt_a")
+	assertKind(t, err, apperrors.KindNotFound)
+
+	for _, ctx := range []context.Context{
+		identity.WithUser(context.Background(), "usr_friend", "member"),
+		identity.WithUser(context.Background(), "usr_owner", "member"),
+		identity.WithUser(context.Background(), "usr_admin", "admin"),
+	} {
+		if err := svc.Delete(ctx, "snp_1", "cmt_a"); err != nil {
+			t.Fatalf("delete error: %v", err)
+		}
+	}
+	if len(store.deleted) != 3 {
+		t.Fatalf("expected three deletes, got %v", store.deleted)
+	}
+
+	_, err = svc.Update(identity.WithUser(context.Background(), "usr_owner", "member"), "snp_1", "cmt_a", "edited")
+	assertKind(t, err, apperrors.KindForbidden)
+}
+
+func TestThread(t *testing.T) {
+	list := []*Comment{
+		{ID: "a"},
+		{ID: "b", ParentID: "a"},
+		{ID: "c"},
+		{ID: "d", ParentID: "b"},
+		{ID: "e", ParentID: "gone"},
+	}
+	roots := Thread(list)
+	if len(roots) != 3 || roots[0].ID != "a" || roots[1].ID != "c" || roots[2].ID != "e" {
+		t.Fatalf("unexpected roots: %+v", roots)
+	}
+	if len(roots[0].Replies) != 1 || len(roots[0].Replies[0].Replies) != 1 {
+		t.Fatalf("expected nested replies")
+	}
+}
+
+func assertKind(t *testing.T, err error, kind apperrors.Kind) {
+	t.Helper()
+	if err == nil {
+		t.Fatalf("expected error kind %s", kind)
+	}
+	var appErr *apperrors.Error
+	if !errors.As(err, &appErr) {
+		t.Fatalf("expected app error, got: %v", err)
+	}
+	if appErr.Kind != kind {
+		t.Fatalf("unexpected kind: %s", appErr.Kind)
+	}
+}
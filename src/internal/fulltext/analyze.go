@@ -0,0 +1,41 @@
+package fulltext
+
+import (
+	"strings"
+	"unicode"
+)
+
+// tokenize lowercases text and splits it into runs of letters and digits,
+// like the Postgres "simple" configuration.
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// trigrams returns the pg_trgm trigram set of text: every word is
+// lowercased and padded with two spaces in front and one behind.
+func trigrams(text string) map[string]struct{} {
+	set := make(map[string]struct{})
+	for _, word := range tokenize(text) {
+		padded := []rune("  " + word + " ")
+		for i := 0; i+3 <= len(padded); i++ {
+			set[string(padded[i:i+3])] = struct{}{}
+		}
+	}
+	return set
+}
+
+// similarity is pg_trgm's similarity(): shared trigrams over all trigrams.
+func similarity(a, b map[string]struct{}) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	shared := 0
+	for t := range a {
+		if _, ok := b[t]; ok {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(a)+len(b)-shared)
+}
@@ -0,0 +1,192 @@
+package fulltext
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/PabloPavan/sniply_api/internal/snippets"
+	"github.com/PabloPavan/sniply_api/internal/symbols"
+)
+
+var base = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func snippet(id, name, content string, age int, tags ...string) *snippets.Snippet {
+	return &snippets.Snippet{
+		ID:         id,
+		Name:       name,
+		Content:    content,
+		Language:   "go",
+		Tags:       tags,
+		Visibility: snippets.VisibilityPublic,
+		CreatorID:  "usr_1",
+		Symbols:    symbols.Extract("go", content),
+		CreatedAt:  base.Add(-time.Duration(age) * time.Hour),
+	}
+}
+
+func newTestIndex(t *testing.T, list ...*snippets.Snippet) *Index {
+	t.Helper()
+	ix := New()
+	for _, s := range list {
+		if err := ix.Index(context.Background(), s); err != nil {
+			t.Fatalf("index %s: %v", s.ID, err)
+		}
+	}
+	return ix
+}
+
+func ids(list []*snippets.Snippet) []string {
+	out := make([]string, 0, len(list))
+	for _, s := range list {
+		out = append(out, s.ID)
+	}
+	return out
+}
+
+func assertIDs(t *testing.T, got []*snippets.Snippet, want ...string) {
+	t.Helper()
+	g := ids(got)
+	if len(g) != len(want) {
+		t.Fatalf("expected %v, got %v", want, g)
+	}
+	for i := range want {
+		if g[i] != want[i] {
+			t.Fatalf("expected %v, got %v", want, g)
+		}
+	}
+}
+
+func TestQueryWordsRankedByRelevance(t *testing.T) {
+	ix := newTestIndex(t,
+		snippet("s1", "notes", "retry the request later, the request failed", 1),
+		snippet("s2", "retry with backoff", "func retry() {}", 2),
+		snippet("s3", "unrelated", "nothing to see", 3),
+		snippet("s4", "jitter", "exponential retry with backoff", 4),
+	)
+
+	list, err := ix.Query(context.Background(), snippets.SnippetFilter{Query: "retry backoff"})
+	if err != nil {
+		t.Fatalf("query: %v", err)
+	}
+	// Every word must match; words in the name weigh more.
+	assertIDs(t, list, "s2", "s4")
+
+	list, _ = ix.Query(context.Background(), snippets.SnippetFilter{Query: "retry"})
+	if len(list) != 3 || list[0].ID != "s2" {
+		t.Fatalf("expected the name match first, got %v", ids(list))
+	}
+}
+
+func TestQuerySimilarNameAndIdentifierParts(t *testing.T) {
+	ix := newTestIndex(t,
+		snippet("s1", "postgres pool", "db.Ping()", 1),
+		snippet("s2", "handler", "func parseHTTPRequest() {}", 2),
+	)
+
+	list, _ := ix.Query(context.Background(), snippets.SnippetFilter{Query: "postgre"})
+	assertIDs(t, list, "s1")
+
+	list, _ = ix.Query(context.Background(), snippets.SnippetFilter{Query: "request"})
+	assertIDs(t, list, "s2")
+}
+
+func TestQueryModesAndFilters(t *testing.T) {
+	private := snippet("s3", "secret", "os.Getenv(\"TOKEN\")", 3, "env")
+	private.Visibility = snippets.VisibilityPrivate
+	ix := newTestIndex(t,
+		snippet("s1", "exit", "if err != nil { os.Exit(1) }", 1, "cli", "errors"),
+		snippet("s2", "fatal", "log.Fatal(err)", 2, "errors"),
+		private,
+	)
+	ctx := context.Background()
+
+	list, _ := ix.Query(ctx, snippets.SnippetFilter{Query: "!= NIL", Mode: snippets.SearchPhrase})
+	assertIDs(t, list, "s1")
+
+	list, _ = ix.Query(ctx, snippets.SnippetFilter{Query: `(Exit|Fatal)\(`, Mode: snippets.SearchRegex})
+	assertIDs(t, list, "s1", "s2")
+
+	if _, err := ix.Query(ctx, snippets.SnippetFilter{Query: "(", Mode: snippets.SearchRegex}); !errors.Is(err, snippets.ErrInvalidRegex) {
+		t.Fatalf("expected ErrInvalidRegex, got %v", err)
+	}
+
+	list, _ = ix.Query(ctx, snippets.SnippetFilter{Tags: []string{"errors"}, ExcludeTags: []string{"cli"}})
+	assertIDs(t, list, "s2")
+
+	list, _ = ix.Query(ctx, snippets.SnippetFilter{Visibility: snippets.VisibilityPublic, CreatedBefore: base.Add(-90 * time.Minute)})
+	assertIDs(t, list, "s2")
+
+	list, _ = ix.Query(ctx, snippets.SnippetFilter{Visibility: snippets.VisibilityPrivate, Creator: "usr_1"})
+	assertIDs(t, list, "s3")
+
+	list, _ = ix.Query(ctx, snippets.SnippetFilter{Limit: 1, Offset: 1})
+	assertIDs(t, list, "s2")
+}
+
+func TestQuerySymbols(t *testing.T) {
+	ix := newTestIndex(t,
+		snippet("s1", "server", "type Server struct{}\nfunc (s *Server) ServeHTTP() {}", 1),
+		snippet("s2", "client", "type Client struct{}", 2),
+	)
+
+	list, _ := ix.Query(context.Background(), snippets.SnippetFilter{Symbols: []string{"servehttp"}})
+	assertIDs(t, list, "s1")
+}
+
+func TestIndexReplacesAndDeletes(t *testing.T) {
+	ix := newTestIndex(t, snippet("s1", "old name", "alpha", 1))
+	ctx := context.Background()
+
+	if err := ix.Index(ctx, snippet("s1", "new name", "beta", 1)); err != nil {
+		t.Fatalf("index: %v", err)
+	}
+	if list, _ := ix.Query(ctx, snippets.SnippetFilter{Query: "alpha"}); len(list) != 0 {
+		t.Fatalf("expected old content to be gone, got %v", ids(list))
+	}
+	list, _ := ix.Query(ctx, snippets.SnippetFilter{Query: "beta"})
+	assertIDs(t, list, "s1")
+
+	if err := ix.Delete(ctx, "s1"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if ix.Len() != 0 || len(ix.st.postings) != 0 || len(ix.st.names) != 0 || ix.st.totalLen != 0 {
+		t.Fatalf("expected an empty index, got %d docs", ix.Len())
+	}
+}
+
+func TestRebuildReplaysConcurrentWrites(t *testing.T) {
+	ix := newTestIndex(t, snippet("stale", "removed with its owner", "gone", 5))
+	ctx := context.Background()
+
+	err := ix.Rebuild(ctx, func(ctx context.Context, into snippets.SearchIndex) error {
+		if err := into.Index(ctx, snippet("s1", "first", "one", 1)); err != nil {
+			return err
+		}
+		if err := into.Index(ctx, snippet("s2", "second", "two", 2)); err != nil {
+			return err
+		}
+		// Writes served while the rebuild runs.
+		_ = ix.Index(ctx, snippet("s3", "third", "three", 0))
+		_ = ix.Delete(ctx, "s2")
+		if err := ix.Rebuild(ctx, nil); !errors.Is(err, ErrRebuildRunning) {
+			t.Errorf("expected ErrRebuildRunning, got %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("rebuild: %v", err)
+	}
+
+	list, _ := ix.Query(ctx, snippets.SnippetFilter{})
+	assertIDs(t, list, "s3", "s1")
+
+	failed := errors.New("db down")
+	if err := ix.Rebuild(ctx, func(context.Context, snippets.SearchIndex) error { return failed }); !errors.Is(err, failed) {
+		t.Fatalf("expected fill error, got %v", err)
+	}
+	if ix.Len() != 2 {
+		t.Fatalf("expected a failed rebuild to keep the index, got %d docs", ix.Len())
+	}
+}
@@ -0,0 +1,397 @@
+package fulltext
+
+import (
+	"context"
+	"errors"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/PabloPavan/sniply_api/internal/snippets"
+	"github.com/PabloPavan/sniply_api/internal/symbols"
+)
+
+const (
+	// BM25 term saturation and length normalization.
+	k1 = 1.2
+	b  = 0.75
+	// nameBoost counts a word of the name this many times.
+	nameBoost = 3
+	// minNameSimilarity is the trigram similarity above which a name matches
+	// the query on its own, as in the Postgres backend.
+	minNameSimilarity = 0.25
+	// nameWeight scales the name similarity added to the text score.
+	nameWeight = 2.0
+)
+
+var ErrRebuildRunning = errors.New("search index rebuild already running")
+
+// Index is an in-memory full-text index of snippets. Matches follow the
+// Postgres backend; results of a word search are ranked with BM25 plus the
+// trigram similarity of the name instead of by creation date.
+type Index struct {
+	mu sync.RWMutex
+	st *state
+
+	// While a rebuild runs, writes are also journaled so they can be
+	// replayed onto the new content before it is swapped in.
+	rebuilding bool
+	journal    []change
+}
+
+type change struct {
+	id      string
+	snippet *snippets.Snippet // nil for a delete
+}
+
+type state struct {
+	docs map[string]*document
+	// postings maps a term to the weighted term frequency in each document.
+	postings map[string]map[string]int
+	// names maps a name trigram to the documents whose name has it.
+	names    map[string]map[string]struct{}
+	totalLen int
+}
+
+type document struct {
+	snippet  *snippets.Snippet
+	terms    map[string]int
+	length   int
+	trigrams map[string]struct{}
+	tags     map[string]struct{}
+	symbols  map[string]struct{}
+}
+
+func New() *Index {
+	return &Index{st: newState()}
+}
+
+func newState() *state {
+	return &state{
+		docs:     make(map[string]*document),
+		postings: make(map[string]map[string]int),
+		names:    make(map[string]map[string]struct{}),
+	}
+}
+
+// Len returns the number of indexed snippets.
+func (ix *Index) Len() int {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	return len(ix.st.docs)
+}
+
+func (ix *Index) Index(ctx context.Context, s *snippets.Snippet) error {
+	cp := *s
+	cp.Excerpt, cp.Detection, cp.SecretFindings = nil, nil, nil
+
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	ix.st.put(&cp)
+	if ix.rebuilding {
+		ix.journal = append(ix.journal, change{id: cp.ID, snippet: &cp})
+	}
+	return nil
+}
+
+func (ix *Index) Delete(ctx context.Context, id string) error {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	ix.st.remove(id)
+	if ix.rebuilding {
+		ix.journal = append(ix.journal, change{id: id})
+	}
+	return nil
+}
+
+// Rebuild lets fill index into an empty index and then replaces the content
+// of ix with it. Queries keep using the old content until then.
+func (ix *Index) Rebuild(ctx context.Context, fill func(ctx context.Context, into snippets.SearchIndex) error) error {
+	ix.mu.Lock()
+	if ix.rebuilding {
+		ix.mu.Unlock()
+		return ErrRebuildRunning
+	}
+	ix.rebuilding = true
+	ix.journal = nil
+	ix.mu.Unlock()
+
+	fresh := New()
+	err := fill(ctx, fresh)
+
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	journal := ix.journal
+	ix.rebuilding, ix.journal = false, nil
+	if err != nil {
+		return err
+	}
+	// fill may have read a snippet before a write that happened meanwhile.
+	for _, c := range journal {
+		if c.snippet == nil {
+			fresh.st.remove(c.id)
+		} else {
+			fresh.st.put(c.snippet)
+		}
+	}
+	ix.st = fresh.st
+	return nil
+}
+
+type hit struct {
+	doc   *document
+	score float64
+}
+
+// Query returns the snippets matching f. A Limit of zero or less returns
+// every match after Offset.
+func (ix *Index) Query(ctx context.Context, f snippets.SnippetFilter) ([]*snippets.Snippet, error) {
+	query := strings.TrimSpace(f.Query)
+	mode := f.Mode
+	if mode == "" {
+		mode = snippets.SearchFTS
+	}
+
+	var re *regexp.Regexp
+	if query != "" && mode == snippets.SearchRegex {
+		var err error
+		if re, err = regexp.Compile(query); err != nil {
+			return nil, snippets.ErrInvalidRegex
+		}
+	}
+	phrases := make([]string, 0, len(f.Phrases)+1)
+	for _, p := range f.Phrases {
+		phrases = append(phrases, strings.ToLower(p))
+	}
+	if query != "" && mode == snippets.SearchPhrase {
+		phrases = append(phrases, strings.ToLower(query))
+	}
+
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	st := ix.st
+
+	ranked := query != "" && mode == snippets.SearchFTS
+	var scores map[string]float64
+	if ranked {
+		scores = st.score(query)
+	}
+
+	hits := make([]hit, 0)
+	visit := func(doc *document, score float64) {
+		if !matches(doc, f, re, phrases) {
+			return
+		}
+		hits = append(hits, hit{doc: doc, score: score})
+	}
+	if ranked {
+		for id, score := range scores {
+			visit(st.docs[id], score)
+		}
+	} else {
+		n := 0
+		for _, doc := range st.docs {
+			if n++; n%1024 == 0 && ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			visit(doc, 0)
+		}
+	}
+
+	sort.Slice(hits, func(i, j int) bool {
+		x, y := hits[i], hits[j]
+		if x.score != y.score {
+			return x.score > y.score
+		}
+		if !x.doc.snippet.CreatedAt.Equal(y.doc.snippet.CreatedAt) {
+			return x.doc.snippet.CreatedAt.After(y.doc.snippet.CreatedAt)
+		}
+		return x.doc.snippet.ID < y.doc.snippet.ID
+	})
+
+	start := min(max(f.Offset, 0), len(hits))
+	end := len(hits)
+	if f.Limit > 0 {
+		end = min(start+f.Limit, len(hits))
+	}
+	out := make([]*snippets.Snippet, 0, end-start)
+	for _, h := range hits[start:end] {
+		cp := *h.doc.snippet
+		out = append(out, &cp)
+	}
+	return out, nil
+}
+
+// score returns the documents matching a word query: those containing every
+// word, ranked with BM25, and those whose name is similar to the query.
+func (st *state) score(query string) map[string]float64 {
+	scores := make(map[string]float64)
+
+	terms := unique(tokenize(query))
+	if len(terms) > 0 && len(st.docs) > 0 {
+		// Walk the rarest term's postings; every term must match.
+		sort.Slice(terms, func(i, j int) bool {
+			return len(st.postings[terms[i]]) < len(st.postings[terms[j]])
+		})
+		n := float64(len(st.docs))
+		avg := float64(st.totalLen) / n
+	docs:
+		for id := range st.postings[terms[0]] {
+			doc := st.docs[id]
+			score := 0.0
+			for _, term := range terms {
+				tf := float64(doc.terms[term])
+				if tf == 0 {
+					continue docs
+				}
+				df := float64(len(st.postings[term]))
+				idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+				score += idf * tf * (k1 + 1) / (tf + k1*(1-b+b*float64(doc.length)/avg))
+			}
+			scores[id] = score
+		}
+	}
+
+	queryTrigrams := trigrams(query)
+	candidates := make(map[string]struct{})
+	for t := range queryTrigrams {
+		for id := range st.names[t] {
+			candidates[id] = struct{}{}
+		}
+	}
+	for id := range candidates {
+		sim := similarity(st.docs[id].trigrams, queryTrigrams)
+		if _, ok := scores[id]; ok {
+			scores[id] += nameWeight * sim
+		} else if sim > minNameSimilarity {
+			scores[id] = nameWeight * sim
+		}
+	}
+	return scores
+}
+
+func matches(doc *document, f snippets.SnippetFilter, re *regexp.Regexp, phrases []string) bool {
+	s := doc.snippet
+	if f.Creator != "" && s.CreatorID != f.Creator {
+		return false
+	}
+	if f.Language != "" && s.Language != f.Language {
+		return false
+	}
+	if f.Visibility != "" && s.Visibility != f.Visibility {
+		return false
+	}
+	if !f.CreatedAfter.IsZero() && s.CreatedAt.Before(f.CreatedAfter) {
+		return false
+	}
+	if !f.CreatedBefore.IsZero() && !s.CreatedAt.Before(f.CreatedBefore) {
+		return false
+	}
+	for _, tag := range f.Tags {
+		if _, ok := doc.tags[tag]; !ok {
+			return false
+		}
+	}
+	for _, tag := range f.ExcludeTags {
+		if _, ok := doc.tags[tag]; ok {
+			return false
+		}
+	}
+	for _, name := range f.Symbols {
+		if _, ok := doc.symbols[name]; !ok {
+			return false
+		}
+	}
+	if len(phrases) > 0 {
+		name, content := strings.ToLower(s.Name), strings.ToLower(s.Content)
+		for _, p := range phrases {
+			if !strings.Contains(name, p) && !strings.Contains(content, p) {
+				return false
+			}
+		}
+	}
+	if re != nil && !re.MatchString(s.Content) {
+		return false
+	}
+	return true
+}
+
+func (st *state) put(s *snippets.Snippet) {
+	st.remove(s.ID)
+
+	doc := &document{
+		snippet:  s,
+		terms:    make(map[string]int),
+		trigrams: trigrams(s.Name),
+		tags:     make(map[string]struct{}, len(s.Tags)),
+		symbols:  make(map[string]struct{}),
+	}
+	for _, term := range tokenize(s.Name) {
+		doc.terms[term] += nameBoost
+	}
+	for _, term := range tokenize(s.Content) {
+		doc.terms[term]++
+	}
+	for _, term := range symbols.Terms(s.Name + "\n" + s.Content) {
+		doc.terms[term]++
+	}
+	for _, tag := range s.Tags {
+		doc.tags[tag] = struct{}{}
+		doc.terms[strings.ToLower(tag)]++
+	}
+	for _, name := range symbols.Names(s.Symbols) {
+		doc.symbols[name] = struct{}{}
+	}
+
+	for term, tf := range doc.terms {
+		if st.postings[term] == nil {
+			st.postings[term] = make(map[string]int)
+		}
+		st.postings[term][s.ID] = tf
+		doc.length += tf
+	}
+	for t := range doc.trigrams {
+		if st.names[t] == nil {
+			st.names[t] = make(map[string]struct{})
+		}
+		st.names[t][s.ID] = struct{}{}
+	}
+	st.docs[s.ID] = doc
+	st.totalLen += doc.length
+}
+
+func (st *state) remove(id string) {
+	doc, ok := st.docs[id]
+	if !ok {
+		return
+	}
+	for term := range doc.terms {
+		delete(st.postings[term], id)
+		if len(st.postings[term]) == 0 {
+			delete(st.postings, term)
+		}
+	}
+	for t := range doc.trigrams {
+		delete(st.names[t], id)
+		if len(st.names[t]) == 0 {
+			delete(st.names, t)
+		}
+	}
+	st.totalLen -= doc.length
+	delete(st.docs, id)
+}
+
+func unique(list []string) []string {
+	seen := make(map[string]struct{}, len(list))
+	out := list[:0]
+	for _, s := range list {
+		if _, ok := seen[s]; ok {
+			continue
+		}
+		seen[s] = struct{}{}
+		out = append(out, s)
+	}
+	return out
+}
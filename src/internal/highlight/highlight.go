@@ -0,0 +1,211 @@
+package highlight
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/alecthomas/chroma/v2"
+	"github.com/alecthomas/chroma/v2/formatters"
+	"github.com/alecthomas/chroma/v2/formatters/html"
+	"github.com/alecthomas/chroma/v2/formatters/svg"
+	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/alecthomas/chroma/v2/styles"
+)
+
+type Format string
+
+const (
+	FormatHTML Format = "html"
+	FormatANSI Format = "ansi"
+	FormatSVG  Format = "svg"
+)
+
+const DefaultTheme = "github"
+
+var (
+	ErrInvalidFormat = errors.New("invalid format")
+	ErrInvalidTheme  = errors.New("invalid theme")
+	ErrInvalidRange  = errors.New("invalid line range")
+)
+
+// Range selects lines From..To (1-based, inclusive). A zero To means until
+// the end of the content.
+type Range struct {
+	From int
+	To   int
+}
+
+func (r Range) IsZero() bool {
+	return r.From == 0 && r.To == 0
+}
+
+func (r Range) String() string {
+	if r.IsZero() {
+		return ""
+	}
+	if r.To == 0 {
+		return strconv.Itoa(r.From) + "-"
+	}
+	return strconv.Itoa(r.From) + "-" + strconv.Itoa(r.To)
+}
+
+// ParseRange parses "10", "10-25" or "10-".
+func ParseRange(s string) (Range, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return Range{}, nil
+	}
+	from, to, hasDash := strings.Cut(s, "-")
+	start, err := strconv.Atoi(strings.TrimSpace(from))
+	if err != nil || start < 1 {
+		return Range{}, ErrInvalidRange
+	}
+	if !hasDash {
+		return Range{From: start, To: start}, nil
+	}
+	if strings.TrimSpace(to) == "" {
+		return Range{From: start}, nil
+	}
+	end, err := strconv.Atoi(strings.TrimSpace(to))
+	if err != nil || end < start {
+		return Range{}, ErrInvalidRange
+	}
+	return Range{From: start, To: end}, nil
+}
+
+// SliceLines returns the lines of content selected by r and the range that
+// was actually applied, with To clamped to the last line. A zero range
+// returns the whole content.
+func SliceLines(content string, r Range) (string, Range, error) {
+	lines := strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+	if r.IsZero() {
+		return content, Range{From: 1, To: len(lines)}, nil
+	}
+	if r.From < 1 || r.From > len(lines) || (r.To != 0 && r.To < r.From) {
+		return "", r, ErrInvalidRange
+	}
+	end := len(lines)
+	if r.To > 0 && r.To < end {
+		end = r.To
+	}
+	return strings.Join(lines[r.From-1:end], "\n"), Range{From: r.From, To: end}, nil
+}
+
+type Options struct {
+	Format      Format
+	Theme       string
+	LineNumbers bool
+	Lines       Range
+}
+
+// Normalize fills in defaults and validates the format and theme.
+func (o Options) Normalize() (Options, error) {
+	o.Format = Format(strings.ToLower(strings.TrimSpace(string(o.Format))))
+	if o.Format == "" {
+		o.Format = FormatHTML
+	}
+	switch o.Format {
+	case FormatHTML, FormatANSI, FormatSVG:
+	default:
+		return o, ErrInvalidFormat
+	}
+
+	o.Theme = strings.ToLower(strings.TrimSpace(o.Theme))
+	if o.Theme == "" {
+		o.Theme = DefaultTheme
+	}
+	if _, ok := styles.Registry[o.Theme]; !ok {
+		return o, ErrInvalidTheme
+	}
+	return o, nil
+}
+
+// Key identifies the rendered output of the options, for caching.
+func (o Options) Key() string {
+	ln := "0"
+	if o.LineNumbers {
+		ln = "1"
+	}
+	return string(o.Format) + ":" + o.Theme + ":" + ln + ":" + o.Lines.String()
+}
+
+func (f Format) ContentType() string {
+	switch f {
+	case FormatSVG:
+		return "image/svg+xml"
+	case FormatANSI:
+		return "text/plain; charset=utf-8"
+	default:
+		return "text/html; charset=utf-8"
+	}
+}
+
+// Render highlights content with the lexer registered for language, falling
+// back to plain text when the language is unknown.
+func Render(content, language string, opts Options) ([]byte, error) {
+	opts, err := opts.Normalize()
+	if err != nil {
+		return nil, err
+	}
+
+	lexer := lexers.Get(strings.TrimSpace(language))
+	if lexer == nil {
+		lexer = lexers.Fallback
+	}
+	lexer = chroma.Coalesce(lexer)
+
+	it, err := lexer.Tokenise(nil, content)
+	if err != nil {
+		return nil, err
+	}
+	lines := chroma.SplitTokensIntoLines(it.Tokens())
+
+	first := 1
+	if !opts.Lines.IsZero() {
+		if opts.Lines.From > len(lines) {
+			return nil, ErrInvalidRange
+		}
+		end := len(lines)
+		if opts.Lines.To > 0 && opts.Lines.To < end {
+			end = opts.Lines.To
+		}
+		first = opts.Lines.From
+		lines = lines[first-1 : end]
+	}
+
+	var tokens []chroma.Token
+	width := len(strconv.Itoa(first + len(lines) - 1))
+	for i, line := range lines {
+		if opts.LineNumbers && opts.Format != FormatHTML {
+			tokens = append(tokens, chroma.Token{
+				Type:  chroma.LineNumbers,
+				Value: fmt.Sprintf("%*d ", width, first+i),
+			})
+		}
+		tokens = append(tokens, line...)
+	}
+
+	var formatter chroma.Formatter
+	switch opts.Format {
+	case FormatANSI:
+		formatter = formatters.TTY256
+	case FormatSVG:
+		formatter = svg.New()
+	default:
+		formatter = html.New(
+			html.WithClasses(false),
+			html.WithLineNumbers(opts.LineNumbers),
+			html.BaseLineNumber(first),
+			html.TabWidth(4),
+		)
+	}
+
+	var buf bytes.Buffer
+	if err := formatter.Format(&buf, styles.Get(opts.Theme), chroma.Literator(tokens...)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,103 @@
+package highlight
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+const goSource = "package main\n\nimport \"fmt\"\n\nfunc main() {\n\tfmt.Println(\"hi\")\n}\n"
+
+func TestParseRange(t *testing.T) {
+	cases := map[string]Range{
+		"":      {},
+		"3":     {From: 3, To: 3},
+		"10-25": {From: 10, To: 25},
+		"7-":    {From: 7},
+	}
+	for in, want := range cases {
+		got, err := ParseRange(in)
+		if err != nil {
+			t.Fatalf("%q: unexpected error: %v", in, err)
+		}
+		if got != want {
+			t.Fatalf("%q: got %+v, want %+v", in, got, want)
+		}
+	}
+
+	for _, in := range []string{"0", "a-b", "5-2", "-3"} {
+		if _, err := ParseRange(in); !errors.Is(err, ErrInvalidRange) {
+			t.Fatalf("%q: expected invalid range, got %v", in, err)
+		}
+	}
+}
+
+func TestSliceLines(t *testing.T) {
+	got, r, err := SliceLines(goSource, Range{From: 5, To: 99})
+	if err != nil {
+		t.Fatalf("slice error: %v", err)
+	}
+	if got != "func main() {\n\tfmt.Println(\"hi\")\n}" || r != (Range{From: 5, To: 7}) {
+		t.Fatalf("unexpected slice %q %+v", got, r)
+	}
+
+	if _, r, _ := SliceLines(goSource, Range{}); r != (Range{From: 1, To: 7}) {
+		t.Fatalf("unexpected full range %+v", r)
+	}
+	if _, _, err := SliceLines(goSource, Range{From: 8}); !errors.Is(err, ErrInvalidRange) {
+		t.Fatalf("expected invalid range past the end, got %v", err)
+	}
+}
+
+func TestRenderHTML(t *testing.T) {
+	out, err := Render(goSource, "go", Options{LineNumbers: true, Lines: Range{From: 5, To: 6}})
+	if err != nil {
+		t.Fatalf("render error: %v", err)
+	}
+	html := string(out)
+	if !strings.Contains(html, "<pre") || !strings.Contains(html, "style=") {
+		t.Fatalf("expected inline styled html, got %s", html)
+	}
+	if !strings.Contains(html, ">5<") || !strings.Contains(html, ">6<") {
+		t.Fatalf("expected line numbers 5 and 6, got %s", html)
+	}
+	if strings.Contains(html, "import") {
+		t.Fatalf("expected lines outside the range to be dropped")
+	}
+}
+
+func TestRenderANSI(t *testing.T) {
+	out, err := Render(goSource, "go", Options{Format: FormatANSI, LineNumbers: true, Lines: Range{From: 1, To: 1}})
+	if err != nil {
+		t.Fatalf("render error: %v", err)
+	}
+	s := string(out)
+	if !strings.Contains(s, "\x1b[") {
+		t.Fatalf("expected ansi escapes, got %q", s)
+	}
+	if !strings.Contains(s, "1 ") || strings.Contains(s, "fmt") {
+		t.Fatalf("unexpected ansi output: %q", s)
+	}
+}
+
+func TestRenderInvalidOptions(t *testing.T) {
+	if _, err := Render(goSource, "go", Options{Format: "pdf"}); !errors.Is(err, ErrInvalidFormat) {
+		t.Fatalf("expected invalid format, got %v", err)
+	}
+	if _, err := Render(goSource, "go", Options{Theme: "nope"}); !errors.Is(err, ErrInvalidTheme) {
+		t.Fatalf("expected invalid theme, got %v", err)
+	}
+	if _, err := Render(goSource, "go", Options{Lines: Range{From: 100}}); !errors.Is(err, ErrInvalidRange) {
+		t.Fatalf("expected invalid range, got %v", err)
+	}
+}
+
+func TestRenderUnknownLanguage(t *testing.T) {
+	out, err := Render("<b>plain</b>", "not-a-language", Options{})
+	if err != nil {
+		t.Fatalf("render error: %v", err)
+	}
+	if strings.Contains(string(out), "<b>") {
+		t.Fatalf("expected content to be escaped, got %s", out)
+	}
+}
@@ -1,6 +1,7 @@
 package httpapi
 
 import (
+	"encoding/json"
 	"errors"
 	"net/http"
 	"strconv"
@@ -22,6 +23,10 @@ func writeAppError(w http.ResponseWriter, err error) {
 			}
 			w.Header().Set("Retry-After", strconv.Itoa(seconds))
 		}
+		if len(appErr.Fields) > 0 {
+			writeValidationError(w, appErr)
+			return
+		}
 		http.Error(w, errorMessage(appErr), statusFromKind(appErr.Kind))
 		return
 	}
@@ -29,6 +34,21 @@ func writeAppError(w http.ResponseWriter, err error) {
 	http.Error(w, "internal error", http.StatusInternalServerError)
 }
 
+type ValidationErrorResponse struct {
+	Error  string                 `json:"error"`
+	Fields []apperrors.FieldError `json:"fields"`
+}
+
+func writeValidationError(w http.ResponseWriter, appErr *apperrors.Error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(statusFromKind(appErr.Kind))
+	_ = json.NewEncoder(w).Encode(ValidationErrorResponse{
+		Error:  errorMessage(appErr),
+		Fields: appErr.Fields,
+	})
+}
+
 func statusFromKind(kind apperrors.Kind) int {
 	switch kind {
 	case apperrors.KindInvalidInput:
@@ -0,0 +1,122 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+)
+
+type AccountsService interface {
+	ForgotPassword(ctx context.Context, email, clientIP string) error
+	ResetPassword(ctx context.Context, token, password string) error
+	RequestVerification(ctx context.Context) error
+	VerifyEmail(ctx context.Context, token string) error
+}
+
+type AccountsHandler struct {
+	Service AccountsService
+}
+
+// ForgotPassword Accounts
+// @Summary Request a password reset link
+// @Description Mails a reset link when the email belongs to an account. The response is the same either way.
+// @Tags auth
+// @Accept json
+// @Param body body ForgotPasswordDTO true "email"
+// @Success 202
+// @Failure 400 {string} string
+// @Failure 429 {string} string
+// @Failure 500 {string} string
+// @Router /auth/password/forgot [post]
+func (h *AccountsHandler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	var req ForgotPasswordDTO
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	if err := req.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.Service.ForgotPassword(r.Context(), req.Email, clientIP(r)); err != nil {
+		writeAppError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// ResetPassword Accounts
+// @Summary Reset the password with a mailed token
+// @Tags auth
+// @Accept json
+// @Param body body ResetPasswordDTO true "token and new password"
+// @Success 204
+// @Failure 400 {string} string
+// @Failure 500 {string} string
+// @Router /auth/password/reset [post]
+func (h *AccountsHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var req ResetPasswordDTO
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	if err := req.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.Service.ResetPassword(r.Context(), req.Token, req.Password); err != nil {
+		writeAppError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RequestVerification Accounts
+// @Summary Mail me an email verification link
+// @Tags users
+// @Security SessionAuth
+// @Security ApiKeyAuth
+// @Param X-CSRF-Token header string false "CSRF token (required for SessionAuth)"
+// @Success 202
+// @Failure 401 {string} string
+// @Failure 409 {string} string
+// @Failure 429 {string} string
+// @Failure 500 {string} string
+// @Router /users/me/verify-email [post]
+func (h *AccountsHandler) RequestVerification(w http.ResponseWriter, r *http.Request) {
+	if err := h.Service.RequestVerification(r.Context()); err != nil {
+		writeAppError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// VerifyEmail Accounts
+// @Summary Confirm an email with a mailed token
+// @Description The token identifies the user, so no session is needed.
+// @Tags users
+// @Accept json
+// @Param body body VerifyEmailDTO true "token"
+// @Success 204
+// @Failure 400 {string} string
+// @Failure 500 {string} string
+// @Router /users/verify-email [post]
+func (h *AccountsHandler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	var req VerifyEmailDTO
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	if err := req.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.Service.VerifyEmail(r.Context(), req.Token); err != nil {
+		writeAppError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,97 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/PabloPavan/sniply_api/internal/analytics"
+	"github.com/PabloPavan/sniply_api/internal/snippets"
+)
+
+type AnalyticsService interface {
+	Trending(ctx context.Context, window string, limit int) ([]analytics.TrendingSnippet, error)
+	Stats(ctx context.Context, snippetID string, days int) (*analytics.SnippetStats, error)
+}
+
+// ViewRecorder counts snippet views and raw downloads.
+type ViewRecorder interface {
+	Record(ctx context.Context, snippet *snippets.Snippet, kind analytics.Kind) error
+}
+
+type AnalyticsHandler struct {
+	Service AnalyticsService
+}
+
+// Trending Snippets
+// @Summary List trending public snippets
+// @Description Public snippets ranked by views and raw downloads in the window. Each hour of views counts half as much every 6 hours (24h) or 48 hours (7d). Counts are flushed from Redis periodically, so the newest views can take a moment to show up.
+// @Tags snippets
+// @Produce json
+// @Security SessionAuth
+// @Security ApiKeyAuth
+// @Param window query string false "time window" Enums(24h, 7d) default(24h)
+// @Param limit query int false "limit (default 20, max 100)"
+// @Success 200 {array} analytics.TrendingSnippet
+// @Failure 400 {string} string
+// @Failure 401 {string} string
+// @Failure 500 {string} string
+// @Router /snippets/trending [get]
+func (h *AnalyticsHandler) Trending(w http.ResponseWriter, r *http.Request) {
+	window := strings.TrimSpace(r.URL.Query().Get("window"))
+
+	limit := 0
+	if l := strings.TrimSpace(r.URL.Query().Get("limit")); l != "" {
+		if v, err := strconv.Atoi(l); err == nil && v > 0 {
+			limit = v
+		}
+	}
+
+	list, err := h.Service.Trending(r.Context(), window, limit)
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(list)
+}
+
+// Stats Snippet
+// @Summary Get snippet view analytics
+// @Description Total views and raw downloads plus daily counts (UTC) for the last days. Only the owner and admins can see them; the owner's own views are not counted.
+// @Tags snippets
+// @Produce json
+// @Security SessionAuth
+// @Security ApiKeyAuth
+// @Param id path string true "snippet id"
+// @Param days query int false "days (default 30, max 365)"
+// @Success 200 {object} analytics.SnippetStats
+// @Failure 401 {string} string
+// @Failure 403 {string} string
+// @Failure 404 {string} string
+// @Failure 500 {string} string
+// @Router /snippets/{id}/analytics [get]
+func (h *AnalyticsHandler) Stats(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimSpace(chi.URLParam(r, "id"))
+
+	days := 0
+	if d := strings.TrimSpace(r.URL.Query().Get("days")); d != "" {
+		if v, err := strconv.Atoi(d); err == nil && v > 0 {
+			days = v
+		}
+	}
+
+	stats, err := h.Service.Stats(r.Context(), id, days)
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(stats)
+}
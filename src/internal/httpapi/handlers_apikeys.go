@@ -3,6 +3,8 @@ package httpapi
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"io"
 	"net/http"
 	"strings"
 	"time"
@@ -15,6 +17,7 @@ type APIKeysService interface {
 	Create(ctx context.Context, input apikeys.CreateInput) (*apikeys.Key, string, error)
 	List(ctx context.Context) ([]*apikeys.Key, error)
 	Revoke(ctx context.Context, id string) error
+	Rotate(ctx context.Context, id string, input apikeys.RotateInput) (*apikeys.Key, string, error)
 }
 
 type APIKeysHandler struct {
@@ -22,12 +25,13 @@ type APIKeysHandler struct {
 }
 
 type APIKeyCreateResponse struct {
-	ID          string    `json:"id"`
-	Name        string    `json:"name"`
-	Scope       string    `json:"scope"`
-	Token       string    `json:"token"`
-	TokenPrefix string    `json:"token_prefix"`
-	CreatedAt   time.Time `json:"created_at"`
+	ID          string     `json:"id"`
+	Name        string     `json:"name"`
+	Scope       string     `json:"scope"`
+	Token       string     `json:"token"`
+	TokenPrefix string     `json:"token_prefix"`
+	CreatedAt   time.Time  `json:"created_at"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
 }
 
 type APIKeyResponse struct {
@@ -37,6 +41,21 @@ type APIKeyResponse struct {
 	TokenPrefix string     `json:"token_prefix"`
 	CreatedAt   time.Time  `json:"created_at"`
 	RevokedAt   *time.Time `json:"revoked_at,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt  *time.Time `json:"last_used_at,omitempty"`
+	LastUsedIP  string     `json:"last_used_ip,omitempty"`
+}
+
+func newAPIKeyCreateResponse(key *apikeys.Key, token string) APIKeyCreateResponse {
+	return APIKeyCreateResponse{
+		ID:          key.ID,
+		Name:        key.Name,
+		Scope:       string(key.Scope),
+		Token:       token,
+		TokenPrefix: key.TokenPrefix,
+		CreatedAt:   key.CreatedAt,
+		ExpiresAt:   key.ExpiresAt,
+	}
 }
 
 // Create API Key
@@ -64,26 +83,18 @@ func (h *APIKeysHandler) Create(w http.ResponseWriter, r *http.Request) {
 	}
 
 	key, token, err := h.Service.Create(r.Context(), apikeys.CreateInput{
-		Name:  req.Name,
-		Scope: req.Scope,
+		Name:      req.Name,
+		Scope:     req.Scope,
+		ExpiresAt: req.ExpiresAt,
 	})
 	if err != nil {
 		writeAppError(w, err)
 		return
 	}
 
-	resp := APIKeyCreateResponse{
-		ID:          key.ID,
-		Name:        key.Name,
-		Scope:       string(key.Scope),
-		Token:       token,
-		TokenPrefix: key.TokenPrefix,
-		CreatedAt:   key.CreatedAt,
-	}
-
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
-	_ = json.NewEncoder(w).Encode(resp)
+	_ = json.NewEncoder(w).Encode(newAPIKeyCreateResponse(key, token))
 }
 
 // List API Keys
@@ -111,6 +122,9 @@ func (h *APIKeysHandler) List(w http.ResponseWriter, r *http.Request) {
 			TokenPrefix: k.TokenPrefix,
 			CreatedAt:   k.CreatedAt,
 			RevokedAt:   k.RevokedAt,
+			ExpiresAt:   k.ExpiresAt,
+			LastUsedAt:  k.LastUsedAt,
+			LastUsedIP:  k.LastUsedIP,
 		})
 	}
 
@@ -140,3 +154,49 @@ func (h *APIKeysHandler) Revoke(w http.ResponseWriter, r *http.Request) {
 
 	w.WriteHeader(http.StatusNoContent)
 }
+
+// Rotate API Key
+// @Summary Rotate API key
+// @Description Issues a new token with the same name and scope. The old token keeps working for the grace period; the body is optional. A key can be rotated once: rotating it again, or an expired key, returns 409.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security SessionAuth
+// @Param id path string true "api key id"
+// @Param body body APIKeyRotateDTO false "grace period"
+// @Param X-CSRF-Token header string false "CSRF token (required for SessionAuth)"
+// @Success 201 {object} APIKeyCreateResponse
+// @Failure 400 {string} string
+// @Failure 401 {string} string
+// @Failure 404 {string} string
+// @Failure 409 {string} string
+// @Failure 500 {string} string
+// @Router /auth/api-keys/{id}/rotate [post]
+func (h *APIKeysHandler) Rotate(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimSpace(chi.URLParam(r, "id"))
+
+	var req APIKeyRotateDTO
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	if err := req.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var input apikeys.RotateInput
+	if req.GracePeriodSeconds != nil {
+		grace := time.Duration(*req.GracePeriodSeconds) * time.Second
+		input.GracePeriod = &grace
+	}
+	key, token, err := h.Service.Rotate(r.Context(), id, input)
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(newAPIKeyCreateResponse(key, token))
+}
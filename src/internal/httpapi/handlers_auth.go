@@ -5,17 +5,37 @@ import (
 	"encoding/json"
 	"net"
 	"net/http"
+	"net/url"
 	"strings"
 	"time"
 
+	"github.com/PabloPavan/sniply_api/internal/apperrors"
 	"github.com/PabloPavan/sniply_api/internal/auth"
+	"github.com/PabloPavan/sniply_api/internal/passkeys"
 	"github.com/PabloPavan/sniply_api/internal/session"
 	"github.com/PabloPavan/sniply_api/internal/telemetry"
+	"github.com/PabloPavan/sniply_api/internal/twofactor"
+	"github.com/go-chi/chi/v5"
 )
 
+// oidcStateCookie binds a single sign-on login to the browser that started
+// it.
+const oidcStateCookie = "sniply_oidc_state"
+
+// pendingCookie holds a login waiting for its second factor.
+const pendingCookie = "sniply_2fa"
+
 type AuthService interface {
 	Login(ctx context.Context, input auth.LoginInput) (auth.LoginResult, error)
 	Logout(ctx context.Context, sessionID string) error
+	StartOIDC(ctx context.Context, provider string) (auth.OIDCStart, error)
+	FinishOIDC(ctx context.Context, input auth.OIDCCallbackInput) (auth.LoginResult, error)
+	EnrollPending(ctx context.Context, pendingID string) (*twofactor.Enrollment, error)
+	CompleteTwoFactor(ctx context.Context, pendingID, code string) (auth.LoginResult, error)
+	BeginPasskeyLogin(ctx context.Context) (*passkeys.Ceremony, error)
+	FinishPasskeyLogin(ctx context.Context, ceremonyID string, response []byte) (auth.LoginResult, error)
+	BeginPasskeyTwoFactor(ctx context.Context, pendingID string) (*passkeys.Ceremony, error)
+	CompletePasskeyTwoFactor(ctx context.Context, pendingID, ceremonyID string, response []byte) (auth.LoginResult, error)
 }
 
 type AuthHandler struct {
@@ -23,6 +43,9 @@ type AuthHandler struct {
 	Authenticator Authenticator
 	Cookie        session.CookieConfig
 	CSRFCookie    session.CSRFCookieConfig
+	// PostLoginURL is where the browser goes after a single sign-on login.
+	// Without it, the callback answers like Login.
+	PostLoginURL string
 }
 
 type LoginRequest struct {
@@ -30,9 +53,16 @@ type LoginRequest struct {
 	Password string `json:"password"`
 }
 
+// LoginResponse describes the new session, or, with TwoFactorRequired, a
+// login to finish with POST /auth/2fa.
 type LoginResponse struct {
-	SessionExpiresAt string `json:"session_expires_at"` // RFC3339
-	CSRFToken        string `json:"csrf_token"`
+	SessionExpiresAt string `json:"session_expires_at,omitempty"` // RFC3339
+	CSRFToken        string `json:"csrf_token,omitempty"`
+
+	TwoFactorRequired   bool     `json:"two_factor_required,omitempty"`
+	TwoFactorEnrollment bool     `json:"two_factor_enrollment,omitempty"`
+	PendingExpiresAt    string   `json:"pending_expires_at,omitempty"` // RFC3339
+	RecoveryCodes       []string `json:"recovery_codes,omitempty"`
 }
 
 type CSRFResponse struct {
@@ -41,6 +71,7 @@ type CSRFResponse struct {
 
 // Login Auth
 // @Summary Login
+// @Description Users with two-factor authentication or a passkey, or whose role requires a second factor, get two_factor_required and a pending login to finish with POST /auth/2fa or POST /auth/webauthn/2fa/finish.
 // @Tags auth
 // @Accept json
 // @Produce json
@@ -73,24 +104,352 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	h.Cookie.Write(w, result.Session.ID, result.Session.ExpiresAt)
-	h.CSRFCookie.Write(w, result.Session.CSRFToken, result.Session.ExpiresAt)
+	h.writeLogin(w, r, result, "password")
+}
 
-	resp := LoginResponse{
-		SessionExpiresAt: result.Session.ExpiresAt.UTC().Format(time.RFC3339),
-		CSRFToken:        result.Session.CSRFToken,
+func (h *AuthHandler) writeLogin(w http.ResponseWriter, r *http.Request, result auth.LoginResult, method string) {
+	if p := result.Pending; p != nil {
+		h.writePendingCookie(w, p.ID, p.ExpiresAt)
+		if method == "oidc" && h.PostLoginURL != "" {
+			step := "required"
+			if p.Enroll {
+				step = "enroll"
+			}
+			http.Redirect(w, r, withQuery(h.PostLoginURL, "two_factor", step), http.StatusSeeOther)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(LoginResponse{
+			TwoFactorRequired:   true,
+			TwoFactorEnrollment: p.Enroll,
+			PendingExpiresAt:    p.ExpiresAt.UTC().Format(time.RFC3339),
+		})
+		return
 	}
 
+	h.Cookie.Write(w, result.Session.ID, result.Session.ExpiresAt)
+	h.CSRFCookie.Write(w, result.Session.CSRFToken, result.Session.ExpiresAt)
+
 	telemetry.LogInfo(r.Context(), "user login",
 		telemetry.LogString("event", "user.login"),
+		telemetry.LogString("auth.method", method),
 		telemetry.LogString("user.id", result.UserID),
 		telemetry.LogString("user.email", result.UserEmail),
 	)
 
+	if method == "oidc" && h.PostLoginURL != "" {
+		http.Redirect(w, r, h.PostLoginURL, http.StatusSeeOther)
+		return
+	}
+
+	resp := LoginResponse{
+		SessionExpiresAt: result.Session.ExpiresAt.UTC().Format(time.RFC3339),
+		CSRFToken:        result.Session.CSRFToken,
+		RecoveryCodes:    result.RecoveryCodes,
+	}
 	w.Header().Set("Content-Type", "application/json")
 	_ = json.NewEncoder(w).Encode(resp)
 }
 
+func (h *AuthHandler) writePendingCookie(w http.ResponseWriter, value string, expiresAt time.Time) {
+	maxAge := int(time.Until(expiresAt).Seconds())
+	if value == "" {
+		expiresAt, maxAge = time.Unix(0, 0), -1
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     pendingCookie,
+		Value:    value,
+		Path:     "/v1/auth",
+		Domain:   h.Cookie.Domain,
+		Expires:  expiresAt,
+		MaxAge:   maxAge,
+		Secure:   h.Cookie.Secure,
+		HttpOnly: true,
+		SameSite: h.Cookie.SameSite,
+	})
+}
+
+// withQuery adds key=value to the query of rawURL.
+func withQuery(rawURL, key, value string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	q := u.Query()
+	q.Set(key, value)
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+func pendingID(r *http.Request) string {
+	if c, err := r.Cookie(pendingCookie); err == nil {
+		return c.Value
+	}
+	return ""
+}
+
+// TwoFactor Auth
+// @Summary Finish a two-factor login
+// @Description Takes a TOTP or recovery code for the pending login started by Login. When the login required enrollment, the code confirms it and the response carries the recovery codes.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param body body TwoFactorCodeDTO true "TOTP or recovery code"
+// @Success 200 {object} LoginResponse
+// @Failure 400 {string} string
+// @Failure 401 {string} string
+// @Failure 500 {string} string
+// @Router /auth/2fa [post]
+func (h *AuthHandler) TwoFactor(w http.ResponseWriter, r *http.Request) {
+	if h.Service == nil {
+		http.Error(w, "auth not configured", http.StatusInternalServerError)
+		return
+	}
+
+	code, ok := decodeTwoFactorCode(w, r)
+	if !ok {
+		return
+	}
+
+	result, err := h.Service.CompleteTwoFactor(r.Context(), pendingID(r), code)
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	h.writePendingCookie(w, "", time.Time{})
+	h.writeLogin(w, r, result, "two_factor")
+}
+
+// TwoFactorEnroll Auth
+// @Summary Enroll TOTP during login
+// @Description For a pending login whose role requires two-factor authentication the user has not set up. Confirm by finishing the login with POST /auth/2fa.
+// @Tags auth
+// @Produce json
+// @Success 200 {object} TOTPEnrollmentResponse
+// @Failure 401 {string} string
+// @Failure 409 {string} string
+// @Failure 500 {string} string
+// @Router /auth/2fa/enroll [post]
+func (h *AuthHandler) TwoFactorEnroll(w http.ResponseWriter, r *http.Request) {
+	if h.Service == nil {
+		http.Error(w, "auth not configured", http.StatusInternalServerError)
+		return
+	}
+
+	e, err := h.Service.EnrollPending(r.Context(), pendingID(r))
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(enrollmentResponse(e))
+}
+
+// PasskeyLoginBegin Auth
+// @Summary Start a passkey login
+// @Description Passwordless: returns the options for navigator.credentials.get() without a user, so the authenticator picks the account. Finish with POST /auth/webauthn/login/finish.
+// @Tags auth
+// @Produce json
+// @Success 200 {object} WebAuthnCeremonyResponse
+// @Failure 500 {string} string
+// @Router /auth/webauthn/login/begin [post]
+func (h *AuthHandler) PasskeyLoginBegin(w http.ResponseWriter, r *http.Request) {
+	if h.Service == nil {
+		http.Error(w, "auth not configured", http.StatusInternalServerError)
+		return
+	}
+
+	c, err := h.Service.BeginPasskeyLogin(r.Context())
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+	writeCeremony(w, c)
+}
+
+// PasskeyLogin Auth
+// @Summary Finish a passkey login
+// @Description Creates the same session as Login. The authenticator verifies the user, so no second factor is asked for.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param body body WebAuthnFinishDTO true "ceremony and assertion"
+// @Success 200 {object} LoginResponse
+// @Failure 400 {string} string
+// @Failure 401 {string} string
+// @Failure 500 {string} string
+// @Router /auth/webauthn/login/finish [post]
+func (h *AuthHandler) PasskeyLogin(w http.ResponseWriter, r *http.Request) {
+	if h.Service == nil {
+		http.Error(w, "auth not configured", http.StatusInternalServerError)
+		return
+	}
+
+	req, ok := decodeWebAuthnFinish(w, r)
+	if !ok {
+		return
+	}
+
+	result, err := h.Service.FinishPasskeyLogin(r.Context(), req.Ceremony, req.Credential)
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	h.writeLogin(w, r, result, "passkey")
+}
+
+// PasskeyTwoFactorBegin Auth
+// @Summary Start a passkey second factor
+// @Description For the pending login started by Login: returns the options for navigator.credentials.get() with the user's passkeys. Finish with POST /auth/webauthn/2fa/finish.
+// @Tags auth
+// @Produce json
+// @Success 200 {object} WebAuthnCeremonyResponse
+// @Failure 400 {string} string
+// @Failure 401 {string} string
+// @Failure 500 {string} string
+// @Router /auth/webauthn/2fa/begin [post]
+func (h *AuthHandler) PasskeyTwoFactorBegin(w http.ResponseWriter, r *http.Request) {
+	if h.Service == nil {
+		http.Error(w, "auth not configured", http.StatusInternalServerError)
+		return
+	}
+
+	c, err := h.Service.BeginPasskeyTwoFactor(r.Context(), pendingID(r))
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+	writeCeremony(w, c)
+}
+
+// PasskeyTwoFactor Auth
+// @Summary Finish a two-factor login with a passkey
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param body body WebAuthnFinishDTO true "ceremony and assertion"
+// @Success 200 {object} LoginResponse
+// @Failure 400 {string} string
+// @Failure 401 {string} string
+// @Failure 500 {string} string
+// @Router /auth/webauthn/2fa/finish [post]
+func (h *AuthHandler) PasskeyTwoFactor(w http.ResponseWriter, r *http.Request) {
+	if h.Service == nil {
+		http.Error(w, "auth not configured", http.StatusInternalServerError)
+		return
+	}
+
+	req, ok := decodeWebAuthnFinish(w, r)
+	if !ok {
+		return
+	}
+
+	result, err := h.Service.CompletePasskeyTwoFactor(r.Context(), pendingID(r), req.Ceremony, req.Credential)
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	h.writePendingCookie(w, "", time.Time{})
+	h.writeLogin(w, r, result, "two_factor")
+}
+
+// OIDCStart Auth
+// @Summary Start a single sign-on login
+// @Description Redirects the browser to the identity provider for an authorization code login with PKCE.
+// @Tags auth
+// @Param provider path string true "Identity provider name"
+// @Success 302
+// @Failure 404 {string} string
+// @Failure 500 {string} string
+// @Router /auth/oidc/{provider}/start [get]
+func (h *AuthHandler) OIDCStart(w http.ResponseWriter, r *http.Request) {
+	if h.Service == nil {
+		http.Error(w, "auth not configured", http.StatusInternalServerError)
+		return
+	}
+
+	start, err := h.Service.StartOIDC(r.Context(), strings.TrimSpace(chi.URLParam(r, "provider")))
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	// Lax, whatever the session cookie uses: the provider sends the browser
+	// back with a cross-site redirect.
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    start.State,
+		Path:     "/v1/auth/oidc",
+		Domain:   h.Cookie.Domain,
+		Expires:  start.ExpiresAt,
+		MaxAge:   int(time.Until(start.ExpiresAt).Seconds()),
+		Secure:   h.Cookie.Secure,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, start.AuthURL, http.StatusFound)
+}
+
+// OIDCCallback Auth
+// @Summary Finish a single sign-on login
+// @Description Called by the identity provider. Answers like Login, a pending second factor included; users are linked by verified email or provisioned on their first login.
+// @Tags auth
+// @Produce json
+// @Param provider path string true "Identity provider name"
+// @Param code query string false "Authorization code"
+// @Param state query string true "Login state"
+// @Param error query string false "Error returned by the identity provider"
+// @Success 200 {object} LoginResponse
+// @Success 303
+// @Failure 400 {string} string
+// @Failure 401 {string} string
+// @Failure 403 {string} string
+// @Failure 404 {string} string
+// @Failure 409 {string} string
+// @Failure 500 {string} string
+// @Router /auth/oidc/{provider}/callback [get]
+func (h *AuthHandler) OIDCCallback(w http.ResponseWriter, r *http.Request) {
+	if h.Service == nil {
+		http.Error(w, "auth not configured", http.StatusInternalServerError)
+		return
+	}
+
+	q := r.URL.Query()
+	state := q.Get("state")
+	cookie, err := r.Cookie(oidcStateCookie)
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookie,
+		Path:     "/v1/auth/oidc",
+		Domain:   h.Cookie.Domain,
+		Expires:  time.Unix(0, 0),
+		MaxAge:   -1,
+		Secure:   h.Cookie.Secure,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	if err != nil || state == "" || cookie.Value != state {
+		writeAppError(w, apperrors.New(apperrors.KindUnauthorized, "invalid or expired login state"))
+		return
+	}
+
+	result, err := h.Service.FinishOIDC(r.Context(), auth.OIDCCallbackInput{
+		Provider: strings.TrimSpace(chi.URLParam(r, "provider")),
+		State:    state,
+		Code:     q.Get("code"),
+		Error:    q.Get("error"),
+	})
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	h.writeLogin(w, r, result, "oidc")
+}
+
 // Logout Auth
 // @Summary Logout
 // @Tags auth
@@ -0,0 +1,194 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/PabloPavan/sniply_api/internal/comments"
+)
+
+type CommentsService interface {
+	List(ctx context.Context, snippetID string) ([]*comments.Comment, error)
+	Get(ctx context.Context, snippetID, id string) (*comments.Comment, error)
+	Create(ctx context.Context, snippetID string, input comments.CreateInput) (*comments.Comment, error)
+	Update(ctx context.Context, snippetID, id, body string) (*comments.Comment, error)
+	Delete(ctx context.Context, snippetID, id string) error
+}
+
+type CommentsHandler struct {
+	Service CommentsService
+}
+
+// List Comments
+// @Summary List snippet comments
+// @Description Comments are returned as threads, oldest first, with replies nested under their parent. Anyone who can read the snippet can read its comments.
+// @Tags comments
+// @Produce json
+// @Security SessionAuth
+// @Security ApiKeyAuth
+// @Param id path string true "snippet id"
+// @Success 200 {array} comments.Comment
+// @Failure 401 {string} string
+// @Failure 404 {string} string
+// @Failure 500 {string} string
+// @Router /snippets/{id}/comments [get]
+func (h *CommentsHandler) List(w http.ResponseWriter, r *http.Request) {
+	snippetID := strings.TrimSpace(chi.URLParam(r, "id"))
+
+	list, err := h.Service.List(r.Context(), snippetID)
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(list)
+}
+
+// Get Comment
+// @Summary Get a snippet comment
+// @Tags comments
+// @Produce json
+// @Security SessionAuth
+// @Security ApiKeyAuth
+// @Param id path string true "snippet id"
+// @Param commentID path string true "comment id"
+// @Success 200 {object} comments.Comment
+// @Failure 401 {string} string
+// @Failure 404 {string} string
+// @Failure 500 {string} string
+// @Router /snippets/{id}/comments/{commentID} [get]
+func (h *CommentsHandler) Get(w http.ResponseWriter, r *http.Request) {
+	snippetID := strings.TrimSpace(chi.URLParam(r, "id"))
+	commentID := strings.TrimSpace(chi.URLParam(r, "commentID"))
+
+	comment, err := h.Service.Get(r.Context(), snippetID, commentID)
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(comment)
+}
+
+// Create Comment
+// @Summary Comment on a snippet
+// @Description Set parent_id to reply to another comment. line_start and line_end anchor the comment to lines of a revision, the current one unless revision is given.
+// @Tags comments
+// @Accept json
+// @Produce json
+// @Security SessionAuth
+// @Security ApiKeyAuth
+// @Param id path string true "snippet id"
+// @Param body body CommentCreateDTO true "comment"
+// @Param X-CSRF-Token header string false "CSRF token (required for SessionAuth)"
+// @Success 201 {object} comments.Comment
+// @Failure 400 {string} string
+// @Failure 401 {string} string
+// @Failure 404 {string} string
+// @Failure 500 {string} string
+// @Router /snippets/{id}/comments [post]
+func (h *CommentsHandler) Create(w http.ResponseWriter, r *http.Request) {
+	snippetID := strings.TrimSpace(chi.URLParam(r, "id"))
+
+	var req CommentCreateDTO
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	if err := req.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	comment, err := h.Service.Create(r.Context(), snippetID, comments.CreateInput{
+		ParentID:  req.ParentID,
+		Body:      req.Body,
+		LineStart: req.LineStart,
+		LineEnd:   req.LineEnd,
+		Revision:  req.Revision,
+	})
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(comment)
+}
+
+// Update Comment
+// @Summary Edit a snippet comment
+// @Description Only the author can edit a comment.
+// @Tags comments
+// @Accept json
+// @Produce json
+// @Security SessionAuth
+// @Security ApiKeyAuth
+// @Param id path string true "snippet id"
+// @Param commentID path string true "comment id"
+// @Param body body CommentUpdateDTO true "comment"
+// @Param X-CSRF-Token header string false "CSRF token (required for SessionAuth)"
+// @Success 200 {object} comments.Comment
+// @Failure 400 {string} string
+// @Failure 401 {string} string
+// @Failure 403 {string} string
+// @Failure 404 {string} string
+// @Failure 500 {string} string
+// @Router /snippets/{id}/comments/{commentID} [put]
+func (h *CommentsHandler) Update(w http.ResponseWriter, r *http.Request) {
+	snippetID := strings.TrimSpace(chi.URLParam(r, "id"))
+	commentID := strings.TrimSpace(chi.URLParam(r, "commentID"))
+
+	var req CommentUpdateDTO
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	if err := req.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	comment, err := h.Service.Update(r.Context(), snippetID, commentID, req.Body)
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(comment)
+}
+
+// Delete Comment
+// @Summary Delete a snippet comment
+// @Description Deletes the comment and its replies. Allowed for the comment author, the snippet owner and admins.
+// @Tags comments
+// @Security SessionAuth
+// @Security ApiKeyAuth
+// @Param id path string true "snippet id"
+// @Param commentID path string true "comment id"
+// @Param X-CSRF-Token header string false "CSRF token (required for SessionAuth)"
+// @Success 204
+// @Failure 401 {string} string
+// @Failure 403 {string} string
+// @Failure 404 {string} string
+// @Failure 500 {string} string
+// @Router /snippets/{id}/comments/{commentID} [delete]
+func (h *CommentsHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	snippetID := strings.TrimSpace(chi.URLParam(r, "id"))
+	commentID := strings.TrimSpace(chi.URLParam(r, "commentID"))
+
+	if err := h.Service.Delete(r.Context(), snippetID, commentID); err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,39 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/PabloPavan/sniply_api/internal/snippets"
+)
+
+type LanguagesService interface {
+	LanguageStats(ctx context.Context) ([]snippets.LanguageStat, error)
+}
+
+type LanguagesHandler struct {
+	Service LanguagesService
+}
+
+// List Languages
+// @Summary List supported languages
+// @Description Returns the canonical language registry with the number of snippets visible to the caller in each language.
+// @Tags languages
+// @Produce json
+// @Security SessionAuth
+// @Security ApiKeyAuth
+// @Success 200 {array} snippets.LanguageStat
+// @Failure 401 {string} string
+// @Failure 500 {string} string
+// @Router /languages [get]
+func (h *LanguagesHandler) List(w http.ResponseWriter, r *http.Request) {
+	list, err := h.Service.LanguageStats(r.Context())
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(list)
+}
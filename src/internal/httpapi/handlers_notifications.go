@@ -0,0 +1,105 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/PabloPavan/sniply_api/internal/notifications"
+)
+
+type NotificationsService interface {
+	List(ctx context.Context, unreadOnly bool, limit int) ([]*notifications.Notification, error)
+	MarkRead(ctx context.Context, id string) error
+	MarkAllRead(ctx context.Context) (int, error)
+}
+
+type NotificationsHandler struct {
+	Service NotificationsService
+}
+
+type NotificationsReadResponse struct {
+	Updated int `json:"updated"`
+}
+
+// List Notifications
+// @Summary List my notifications
+// @Description Newest first. Saved search matches have kind saved_search.match and the matching snippets in data.
+// @Tags notifications
+// @Produce json
+// @Security SessionAuth
+// @Security ApiKeyAuth
+// @Param unread query bool false "only unread notifications"
+// @Param limit query int false "limit (default 50, max 200)"
+// @Success 200 {array} notifications.Notification
+// @Failure 401 {string} string
+// @Failure 500 {string} string
+// @Router /notifications [get]
+func (h *NotificationsHandler) List(w http.ResponseWriter, r *http.Request) {
+	unread, _ := strconv.ParseBool(strings.TrimSpace(r.URL.Query().Get("unread")))
+
+	limit := 0
+	if l := strings.TrimSpace(r.URL.Query().Get("limit")); l != "" {
+		if v, err := strconv.Atoi(l); err == nil && v > 0 {
+			limit = v
+		}
+	}
+
+	list, err := h.Service.List(r.Context(), unread, limit)
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(list)
+}
+
+// Read Notification
+// @Summary Mark a notification as read
+// @Tags notifications
+// @Security SessionAuth
+// @Security ApiKeyAuth
+// @Param id path string true "notification id"
+// @Param X-CSRF-Token header string false "CSRF token (required for SessionAuth)"
+// @Success 204
+// @Failure 401 {string} string
+// @Failure 404 {string} string
+// @Failure 500 {string} string
+// @Router /notifications/{id}/read [post]
+func (h *NotificationsHandler) Read(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimSpace(chi.URLParam(r, "id"))
+
+	if err := h.Service.MarkRead(r.Context(), id); err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Read All Notifications
+// @Summary Mark all my notifications as read
+// @Tags notifications
+// @Produce json
+// @Security SessionAuth
+// @Security ApiKeyAuth
+// @Param X-CSRF-Token header string false "CSRF token (required for SessionAuth)"
+// @Success 200 {object} NotificationsReadResponse
+// @Failure 401 {string} string
+// @Failure 500 {string} string
+// @Router /notifications/read-all [post]
+func (h *NotificationsHandler) ReadAll(w http.ResponseWriter, r *http.Request) {
+	n, err := h.Service.MarkAllRead(r.Context())
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(NotificationsReadResponse{Updated: n})
+}
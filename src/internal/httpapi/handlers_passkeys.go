@@ -0,0 +1,139 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/PabloPavan/sniply_api/internal/passkeys"
+)
+
+type PasskeysService interface {
+	List(ctx context.Context) ([]passkeys.Credential, error)
+	BeginRegistration(ctx context.Context) (*passkeys.Ceremony, error)
+	FinishRegistration(ctx context.Context, ceremonyID, name string, response []byte) (*passkeys.Credential, error)
+	Delete(ctx context.Context, id string) error
+}
+
+type PasskeysHandler struct {
+	Service PasskeysService
+}
+
+// WebAuthnCeremonyResponse holds the options for navigator.credentials
+// create() or get(); send the result back with the ceremony ID.
+type WebAuthnCeremonyResponse struct {
+	Ceremony string          `json:"ceremony"`
+	Options  json.RawMessage `json:"options" swaggertype:"object"`
+}
+
+func writeCeremony(w http.ResponseWriter, c *passkeys.Ceremony) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(WebAuthnCeremonyResponse{Ceremony: c.ID, Options: c.Options})
+}
+
+func decodeWebAuthnFinish(w http.ResponseWriter, r *http.Request) (WebAuthnFinishDTO, bool) {
+	var req WebAuthnFinishDTO
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return req, false
+	}
+	if err := req.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return req, false
+	}
+	return req, true
+}
+
+// BeginRegistration Passkeys
+// @Summary Start registering a passkey
+// @Description Returns the options for navigator.credentials.create(). Finish with POST /auth/webauthn/register/finish.
+// @Tags auth
+// @Produce json
+// @Security SessionAuth
+// @Param X-CSRF-Token header string false "CSRF token (required for SessionAuth)"
+// @Success 200 {object} WebAuthnCeremonyResponse
+// @Failure 401 {string} string
+// @Failure 500 {string} string
+// @Router /auth/webauthn/register/begin [post]
+func (h *PasskeysHandler) BeginRegistration(w http.ResponseWriter, r *http.Request) {
+	c, err := h.Service.BeginRegistration(r.Context())
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+	writeCeremony(w, c)
+}
+
+// FinishRegistration Passkeys
+// @Summary Finish registering a passkey
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security SessionAuth
+// @Param X-CSRF-Token header string false "CSRF token (required for SessionAuth)"
+// @Param body body WebAuthnFinishDTO true "ceremony, optional name and the new credential"
+// @Success 201 {object} passkeys.Credential
+// @Failure 400 {string} string
+// @Failure 401 {string} string
+// @Failure 409 {string} string
+// @Failure 500 {string} string
+// @Router /auth/webauthn/register/finish [post]
+func (h *PasskeysHandler) FinishRegistration(w http.ResponseWriter, r *http.Request) {
+	req, ok := decodeWebAuthnFinish(w, r)
+	if !ok {
+		return
+	}
+
+	c, err := h.Service.FinishRegistration(r.Context(), req.Ceremony, req.Name, req.Credential)
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(c)
+}
+
+// List Passkeys
+// @Summary List my passkeys
+// @Tags auth
+// @Produce json
+// @Security SessionAuth
+// @Success 200 {array} passkeys.Credential
+// @Failure 401 {string} string
+// @Failure 500 {string} string
+// @Router /auth/webauthn/credentials [get]
+func (h *PasskeysHandler) List(w http.ResponseWriter, r *http.Request) {
+	list, err := h.Service.List(r.Context())
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(list)
+}
+
+// Delete Passkeys
+// @Summary Delete a passkey
+// @Tags auth
+// @Security SessionAuth
+// @Param X-CSRF-Token header string false "CSRF token (required for SessionAuth)"
+// @Param id path string true "Credential ID"
+// @Success 204
+// @Failure 401 {string} string
+// @Failure 404 {string} string
+// @Failure 500 {string} string
+// @Router /auth/webauthn/credentials/{id} [delete]
+func (h *PasskeysHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimSpace(chi.URLParam(r, "id"))
+	if err := h.Service.Delete(r.Context(), id); err != nil {
+		writeAppError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
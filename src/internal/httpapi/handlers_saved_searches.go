@@ -0,0 +1,234 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/PabloPavan/sniply_api/internal/savedsearches"
+	"github.com/PabloPavan/sniply_api/internal/snippets"
+)
+
+type SavedSearchesService interface {
+	Create(ctx context.Context, input savedsearches.Input) (*savedsearches.SavedSearch, error)
+	Get(ctx context.Context, id string) (*savedsearches.SavedSearch, error)
+	List(ctx context.Context) ([]*savedsearches.SavedSearch, error)
+	Update(ctx context.Context, id string, input savedsearches.Input) (*savedsearches.SavedSearch, error)
+	Delete(ctx context.Context, id string) error
+	Run(ctx context.Context, id string, limit, offset int) ([]*snippets.Snippet, error)
+}
+
+type SavedSearchesHandler struct {
+	Service SavedSearchesService
+}
+
+// Create Saved Search
+// @Summary Save a snippet search
+// @Description Stores the parameters of GET /snippets under a name. With notify=inbox or notify=webhook, snippets created after the search was saved are reported periodically. Webhooks are POSTed as JSON and signed in the X-Sniply-Signature header (sha256=HMAC-SHA256 of the body keyed with webhook_secret).
+// @Tags saved-searches
+// @Accept json
+// @Produce json
+// @Security SessionAuth
+// @Security ApiKeyAuth
+// @Param body body SavedSearchDTO true "saved search"
+// @Param X-CSRF-Token header string false "CSRF token (required for SessionAuth)"
+// @Success 201 {object} savedsearches.SavedSearch
+// @Failure 400 {string} string
+// @Failure 401 {string} string
+// @Failure 403 {string} string
+// @Failure 409 {string} string
+// @Failure 500 {string} string
+// @Router /saved-searches [post]
+func (h *SavedSearchesHandler) Create(w http.ResponseWriter, r *http.Request) {
+	input, ok := decodeSavedSearch(w, r)
+	if !ok {
+		return
+	}
+
+	saved, err := h.Service.Create(r.Context(), input)
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(saved)
+}
+
+// List Saved Searches
+// @Summary List my saved searches
+// @Tags saved-searches
+// @Produce json
+// @Security SessionAuth
+// @Security ApiKeyAuth
+// @Success 200 {array} savedsearches.SavedSearch
+// @Failure 401 {string} string
+// @Failure 500 {string} string
+// @Router /saved-searches [get]
+func (h *SavedSearchesHandler) List(w http.ResponseWriter, r *http.Request) {
+	list, err := h.Service.List(r.Context())
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(list)
+}
+
+// Get Saved Search
+// @Summary Get a saved search
+// @Tags saved-searches
+// @Produce json
+// @Security SessionAuth
+// @Security ApiKeyAuth
+// @Param id path string true "saved search id"
+// @Success 200 {object} savedsearches.SavedSearch
+// @Failure 401 {string} string
+// @Failure 404 {string} string
+// @Failure 500 {string} string
+// @Router /saved-searches/{id} [get]
+func (h *SavedSearchesHandler) Get(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimSpace(chi.URLParam(r, "id"))
+
+	saved, err := h.Service.Get(r.Context(), id)
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(saved)
+}
+
+// Update Saved Search
+// @Summary Update a saved search
+// @Description Replaces the name, query and notification settings. The webhook secret is kept while notify stays webhook.
+// @Tags saved-searches
+// @Accept json
+// @Produce json
+// @Security SessionAuth
+// @Security ApiKeyAuth
+// @Param id path string true "saved search id"
+// @Param body body SavedSearchDTO true "saved search"
+// @Param X-CSRF-Token header string false "CSRF token (required for SessionAuth)"
+// @Success 200 {object} savedsearches.SavedSearch
+// @Failure 400 {string} string
+// @Failure 401 {string} string
+// @Failure 404 {string} string
+// @Failure 409 {string} string
+// @Failure 500 {string} string
+// @Router /saved-searches/{id} [put]
+func (h *SavedSearchesHandler) Update(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimSpace(chi.URLParam(r, "id"))
+
+	input, ok := decodeSavedSearch(w, r)
+	if !ok {
+		return
+	}
+
+	saved, err := h.Service.Update(r.Context(), id, input)
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(saved)
+}
+
+// Delete Saved Search
+// @Summary Delete a saved search
+// @Tags saved-searches
+// @Security SessionAuth
+// @Security ApiKeyAuth
+// @Param id path string true "saved search id"
+// @Param X-CSRF-Token header string false "CSRF token (required for SessionAuth)"
+// @Success 204
+// @Failure 401 {string} string
+// @Failure 404 {string} string
+// @Failure 500 {string} string
+// @Router /saved-searches/{id} [delete]
+func (h *SavedSearchesHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimSpace(chi.URLParam(r, "id"))
+
+	if err := h.Service.Delete(r.Context(), id); err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Run Saved Search
+// @Summary Run a saved search
+// @Description Returns the same result as GET /snippets with the saved parameters.
+// @Tags saved-searches
+// @Produce json
+// @Security SessionAuth
+// @Security ApiKeyAuth
+// @Param id path string true "saved search id"
+// @Param limit query int false "limit"
+// @Param offset query int false "offset"
+// @Success 200 {array} snippets.Snippet
+// @Failure 400 {string} string
+// @Failure 401 {string} string
+// @Failure 404 {string} string
+// @Failure 500 {string} string
+// @Router /saved-searches/{id}/run [get]
+func (h *SavedSearchesHandler) Run(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimSpace(chi.URLParam(r, "id"))
+
+	limit := 0
+	offset := 0
+	if l := strings.TrimSpace(r.URL.Query().Get("limit")); l != "" {
+		if v, err := strconv.Atoi(l); err == nil && v > 0 {
+			limit = v
+		}
+	}
+	if o := strings.TrimSpace(r.URL.Query().Get("offset")); o != "" {
+		if v, err := strconv.Atoi(o); err == nil && v >= 0 {
+			offset = v
+		}
+	}
+
+	list, err := h.Service.Run(r.Context(), id, limit, offset)
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(list)
+}
+
+func decodeSavedSearch(w http.ResponseWriter, r *http.Request) (savedsearches.Input, bool) {
+	var req SavedSearchDTO
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return savedsearches.Input{}, false
+	}
+	if err := req.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return savedsearches.Input{}, false
+	}
+
+	return savedsearches.Input{
+		Name: req.Name,
+		Query: savedsearches.Query{
+			Q:          req.Query.Q,
+			Mode:       req.Query.Mode,
+			Creator:    req.Query.Creator,
+			Language:   req.Query.Language,
+			Tag:        req.Query.Tag,
+			Visibility: req.Query.Visibility,
+		},
+		Notify:     savedsearches.Notify(req.Notify),
+		WebhookURL: req.WebhookURL,
+	}, true
+}
@@ -0,0 +1,91 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/PabloPavan/sniply_api/internal/auth"
+	"github.com/PabloPavan/sniply_api/internal/session"
+)
+
+type SessionsService interface {
+	ListSessions(ctx context.Context) ([]auth.ActiveSession, error)
+	RevokeSession(ctx context.Context, publicID string) (bool, error)
+	RevokeAllSessions(ctx context.Context) error
+}
+
+type SessionsHandler struct {
+	Service    SessionsService
+	Cookie     session.CookieConfig
+	CSRFCookie session.CSRFCookieConfig
+}
+
+// List Sessions
+// @Summary List my sessions
+// @Description Every login of the current user, newest first; current marks the calling session.
+// @Tags auth
+// @Produce json
+// @Security SessionAuth
+// @Success 200 {array} auth.ActiveSession
+// @Failure 401 {string} string
+// @Failure 500 {string} string
+// @Router /auth/sessions [get]
+func (h *SessionsHandler) List(w http.ResponseWriter, r *http.Request) {
+	list, err := h.Service.ListSessions(r.Context())
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(list)
+}
+
+// Revoke Sessions
+// @Summary Revoke a session
+// @Tags auth
+// @Security SessionAuth
+// @Param X-CSRF-Token header string false "CSRF token (required for SessionAuth)"
+// @Param id path string true "Session ID from the list"
+// @Success 204
+// @Failure 401 {string} string
+// @Failure 404 {string} string
+// @Failure 500 {string} string
+// @Router /auth/sessions/{id} [delete]
+func (h *SessionsHandler) Revoke(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimSpace(chi.URLParam(r, "id"))
+	current, err := h.Service.RevokeSession(r.Context(), id)
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+	if current {
+		h.Cookie.Clear(w)
+		h.CSRFCookie.Clear(w)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RevokeAll Sessions
+// @Summary Log out everywhere
+// @Description Ends every session of the current user, this one included.
+// @Tags auth
+// @Security SessionAuth
+// @Param X-CSRF-Token header string false "CSRF token (required for SessionAuth)"
+// @Success 204
+// @Failure 401 {string} string
+// @Failure 500 {string} string
+// @Router /auth/sessions [delete]
+func (h *SessionsHandler) RevokeAll(w http.ResponseWriter, r *http.Request) {
+	if err := h.Service.RevokeAllSessions(r.Context()); err != nil {
+		writeAppError(w, err)
+		return
+	}
+	h.Cookie.Clear(w)
+	h.CSRFCookie.Clear(w)
+	w.WriteHeader(http.StatusNoContent)
+}
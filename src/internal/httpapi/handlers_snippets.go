@@ -3,25 +3,44 @@ package httpapi
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 	"strings"
 
 	"github.com/go-chi/chi/v5"
 
+	"github.com/PabloPavan/sniply_api/internal/analytics"
+	"github.com/PabloPavan/sniply_api/internal/highlight"
+	"github.com/PabloPavan/sniply_api/internal/secrets"
 	"github.com/PabloPavan/sniply_api/internal/snippets"
 )
 
 type SnippetsService interface {
 	Create(ctx context.Context, req snippets.CreateSnippetRequest) (*snippets.Snippet, error)
-	GetByID(ctx context.Context, id string) (*snippets.Snippet, error)
+	Preview(ctx context.Context, req snippets.CreateSnippetRequest) (*snippets.Snippet, error)
 	List(ctx context.Context, input snippets.ListInput) ([]*snippets.Snippet, error)
+	ListOwned(ctx context.Context) ([]*snippets.Snippet, error)
 	Update(ctx context.Context, id string, req snippets.CreateSnippetRequest) (*snippets.Snippet, error)
+	Render(ctx context.Context, id string, values map[string]string) (string, error)
+	View(ctx context.Context, id string, sel snippets.Selection) (*snippets.Snippet, error)
+	Highlight(ctx context.Context, id string, revision int, opts highlight.Options) ([]byte, highlight.Options, error)
+	SecretFindings(ctx context.Context, id string) ([]secrets.Finding, error)
+	Related(ctx context.Context, id string, limit int) ([]snippets.RelatedSnippet, error)
+	Format(ctx context.Context, id string, dryRun bool) (*snippets.Snippet, error)
 	Delete(ctx context.Context, id string) error
+	Reindex(ctx context.Context) (int, error)
+	Facets(ctx context.Context, input snippets.ListInput, facets []string, limit int) (*snippets.Facets, error)
+}
+
+type SnippetsReindexResponse struct {
+	Indexed int `json:"indexed"`
 }
 
 type SnippetsHandler struct {
 	Service SnippetsService
+	// Views, when set, counts reads of GET /snippets/{id} and /raw.
+	Views ViewRecorder
 }
 
 // Create Snippet
@@ -51,11 +70,14 @@ func (h *SnippetsHandler) Create(w http.ResponseWriter, r *http.Request) {
 	}
 
 	snippet, err := h.Service.Create(r.Context(), snippets.CreateSnippetRequest{
-		Name:       req.Name,
-		Content:    req.Content,
-		Language:   req.Language,
-		Tags:       req.Tags,
-		Visibility: req.Visibility,
+		Name:         req.Name,
+		Content:      req.Content,
+		Language:     req.Language,
+		Tags:         req.Tags,
+		Visibility:   req.Visibility,
+		Placeholders: req.Placeholders,
+
+		ValidateSyntax: req.ValidateSyntax,
 	})
 	if err != nil {
 		writeAppError(w, err)
@@ -69,11 +91,14 @@ func (h *SnippetsHandler) Create(w http.ResponseWriter, r *http.Request) {
 
 // GetByID Snippet
 // @Summary Get snippet by id
+// @Description Public snippets can be read by anyone, private ones by their owner and admins. With `lines` only that slice of the content is returned and `excerpt` holds its original line numbers. With `rev` the content comes from that revision, so permalinks never drift.
 // @Tags snippets
 // @Produce json
 // @Security SessionAuth
 // @Security ApiKeyAuth
 // @Param id path string true "snippet id"
+// @Param lines query string false "line range, e.g. 10-25"
+// @Param rev query int false "pinned revision"
 // @Success 200 {object} snippets.Snippet
 // @Failure 400 {string} string
 // @Failure 404 {string} string
@@ -82,11 +107,20 @@ func (h *SnippetsHandler) Create(w http.ResponseWriter, r *http.Request) {
 func (h *SnippetsHandler) GetByID(w http.ResponseWriter, r *http.Request) {
 	id := strings.TrimSpace(chi.URLParam(r, "id"))
 
-	snippet, err := h.Service.GetByID(r.Context(), id)
+	sel, err := parseSelection(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	snippet, err := h.Service.View(r.Context(), id, sel)
 	if err != nil {
 		writeAppError(w, err)
 		return
 	}
+	if h.Views != nil {
+		_ = h.Views.Record(r.Context(), snippet, analytics.KindView)
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	_ = json.NewEncoder(w).Encode(snippet)
@@ -94,11 +128,13 @@ func (h *SnippetsHandler) GetByID(w http.ResponseWriter, r *http.Request) {
 
 // List Snippets
 // @Summary List snippets
+// @Description In fts mode q accepts qualifiers mixed with free words: lang:go, symbol:ServeHTTP, tag:http (repeat to require several), -tag:deprecated, author:usr_123, is:public or is:private, created:>2025-01-01 (also >=, <, <=, a single day or 2025-01-01..2025-01-31) and "exact phrase". Words with an unknown prefix, such as URLs, are searched as text. A bad qualifier returns 400 with the reason, e.g. "created: invalid date"; is:starred is rejected because snippets cannot be starred.
 // @Tags snippets
 // @Produce json
 // @Security SessionAuth
 // @Security ApiKeyAuth
-// @Param q query string false "search"
+// @Param q query string false "search words and qualifiers, e.g. lang:go tag:http -tag:deprecated router"
+// @Param mode query string false "how q is matched: words (fts), verbatim text (phrase) or a POSIX regular expression on the content (regex)" Enums(fts, phrase, regex) default(fts)
 // @Param creator query string false "creator id"
 // @Param language query string false "language"
 // @Param tag query string false "tag"
@@ -112,7 +148,63 @@ func (h *SnippetsHandler) GetByID(w http.ResponseWriter, r *http.Request) {
 // @Failure 500 {string} string
 // @Router /snippets [get]
 func (h *SnippetsHandler) List(w http.ResponseWriter, r *http.Request) {
+	list, err := h.Service.List(r.Context(), parseListInput(r))
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(list)
+}
+
+// Snippet Facets
+// @Summary Count snippets by language, tag and creator
+// @Description Takes the same filters as GET /snippets and applies the same visibility rules. Each facet lists its most frequent values first; total counts all matching snippets.
+// @Tags snippets
+// @Produce json
+// @Security SessionAuth
+// @Security ApiKeyAuth
+// @Param facets query string false "comma separated facets: language, tags, creator (default all)"
+// @Param facet_limit query int false "values per facet (default 10, max 50)"
+// @Param q query string false "search words and qualifiers, as in GET /snippets"
+// @Param mode query string false "how q is matched" Enums(fts, phrase, regex) default(fts)
+// @Param creator query string false "creator id"
+// @Param language query string false "language"
+// @Param tag query string false "tag"
+// @Param visibility query string false "visibility"
+// @Success 200 {object} snippets.Facets
+// @Failure 400 {string} string
+// @Failure 401 {string} string
+// @Failure 403 {string} string
+// @Failure 500 {string} string
+// @Router /snippets/facets [get]
+func (h *SnippetsHandler) Facets(w http.ResponseWriter, r *http.Request) {
+	var facets []string
+	if v := strings.TrimSpace(r.URL.Query().Get("facets")); v != "" {
+		facets = strings.Split(v, ",")
+	}
+	limit := 0
+	if l := strings.TrimSpace(r.URL.Query().Get("facet_limit")); l != "" {
+		if v, err := strconv.Atoi(l); err == nil && v > 0 {
+			limit = v
+		}
+	}
+
+	out, err := h.Service.Facets(r.Context(), parseListInput(r), facets, limit)
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(out)
+}
+
+// parseListInput reads the filter and paging parameters of GET /snippets.
+func parseListInput(r *http.Request) snippets.ListInput {
 	q := strings.TrimSpace(r.URL.Query().Get("q"))
+	mode := snippets.SearchMode(strings.TrimSpace(r.URL.Query().Get("mode")))
 	creator := strings.TrimSpace(r.URL.Query().Get("creator"))
 	language := strings.TrimSpace(r.URL.Query().Get("language"))
 	tag := strings.TrimSpace(r.URL.Query().Get("tag"))
@@ -132,8 +224,9 @@ func (h *SnippetsHandler) List(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	input := snippets.ListInput{
+	return snippets.ListInput{
 		Query:      q,
+		Mode:       mode,
 		Creator:    creator,
 		Language:   language,
 		Tag:        tag,
@@ -141,19 +234,11 @@ func (h *SnippetsHandler) List(w http.ResponseWriter, r *http.Request) {
 		Limit:      limit,
 		Offset:     offset,
 	}
-
-	list, err := h.Service.List(r.Context(), input)
-	if err != nil {
-		writeAppError(w, err)
-		return
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(list)
 }
 
 // Update Snippet
 // @Summary Update snippet
+// @Description Only the owner and admins can update a snippet; others get 404.
 // @Tags snippets
 // @Accept json
 // @Security SessionAuth
@@ -181,11 +266,14 @@ func (h *SnippetsHandler) Update(w http.ResponseWriter, r *http.Request) {
 	}
 
 	snippet, err := h.Service.Update(r.Context(), id, snippets.CreateSnippetRequest{
-		Name:       req.Name,
-		Content:    req.Content,
-		Language:   req.Language,
-		Tags:       req.Tags,
-		Visibility: req.Visibility,
+		Name:         req.Name,
+		Content:      req.Content,
+		Language:     req.Language,
+		Tags:         req.Tags,
+		Visibility:   req.Visibility,
+		Placeholders: req.Placeholders,
+
+		ValidateSyntax: req.ValidateSyntax,
 	})
 	if err != nil {
 		writeAppError(w, err)
@@ -196,6 +284,254 @@ func (h *SnippetsHandler) Update(w http.ResponseWriter, r *http.Request) {
 	_ = json.NewEncoder(w).Encode(snippet)
 }
 
+// maxRenderBytes bounds a render request body: every variable at its
+// longest length, with room for the JSON around it.
+const maxRenderBytes = 1 << 20
+
+type SnippetRenderResponse struct {
+	ID      string `json:"id"`
+	Content string `json:"content"`
+}
+
+// Render Snippet
+// @Summary Render snippet placeholders
+// @Description Substitutes the snippet placeholders with the given variables. Missing variables fall back to their defaults; invalid or unknown variables are reported per field. At most 50 variables of up to 10000 bytes each are accepted, and the rendered content may not exceed 1 MiB.
+// @Tags snippets
+// @Accept json
+// @Produce json
+// @Security SessionAuth
+// @Security ApiKeyAuth
+// @Param id path string true "snippet id"
+// @Param body body SnippetRenderDTO true "variables"
+// @Param X-CSRF-Token header string false "CSRF token (required for SessionAuth)"
+// @Success 200 {object} SnippetRenderResponse
+// @Failure 400 {object} ValidationErrorResponse
+// @Failure 401 {string} string
+// @Failure 404 {string} string
+// @Failure 413 {string} string
+// @Failure 500 {string} string
+// @Router /snippets/{id}/render [post]
+func (h *SnippetsHandler) Render(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimSpace(chi.URLParam(r, "id"))
+
+	var req SnippetRenderDTO
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRenderBytes)).Decode(&req); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, "request body too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+
+	content, err := h.Service.Render(r.Context(), id, req.Variables)
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(SnippetRenderResponse{ID: id, Content: content})
+}
+
+// Highlight Snippet
+// @Summary Render snippet with syntax highlighting
+// @Description Highlights the snippet content using its language. `html` returns a `<pre>` fragment with inline styles, `ansi` returns 256-colour terminal escapes and `svg` returns an image.
+// @Tags snippets
+// @Produce html
+// @Produce plain
+// @Produce image/svg+xml
+// @Security SessionAuth
+// @Security ApiKeyAuth
+// @Param id path string true "snippet id"
+// @Param format query string false "output format" Enums(html, ansi, svg) default(html)
+// @Param theme query string false "chroma style name" default(github)
+// @Param line_numbers query bool false "include line numbers"
+// @Param lines query string false "line range, e.g. 10-25"
+// @Param rev query int false "pinned revision"
+// @Success 200 {string} string
+// @Failure 400 {string} string
+// @Failure 401 {string} string
+// @Failure 404 {string} string
+// @Failure 500 {string} string
+// @Router /snippets/{id}/render [get]
+func (h *SnippetsHandler) Highlight(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimSpace(chi.URLParam(r, "id"))
+	query := r.URL.Query()
+
+	opts := highlight.Options{
+		Format: highlight.Format(query.Get("format")),
+		Theme:  query.Get("theme"),
+	}
+	if v := strings.TrimSpace(query.Get("line_numbers")); v != "" {
+		lineNumbers, err := strconv.ParseBool(v)
+		if err != nil {
+			http.Error(w, "invalid line_numbers", http.StatusBadRequest)
+			return
+		}
+		opts.LineNumbers = lineNumbers
+	}
+	sel, err := parseSelection(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	opts.Lines = sel.Lines
+
+	out, opts, err := h.Service.Highlight(r.Context(), id, sel.Revision, opts)
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", opts.Format.ContentType())
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'")
+	_, _ = w.Write(out)
+}
+
+// Raw Snippet
+// @Summary Get raw snippet content
+// @Description Returns the content as plain text. `lines` and `rev` work as on `GET /snippets/{id}`; the applied range and revision are echoed in the `X-Snippet-Lines` and `X-Snippet-Revision` headers.
+// @Tags snippets
+// @Produce plain
+// @Security SessionAuth
+// @Security ApiKeyAuth
+// @Param id path string true "snippet id"
+// @Param lines query string false "line range, e.g. 10-25"
+// @Param rev query int false "pinned revision"
+// @Success 200 {string} string
+// @Failure 400 {string} string
+// @Failure 404 {string} string
+// @Failure 500 {string} string
+// @Router /snippets/{id}/raw [get]
+func (h *SnippetsHandler) Raw(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimSpace(chi.URLParam(r, "id"))
+
+	sel, err := parseSelection(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	snippet, err := h.Service.View(r.Context(), id, sel)
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+	if h.Views != nil {
+		_ = h.Views.Record(r.Context(), snippet, analytics.KindRaw)
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("X-Snippet-Revision", strconv.Itoa(snippet.Revision))
+	if snippet.Excerpt != nil {
+		w.Header().Set("X-Snippet-Lines", strconv.Itoa(snippet.Excerpt.From)+"-"+strconv.Itoa(snippet.Excerpt.To))
+	}
+	_, _ = w.Write([]byte(snippet.Content))
+}
+
+// Related Snippets
+// @Summary List related snippets
+// @Description Snippets similar to this one, scored from shared tags, same language, name similarity and shared words, best match first. Only snippets the caller can read are included.
+// @Tags snippets
+// @Produce json
+// @Security SessionAuth
+// @Security ApiKeyAuth
+// @Param id path string true "snippet id"
+// @Param limit query int false "limit (default 10, max 50)"
+// @Success 200 {array} snippets.RelatedSnippet
+// @Failure 401 {string} string
+// @Failure 404 {string} string
+// @Failure 500 {string} string
+// @Router /snippets/{id}/related [get]
+func (h *SnippetsHandler) Related(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimSpace(chi.URLParam(r, "id"))
+
+	limit := 0
+	if l := strings.TrimSpace(r.URL.Query().Get("limit")); l != "" {
+		if v, err := strconv.Atoi(l); err == nil && v > 0 {
+			limit = v
+		}
+	}
+
+	related, err := h.Service.Related(r.Context(), id, limit)
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(related)
+}
+
+// SecretFindings Snippet
+// @Summary List secrets found in a snippet
+// @Description Findings from the last save of the snippet. Only the owner and admins can see them; the secret itself is never returned.
+// @Tags snippets
+// @Produce json
+// @Security SessionAuth
+// @Security ApiKeyAuth
+// @Param id path string true "snippet id"
+// @Success 200 {array} secrets.Finding
+// @Failure 401 {string} string
+// @Failure 404 {string} string
+// @Failure 500 {string} string
+// @Router /snippets/{id}/secrets [get]
+func (h *SnippetsHandler) SecretFindings(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimSpace(chi.URLParam(r, "id"))
+
+	findings, err := h.Service.SecretFindings(r.Context(), id)
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(findings)
+}
+
+// Format Snippet
+// @Summary Format snippet content
+// @Description Rewrites the content in the canonical layout for go, json, yaml and sql snippets; toml is validated on save but not formatted. Content that does not parse is rejected with its line and column. With dry_run the formatted snippet is returned without saving.
+// @Tags snippets
+// @Produce json
+// @Security SessionAuth
+// @Security ApiKeyAuth
+// @Param id path string true "snippet id"
+// @Param dry_run query bool false "return the result without saving"
+// @Param X-CSRF-Token header string false "CSRF token (required for SessionAuth)"
+// @Success 200 {object} snippets.Snippet
+// @Failure 400 {object} ValidationErrorResponse
+// @Failure 401 {string} string
+// @Failure 404 {string} string
+// @Failure 500 {string} string
+// @Router /snippets/{id}/format [post]
+func (h *SnippetsHandler) Format(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimSpace(chi.URLParam(r, "id"))
+
+	dryRun := false
+	if v := strings.TrimSpace(r.URL.Query().Get("dry_run")); v != "" {
+		parsed, err := strconv.ParseBool(v)
+		if err != nil {
+			http.Error(w, "invalid dry_run", http.StatusBadRequest)
+			return
+		}
+		dryRun = parsed
+	}
+
+	snippet, err := h.Service.Format(r.Context(), id, dryRun)
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(snippet)
+}
+
 // Delete Snippet
 // @Summary Delete snippet
 // @Tags snippets
@@ -219,3 +555,46 @@ func (h *SnippetsHandler) Delete(w http.ResponseWriter, r *http.Request) {
 
 	w.WriteHeader(http.StatusNoContent)
 }
+
+// Reindex Snippets
+// @Summary Rebuild the search index
+// @Description Feeds every snippet to the search index again and extracts its symbols anew. Only the instance serving the request is rebuilt. Admin only.
+// @Tags snippets
+// @Produce json
+// @Security SessionAuth
+// @Security ApiKeyAuth
+// @Param X-CSRF-Token header string false "CSRF token (required for SessionAuth)"
+// @Success 200 {object} SnippetsReindexResponse
+// @Failure 401 {string} string
+// @Failure 403 {string} string
+// @Failure 500 {string} string
+// @Router /snippets/reindex [post]
+func (h *SnippetsHandler) Reindex(w http.ResponseWriter, r *http.Request) {
+	n, err := h.Service.Reindex(r.Context())
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(SnippetsReindexResponse{Indexed: n})
+}
+
+func parseSelection(r *http.Request) (snippets.Selection, error) {
+	query := r.URL.Query()
+
+	lines, err := highlight.ParseRange(query.Get("lines"))
+	if err != nil {
+		return snippets.Selection{}, err
+	}
+	sel := snippets.Selection{Lines: lines}
+
+	if v := strings.TrimSpace(query.Get("rev")); v != "" {
+		rev, err := strconv.Atoi(v)
+		if err != nil || rev < 1 {
+			return snippets.Selection{}, errors.New("invalid rev")
+		}
+		sel.Revision = rev
+	}
+	return sel, nil
+}
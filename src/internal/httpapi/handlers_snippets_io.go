@@ -0,0 +1,204 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/PabloPavan/sniply_api/internal/apperrors"
+	"github.com/PabloPavan/sniply_api/internal/snippetio"
+	"github.com/PabloPavan/sniply_api/internal/snippets"
+)
+
+const (
+	maxImportBytes    = 5 << 20
+	maxImportSnippets = 500
+)
+
+type SnippetImportItem struct {
+	Index      int                 `json:"index"`
+	ID         string              `json:"id,omitempty"`
+	Name       string              `json:"name"`
+	Language   string              `json:"language"`
+	Tags       []string            `json:"tags"`
+	Visibility snippets.Visibility `json:"visibility"`
+	Lines      int                 `json:"lines"`
+	Error      string              `json:"error,omitempty"`
+}
+
+type SnippetImportResponse struct {
+	Format  string              `json:"format"`
+	DryRun  bool                `json:"dry_run"`
+	Total   int                 `json:"total"`
+	Created int                 `json:"created"`
+	Failed  int                 `json:"failed"`
+	Items   []SnippetImportItem `json:"items"`
+}
+
+// Import Snippets
+// @Summary Import snippets from an editor export
+// @Description Accepts a VS Code `.code-snippets` file, a JetBrains live-template XML file or a GitHub Gist export (single gist or array). With dry_run=true nothing is stored and each snippet is run through the same checks as a real import, so the preview shows the resolved language, normalized tags and any rejection.
+// @Tags snippets
+// @Accept json
+// @Accept xml
+// @Produce json
+// @Security SessionAuth
+// @Security ApiKeyAuth
+// @Param format query string true "source format" Enums(vscode, jetbrains, gist)
+// @Param dry_run query bool false "preview without creating snippets"
+// @Param visibility query string false "visibility applied to every imported snippet" Enums(public, private)
+// @Param body body string true "export file contents"
+// @Param X-CSRF-Token header string false "CSRF token (required for SessionAuth)"
+// @Success 200 {object} SnippetImportResponse "dry run preview"
+// @Success 201 {object} SnippetImportResponse
+// @Failure 400 {string} string
+// @Failure 401 {string} string
+// @Failure 413 {string} string
+// @Failure 500 {string} string
+// @Router /snippets/import [post]
+func (h *SnippetsHandler) Import(w http.ResponseWriter, r *http.Request) {
+	format, err := snippetio.ParseFormat(r.URL.Query().Get("format"))
+	if err != nil {
+		http.Error(w, "invalid format", http.StatusBadRequest)
+		return
+	}
+
+	dryRun := false
+	if v := strings.TrimSpace(r.URL.Query().Get("dry_run")); v != "" {
+		dryRun, err = strconv.ParseBool(v)
+		if err != nil {
+			http.Error(w, "invalid dry_run", http.StatusBadRequest)
+			return
+		}
+	}
+
+	visibility := snippets.Visibility(strings.TrimSpace(r.URL.Query().Get("visibility")))
+	if visibility != "" && visibility != snippets.VisibilityPublic && visibility != snippets.VisibilityPrivate {
+		http.Error(w, "invalid visibility", http.StatusBadRequest)
+		return
+	}
+
+	reqs, err := snippetio.Decode(format, http.MaxBytesReader(w, r.Body, maxImportBytes))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, "import file too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(reqs) > maxImportSnippets {
+		http.Error(w, "too many snippets in import file", http.StatusBadRequest)
+		return
+	}
+
+	resp := SnippetImportResponse{
+		Format: string(format),
+		DryRun: dryRun,
+		Total:  len(reqs),
+		Items:  make([]SnippetImportItem, 0, len(reqs)),
+	}
+	for i, req := range reqs {
+		if visibility != "" {
+			req.Visibility = visibility
+		}
+		item := SnippetImportItem{
+			Index:      i,
+			Name:       req.Name,
+			Language:   req.Language,
+			Tags:       req.Tags,
+			Visibility: req.Visibility,
+			Lines:      strings.Count(req.Content, "\n") + 1,
+		}
+		if item.Visibility == "" {
+			item.Visibility = snippets.VisibilityPrivate
+		}
+
+		dto := SnippetCreateDTO{
+			Name:         req.Name,
+			Content:      req.Content,
+			Language:     req.Language,
+			Tags:         req.Tags,
+			Visibility:   req.Visibility,
+			Placeholders: req.Placeholders,
+		}
+		if err := dto.Validate(); err != nil {
+			item.Error = err.Error()
+		} else if dryRun {
+			preview, err := h.Service.Preview(r.Context(), req)
+			if err != nil {
+				item.Error = appErrorText(err)
+			} else {
+				item.Language = preview.Language
+				item.Tags = preview.Tags
+				item.Visibility = preview.Visibility
+			}
+		} else {
+			created, err := h.Service.Create(r.Context(), req)
+			if err != nil {
+				item.Error = appErrorText(err)
+			} else {
+				item.ID = created.ID
+				item.Language = created.Language
+				item.Tags = created.Tags
+				item.Visibility = created.Visibility
+				resp.Created++
+			}
+		}
+		if item.Error != "" {
+			resp.Failed++
+		}
+		resp.Items = append(resp.Items, item)
+	}
+
+	status := http.StatusCreated
+	if dryRun {
+		status = http.StatusOK
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// Export Snippets
+// @Summary Export the current user's snippets
+// @Description Returns every snippet owned by the caller as a file that can be dropped into VS Code, a JetBrains IDE or re-created as GitHub Gists.
+// @Tags snippets
+// @Produce json
+// @Produce xml
+// @Security SessionAuth
+// @Security ApiKeyAuth
+// @Param format query string true "target format" Enums(vscode, jetbrains, gist)
+// @Success 200 {string} string
+// @Failure 400 {string} string
+// @Failure 401 {string} string
+// @Failure 500 {string} string
+// @Router /snippets/export [get]
+func (h *SnippetsHandler) Export(w http.ResponseWriter, r *http.Request) {
+	format, err := snippetio.ParseFormat(r.URL.Query().Get("format"))
+	if err != nil {
+		http.Error(w, "invalid format", http.StatusBadRequest)
+		return
+	}
+
+	list, err := h.Service.ListOwned(r.Context())
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", format.ContentType())
+	w.Header().Set("Content-Disposition", `attachment; filename="`+format.FileName()+`"`)
+	_ = snippetio.Encode(format, w, list)
+}
+
+func appErrorText(err error) string {
+	var appErr *apperrors.Error
+	if errors.As(err, &appErr) {
+		return errorMessage(appErr)
+	}
+	return "internal error"
+}
@@ -0,0 +1,129 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/PabloPavan/sniply_api/internal/snippets"
+)
+
+type TagsService interface {
+	Tags(ctx context.Context, prefix string, limit int) ([]snippets.TagCount, error)
+	RenameTag(ctx context.Context, from, to string) (int, error)
+	MergeTags(ctx context.Context, sources []string, target string) (int, error)
+}
+
+type TagsHandler struct {
+	Service TagsService
+}
+
+type TagUpdateResponse struct {
+	Updated int `json:"updated"`
+}
+
+// List Tags
+// @Summary List tags with usage counts
+// @Description Tags used on public snippets and on the caller's own snippets, most used first. Use prefix for autocomplete.
+// @Tags tags
+// @Produce json
+// @Security SessionAuth
+// @Security ApiKeyAuth
+// @Param prefix query string false "tag prefix"
+// @Param limit query int false "limit (default 50, max 500)"
+// @Success 200 {array} snippets.TagCount
+// @Failure 401 {string} string
+// @Failure 500 {string} string
+// @Router /tags [get]
+func (h *TagsHandler) List(w http.ResponseWriter, r *http.Request) {
+	prefix := strings.TrimSpace(r.URL.Query().Get("prefix"))
+
+	limit := 0
+	if l := strings.TrimSpace(r.URL.Query().Get("limit")); l != "" {
+		if v, err := strconv.Atoi(l); err == nil && v > 0 {
+			limit = v
+		}
+	}
+
+	tags, err := h.Service.Tags(r.Context(), prefix, limit)
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(tags)
+}
+
+// Rename Tag
+// @Summary Rename a tag on your snippets
+// @Description Renaming onto an existing tag merges the two.
+// @Tags tags
+// @Accept json
+// @Produce json
+// @Security SessionAuth
+// @Security ApiKeyAuth
+// @Param body body TagRenameDTO true "rename"
+// @Param X-CSRF-Token header string false "CSRF token (required for SessionAuth)"
+// @Success 200 {object} TagUpdateResponse
+// @Failure 400 {string} string
+// @Failure 401 {string} string
+// @Failure 500 {string} string
+// @Router /tags/rename [post]
+func (h *TagsHandler) Rename(w http.ResponseWriter, r *http.Request) {
+	var req TagRenameDTO
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	if err := req.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	updated, err := h.Service.RenameTag(r.Context(), req.From, req.To)
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(TagUpdateResponse{Updated: updated})
+}
+
+// Merge Tags
+// @Summary Merge tags on your snippets
+// @Tags tags
+// @Accept json
+// @Produce json
+// @Security SessionAuth
+// @Security ApiKeyAuth
+// @Param body body TagMergeDTO true "merge"
+// @Param X-CSRF-Token header string false "CSRF token (required for SessionAuth)"
+// @Success 200 {object} TagUpdateResponse
+// @Failure 400 {string} string
+// @Failure 401 {string} string
+// @Failure 500 {string} string
+// @Router /tags/merge [post]
+func (h *TagsHandler) Merge(w http.ResponseWriter, r *http.Request) {
+	var req TagMergeDTO
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	if err := req.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	updated, err := h.Service.MergeTags(r.Context(), req.Sources, req.Target)
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(TagUpdateResponse{Updated: updated})
+}
@@ -0,0 +1,240 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/PabloPavan/sniply_api/internal/twofactor"
+	"github.com/PabloPavan/sniply_api/internal/users"
+)
+
+type TwoFactorService interface {
+	Status(ctx context.Context) (twofactor.Status, error)
+	Enroll(ctx context.Context) (*twofactor.Enrollment, error)
+	Confirm(ctx context.Context, code string) ([]string, error)
+	Disable(ctx context.Context, code string) error
+	RegenerateRecoveryCodes(ctx context.Context, code string) ([]string, error)
+	Policies(ctx context.Context) ([]twofactor.Policy, error)
+	SetPolicy(ctx context.Context, p twofactor.Policy) error
+}
+
+type TwoFactorHandler struct {
+	Service TwoFactorService
+}
+
+type TOTPEnrollmentResponse struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"`
+	QRPNG      []byte `json:"qr_png" swaggertype:"string" format:"base64"`
+}
+
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+func enrollmentResponse(e *twofactor.Enrollment) TOTPEnrollmentResponse {
+	return TOTPEnrollmentResponse{Secret: e.Secret, OTPAuthURI: e.URI, QRPNG: e.QRCode}
+}
+
+func decodeTwoFactorCode(w http.ResponseWriter, r *http.Request) (string, bool) {
+	var req TwoFactorCodeDTO
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return "", false
+	}
+	if err := req.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return "", false
+	}
+	return req.Code, true
+}
+
+// Status TwoFactor
+// @Summary Get my two-factor authentication status
+// @Tags auth
+// @Produce json
+// @Security SessionAuth
+// @Success 200 {object} twofactor.Status
+// @Failure 401 {string} string
+// @Failure 500 {string} string
+// @Router /auth/2fa [get]
+func (h *TwoFactorHandler) Status(w http.ResponseWriter, r *http.Request) {
+	st, err := h.Service.Status(r.Context())
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(st)
+}
+
+// Enroll TwoFactor
+// @Summary Start TOTP enrollment
+// @Description Returns a new secret, its otpauth URI and a QR code PNG (base64) for authenticator apps. It is enabled by confirming a code.
+// @Tags auth
+// @Produce json
+// @Security SessionAuth
+// @Param X-CSRF-Token header string false "CSRF token (required for SessionAuth)"
+// @Success 200 {object} TOTPEnrollmentResponse
+// @Failure 401 {string} string
+// @Failure 409 {string} string
+// @Failure 500 {string} string
+// @Router /auth/2fa/totp [post]
+func (h *TwoFactorHandler) Enroll(w http.ResponseWriter, r *http.Request) {
+	e, err := h.Service.Enroll(r.Context())
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(enrollmentResponse(e))
+}
+
+// Confirm TwoFactor
+// @Summary Confirm TOTP enrollment
+// @Description Enables two-factor authentication and returns one-time recovery codes, shown only once.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security SessionAuth
+// @Param X-CSRF-Token header string false "CSRF token (required for SessionAuth)"
+// @Param body body TwoFactorCodeDTO true "code from the authenticator app"
+// @Success 200 {object} RecoveryCodesResponse
+// @Failure 400 {string} string
+// @Failure 401 {string} string
+// @Failure 409 {string} string
+// @Failure 500 {string} string
+// @Router /auth/2fa/totp/confirm [post]
+func (h *TwoFactorHandler) Confirm(w http.ResponseWriter, r *http.Request) {
+	code, ok := decodeTwoFactorCode(w, r)
+	if !ok {
+		return
+	}
+
+	codes, err := h.Service.Confirm(r.Context(), code)
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// Disable TwoFactor
+// @Summary Disable two-factor authentication
+// @Description Not allowed when the user's role requires it.
+// @Tags auth
+// @Accept json
+// @Security SessionAuth
+// @Param X-CSRF-Token header string false "CSRF token (required for SessionAuth)"
+// @Param body body TwoFactorCodeDTO true "TOTP or recovery code"
+// @Success 204
+// @Failure 400 {string} string
+// @Failure 401 {string} string
+// @Failure 403 {string} string
+// @Failure 500 {string} string
+// @Router /auth/2fa/totp [delete]
+func (h *TwoFactorHandler) Disable(w http.ResponseWriter, r *http.Request) {
+	code, ok := decodeTwoFactorCode(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.Service.Disable(r.Context(), code); err != nil {
+		writeAppError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RecoveryCodes TwoFactor
+// @Summary Regenerate recovery codes
+// @Description Replaces every recovery code; the new ones are shown only once.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security SessionAuth
+// @Param X-CSRF-Token header string false "CSRF token (required for SessionAuth)"
+// @Param body body TwoFactorCodeDTO true "TOTP or recovery code"
+// @Success 200 {object} RecoveryCodesResponse
+// @Failure 400 {string} string
+// @Failure 401 {string} string
+// @Failure 500 {string} string
+// @Router /auth/2fa/recovery-codes [post]
+func (h *TwoFactorHandler) RecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	code, ok := decodeTwoFactorCode(w, r)
+	if !ok {
+		return
+	}
+
+	codes, err := h.Service.RegenerateRecoveryCodes(r.Context(), code)
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// Policies TwoFactor
+// @Summary List two-factor policies (admin)
+// @Tags auth
+// @Produce json
+// @Security SessionAuth
+// @Success 200 {array} twofactor.Policy
+// @Failure 401 {string} string
+// @Failure 403 {string} string
+// @Failure 500 {string} string
+// @Router /auth/2fa/policies [get]
+func (h *TwoFactorHandler) Policies(w http.ResponseWriter, r *http.Request) {
+	list, err := h.Service.Policies(r.Context())
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(list)
+}
+
+// SetPolicy TwoFactor
+// @Summary Require two-factor authentication for a role (admin)
+// @Description Applies from the next login: users of the role without a second factor must enroll one to log in.
+// @Tags auth
+// @Accept json
+// @Security SessionAuth
+// @Param X-CSRF-Token header string false "CSRF token (required for SessionAuth)"
+// @Param role path string true "role (user or admin)"
+// @Param body body TwoFactorPolicyDTO true "policy"
+// @Success 204
+// @Failure 400 {string} string
+// @Failure 401 {string} string
+// @Failure 403 {string} string
+// @Failure 500 {string} string
+// @Router /auth/2fa/policies/{role} [put]
+func (h *TwoFactorHandler) SetPolicy(w http.ResponseWriter, r *http.Request) {
+	var req TwoFactorPolicyDTO
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	if err := req.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	role := users.UserRole(strings.TrimSpace(chi.URLParam(r, "role")))
+	if err := h.Service.SetPolicy(r.Context(), twofactor.Policy{Role: role, Required: *req.Required}); err != nil {
+		writeAppError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
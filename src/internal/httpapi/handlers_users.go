@@ -150,9 +150,10 @@ func (h *UsersHandler) Me(w http.ResponseWriter, r *http.Request) {
 	}
 
 	resp := users.UserResponse{
-		ID:        u.ID,
-		Email:     u.Email,
-		CreatedAt: u.CreatedAt,
+		ID:              u.ID,
+		Email:           u.Email,
+		CreatedAt:       u.CreatedAt,
+		EmailVerifiedAt: u.EmailVerifiedAt,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -83,11 +83,21 @@ func AuthMiddleware(authenticator Authenticator, opts AuthOptions) func(http.Han
 			}
 
 			ctx := identity.WithUser(r.Context(), sess.UserID, sess.Role)
+			ctx = identity.WithSession(ctx, sess.ID)
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
 }
 
+// ClientMiddleware records the caller's user agent and address for the
+// sessions a request creates or refreshes.
+func ClientMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := identity.WithClient(r.Context(), r.UserAgent(), clientIP(r))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
 func apiKeyFromRequest(r *http.Request) string {
 	if v := strings.TrimSpace(r.Header.Get("X-API-Key")); v != "" {
 		return v
@@ -1,12 +1,16 @@
 package httpapi
 
 import (
+	"encoding/json"
 	"errors"
 	"regexp"
 	"reflect"
 	"strings"
+	"time"
 
+	"github.com/PabloPavan/sniply_api/internal/languages"
 	"github.com/PabloPavan/sniply_api/internal/snippets"
+	"github.com/PabloPavan/sniply_api/internal/templates"
 	"github.com/go-playground/validator/v10"
 )
 
@@ -48,6 +52,18 @@ func init() {
 		lines := strings.Count(raw, "\n") + 1
 		return lines <= maxLinesFromParam(fl.Param())
 	})
+	validate.RegisterValidation("language", func(fl validator.FieldLevel) bool {
+		field := fl.Field()
+		if field.Kind() != reflect.String {
+			return false
+		}
+		name := strings.TrimSpace(field.String())
+		if name == "" || strings.EqualFold(name, "auto") {
+			return true
+		}
+		_, ok := languages.Lookup(name)
+		return ok
+	})
 }
 
 
@@ -93,9 +109,64 @@ func (r *UserUpdateDTO) Validate() error {
 	return nil
 }
 
+type ForgotPasswordDTO struct {
+	Email string `json:"email" validate:"required,notblank,trimmedemail"`
+}
+
+func (r *ForgotPasswordDTO) Validate() error {
+	if err := validate.Struct(r); err != nil {
+		return validationMessage(err, map[string]map[string]string{
+			"Email": {
+				"required":     "email is required",
+				"notblank":     "email is required",
+				"trimmedemail": "invalid email",
+			},
+		}, "invalid request")
+	}
+	return nil
+}
+
+type ResetPasswordDTO struct {
+	Token    string `json:"token" validate:"required,notblank"`
+	Password string `json:"password" validate:"required,notblank,max=72"`
+}
+
+func (r *ResetPasswordDTO) Validate() error {
+	if err := validate.Struct(r); err != nil {
+		return validationMessage(err, map[string]map[string]string{
+			"Token": {
+				"required": "token and password are required",
+				"notblank": "token and password are required",
+			},
+			"Password": {
+				"required": "token and password are required",
+				"notblank": "token and password are required",
+			},
+		}, "invalid request")
+	}
+	return nil
+}
+
+type VerifyEmailDTO struct {
+	Token string `json:"token" validate:"required,notblank"`
+}
+
+func (r *VerifyEmailDTO) Validate() error {
+	if err := validate.Struct(r); err != nil {
+		return validationMessage(err, map[string]map[string]string{
+			"Token": {
+				"required": "token is required",
+				"notblank": "token is required",
+			},
+		}, "invalid request")
+	}
+	return nil
+}
+
 type APIKeyCreateDTO struct {
-	Name  string `json:"name"`
-	Scope string `json:"scope" validate:"omitempty,oneof=read write read_write"`
+	Name      string     `json:"name"`
+	Scope     string     `json:"scope" validate:"omitempty,oneof=read write read_write"`
+	ExpiresAt *time.Time `json:"expires_at"`
 }
 
 func (r *APIKeyCreateDTO) Validate() error {
@@ -109,12 +180,90 @@ func (r *APIKeyCreateDTO) Validate() error {
 	return nil
 }
 
+type APIKeyRotateDTO struct {
+	// GracePeriodSeconds is how long the old token keeps working; the
+	// server default applies when it is missing.
+	GracePeriodSeconds *int64 `json:"grace_period_seconds" validate:"omitempty,min=0,max=2592000"`
+}
+
+func (r *APIKeyRotateDTO) Validate() error {
+	if err := validate.Struct(r); err != nil {
+		return validationMessage(err, map[string]map[string]string{
+			"GracePeriodSeconds": {
+				"min": "invalid grace_period_seconds",
+				"max": "grace_period_seconds is too long",
+			},
+		}, "invalid request")
+	}
+	return nil
+}
+
+type TwoFactorCodeDTO struct {
+	Code string `json:"code" validate:"required,notblank"`
+}
+
+func (r *TwoFactorCodeDTO) Validate() error {
+	if err := validate.Struct(r); err != nil {
+		return validationMessage(err, map[string]map[string]string{
+			"Code": {
+				"required": "code is required",
+				"notblank": "code is required",
+			},
+		}, "invalid request")
+	}
+	return nil
+}
+
+type TwoFactorPolicyDTO struct {
+	Required *bool `json:"required" validate:"required"`
+}
+
+func (r *TwoFactorPolicyDTO) Validate() error {
+	if err := validate.Struct(r); err != nil {
+		return validationMessage(err, map[string]map[string]string{
+			"Required": {
+				"required": "required is required",
+			},
+		}, "invalid request")
+	}
+	return nil
+}
+
+// WebAuthnFinishDTO carries the result of navigator.credentials.create()
+// or get(), serialized with toJSON(), for the ceremony it answers.
+type WebAuthnFinishDTO struct {
+	Ceremony   string          `json:"ceremony" validate:"required,notblank"`
+	Name       string          `json:"name" validate:"max=64"`
+	Credential json.RawMessage `json:"credential" validate:"required" swaggertype:"object"`
+}
+
+func (r *WebAuthnFinishDTO) Validate() error {
+	if err := validate.Struct(r); err != nil {
+		return validationMessage(err, map[string]map[string]string{
+			"Ceremony": {
+				"required": "ceremony is required",
+				"notblank": "ceremony is required",
+			},
+			"Name": {
+				"max": "name must be at most 64 characters",
+			},
+			"Credential": {
+				"required": "credential is required",
+			},
+		}, "invalid request")
+	}
+	return nil
+}
+
 type SnippetCreateDTO struct {
-	Name       string              `json:"name" validate:"required,notblank,max=200"`
-	Content    string              `json:"content" validate:"required,notblank,max=250000,maxlines=5000"`
-	Language   string              `json:"language" validate:"omitempty,notblank,max=32"`
-	Tags       []string            `json:"tags" validate:"max=20,dive,max=32"`
-	Visibility snippets.Visibility `json:"visibility" validate:"omitempty,oneof=public private"`
+	Name         string                  `json:"name" validate:"required,notblank,max=200"`
+	Content      string                  `json:"content" validate:"required,notblank,max=250000,maxlines=5000"`
+	Language     string                  `json:"language" validate:"omitempty,notblank,max=32,language"`
+	Tags         []string                `json:"tags" validate:"max=20,dive,max=32"`
+	Visibility   snippets.Visibility     `json:"visibility" validate:"omitempty,oneof=public private"`
+	Placeholders []templates.Placeholder `json:"placeholders" validate:"max=50"`
+	// ValidateSyntax rejects go, json, yaml, toml and sql content that does not parse.
+	ValidateSyntax bool `json:"validate_syntax"`
 }
 
 func (r *SnippetCreateDTO) Validate() error {
@@ -134,15 +283,170 @@ func (r *SnippetCreateDTO) Validate() error {
 			"Language": {
 				"notblank": "invalid language",
 				"max":      "invalid language",
+				"language": "unsupported language",
 			},
 			"Tags": {
 				"max": "too many tags",
 			},
+			"Placeholders": {
+				"max": "too many placeholders",
+			},
+		}, "invalid request")
+	}
+	return nil
+}
+
+type TagRenameDTO struct {
+	From string `json:"from" validate:"required,notblank,max=32"`
+	To   string `json:"to" validate:"required,notblank,max=32"`
+}
+
+func (r *TagRenameDTO) Validate() error {
+	if err := validate.Struct(r); err != nil {
+		return validationMessage(err, map[string]map[string]string{
+			"From": {
+				"*":   "from and to are required",
+				"max": "tag is too long",
+			},
+			"To": {
+				"*":   "from and to are required",
+				"max": "tag is too long",
+			},
+		}, "invalid request")
+	}
+	return nil
+}
+
+type TagMergeDTO struct {
+	Sources []string `json:"sources" validate:"required,min=1,max=20,dive,notblank,max=32"`
+	Target  string   `json:"target" validate:"required,notblank,max=32"`
+}
+
+func (r *TagMergeDTO) Validate() error {
+	if err := validate.Struct(r); err != nil {
+		return validationMessage(err, map[string]map[string]string{
+			"Sources": {
+				"required": "sources are required",
+				"min":      "sources are required",
+				"max":      "too many sources",
+			},
+			"Target": {
+				"*":   "target is required",
+				"max": "tag is too long",
+			},
+		}, "invalid request")
+	}
+	return nil
+}
+
+type CommentCreateDTO struct {
+	ParentID  string `json:"parent_id" validate:"omitempty,max=64"`
+	Body      string `json:"body" validate:"required,notblank,max=10000"`
+	LineStart *int   `json:"line_start" validate:"omitempty,min=1"`
+	LineEnd   *int   `json:"line_end" validate:"omitempty,min=1"`
+	Revision  *int   `json:"revision" validate:"omitempty,min=1"`
+}
+
+func (r *CommentCreateDTO) Validate() error {
+	if err := validate.Struct(r); err != nil {
+		return validationMessage(err, map[string]map[string]string{
+			"ParentID": {
+				"max": "invalid parent_id",
+			},
+			"Body": {
+				"required": "body is required",
+				"notblank": "body is required",
+				"max":      "body is too long",
+			},
+			"LineStart": {
+				"min": "invalid line_start",
+			},
+			"LineEnd": {
+				"min": "invalid line_end",
+			},
+			"Revision": {
+				"min": "invalid revision",
+			},
+		}, "invalid request")
+	}
+	return nil
+}
+
+type CommentUpdateDTO struct {
+	Body string `json:"body" validate:"required,notblank,max=10000"`
+}
+
+func (r *CommentUpdateDTO) Validate() error {
+	if err := validate.Struct(r); err != nil {
+		return validationMessage(err, map[string]map[string]string{
+			"Body": {
+				"required": "body is required",
+				"notblank": "body is required",
+				"max":      "body is too long",
+			},
 		}, "invalid request")
 	}
 	return nil
 }
 
+type SavedSearchQueryDTO struct {
+	Q          string `json:"q" validate:"max=1000"`
+	Mode       string `json:"mode" validate:"omitempty,oneof=fts phrase regex"`
+	Creator    string `json:"creator" validate:"max=64"`
+	Language   string `json:"language" validate:"max=64"`
+	Tag        string `json:"tag" validate:"max=64"`
+	Visibility string `json:"visibility" validate:"omitempty,oneof=public private"`
+}
+
+type SavedSearchDTO struct {
+	Name       string              `json:"name" validate:"required,notblank,max=100"`
+	Query      SavedSearchQueryDTO `json:"query"`
+	Notify     string              `json:"notify" validate:"omitempty,oneof=none inbox webhook" enums:"none,inbox,webhook"`
+	WebhookURL string              `json:"webhook_url" validate:"omitempty,url,max=2048"`
+}
+
+func (r *SavedSearchDTO) Validate() error {
+	if err := validate.Struct(r); err != nil {
+		return validationMessage(err, map[string]map[string]string{
+			"Name": {
+				"required": "name is required",
+				"notblank": "name is required",
+				"max":      "name is too long",
+			},
+			"Q": {
+				"max": "q is too long",
+			},
+			"Mode": {
+				"oneof": "mode must be fts, phrase or regex",
+			},
+			"Creator": {
+				"max": "invalid creator",
+			},
+			"Language": {
+				"max": "invalid language",
+			},
+			"Tag": {
+				"max": "invalid tag",
+			},
+			"Visibility": {
+				"oneof": "visibility must be public or private",
+			},
+			"Notify": {
+				"oneof": "notify must be none, inbox or webhook",
+			},
+			"WebhookURL": {
+				"url": "invalid webhook_url",
+				"max": "webhook_url is too long",
+			},
+		}, "invalid request")
+	}
+	return nil
+}
+
+type SnippetRenderDTO struct {
+	Variables map[string]string `json:"variables"`
+}
+
 func validationMessage(err error, messages map[string]map[string]string, fallback string) error {
 	var valErrs validator.ValidationErrors
 	if !errors.As(err, &valErrs) {
@@ -13,9 +13,19 @@ import (
 type App struct {
 	Health        *HealthHandler
 	Snippets      *SnippetsHandler
+	Languages     *LanguagesHandler
+	Tags          *TagsHandler
+	Comments      *CommentsHandler
+	Analytics     *AnalyticsHandler
 	Users         *UsersHandler
 	Auth          *AuthHandler
 	APIKeys       *APIKeysHandler
+	TwoFactor     *TwoFactorHandler
+	Passkeys      *PasskeysHandler
+	Accounts      *AccountsHandler
+	Sessions      *SessionsHandler
+	SavedSearches *SavedSearchesHandler
+	Notifications *NotificationsHandler
 	Authenticator Authenticator
 }
 
@@ -23,6 +33,7 @@ func NewRouter(app *App) http.Handler {
 	r := chi.NewRouter()
 	r.Use(middleware.RequestID)
 	r.Use(middleware.RealIP)
+	r.Use(ClientMiddleware)
 	r.Use(middleware.Recoverer)
 	r.Use(middleware.Logger)
 	r.Use(telemetry.ChiTraceMiddleware("sniply-api"))
@@ -42,6 +53,16 @@ func NewRouter(app *App) http.Handler {
 			r.Post("/login", app.Auth.Login)
 			r.Post("/logout", app.Auth.Logout)
 			r.Get("/csrf", app.Auth.CSRFToken)
+			r.Get("/oidc/{provider}/start", app.Auth.OIDCStart)
+			r.Get("/oidc/{provider}/callback", app.Auth.OIDCCallback)
+			r.Post("/2fa", app.Auth.TwoFactor)
+			r.Post("/2fa/enroll", app.Auth.TwoFactorEnroll)
+			r.Post("/webauthn/login/begin", app.Auth.PasskeyLoginBegin)
+			r.Post("/webauthn/login/finish", app.Auth.PasskeyLogin)
+			r.Post("/webauthn/2fa/begin", app.Auth.PasskeyTwoFactorBegin)
+			r.Post("/webauthn/2fa/finish", app.Auth.PasskeyTwoFactor)
+			r.Post("/password/forgot", app.Accounts.ForgotPassword)
+			r.Post("/password/reset", app.Accounts.ResetPassword)
 
 			r.Group(func(r chi.Router) {
 				r.Use(AuthMiddleware(app.Authenticator, AuthOptions{
@@ -53,6 +74,24 @@ func NewRouter(app *App) http.Handler {
 				r.Post("/api-keys", app.APIKeys.Create)
 				r.Get("/api-keys", app.APIKeys.List)
 				r.Delete("/api-keys/{id}", app.APIKeys.Revoke)
+				r.Post("/api-keys/{id}/rotate", app.APIKeys.Rotate)
+
+				r.Get("/sessions", app.Sessions.List)
+				r.Delete("/sessions", app.Sessions.RevokeAll)
+				r.Delete("/sessions/{id}", app.Sessions.Revoke)
+
+				r.Get("/2fa", app.TwoFactor.Status)
+				r.Post("/2fa/totp", app.TwoFactor.Enroll)
+				r.Post("/2fa/totp/confirm", app.TwoFactor.Confirm)
+				r.Delete("/2fa/totp", app.TwoFactor.Disable)
+				r.Post("/2fa/recovery-codes", app.TwoFactor.RecoveryCodes)
+				r.Get("/2fa/policies", app.TwoFactor.Policies)
+				r.Put("/2fa/policies/{role}", app.TwoFactor.SetPolicy)
+
+				r.Post("/webauthn/register/begin", app.Passkeys.BeginRegistration)
+				r.Post("/webauthn/register/finish", app.Passkeys.FinishRegistration)
+				r.Get("/webauthn/credentials", app.Passkeys.List)
+				r.Delete("/webauthn/credentials/{id}", app.Passkeys.Delete)
 			})
 		})
 
@@ -67,15 +106,82 @@ func NewRouter(app *App) http.Handler {
 				}))
 				r.Post("/", app.Snippets.Create)
 				r.Get("/", app.Snippets.List)
+				r.Post("/import", app.Snippets.Import)
+				r.Post("/reindex", app.Snippets.Reindex)
+				r.Get("/export", app.Snippets.Export)
+				r.Get("/trending", app.Analytics.Trending)
+				r.Get("/facets", app.Snippets.Facets)
 				r.Get("/{id}", app.Snippets.GetByID)
 				r.Put("/{id}", app.Snippets.Update)
+				r.Get("/{id}/raw", app.Snippets.Raw)
+				r.Get("/{id}/render", app.Snippets.Highlight)
+				r.Post("/{id}/render", app.Snippets.Render)
+				r.Get("/{id}/related", app.Snippets.Related)
+				r.Get("/{id}/analytics", app.Analytics.Stats)
+				r.Get("/{id}/secrets", app.Snippets.SecretFindings)
+				r.Post("/{id}/format", app.Snippets.Format)
+				r.Get("/{id}/comments", app.Comments.List)
+				r.Post("/{id}/comments", app.Comments.Create)
+				r.Get("/{id}/comments/{commentID}", app.Comments.Get)
+				r.Put("/{id}/comments/{commentID}", app.Comments.Update)
+				r.Delete("/{id}/comments/{commentID}", app.Comments.Delete)
 				r.Delete("/{id}", app.Snippets.Delete)
 			})
 		})
 
+		r.Route("/languages", func(r chi.Router) {
+			r.Use(AuthMiddleware(app.Authenticator, AuthOptions{
+				AllowSession: true,
+				AllowAPIKey:  true,
+				Cookie:       app.Auth.Cookie,
+				CSRFCookie:   app.Auth.CSRFCookie,
+			}))
+			r.Get("/", app.Languages.List)
+		})
+
+		r.Route("/tags", func(r chi.Router) {
+			r.Use(AuthMiddleware(app.Authenticator, AuthOptions{
+				AllowSession: true,
+				AllowAPIKey:  true,
+				Cookie:       app.Auth.Cookie,
+				CSRFCookie:   app.Auth.CSRFCookie,
+			}))
+			r.Get("/", app.Tags.List)
+			r.Post("/rename", app.Tags.Rename)
+			r.Post("/merge", app.Tags.Merge)
+		})
+
+		r.Route("/saved-searches", func(r chi.Router) {
+			r.Use(AuthMiddleware(app.Authenticator, AuthOptions{
+				AllowSession: true,
+				AllowAPIKey:  true,
+				Cookie:       app.Auth.Cookie,
+				CSRFCookie:   app.Auth.CSRFCookie,
+			}))
+			r.Post("/", app.SavedSearches.Create)
+			r.Get("/", app.SavedSearches.List)
+			r.Get("/{id}", app.SavedSearches.Get)
+			r.Put("/{id}", app.SavedSearches.Update)
+			r.Delete("/{id}", app.SavedSearches.Delete)
+			r.Get("/{id}/run", app.SavedSearches.Run)
+		})
+
+		r.Route("/notifications", func(r chi.Router) {
+			r.Use(AuthMiddleware(app.Authenticator, AuthOptions{
+				AllowSession: true,
+				AllowAPIKey:  true,
+				Cookie:       app.Auth.Cookie,
+				CSRFCookie:   app.Auth.CSRFCookie,
+			}))
+			r.Get("/", app.Notifications.List)
+			r.Post("/read-all", app.Notifications.ReadAll)
+			r.Post("/{id}/read", app.Notifications.Read)
+		})
+
 		r.Route("/users", func(r chi.Router) {
 			// Public
 			r.Post("/", app.Users.Create)
+			r.Post("/verify-email", app.Accounts.VerifyEmail)
 
 			// Protected
 			r.Group(func(r chi.Router) {
@@ -90,6 +196,7 @@ func NewRouter(app *App) http.Handler {
 				r.Get("/me", app.Users.Me)
 				r.Put("/me", app.Users.UpdateMe)
 				r.Delete("/me", app.Users.DeleteMe)
+				r.Post("/me/verify-email", app.Accounts.RequestVerification)
 
 				// Admin endpoints
 				r.Get("/", app.Users.List)
@@ -5,8 +5,11 @@ import "context"
 type ctxKey string
 
 const (
-	ctxUserIDKey ctxKey = "user_id"
-	ctxRoleKey   ctxKey = "role"
+	ctxUserIDKey    ctxKey = "user_id"
+	ctxRoleKey      ctxKey = "role"
+	ctxSessionKey   ctxKey = "session_id"
+	ctxUserAgentKey ctxKey = "user_agent"
+	ctxClientIPKey  ctxKey = "client_ip"
 )
 
 func WithUser(ctx context.Context, userID string, role string) context.Context {
@@ -31,3 +34,25 @@ func IsAdmin(ctx context.Context) bool {
 	role, _ := Role(ctx)
 	return role == "admin"
 }
+
+// WithSession records the session that authenticated the request.
+func WithSession(ctx context.Context, sessionID string) context.Context {
+	return context.WithValue(ctx, ctxSessionKey, sessionID)
+}
+
+func SessionID(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(ctxSessionKey).(string)
+	return id, ok
+}
+
+// WithClient records the user agent and address of the caller.
+func WithClient(ctx context.Context, userAgent, ip string) context.Context {
+	ctx = context.WithValue(ctx, ctxUserAgentKey, userAgent)
+	return context.WithValue(ctx, ctxClientIPKey, ip)
+}
+
+func Client(ctx context.Context) (userAgent, ip string) {
+	userAgent, _ = ctx.Value(ctxUserAgentKey).(string)
+	ip, _ = ctx.Value(ctxClientIPKey).(string)
+	return userAgent, ip
+}
@@ -0,0 +1,326 @@
+package languages
+
+import (
+	"encoding/json"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// DefaultMinConfidence is the score below which a guess is discarded.
+const DefaultMinConfidence = 0.3
+
+type Input struct {
+	Content  string
+	Filename string
+}
+
+type Detection struct {
+	Language   string  `json:"language"`
+	Confidence float64 `json:"confidence"`
+	Method     string  `json:"method"`
+}
+
+type Detector interface {
+	Detect(in Input) (Detection, bool)
+}
+
+type DetectorFunc func(in Input) (Detection, bool)
+
+func (f DetectorFunc) Detect(in Input) (Detection, bool) { return f(in) }
+
+// Chain runs every detector and keeps the most confident answer.
+type Chain struct {
+	Detectors     []Detector
+	MinConfidence float64
+}
+
+func (c *Chain) Detect(in Input) (Detection, bool) {
+	minConfidence := c.MinConfidence
+	if minConfidence <= 0 {
+		minConfidence = DefaultMinConfidence
+	}
+
+	var best Detection
+	found := false
+	for _, d := range c.Detectors {
+		det, ok := d.Detect(in)
+		if !ok || det.Confidence < minConfidence {
+			continue
+		}
+		if !found || det.Confidence > best.Confidence {
+			best = det
+			found = true
+		}
+	}
+	return best, found
+}
+
+// NewDetector returns the built-in chain: shebang, modeline, file name and
+// content heuristics, resolved against the registry.
+func NewDetector(r *Registry) *Chain {
+	if r == nil {
+		r = Default
+	}
+	return &Chain{
+		Detectors: []Detector{
+			ShebangDetector{Registry: r},
+			ModelineDetector{Registry: r},
+			FilenameDetector{Registry: r},
+			HeuristicDetector{},
+		},
+	}
+}
+
+type ShebangDetector struct {
+	Registry *Registry
+}
+
+var interpreterVersionRe = regexp.MustCompile(`[0-9.]+$`)
+
+func (d ShebangDetector) Detect(in Input) (Detection, bool) {
+	first, _, _ := strings.Cut(in.Content, "\n")
+	first = strings.TrimSpace(first)
+	if !strings.HasPrefix(first, "#!") {
+		return Detection{}, false
+	}
+	fields := strings.Fields(strings.TrimPrefix(first, "#!"))
+	if len(fields) == 0 {
+		return Detection{}, false
+	}
+	interp := path.Base(fields[0])
+	if interp == "env" {
+		interp = ""
+		for _, f := range fields[1:] {
+			if !strings.HasPrefix(f, "-") {
+				interp = path.Base(f)
+				break
+			}
+		}
+	}
+	if l, ok := d.Registry.ByInterpreter(interp); ok {
+		return Detection{Language: l.ID, Confidence: 0.95, Method: "shebang"}, true
+	}
+	if l, ok := d.Registry.ByInterpreter(interpreterVersionRe.ReplaceAllString(interp, "")); ok {
+		return Detection{Language: l.ID, Confidence: 0.95, Method: "shebang"}, true
+	}
+	return Detection{}, false
+}
+
+type ModelineDetector struct {
+	Registry *Registry
+}
+
+var (
+	vimModelineRe   = regexp.MustCompile(`(?:vi|vim|ex):.*?\b(?:ft|filetype|syntax)=([A-Za-z0-9_+#-]+)`)
+	emacsModelineRe = regexp.MustCompile(`-\*-\s*(?:.*?\bmode:\s*)?([A-Za-z0-9_+#-]+)\s*;?.*?-\*-`)
+)
+
+const modelineScanLines = 5
+
+func (d ModelineDetector) Detect(in Input) (Detection, bool) {
+	lines := strings.Split(in.Content, "\n")
+	candidates := lines
+	if len(lines) > 2*modelineScanLines {
+		candidates = append(append([]string{}, lines[:modelineScanLines]...), lines[len(lines)-modelineScanLines:]...)
+	}
+	for _, line := range candidates {
+		for _, re := range []*regexp.Regexp{vimModelineRe, emacsModelineRe} {
+			m := re.FindStringSubmatch(line)
+			if m == nil {
+				continue
+			}
+			if l, ok := d.Registry.Lookup(m[1]); ok {
+				return Detection{Language: l.ID, Confidence: 0.9, Method: "modeline"}, true
+			}
+		}
+	}
+	return Detection{}, false
+}
+
+type FilenameDetector struct {
+	Registry *Registry
+}
+
+func (d FilenameDetector) Detect(in Input) (Detection, bool) {
+	if strings.TrimSpace(in.Filename) == "" || strings.ContainsAny(in.Filename, " \t") {
+		return Detection{}, false
+	}
+	l, ok := d.Registry.ByFilename(in.Filename)
+	if !ok {
+		return Detection{}, false
+	}
+	return Detection{Language: l.ID, Confidence: 0.85, Method: "filename"}, true
+}
+
+type rule struct {
+	re     *regexp.Regexp
+	weight float64
+}
+
+func rules(weight float64, patterns ...string) []rule {
+	out := make([]rule, 0, len(patterns))
+	for _, p := range patterns {
+		out = append(out, rule{re: regexp.MustCompile(p), weight: weight})
+	}
+	return out
+}
+
+func join(groups ...[]rule) []rule {
+	var out []rule
+	for _, g := range groups {
+		out = append(out, g...)
+	}
+	return out
+}
+
+var heuristics = map[string][]rule{
+	"go": join(
+		rules(3, `(?m)^package \w+\s*$`),
+		rules(2, `(?m)^func (\(\w+ \*?\w+\) )?\w+\(`, `(?m)^import \(\s*$`, `\bfmt\.\w+\(`, `\berr != nil\b`),
+		rules(1, `:=`, `\bchan\b`, `\bgo func\(`),
+	),
+	"python": join(
+		rules(3, `(?m)^\s*def \w+\(.*\)( -> .+)?:\s*$`, `(?m)^if __name__ == .__main__.:`),
+		rules(2, `(?m)^from [\w.]+ import `, `(?m)^\s*class \w+(\(.*\))?:\s*$`, `\bself\.\w+`, `\bprint\(`),
+		rules(1, `(?m)^import \w+\s*$`, `\bNone\b`, `\bTrue\b|\bFalse\b`, `(?m)^\s*elif\b`),
+	),
+	"javascript": join(
+		rules(2, `\bconsole\.log\(`, `\brequire\(['"]`, `\bmodule\.exports\b`, `\bdocument\.\w+`),
+		rules(1, `(?m)^\s*(const|let|var) \w+ = `, `=>`, `\bfunction\s*\w*\s*\(`, `===`, `(?m)^import .* from ['"]`),
+	),
+	"typescript": join(
+		rules(3, `(?m)^\s*(export )?interface \w+ \{`, `(?m)^\s*(export )?type \w+ = `),
+		rules(2, `:\s*(string|number|boolean|void|any|unknown)\b`, `\bas const\b`),
+		rules(1, `(?m)^import .* from ['"]`, `=>`),
+	),
+	"java": join(
+		rules(3, `\bpublic static void main\(String`, `\bSystem\.out\.print`),
+		rules(2, `(?m)^\s*(public|private|protected) (static )?(final )?(class|interface|enum) \w+`, `(?m)^import java\.`, `(?m)^package [\w.]+;`),
+		rules(1, `@Override`, `\bnew \w+\(`),
+	),
+	"csharp": join(
+		rules(3, `(?m)^using System(\.\w+)*;`, `\bConsole\.Write(Line)?\(`),
+		rules(2, `(?m)^\s*namespace [\w.]+`, `\bpublic (async )?(Task|void|string|int)\b`),
+		rules(1, `\bvar \w+ = new\b`, `\{ get; (private )?set; \}`),
+	),
+	"c": join(
+		rules(3, `(?m)^#include <(stdio|stdlib|string|unistd)\.h>`),
+		rules(2, `\bint main\(`, `\bprintf\(`, `\bmalloc\(`),
+		rules(1, `(?m)^#(define|ifdef|ifndef|endif)\b`, `->`),
+	),
+	"cpp": join(
+		rules(3, `(?m)^#include <(iostream|vector|string|memory|map)>`, `\bstd::\w+`),
+		rules(2, `\btemplate\s*<`, `\bnamespace \w+ \{`, `\bcout\s*<<`),
+		rules(1, `(?m)^#include\b`, `::`),
+	),
+	"rust": join(
+		rules(3, `(?m)^\s*fn main\(\)`, `\bprintln!\(`, `(?m)^use std::`),
+		rules(2, `(?m)^\s*(pub )?fn \w+(<.*>)?\(`, `\blet mut\b`, `(?m)^\s*impl\b`, `\b(Option|Result|Vec)<`),
+		rules(1, `&mut\b`, `::new\(`, `\bmatch \w+ \{`),
+	),
+	"ruby": join(
+		rules(3, `(?m)^\s*require ['"]\w+['"]\s*$`, `(?m)^\s*def \w+[?!]?(\(.*\))?\s*$`),
+		rules(2, `(?m)^\s*end\s*$`, `\bputs\b`, `\battr_(reader|accessor|writer)\b`, `\bdo \|\w+\|`),
+		rules(1, `(?m)^\s*class \w+( < \w+)?\s*$`, `@\w+`),
+	),
+	"php": rules(5, `<\?php`),
+	"bash": join(
+		rules(2, `(?m)^\s*(if|while) \[\[? `, `(?m)^\s*(export|alias|source) `, `\$\{?\w+\}?`, `(?m)^\s*fi\s*$`, `(?m)^\s*done\s*$`),
+		rules(1, `(?m)^\s*(echo|cd|ls|grep|curl|sudo|apt(-get)?|mkdir|rm|chmod) `, `\|\s*\w+`, `&&`),
+	),
+	"powershell": join(
+		rules(3, `\b(Get|Set|New|Remove|Write)-[A-Z]\w+`),
+		rules(1, `\$\w+ = `, `-eq\b|-ne\b`),
+	),
+	"sql": join(
+		rules(3, `(?im)^\s*(select\s.+\sfrom|insert\s+into|update\s+\w+\s+set|delete\s+from|create\s+(table|index|view)|alter\s+table)\b`),
+		rules(1, `(?i)\b(where|join|group by|order by|values)\b`),
+	),
+	"html": join(
+		rules(4, `(?i)<!doctype html`, `(?i)<html[\s>]`),
+		rules(2, `(?i)<(div|span|body|head|script|a href|p|ul|li)[\s>]`),
+	),
+	"xml": rules(4, `^\s*<\?xml `),
+	"css": join(
+		rules(2, `(?m)^\s*[.#]?[\w-]+(\s*[,>+~]?\s*[.#]?[\w-]+)*\s*\{\s*$`, `(?m)^\s*[\w-]+\s*:\s*[^;]+;\s*$`),
+		rules(1, `@media\b`, `!important`),
+	),
+	"dockerfile": join(
+		rules(4, `(?m)^FROM \S+`),
+		rules(2, `(?m)^(RUN|COPY|WORKDIR|ENTRYPOINT|CMD|EXPOSE|ENV|ARG) `),
+	),
+	"makefile": join(
+		rules(2, `(?m)^[\w.-]+:( [\w.-]+)*\s*$`, `(?m)^\t\S`),
+		rules(1, `\$\(\w+\)`, `(?m)^\.PHONY:`),
+	),
+	"markdown": join(
+		rules(2, "(?m)^#{1,6} \\S", "(?m)^```"),
+		rules(1, `\[[^\]]+\]\([^)]+\)`, `(?m)^\s*[-*] \S`),
+	),
+	"yaml": join(
+		rules(2, `(?m)^[\w-]+:\s*$`, `(?m)^---\s*$`),
+		rules(1, `(?m)^\s*[\w-]+: \S`, `(?m)^\s*- [\w-]+`),
+	),
+	"toml": join(
+		rules(2, `(?m)^\[[\w.-]+\]\s*$`),
+		rules(1, `(?m)^[\w-]+ = ("|\d|true|false|\[)`),
+	),
+}
+
+var heuristicOrder = func() []string {
+	out := make([]string, 0, len(heuristics))
+	for lang := range heuristics {
+		out = append(out, lang)
+	}
+	sort.Strings(out)
+	return out
+}()
+
+// HeuristicDetector scores content against per-language patterns. JSON is
+// recognised by parsing rather than by pattern.
+type HeuristicDetector struct{}
+
+func (HeuristicDetector) Detect(in Input) (Detection, bool) {
+	content := strings.TrimSpace(in.Content)
+	if content == "" {
+		return Detection{}, false
+	}
+	if (content[0] == '{' || content[0] == '[') && json.Valid([]byte(content)) {
+		return Detection{Language: "json", Confidence: 0.9, Method: "heuristic"}, true
+	}
+
+	var best, second float64
+	bestLang := ""
+	for _, lang := range heuristicOrder {
+		score := 0.0
+		for _, r := range heuristics[lang] {
+			if r.re.MatchString(content) {
+				score += r.weight
+			}
+		}
+		switch {
+		case score > best:
+			second = best
+			best = score
+			bestLang = lang
+		case score > second:
+			second = score
+		}
+	}
+	if bestLang == "" || best < 2 {
+		return Detection{}, false
+	}
+
+	// Confidence grows with the absolute score and with the margin over the
+	// runner-up, capped below the structural detectors.
+	strength := min(best/6, 1)
+	margin := (best - second) / best
+	confidence := 0.8 * strength * (0.5 + 0.5*margin)
+	return Detection{Language: bestLang, Confidence: round2(confidence), Method: "heuristic"}, true
+}
+
+func round2(f float64) float64 {
+	return float64(int(f*100+0.5)) / 100
+}
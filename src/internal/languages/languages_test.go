@@ -0,0 +1,132 @@
+package languages
+
+import (
+	"maps"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	cases := map[string]string{
+		"golang":  "go",
+		"Go":      "go",
+		"js":      "javascript",
+		" PY ":    "python",
+		"yml":     "yaml",
+		"c++":     "cpp",
+		"unknown": "unknown",
+	}
+	for in, want := range cases {
+		if got := Normalize(in); got != want {
+			t.Fatalf("Normalize(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestByFilename(t *testing.T) {
+	cases := map[string]string{
+		"main.go":          "go",
+		"src/app.TSX":      "typescript",
+		"Dockerfile":       "dockerfile",
+		"deploy/Makefile":  "makefile",
+		"config.yml":       "yaml",
+		"C:\\tmp\\init.sh": "bash",
+	}
+	for in, want := range cases {
+		l, ok := Default.ByFilename(in)
+		if !ok || l.ID != want {
+			t.Fatalf("ByFilename(%q) = %q, want %q", in, l.ID, want)
+		}
+	}
+	if _, ok := Default.ByFilename("README"); ok {
+		t.Fatalf("expected no match without extension")
+	}
+}
+
+func TestDetect(t *testing.T) {
+	detector := NewDetector(nil)
+	cases := []struct {
+		name   string
+		in     Input
+		lang   string
+		method string
+	}{
+		{"shebang env", Input{Content: "#!/usr/bin/env python3\nprint('hi')\n"}, "python", "shebang"},
+		{"shebang path", Input{Content: "#!/bin/bash\necho hi\n"}, "bash", "shebang"},
+		{"shebang versioned", Input{Content: "#!/usr/bin/python3.12\n"}, "python", "shebang"},
+		{"vim modeline", Input{Content: "x = 1\n# vim: set ft=ruby :\n"}, "ruby", "modeline"},
+		{"emacs modeline", Input{Content: "// -*- mode: javascript -*-\nfoo()\n"}, "javascript", "modeline"},
+		{"filename", Input{Content: "whatever", Filename: "query.sql"}, "sql", "filename"},
+		{"go", Input{Content: "package main\n\nimport \"fmt\"\n\nfunc main() {\n\tfmt.Println(\"hi\")\n}\n"}, "go", "heuristic"},
+		{"python", Input{Content: "import os\n\ndef main():\n    print(os.getcwd())\n\nif __name__ == '__main__':\n    main()\n"}, "python", "heuristic"},
+		{"json", Input{Content: `{"a": [1, 2, 3]}`}, "json", "heuristic"},
+		{"php", Input{Content: "<?php\necho 'hi';\n"}, "php", "heuristic"},
+		{"sql", Input{Content: "SELECT id, name FROM users WHERE id = 1;"}, "sql", "heuristic"},
+		{"dockerfile", Input{Content: "FROM golang:1.24\nRUN go build ./...\n"}, "dockerfile", "heuristic"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			det, ok := detector.Detect(tc.in)
+			if !ok {
+				t.Fatalf("expected detection")
+			}
+			if det.Language != tc.lang || det.Method != tc.method {
+				t.Fatalf("got %+v, want %s via %s", det, tc.lang, tc.method)
+			}
+			if det.Confidence < DefaultMinConfidence || det.Confidence > 1 {
+				t.Fatalf("confidence out of range: %v", det.Confidence)
+			}
+		})
+	}
+}
+
+func TestDetectNothing(t *testing.T) {
+	if det, ok := NewDetector(nil).Detect(Input{Content: "hello there", Filename: "notes"}); ok {
+		t.Fatalf("expected no detection, got %+v", det)
+	}
+}
+
+// The migration that normalized stored languages carries its own copy of
+// the registry; a language added here has to be added there too.
+func TestNormalizeMigrationMatchesRegistry(t *testing.T) {
+	raw, err := os.ReadFile("../../migrations/000004_normalize_snippet_languages.up.sql")
+	if err != nil {
+		t.Fatalf("read migration: %v", err)
+	}
+	sql := string(raw)
+
+	ids := map[string]bool{}
+	aliases := map[string]string{}
+	for _, l := range builtin {
+		ids[l.ID] = true
+		for _, a := range l.Aliases {
+			aliases[strings.ToLower(a)] = l.ID
+		}
+	}
+
+	lists := regexp.MustCompile(`NOT IN \(([^)]*)\)`).FindAllStringSubmatch(sql, -1)
+	if len(lists) == 0 {
+		t.Fatal("no id list found in the migration")
+	}
+	quoted := regexp.MustCompile(`'([^']*)'`)
+	for _, list := range lists {
+		got := map[string]bool{}
+		for _, m := range quoted.FindAllStringSubmatch(list[1], -1) {
+			got[m[1]] = true
+		}
+		if !maps.Equal(got, ids) {
+			t.Fatalf("migration ids %v, registry %v", slices.Sorted(maps.Keys(got)), slices.Sorted(maps.Keys(ids)))
+		}
+	}
+
+	got := map[string]string{}
+	for _, m := range regexp.MustCompile(`\('([^']*)', '([^']*)'\)`).FindAllStringSubmatch(sql, -1) {
+		got[m[1]] = m[2]
+	}
+	if !maps.Equal(got, aliases) {
+		t.Fatalf("migration aliases %v, registry %v", got, aliases)
+	}
+}
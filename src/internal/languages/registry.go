@@ -0,0 +1,168 @@
+package languages
+
+import (
+	"path"
+	"sort"
+	"strings"
+)
+
+// Fallback is the language used when nothing better is known.
+const Fallback = "txt"
+
+type Language struct {
+	ID           string   `json:"id"`
+	Name         string   `json:"name"`
+	Aliases      []string `json:"aliases"`
+	Extensions   []string `json:"extensions"`
+	Filenames    []string `json:"filenames,omitempty"`
+	Interpreters []string `json:"-"`
+	MIME         string   `json:"mime"`
+}
+
+var builtin = []Language{
+	{ID: "bash", Name: "Bash", Aliases: []string{"sh", "shell", "shellscript", "zsh"}, Extensions: []string{".sh", ".bash", ".zsh"}, Filenames: []string{".bashrc", ".zshrc", ".profile"}, Interpreters: []string{"sh", "bash", "zsh", "dash", "ksh"}, MIME: "application/x-sh"},
+	{ID: "c", Name: "C", Extensions: []string{".c", ".h"}, MIME: "text/x-csrc"},
+	{ID: "clojure", Name: "Clojure", Aliases: []string{"clj"}, Extensions: []string{".clj", ".cljs", ".cljc", ".edn"}, MIME: "text/x-clojure"},
+	{ID: "cpp", Name: "C++", Aliases: []string{"c++", "cxx"}, Extensions: []string{".cpp", ".cc", ".cxx", ".hpp", ".hh", ".hxx"}, MIME: "text/x-c++src"},
+	{ID: "csharp", Name: "C#", Aliases: []string{"c#", "cs"}, Extensions: []string{".cs"}, MIME: "text/x-csharp"},
+	{ID: "css", Name: "CSS", Extensions: []string{".css"}, MIME: "text/css"},
+	{ID: "dart", Name: "Dart", Extensions: []string{".dart"}, MIME: "application/dart"},
+	{ID: "diff", Name: "Diff", Aliases: []string{"patch", "udiff"}, Extensions: []string{".diff", ".patch"}, MIME: "text/x-diff"},
+	{ID: "dockerfile", Name: "Dockerfile", Aliases: []string{"docker"}, Extensions: []string{".dockerfile"}, Filenames: []string{"Dockerfile", "Containerfile"}, MIME: "text/x-dockerfile"},
+	{ID: "elixir", Name: "Elixir", Aliases: []string{"ex", "exs"}, Extensions: []string{".ex", ".exs"}, Interpreters: []string{"elixir"}, MIME: "text/x-elixir"},
+	{ID: "erlang", Name: "Erlang", Aliases: []string{"erl"}, Extensions: []string{".erl", ".hrl"}, Interpreters: []string{"escript"}, MIME: "text/x-erlang"},
+	{ID: "go", Name: "Go", Aliases: []string{"golang"}, Extensions: []string{".go"}, MIME: "text/x-go"},
+	{ID: "graphql", Name: "GraphQL", Aliases: []string{"gql"}, Extensions: []string{".graphql", ".gql"}, MIME: "application/graphql"},
+	{ID: "haskell", Name: "Haskell", Aliases: []string{"hs"}, Extensions: []string{".hs", ".lhs"}, Interpreters: []string{"runhaskell"}, MIME: "text/x-haskell"},
+	{ID: "hcl", Name: "HCL", Aliases: []string{"terraform", "tf"}, Extensions: []string{".hcl", ".tf", ".tfvars"}, MIME: "text/x-hcl"},
+	{ID: "html", Name: "HTML", Aliases: []string{"htm", "xhtml"}, Extensions: []string{".html", ".htm", ".xhtml"}, MIME: "text/html"},
+	{ID: "ini", Name: "INI", Aliases: []string{"cfg", "dosini"}, Extensions: []string{".ini", ".cfg", ".conf"}, Filenames: []string{".gitconfig", ".editorconfig"}, MIME: "text/x-ini"},
+	{ID: "java", Name: "Java", Extensions: []string{".java"}, MIME: "text/x-java"},
+	{ID: "javascript", Name: "JavaScript", Aliases: []string{"js", "node", "nodejs", "jsx", "javascriptreact"}, Extensions: []string{".js", ".mjs", ".cjs", ".jsx"}, Interpreters: []string{"node", "nodejs", "deno", "bun"}, MIME: "text/javascript"},
+	{ID: "json", Name: "JSON", Aliases: []string{"jsonc"}, Extensions: []string{".json", ".jsonc"}, MIME: "application/json"},
+	{ID: "kotlin", Name: "Kotlin", Aliases: []string{"kt"}, Extensions: []string{".kt", ".kts"}, MIME: "text/x-kotlin"},
+	{ID: "lua", Name: "Lua", Extensions: []string{".lua"}, Interpreters: []string{"lua", "luajit"}, MIME: "text/x-lua"},
+	{ID: "makefile", Name: "Makefile", Aliases: []string{"make", "mf"}, Extensions: []string{".mk", ".mak"}, Filenames: []string{"Makefile", "GNUmakefile", "makefile"}, Interpreters: []string{"make"}, MIME: "text/x-makefile"},
+	{ID: "markdown", Name: "Markdown", Aliases: []string{"md"}, Extensions: []string{".md", ".markdown"}, MIME: "text/markdown"},
+	{ID: "perl", Name: "Perl", Aliases: []string{"pl"}, Extensions: []string{".pl", ".pm"}, Interpreters: []string{"perl"}, MIME: "text/x-perl"},
+	{ID: "php", Name: "PHP", Extensions: []string{".php", ".phtml"}, Interpreters: []string{"php"}, MIME: "application/x-httpd-php"},
+	{ID: "powershell", Name: "PowerShell", Aliases: []string{"ps1", "pwsh", "posh"}, Extensions: []string{".ps1", ".psm1", ".psd1"}, Interpreters: []string{"pwsh", "powershell"}, MIME: "text/x-powershell"},
+	{ID: "protobuf", Name: "Protocol Buffers", Aliases: []string{"proto"}, Extensions: []string{".proto"}, MIME: "text/x-protobuf"},
+	{ID: "python", Name: "Python", Aliases: []string{"py", "python3", "py3"}, Extensions: []string{".py", ".pyw", ".pyi"}, Interpreters: []string{"python", "python2", "python3"}, MIME: "text/x-python"},
+	{ID: "r", Name: "R", Aliases: []string{"rlang"}, Extensions: []string{".r", ".R"}, Interpreters: []string{"Rscript"}, MIME: "text/x-r"},
+	{ID: "ruby", Name: "Ruby", Aliases: []string{"rb"}, Extensions: []string{".rb", ".rake", ".gemspec"}, Filenames: []string{"Gemfile", "Rakefile"}, Interpreters: []string{"ruby"}, MIME: "text/x-ruby"},
+	{ID: "rust", Name: "Rust", Aliases: []string{"rs"}, Extensions: []string{".rs"}, MIME: "text/x-rust"},
+	{ID: "scala", Name: "Scala", Extensions: []string{".scala", ".sc"}, Interpreters: []string{"scala"}, MIME: "text/x-scala"},
+	{ID: "sql", Name: "SQL", Aliases: []string{"postgresql", "postgres", "mysql", "plpgsql", "sqlite"}, Extensions: []string{".sql"}, MIME: "application/sql"},
+	{ID: "swift", Name: "Swift", Extensions: []string{".swift"}, Interpreters: []string{"swift"}, MIME: "text/x-swift"},
+	{ID: "toml", Name: "TOML", Extensions: []string{".toml"}, Filenames: []string{"Cargo.lock", "Pipfile"}, MIME: "application/toml"},
+	{ID: "txt", Name: "Plain text", Aliases: []string{"text", "plaintext", "plain"}, Extensions: []string{".txt", ".text"}, MIME: "text/plain"},
+	{ID: "typescript", Name: "TypeScript", Aliases: []string{"ts", "tsx", "typescriptreact"}, Extensions: []string{".ts", ".mts", ".cts", ".tsx"}, Interpreters: []string{"ts-node", "tsx"}, MIME: "application/typescript"},
+	{ID: "xml", Name: "XML", Aliases: []string{"svg", "xsd", "xsl"}, Extensions: []string{".xml", ".xsd", ".xsl", ".svg", ".plist"}, MIME: "application/xml"},
+	{ID: "yaml", Name: "YAML", Aliases: []string{"yml"}, Extensions: []string{".yaml", ".yml"}, MIME: "application/yaml"},
+}
+
+type Registry struct {
+	languages   []Language
+	byName      map[string]int
+	byExtension map[string]int
+	byFilename  map[string]int
+	byInterp    map[string]int
+}
+
+func NewRegistry(langs []Language) *Registry {
+	r := &Registry{
+		languages:   make([]Language, len(langs)),
+		byName:      make(map[string]int),
+		byExtension: make(map[string]int),
+		byFilename:  make(map[string]int),
+		byInterp:    make(map[string]int),
+	}
+	copy(r.languages, langs)
+	sort.Slice(r.languages, func(i, j int) bool { return r.languages[i].ID < r.languages[j].ID })
+
+	for i, l := range r.languages {
+		r.byName[strings.ToLower(l.ID)] = i
+	}
+	for i, l := range r.languages {
+		for _, alias := range l.Aliases {
+			if _, ok := r.byName[strings.ToLower(alias)]; !ok {
+				r.byName[strings.ToLower(alias)] = i
+			}
+		}
+		for _, ext := range l.Extensions {
+			if _, ok := r.byExtension[strings.ToLower(ext)]; !ok {
+				r.byExtension[strings.ToLower(ext)] = i
+			}
+		}
+		for _, name := range l.Filenames {
+			r.byFilename[name] = i
+		}
+		for _, interp := range l.Interpreters {
+			r.byInterp[interp] = i
+		}
+	}
+	return r
+}
+
+// Default is the built-in registry.
+var Default = NewRegistry(builtin)
+
+// List returns every language ordered by id.
+func (r *Registry) List() []Language {
+	out := make([]Language, len(r.languages))
+	copy(out, r.languages)
+	return out
+}
+
+// Lookup resolves an id or alias, case-insensitively.
+func (r *Registry) Lookup(name string) (Language, bool) {
+	i, ok := r.byName[strings.ToLower(strings.TrimSpace(name))]
+	if !ok {
+		return Language{}, false
+	}
+	return r.languages[i], true
+}
+
+// Normalize maps an alias to its canonical id. Unknown names are returned
+// lowercased and trimmed.
+func (r *Registry) Normalize(name string) string {
+	if l, ok := r.Lookup(name); ok {
+		return l.ID
+	}
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// ByFilename resolves a language from a file name, trying well-known names
+// before the extension.
+func (r *Registry) ByFilename(filename string) (Language, bool) {
+	base := path.Base(strings.ReplaceAll(strings.TrimSpace(filename), "\\", "/"))
+	if base == "" || base == "." || base == "/" {
+		return Language{}, false
+	}
+	if i, ok := r.byFilename[base]; ok {
+		return r.languages[i], true
+	}
+	ext := strings.ToLower(path.Ext(base))
+	if ext == "" {
+		return Language{}, false
+	}
+	i, ok := r.byExtension[ext]
+	if !ok {
+		return Language{}, false
+	}
+	return r.languages[i], true
+}
+
+// ByInterpreter resolves the language of a shebang interpreter.
+func (r *Registry) ByInterpreter(name string) (Language, bool) {
+	i, ok := r.byInterp[name]
+	if !ok {
+		return Language{}, false
+	}
+	return r.languages[i], true
+}
+
+func Lookup(name string) (Language, bool) { return Default.Lookup(name) }
+
+func Normalize(name string) string { return Default.Normalize(name) }
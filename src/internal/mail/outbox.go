@@ -0,0 +1,115 @@
+package mail
+
+import (
+	"context"
+	"time"
+
+	"github.com/PabloPavan/sniply_api/internal/db"
+)
+
+// Outgoing is a message claimed from the outbox for delivery.
+type Outgoing struct {
+	ID       int64
+	Message  Message
+	Attempts int
+}
+
+type OutboxRepository struct {
+	base *db.Base
+}
+
+func NewOutboxRepository(base *db.Base) *OutboxRepository {
+	return &OutboxRepository{base: base}
+}
+
+const (
+	sqlOutboxInsert = `INSERT INTO mail_outbox (recipient, subject, body)
+		VALUES ($1, $2, $3)`
+
+	// sqlOutboxClaim leases due messages by pushing next_attempt_at past the
+	// lease, so a worker that dies mid-send leaves them to be retried.
+	sqlOutboxClaim = `UPDATE mail_outbox
+		SET attempts = attempts + 1, next_attempt_at = $2
+		WHERE id IN (
+			SELECT id
+			FROM mail_outbox
+			WHERE sent_at IS NULL AND failed_at IS NULL AND next_attempt_at <= $1
+			ORDER BY next_attempt_at
+			LIMIT $3
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, recipient, subject, body, attempts`
+
+	// A sent or failed message is done with its body, which can hold a
+	// single-use token; only the envelope is kept.
+	sqlOutboxSent = `UPDATE mail_outbox
+		SET sent_at = now(), last_error = '', body = ''
+		WHERE id = $1`
+
+	sqlOutboxRetry = `UPDATE mail_outbox
+		SET next_attempt_at = $2, last_error = $3
+		WHERE id = $1`
+
+	sqlOutboxFail = `UPDATE mail_outbox
+		SET failed_at = now(), last_error = $2, body = ''
+		WHERE id = $1`
+)
+
+// Enqueue adds m to the outbox through q, which is usually the transaction
+// making the change the mail is about.
+func Enqueue(ctx context.Context, q db.Queryer, m Message) error {
+	_, err := q.Exec(ctx, sqlOutboxInsert, m.To, m.Subject, m.Body)
+	return err
+}
+
+func (r *OutboxRepository) Enqueue(ctx context.Context, m Message) error {
+	ctx, cancel := r.base.WithTimeout(ctx)
+	defer cancel()
+
+	return Enqueue(ctx, r.base.Q(), m)
+}
+
+func (r *OutboxRepository) Claim(ctx context.Context, now, leaseUntil time.Time, limit int) ([]Outgoing, error) {
+	ctx, cancel := r.base.WithTimeout(ctx)
+	defer cancel()
+
+	rows, err := r.base.Q().Query(ctx, sqlOutboxClaim, now, leaseUntil, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]Outgoing, 0)
+	for rows.Next() {
+		var o Outgoing
+		if err := rows.Scan(&o.ID, &o.Message.To, &o.Message.Subject, &o.Message.Body, &o.Attempts); err != nil {
+			return nil, err
+		}
+		out = append(out, o)
+	}
+	return out, rows.Err()
+}
+
+func (r *OutboxRepository) MarkSent(ctx context.Context, id int64) error {
+	ctx, cancel := r.base.WithTimeout(ctx)
+	defer cancel()
+
+	_, err := r.base.Q().Exec(ctx, sqlOutboxSent, id)
+	return err
+}
+
+func (r *OutboxRepository) Retry(ctx context.Context, id int64, at time.Time, lastError string) error {
+	ctx, cancel := r.base.WithTimeout(ctx)
+	defer cancel()
+
+	_, err := r.base.Q().Exec(ctx, sqlOutboxRetry, id, at, lastError)
+	return err
+}
+
+func (r *OutboxRepository) Fail(ctx context.Context, id int64, lastError string) error {
+	ctx, cancel := r.base.WithTimeout(ctx)
+	defer cancel()
+
+	_, err := r.base.Q().Exec(ctx, sqlOutboxFail, id, lastError)
+	return err
+}
@@ -0,0 +1,142 @@
+package mail
+
+import (
+	"context"
+	"time"
+
+	"github.com/PabloPavan/sniply_api/internal/telemetry"
+)
+
+const (
+	DefaultMaxAttempts = 8
+	maxBackoff         = time.Hour
+	defaultSendTimeout = 30 * time.Second
+)
+
+type OutboxStore interface {
+	Claim(ctx context.Context, now, leaseUntil time.Time, limit int) ([]Outgoing, error)
+	MarkSent(ctx context.Context, id int64) error
+	Retry(ctx context.Context, id int64, at time.Time, lastError string) error
+	Fail(ctx context.Context, id int64, lastError string) error
+}
+
+// Worker delivers the outbox. A message is sent at least once: one whose
+// send succeeded but was not marked sent goes out again after the lease.
+type Worker struct {
+	Store     OutboxStore
+	Mailer    Mailer
+	Interval  time.Duration
+	BatchSize int
+	// Lease is how long a claimed message waits before another worker may
+	// retry it. Messages of a batch are sent one after another, so the
+	// lease is never shorter than BatchSize sends at SendTimeout.
+	Lease       time.Duration
+	SendTimeout time.Duration
+	// MaxAttempts failed sends, spaced by an exponential backoff from
+	// Backoff, give a message up.
+	MaxAttempts int
+	Backoff     time.Duration
+	Now         func() time.Time
+}
+
+func (w *Worker) Run(ctx context.Context) {
+	interval := w.Interval
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := w.Check(ctx); err != nil {
+				telemetry.LogError(ctx, "mail outbox check failed",
+					telemetry.LogString("event", "mail.outbox.check"),
+					telemetry.LogString("error", err.Error()),
+				)
+			}
+		}
+	}
+}
+
+// Check delivers one batch of due messages.
+func (w *Worker) Check(ctx context.Context) error {
+	batch := w.BatchSize
+	if batch <= 0 {
+		batch = 20
+	}
+	lease := w.Lease
+	if lease <= 0 {
+		lease = 5 * time.Minute
+	}
+	// A minute on top covers marking the messages sent.
+	lease = max(lease, time.Duration(batch)*w.sendTimeout()+time.Minute)
+
+	now := w.now()
+	due, err := w.Store.Claim(ctx, now, now.Add(lease), batch)
+	if err != nil {
+		return err
+	}
+	for _, o := range due {
+		if err := w.deliver(ctx, o, now); err != nil {
+			telemetry.LogError(ctx, "mail outbox delivery failed",
+				telemetry.LogString("event", "mail.outbox.deliver"),
+				telemetry.LogInt64("mail.id", o.ID),
+				telemetry.LogString("error", err.Error()),
+			)
+		}
+	}
+	return nil
+}
+
+func (w *Worker) deliver(ctx context.Context, o Outgoing, now time.Time) error {
+	sendCtx, cancel := context.WithTimeout(ctx, w.sendTimeout())
+	sendErr := w.Mailer.Send(sendCtx, o.Message)
+	cancel()
+	if sendErr == nil {
+		return w.Store.MarkSent(ctx, o.ID)
+	}
+
+	maxAttempts := w.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = DefaultMaxAttempts
+	}
+	if o.Attempts >= maxAttempts {
+		telemetry.LogError(ctx, "mail outbox giving up",
+			telemetry.LogString("event", "mail.outbox.failed"),
+			telemetry.LogInt64("mail.id", o.ID),
+			telemetry.LogInt("mail.attempts", o.Attempts),
+			telemetry.LogString("error", sendErr.Error()),
+		)
+		return w.Store.Fail(ctx, o.ID, sendErr.Error())
+	}
+	return w.Store.Retry(ctx, o.ID, now.Add(w.backoff(o.Attempts)), sendErr.Error())
+}
+
+func (w *Worker) backoff(attempts int) time.Duration {
+	d := w.Backoff
+	if d <= 0 {
+		d = 30 * time.Second
+	}
+	for i := 1; i < attempts && d < maxBackoff; i++ {
+		d *= 2
+	}
+	return min(d, maxBackoff)
+}
+
+func (w *Worker) sendTimeout() time.Duration {
+	if w.SendTimeout > 0 {
+		return w.SendTimeout
+	}
+	return defaultSendTimeout
+}
+
+func (w *Worker) now() time.Time {
+	if w.Now != nil {
+		return w.Now().UTC()
+	}
+	return time.Now().UTC()
+}
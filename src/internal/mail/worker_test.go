@@ -0,0 +1,181 @@
+package mail
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+type outboxEntry struct {
+	msg       Message
+	attempts  int
+	nextAt    time.Time
+	sent      bool
+	failed    bool
+	lastError string
+}
+
+type outboxStub struct {
+	entries []*outboxEntry
+}
+
+func (s *outboxStub) Claim(ctx context.Context, now, leaseUntil time.Time, limit int) ([]Outgoing, error) {
+	out := make([]Outgoing, 0)
+	for i, e := range s.entries {
+		if len(out) == limit {
+			break
+		}
+		if e.sent || e.failed || e.nextAt.After(now) {
+			continue
+		}
+		e.attempts++
+		e.nextAt = leaseUntil
+		out = append(out, Outgoing{ID: int64(i), Message: e.msg, Attempts: e.attempts})
+	}
+	return out, nil
+}
+
+func (s *outboxStub) MarkSent(ctx context.Context, id int64) error {
+	s.entries[id].sent = true
+	return nil
+}
+
+func (s *outboxStub) Retry(ctx context.Context, id int64, at time.Time, lastError string) error {
+	s.entries[id].nextAt, s.entries[id].lastError = at, lastError
+	return nil
+}
+
+func (s *outboxStub) Fail(ctx context.Context, id int64, lastError string) error {
+	s.entries[id].failed, s.entries[id].lastError = true, lastError
+	return nil
+}
+
+func TestWorkerDeliversAndRetries(t *testing.T) {
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	store := &outboxStub{entries: []*outboxEntry{
+		{msg: Message{To: "a@local", Subject: "one"}},
+		{msg: Message{To: "b@local", Subject: "two"}},
+	}}
+	mailer := &MemoryMailer{Err: errors.New("relay down")}
+	w := &Worker{Store: store, Mailer: mailer, MaxAttempts: 3, Backoff: time.Minute, Now: func() time.Time { return now }}
+
+	if err := w.Check(context.Background()); err != nil {
+		t.Fatalf("check: %v", err)
+	}
+	e := store.entries[0]
+	if e.sent || e.lastError != "relay down" || !e.nextAt.Equal(now.Add(time.Minute)) {
+		t.Fatalf("expected a retry in a minute: %+v", e)
+	}
+
+	// Not due yet.
+	mailer.Err = nil
+	if err := w.Check(context.Background()); err != nil {
+		t.Fatalf("check: %v", err)
+	}
+	if len(mailer.Sent()) != 0 {
+		t.Fatal("expected nothing sent before the backoff")
+	}
+
+	now = now.Add(time.Minute)
+	if err := w.Check(context.Background()); err != nil {
+		t.Fatalf("check: %v", err)
+	}
+	if sent := mailer.Sent(); len(sent) != 2 || !store.entries[0].sent || !store.entries[1].sent {
+		t.Fatalf("expected both delivered: %+v", sent)
+	}
+}
+
+func TestWorkerGivesUp(t *testing.T) {
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	store := &outboxStub{entries: []*outboxEntry{{msg: Message{To: "a@local"}}}}
+	w := &Worker{Store: store, Mailer: &MemoryMailer{Err: errors.New("rejected")}, MaxAttempts: 3, Backoff: time.Minute, Now: func() time.Time { return now }}
+
+	var waits []time.Duration
+	for range 3 {
+		if err := w.Check(context.Background()); err != nil {
+			t.Fatalf("check: %v", err)
+		}
+		waits = append(waits, store.entries[0].nextAt.Sub(now))
+		now = store.entries[0].nextAt
+	}
+	if !store.entries[0].failed || store.entries[0].attempts != 3 {
+		t.Fatalf("expected the message given up: %+v", store.entries[0])
+	}
+	if waits[0] != time.Minute || waits[1] != 2*time.Minute {
+		t.Fatalf("unexpected backoff: %v", waits)
+	}
+	if d := w.backoff(30); d != maxBackoff {
+		t.Fatalf("expected the backoff capped, got %s", d)
+	}
+}
+
+func TestWorkerLeaseCoversCrashedSends(t *testing.T) {
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	store := &outboxStub{entries: []*outboxEntry{{msg: Message{To: "a@local"}}}}
+
+	// A worker that claimed the message and died leaves it leased.
+	if _, err := store.Claim(context.Background(), now, now.Add(5*time.Minute), 10); err != nil {
+		t.Fatalf("claim: %v", err)
+	}
+	mailer := &MemoryMailer{}
+	w := &Worker{Store: store, Mailer: mailer, Lease: 5 * time.Minute, Now: func() time.Time { return now }}
+	if err := w.Check(context.Background()); err != nil || len(mailer.Sent()) != 0 {
+		t.Fatalf("expected the lease to hold: %v", err)
+	}
+	now = now.Add(5 * time.Minute)
+	if err := w.Check(context.Background()); err != nil || len(mailer.Sent()) != 1 {
+		t.Fatalf("expected the message retried after the lease: %v", err)
+	}
+}
+
+func TestLogMailer(t *testing.T) {
+	var buf bytes.Buffer
+	m := &LogMailer{W: &buf}
+	if err := m.Send(context.Background(), Message{To: "a@local", Subject: "hi\r\nBcc: x@local", Body: "hello"}); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	if !strings.Contains(buf.String(), "Subject: hi  Bcc: x@local\n") || !strings.Contains(buf.String(), "hello") {
+		t.Fatalf("unexpected output: %q", buf.String())
+	}
+	if strings.Contains(string(format("no-reply@local", Message{To: "a@local\nBcc: x@local"})), "\nBcc:") {
+		t.Fatal("expected headers kept on one line")
+	}
+}
+
+func TestWorkerLeaseCoversBatch(t *testing.T) {
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	store := &outboxStub{}
+	w := &Worker{Store: store, Mailer: &MemoryMailer{}, Now: func() time.Time { return now }}
+
+	var lease time.Duration
+	w.Store = leaseRecorder{store, &lease}
+	if err := w.Check(context.Background()); err != nil {
+		t.Fatalf("check: %v", err)
+	}
+	// 20 sends of 30s each, one after another.
+	if lease < 10*time.Minute {
+		t.Fatalf("lease %v is shorter than a batch of sends", lease)
+	}
+
+	w.BatchSize, w.SendTimeout, w.Lease = 2, time.Second, time.Hour
+	if err := w.Check(context.Background()); err != nil {
+		t.Fatalf("check: %v", err)
+	}
+	if lease != time.Hour {
+		t.Fatalf("expected the configured lease, got %v", lease)
+	}
+}
+
+// leaseRecorder notes the lease of the last claim.
+type leaseRecorder struct {
+	*outboxStub
+	lease *time.Duration
+}
+
+func (r leaseRecorder) Claim(ctx context.Context, now, leaseUntil time.Time, limit int) ([]Outgoing, error) {
+	*r.lease = leaseUntil.Sub(now)
+	return r.outboxStub.Claim(ctx, now, leaseUntil, limit)
+}
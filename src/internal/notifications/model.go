@@ -0,0 +1,27 @@
+package notifications
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const KindSavedSearchMatch = "saved_search.match"
+
+// Notification is an entry of a user's in-app inbox. Data carries
+// kind-specific details for clients.
+type Notification struct {
+	ID        string          `json:"id"`
+	UserID    string          `json:"user_id"`
+	Kind      string          `json:"kind"`
+	Title     string          `json:"title"`
+	Body      string          `json:"body,omitempty"`
+	Data      json.RawMessage `json:"data,omitempty" swaggertype:"object"`
+	ReadAt    *time.Time      `json:"read_at,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+type ListFilter struct {
+	UserID     string
+	UnreadOnly bool
+	Limit      int
+}
@@ -0,0 +1,99 @@
+package notifications
+
+import (
+	"context"
+
+	"github.com/PabloPavan/sniply_api/internal/db"
+)
+
+type Repository struct {
+	base *db.Base
+}
+
+func NewRepository(base *db.Base) *Repository {
+	return &Repository{base: base}
+}
+
+const (
+	sqlNotificationInsert = `INSERT INTO notifications (id, user_id, kind, title, body, data)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING created_at`
+
+	sqlNotificationList = `SELECT id, user_id, kind, title, body, data, read_at, created_at
+		FROM notifications
+		WHERE user_id = $1 AND ($2 = false OR read_at IS NULL)
+		ORDER BY created_at DESC, id DESC
+		LIMIT $3`
+
+	sqlNotificationMarkRead = `UPDATE notifications
+		SET read_at = coalesce(read_at, now())
+		WHERE id = $1 AND user_id = $2`
+
+	sqlNotificationMarkAllRead = `UPDATE notifications
+		SET read_at = now()
+		WHERE user_id = $1 AND read_at IS NULL`
+)
+
+func (r *Repository) Create(ctx context.Context, n *Notification) error {
+	ctx, cancel := r.base.WithTimeout(ctx)
+	defer cancel()
+
+	data := n.Data
+	if len(data) == 0 {
+		data = []byte("{}")
+	}
+	return r.base.Q().QueryRow(ctx, sqlNotificationInsert,
+		n.ID, n.UserID, n.Kind, n.Title, n.Body, data,
+	).Scan(&n.CreatedAt)
+}
+
+func (r *Repository) List(ctx context.Context, f ListFilter) ([]*Notification, error) {
+	ctx, cancel := r.base.WithTimeout(ctx)
+	defer cancel()
+
+	rows, err := r.base.Q().Query(ctx, sqlNotificationList, f.UserID, f.UnreadOnly, f.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]*Notification, 0)
+	for rows.Next() {
+		var n Notification
+		if err := rows.Scan(&n.ID, &n.UserID, &n.Kind, &n.Title, &n.Body, &n.Data, &n.ReadAt, &n.CreatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, &n)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// MarkRead marks one notification of the user as read. Reading it twice
+// keeps the first read time.
+func (r *Repository) MarkRead(ctx context.Context, userID, id string) error {
+	ctx, cancel := r.base.WithTimeout(ctx)
+	defer cancel()
+
+	tag, err := r.base.Q().Exec(ctx, sqlNotificationMarkRead, id, userID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *Repository) MarkAllRead(ctx context.Context, userID string) (int, error) {
+	ctx, cancel := r.base.WithTimeout(ctx)
+	defer cancel()
+
+	tag, err := r.base.Q().Exec(ctx, sqlNotificationMarkAllRead, userID)
+	if err != nil {
+		return 0, err
+	}
+	return int(tag.RowsAffected()), nil
+}
@@ -0,0 +1,102 @@
+package notifications
+
+import (
+	"context"
+	"strings"
+
+	"github.com/PabloPavan/sniply_api/internal"
+	"github.com/PabloPavan/sniply_api/internal/apperrors"
+	"github.com/PabloPavan/sniply_api/internal/identity"
+)
+
+type Store interface {
+	Create(ctx context.Context, n *Notification) error
+	List(ctx context.Context, f ListFilter) ([]*Notification, error)
+	MarkRead(ctx context.Context, userID, id string) error
+	MarkAllRead(ctx context.Context, userID string) (int, error)
+}
+
+type Service struct {
+	Store       Store
+	IDGenerator func() string
+}
+
+// Notify adds n to the inbox of n.UserID. It is meant for other services and
+// does not check the caller.
+func (s *Service) Notify(ctx context.Context, n *Notification) error {
+	if s.Store == nil {
+		return apperrors.New(apperrors.KindInternal, "notifications store not configured")
+	}
+	if strings.TrimSpace(n.UserID) == "" || strings.TrimSpace(n.Kind) == "" {
+		return apperrors.New(apperrors.KindInvalidInput, "user and kind are required")
+	}
+
+	idGen := s.IDGenerator
+	if idGen == nil {
+		idGen = func() string {
+			return "ntf_" + internal.RandomHex(12)
+		}
+	}
+	n.ID = idGen()
+
+	if err := s.Store.Create(ctx, n); err != nil {
+		return apperrors.New(apperrors.KindInternal, "failed to create notification")
+	}
+	return nil
+}
+
+// List returns the requester's notifications, newest first.
+func (s *Service) List(ctx context.Context, unreadOnly bool, limit int) ([]*Notification, error) {
+	if s.Store == nil {
+		return nil, apperrors.New(apperrors.KindInternal, "notifications store not configured")
+	}
+	userID, ok := identity.UserID(ctx)
+	if !ok || strings.TrimSpace(userID) == "" {
+		return nil, apperrors.New(apperrors.KindUnauthorized, "unauthorized")
+	}
+
+	if limit <= 0 {
+		limit = 50
+	}
+	list, err := s.Store.List(ctx, ListFilter{UserID: userID, UnreadOnly: unreadOnly, Limit: min(limit, 200)})
+	if err != nil {
+		return nil, apperrors.New(apperrors.KindInternal, "failed to list notifications")
+	}
+	return list, nil
+}
+
+func (s *Service) MarkRead(ctx context.Context, id string) error {
+	if s.Store == nil {
+		return apperrors.New(apperrors.KindInternal, "notifications store not configured")
+	}
+	userID, ok := identity.UserID(ctx)
+	if !ok || strings.TrimSpace(userID) == "" {
+		return apperrors.New(apperrors.KindUnauthorized, "unauthorized")
+	}
+
+	if err := s.Store.MarkRead(ctx, userID, strings.TrimSpace(id)); err != nil {
+		if IsNotFound(err) {
+			return apperrors.New(apperrors.KindNotFound, "not found")
+		}
+		return apperrors.New(apperrors.KindInternal, "failed to update notification")
+	}
+	return nil
+}
+
+// MarkAllRead marks every unread notification of the requester as read and
+// returns how many there were.
+func (s *Service) MarkAllRead(ctx context.Context) (int, error) {
+	if s.Store == nil {
+		return 0, apperrors.New(apperrors.KindInternal, "notifications store not configured")
+	}
+	userID, ok := identity.UserID(ctx)
+	if !ok || strings.TrimSpace(userID) == "" {
+		return 0, apperrors.New(apperrors.KindUnauthorized, "unauthorized")
+	}
+
+	n, err := s.Store.MarkAllRead(ctx, userID)
+	if err != nil {
+		return 0, apperrors.New(apperrors.KindInternal, "failed to update notifications")
+	}
+	return n, nil
+}
@@ -0,0 +1,30 @@
+package passkeys
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Credential is a WebAuthn public key credential registered by a user.
+type Credential struct {
+	ID              string     `json:"id"` // base64url credential ID
+	UserID          string     `json:"-"`
+	Name            string     `json:"name"`
+	PublicKey       []byte     `json:"-"` // COSE key
+	AttestationType string     `json:"-"`
+	AAGUID          []byte     `json:"-"`
+	SignCount       uint32     `json:"sign_count"`
+	Transports      []string   `json:"transports"`
+	BackupEligible  bool       `json:"backup_eligible"`
+	BackupState     bool       `json:"backup_state"`
+	CreatedAt       time.Time  `json:"created_at"`
+	LastUsedAt      *time.Time `json:"last_used_at,omitempty"`
+}
+
+// Ceremony is the first half of a registration or login: the options to
+// pass to navigator.credentials.create() or get(), and the ID to send back
+// with the result.
+type Ceremony struct {
+	ID      string
+	Options json.RawMessage
+}
@@ -0,0 +1,122 @@
+package passkeys
+
+import (
+	"context"
+
+	"github.com/PabloPavan/sniply_api/internal/db"
+)
+
+type Repository struct {
+	base *db.Base
+}
+
+func NewRepository(base *db.Base) *Repository {
+	return &Repository{base: base}
+}
+
+const (
+	sqlCredentialColumns = `id, user_id, name, public_key, attestation_type, aaguid, sign_count,
+		transports, backup_eligible, backup_state, created_at, last_used_at`
+
+	sqlCredentialsList = `SELECT ` + sqlCredentialColumns + `
+		FROM user_credentials
+		WHERE user_id = $1
+		ORDER BY created_at, id`
+
+	sqlCredentialsCount = `SELECT count(*)
+		FROM user_credentials
+		WHERE user_id = $1`
+
+	sqlCredentialInsert = `INSERT INTO user_credentials (id, user_id, name, public_key, attestation_type,
+			aaguid, sign_count, transports, backup_eligible, backup_state)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		ON CONFLICT (id) DO NOTHING
+		RETURNING created_at`
+
+	// sqlCredentialUse only moves the sign counter forward; authenticators
+	// that do not count always report zero.
+	sqlCredentialUse = `UPDATE user_credentials
+		SET sign_count = $2, backup_state = $3, last_used_at = now()
+		WHERE id = $1 AND (sign_count < $2 OR (sign_count = 0 AND $2 = 0))`
+
+	sqlCredentialDelete = `DELETE FROM user_credentials
+		WHERE id = $1 AND user_id = $2`
+)
+
+func (r *Repository) List(ctx context.Context, userID string) ([]Credential, error) {
+	ctx, cancel := r.base.WithTimeout(ctx)
+	defer cancel()
+
+	rows, err := r.base.Q().Query(ctx, sqlCredentialsList, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]Credential, 0)
+	for rows.Next() {
+		var c Credential
+		var signCount int64
+		if err := rows.Scan(&c.ID, &c.UserID, &c.Name, &c.PublicKey, &c.AttestationType, &c.AAGUID, &signCount,
+			&c.Transports, &c.BackupEligible, &c.BackupState, &c.CreatedAt, &c.LastUsedAt); err != nil {
+			return nil, err
+		}
+		c.SignCount = uint32(signCount)
+		out = append(out, c)
+	}
+	return out, rows.Err()
+}
+
+func (r *Repository) Count(ctx context.Context, userID string) (int, error) {
+	ctx, cancel := r.base.WithTimeout(ctx)
+	defer cancel()
+
+	var n int
+	err := r.base.Q().QueryRow(ctx, sqlCredentialsCount, userID).Scan(&n)
+	return n, err
+}
+
+// Create reports false if the credential ID is already registered.
+func (r *Repository) Create(ctx context.Context, c *Credential) (bool, error) {
+	ctx, cancel := r.base.WithTimeout(ctx)
+	defer cancel()
+
+	if c.Transports == nil {
+		c.Transports = []string{}
+	}
+	err := r.base.Q().QueryRow(ctx, sqlCredentialInsert,
+		c.ID, c.UserID, c.Name, c.PublicKey, c.AttestationType, c.AAGUID, int64(c.SignCount),
+		c.Transports, c.BackupEligible, c.BackupState,
+	).Scan(&c.CreatedAt)
+	if IsNotFound(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Use records a login with the credential. It reports false if the sign
+// counter did not move forward, a sign of a cloned authenticator.
+func (r *Repository) Use(ctx context.Context, id string, signCount uint32, backupState bool) (bool, error) {
+	ctx, cancel := r.base.WithTimeout(ctx)
+	defer cancel()
+
+	tag, err := r.base.Q().Exec(ctx, sqlCredentialUse, id, int64(signCount), backupState)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+func (r *Repository) Delete(ctx context.Context, userID, id string) error {
+	ctx, cancel := r.base.WithTimeout(ctx)
+	defer cancel()
+
+	tag, err := r.base.Q().Exec(ctx, sqlCredentialDelete, id, userID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
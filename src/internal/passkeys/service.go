@@ -0,0 +1,356 @@
+package passkeys
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/PabloPavan/sniply_api/internal"
+	"github.com/PabloPavan/sniply_api/internal/apperrors"
+	"github.com/PabloPavan/sniply_api/internal/identity"
+	"github.com/PabloPavan/sniply_api/internal/session"
+	"github.com/PabloPavan/sniply_api/internal/users"
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+)
+
+const (
+	DefaultCeremonyTTL = 5 * time.Minute
+	// DefaultName names credentials registered without one.
+	DefaultName = "Passkey"
+
+	kindRegister = "register"
+	kindLogin    = "login"
+)
+
+type Store interface {
+	List(ctx context.Context, userID string) ([]Credential, error)
+	Count(ctx context.Context, userID string) (int, error)
+	Create(ctx context.Context, c *Credential) (bool, error)
+	Use(ctx context.Context, id string, signCount uint32, backupState bool) (bool, error)
+	Delete(ctx context.Context, userID, id string) error
+}
+
+type UserLookup interface {
+	GetByID(ctx context.Context, id string) (*users.User, error)
+}
+
+// Service runs WebAuthn ceremonies. Registration is for the signed-in user;
+// logins take the user ID of a login in progress, or none for a passwordless
+// login where the authenticator picks the account.
+type Service struct {
+	Store    Store
+	Users    UserLookup
+	WebAuthn *webauthn.WebAuthn
+	// States keeps each ceremony's challenge until it is finished.
+	States      session.StateStore
+	CeremonyTTL time.Duration
+}
+
+// ceremonyState is what a ceremony needs to verify the authenticator's
+// response.
+type ceremonyState struct {
+	Kind   string               `json:"kind"`
+	UserID string               `json:"user_id,omitempty"`
+	Data   webauthn.SessionData `json:"data"`
+}
+
+// account adapts a user and their credentials to the WebAuthn library.
+// The user ID doubles as the user handle.
+type account struct {
+	user  *users.User
+	creds []Credential
+}
+
+func (a account) WebAuthnID() []byte          { return []byte(a.user.ID) }
+func (a account) WebAuthnName() string        { return a.user.Email }
+func (a account) WebAuthnDisplayName() string { return a.user.Email }
+
+func (a account) WebAuthnCredentials() []webauthn.Credential {
+	out := make([]webauthn.Credential, 0, len(a.creds))
+	for _, c := range a.creds {
+		id, err := base64.RawURLEncoding.DecodeString(c.ID)
+		if err != nil {
+			continue
+		}
+		transports := make([]protocol.AuthenticatorTransport, 0, len(c.Transports))
+		for _, t := range c.Transports {
+			transports = append(transports, protocol.AuthenticatorTransport(t))
+		}
+		out = append(out, webauthn.Credential{
+			ID:              id,
+			PublicKey:       c.PublicKey,
+			AttestationType: c.AttestationType,
+			Transport:       transports,
+			Flags: webauthn.CredentialFlags{
+				BackupEligible: c.BackupEligible,
+				BackupState:    c.BackupState,
+			},
+			Authenticator: webauthn.Authenticator{AAGUID: c.AAGUID, SignCount: c.SignCount},
+		})
+	}
+	return out
+}
+
+func (s *Service) configured() error {
+	if s.Store == nil || s.Users == nil || s.WebAuthn == nil || s.States == nil {
+		return apperrors.New(apperrors.KindInternal, "webauthn not configured")
+	}
+	return nil
+}
+
+func (s *Service) user(ctx context.Context) (string, error) {
+	if err := s.configured(); err != nil {
+		return "", err
+	}
+	userID, ok := identity.UserID(ctx)
+	if !ok || strings.TrimSpace(userID) == "" {
+		return "", apperrors.New(apperrors.KindUnauthorized, "unauthorized")
+	}
+	return userID, nil
+}
+
+func (s *Service) account(ctx context.Context, userID string) (account, error) {
+	u, err := s.Users.GetByID(ctx, userID)
+	if err != nil {
+		if users.IsNotFound(err) {
+			return account{}, apperrors.New(apperrors.KindUnauthorized, "unauthorized")
+		}
+		return account{}, apperrors.New(apperrors.KindInternal, "failed to load user")
+	}
+	creds, err := s.Store.List(ctx, userID)
+	if err != nil {
+		return account{}, apperrors.New(apperrors.KindInternal, "failed to load credentials")
+	}
+	return account{user: u, creds: creds}, nil
+}
+
+func (s *Service) begin(ctx context.Context, kind, userID string, options any, data *webauthn.SessionData) (*Ceremony, error) {
+	state, err := json.Marshal(ceremonyState{Kind: kind, UserID: userID, Data: *data})
+	if err != nil {
+		return nil, apperrors.New(apperrors.KindInternal, "failed to start ceremony")
+	}
+	raw, err := json.Marshal(options)
+	if err != nil {
+		return nil, apperrors.New(apperrors.KindInternal, "failed to start ceremony")
+	}
+
+	ttl := s.CeremonyTTL
+	if ttl <= 0 {
+		ttl = DefaultCeremonyTTL
+	}
+	c := &Ceremony{ID: internal.RandomHex(16), Options: raw}
+	if err := s.States.Put(ctx, "webauthn:"+c.ID, state, ttl); err != nil {
+		return nil, apperrors.New(apperrors.KindInternal, "failed to start ceremony")
+	}
+	return c, nil
+}
+
+// take returns the state of a ceremony, which can be finished only once.
+func (s *Service) take(ctx context.Context, id, kind, userID string) (ceremonyState, error) {
+	var state ceremonyState
+	expired := apperrors.New(apperrors.KindUnauthorized, "invalid or expired ceremony")
+	if strings.TrimSpace(id) == "" {
+		return state, expired
+	}
+	payload, err := s.States.Take(ctx, "webauthn:"+id)
+	if err != nil {
+		return state, expired
+	}
+	if err := json.Unmarshal(payload, &state); err != nil || state.Kind != kind || state.UserID != userID {
+		return state, expired
+	}
+	return state, nil
+}
+
+func (s *Service) List(ctx context.Context) ([]Credential, error) {
+	userID, err := s.user(ctx)
+	if err != nil {
+		return nil, err
+	}
+	creds, err := s.Store.List(ctx, userID)
+	if err != nil {
+		return nil, apperrors.New(apperrors.KindInternal, "failed to list credentials")
+	}
+	return creds, nil
+}
+
+// BeginRegistration asks for a new credential, preferably a discoverable
+// one so it can log in without a password.
+func (s *Service) BeginRegistration(ctx context.Context) (*Ceremony, error) {
+	userID, err := s.user(ctx)
+	if err != nil {
+		return nil, err
+	}
+	acct, err := s.account(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	exclude := webauthn.Credentials(acct.WebAuthnCredentials()).CredentialDescriptors()
+	options, data, err := s.WebAuthn.BeginRegistration(acct,
+		webauthn.WithExclusions(exclude),
+		webauthn.WithResidentKeyRequirement(protocol.ResidentKeyRequirementPreferred),
+	)
+	if err != nil {
+		return nil, apperrors.New(apperrors.KindInternal, "failed to start registration")
+	}
+	return s.begin(ctx, kindRegister, userID, options, data)
+}
+
+// FinishRegistration verifies the authenticator's response and saves the
+// credential.
+func (s *Service) FinishRegistration(ctx context.Context, ceremonyID, name string, response []byte) (*Credential, error) {
+	userID, err := s.user(ctx)
+	if err != nil {
+		return nil, err
+	}
+	state, err := s.take(ctx, ceremonyID, kindRegister, userID)
+	if err != nil {
+		return nil, err
+	}
+	parsed, err := protocol.ParseCredentialCreationResponseBytes(response)
+	if err != nil {
+		return nil, apperrors.New(apperrors.KindInvalidInput, "invalid credential")
+	}
+	acct, err := s.account(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	wc, err := s.WebAuthn.CreateCredential(acct, state.Data, parsed)
+	if err != nil {
+		return nil, apperrors.New(apperrors.KindInvalidInput, "credential verification failed")
+	}
+
+	c := &Credential{
+		ID:              base64.RawURLEncoding.EncodeToString(wc.ID),
+		UserID:          userID,
+		Name:            strings.TrimSpace(name),
+		PublicKey:       wc.PublicKey,
+		AttestationType: wc.AttestationType,
+		AAGUID:          wc.Authenticator.AAGUID,
+		SignCount:       wc.Authenticator.SignCount,
+		Transports:      make([]string, 0, len(wc.Transport)),
+		BackupEligible:  wc.Flags.BackupEligible,
+		BackupState:     wc.Flags.BackupState,
+	}
+	if c.Name == "" {
+		c.Name = DefaultName
+	}
+	for _, t := range wc.Transport {
+		c.Transports = append(c.Transports, string(t))
+	}
+
+	created, err := s.Store.Create(ctx, c)
+	if err != nil {
+		return nil, apperrors.New(apperrors.KindInternal, "failed to save credential")
+	}
+	if !created {
+		return nil, apperrors.New(apperrors.KindConflict, "credential already registered")
+	}
+	return c, nil
+}
+
+func (s *Service) Delete(ctx context.Context, id string) error {
+	userID, err := s.user(ctx)
+	if err != nil {
+		return err
+	}
+	if err := s.Store.Delete(ctx, userID, strings.TrimSpace(id)); err != nil {
+		if IsNotFound(err) {
+			return apperrors.New(apperrors.KindNotFound, "credential not found")
+		}
+		return apperrors.New(apperrors.KindInternal, "failed to delete credential")
+	}
+	return nil
+}
+
+// Has reports whether the user registered any credential.
+func (s *Service) Has(ctx context.Context, userID string) (bool, error) {
+	if s.Store == nil {
+		return false, apperrors.New(apperrors.KindInternal, "webauthn not configured")
+	}
+	n, err := s.Store.Count(ctx, userID)
+	if err != nil {
+		return false, apperrors.New(apperrors.KindInternal, "failed to load credentials")
+	}
+	return n > 0, nil
+}
+
+// BeginLogin starts an assertion with one of the user's credentials, as a
+// second factor. Without a user it starts a passwordless login, which
+// requires user verification by the authenticator.
+func (s *Service) BeginLogin(ctx context.Context, userID string) (*Ceremony, error) {
+	if err := s.configured(); err != nil {
+		return nil, err
+	}
+
+	if userID == "" {
+		options, data, err := s.WebAuthn.BeginDiscoverableLogin(webauthn.WithUserVerification(protocol.VerificationRequired))
+		if err != nil {
+			return nil, apperrors.New(apperrors.KindInternal, "failed to start login")
+		}
+		return s.begin(ctx, kindLogin, "", options, data)
+	}
+
+	acct, err := s.account(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(acct.creds) == 0 {
+		return nil, apperrors.New(apperrors.KindInvalidInput, "no passkeys registered")
+	}
+	options, data, err := s.WebAuthn.BeginLogin(acct)
+	if err != nil {
+		return nil, apperrors.New(apperrors.KindInternal, "failed to start login")
+	}
+	return s.begin(ctx, kindLogin, userID, options, data)
+}
+
+// FinishLogin verifies an assertion for a ceremony started by BeginLogin
+// with the same user ID and returns the user it authenticates. A sign
+// counter that did not move forward rejects the login.
+func (s *Service) FinishLogin(ctx context.Context, ceremonyID, userID string, response []byte) (string, error) {
+	if err := s.configured(); err != nil {
+		return "", err
+	}
+	state, err := s.take(ctx, ceremonyID, kindLogin, userID)
+	if err != nil {
+		return "", err
+	}
+	parsed, err := protocol.ParseCredentialRequestResponseBytes(response)
+	if err != nil {
+		return "", apperrors.New(apperrors.KindInvalidInput, "invalid credential")
+	}
+
+	var acct account
+	var lookupErr error
+	var wc *webauthn.Credential
+	if userID == "" {
+		wc, err = s.WebAuthn.ValidateDiscoverableLogin(func(_, userHandle []byte) (webauthn.User, error) {
+			acct, lookupErr = s.account(ctx, string(userHandle))
+			return acct, lookupErr
+		}, state.Data, parsed)
+	} else {
+		if acct, lookupErr = s.account(ctx, userID); lookupErr == nil {
+			wc, err = s.WebAuthn.ValidateLogin(acct, state.Data, parsed)
+		}
+	}
+	if lookupErr != nil {
+		return "", lookupErr
+	}
+	if err != nil || wc.Authenticator.CloneWarning {
+		return "", apperrors.New(apperrors.KindUnauthorized, "invalid credential")
+	}
+
+	used, err := s.Store.Use(ctx, base64.RawURLEncoding.EncodeToString(wc.ID), wc.Authenticator.SignCount, wc.Flags.BackupState)
+	if err != nil {
+		return "", apperrors.New(apperrors.KindInternal, "failed to verify credential")
+	}
+	if !used {
+		return "", apperrors.New(apperrors.KindUnauthorized, "invalid credential")
+	}
+	return acct.user.ID, nil
+}
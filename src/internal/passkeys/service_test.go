@@ -0,0 +1,219 @@
+package passkeys
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/PabloPavan/sniply_api/internal/apperrors"
+	"github.com/PabloPavan/sniply_api/internal/identity"
+	"github.com/PabloPavan/sniply_api/internal/passkeytest"
+	"github.com/PabloPavan/sniply_api/internal/session"
+	"github.com/PabloPavan/sniply_api/internal/users"
+	"github.com/go-webauthn/webauthn/webauthn"
+)
+
+type storeStub struct {
+	creds []Credential
+}
+
+func (s *storeStub) List(ctx context.Context, userID string) ([]Credential, error) {
+	out := make([]Credential, 0)
+	for _, c := range s.creds {
+		if c.UserID == userID {
+			out = append(out, c)
+		}
+	}
+	return out, nil
+}
+
+func (s *storeStub) Count(ctx context.Context, userID string) (int, error) {
+	list, _ := s.List(ctx, userID)
+	return len(list), nil
+}
+
+func (s *storeStub) Create(ctx context.Context, c *Credential) (bool, error) {
+	for _, existing := range s.creds {
+		if existing.ID == c.ID {
+			return false, nil
+		}
+	}
+	c.CreatedAt = time.Now()
+	s.creds = append(s.creds, *c)
+	return true, nil
+}
+
+func (s *storeStub) Use(ctx context.Context, id string, signCount uint32, backupState bool) (bool, error) {
+	for i := range s.creds {
+		c := &s.creds[i]
+		if c.ID == id && (c.SignCount < signCount || (c.SignCount == 0 && signCount == 0)) {
+			now := time.Now()
+			c.SignCount, c.BackupState, c.LastUsedAt = signCount, backupState, &now
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *storeStub) Delete(ctx context.Context, userID, id string) error {
+	for i, c := range s.creds {
+		if c.ID == id && c.UserID == userID {
+			s.creds = append(s.creds[:i], s.creds[i+1:]...)
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+type userLookupStub struct{}
+
+func (userLookupStub) GetByID(ctx context.Context, id string) (*users.User, error) {
+	return &users.User{ID: id, Email: id + "@local"}, nil
+}
+
+func newTestService(t *testing.T) (*Service, *storeStub) {
+	t.Helper()
+	wa, err := webauthn.New(&webauthn.Config{
+		RPID:          "localhost",
+		RPDisplayName: "Sniply",
+		RPOrigins:     []string{"http://localhost:8080"},
+	})
+	if err != nil {
+		t.Fatalf("webauthn config: %v", err)
+	}
+	store := &storeStub{}
+	return &Service{Store: store, Users: userLookupStub{}, WebAuthn: wa, States: session.NewMemoryStateStore()}, store
+}
+
+func register(t *testing.T, svc *Service, ctx context.Context, a *passkeytest.Authenticator) *Credential {
+	t.Helper()
+	c, err := svc.BeginRegistration(ctx)
+	if err != nil {
+		t.Fatalf("begin registration: %v", err)
+	}
+	resp, err := a.Create(c.Options)
+	if err != nil {
+		t.Fatalf("authenticator create: %v", err)
+	}
+	cred, err := svc.FinishRegistration(ctx, c.ID, "  ", resp)
+	if err != nil {
+		t.Fatalf("finish registration: %v", err)
+	}
+	return cred
+}
+
+func login(svc *Service, a *passkeytest.Authenticator, userID string) (string, error) {
+	c, err := svc.BeginLogin(context.Background(), userID)
+	if err != nil {
+		return "", err
+	}
+	resp, err := a.Get(c.Options)
+	if err != nil {
+		return "", err
+	}
+	return svc.FinishLogin(context.Background(), c.ID, userID, resp)
+}
+
+func TestServiceRegisterAndLogin(t *testing.T) {
+	svc, store := newTestService(t)
+	ctx := identity.WithUser(context.Background(), "usr_1", string(users.RoleUser))
+	a := passkeytest.New("localhost", "http://localhost:8080")
+
+	cred := register(t, svc, ctx, a)
+	if cred.Name != DefaultName || len(cred.Transports) != 1 || cred.Transports[0] != "internal" {
+		t.Fatalf("unexpected credential: %+v", cred)
+	}
+	if has, err := svc.Has(ctx, "usr_1"); err != nil || !has {
+		t.Fatalf("expected a registered credential: %v %v", has, err)
+	}
+
+	// The same authenticator is excluded from registering twice.
+	c, err := svc.BeginRegistration(ctx)
+	if err != nil {
+		t.Fatalf("begin registration: %v", err)
+	}
+	if _, err := a.Create(c.Options); err == nil {
+		t.Fatal("expected the registered credential to be excluded")
+	}
+
+	// Passwordless: the authenticator picks the account.
+	userID, err := login(svc, a, "")
+	if err != nil || userID != "usr_1" {
+		t.Fatalf("passwordless login: %q %v", userID, err)
+	}
+	// Second factor for a known user.
+	if userID, err = login(svc, a, "usr_1"); err != nil || userID != "usr_1" {
+		t.Fatalf("second factor login: %q %v", userID, err)
+	}
+	if store.creds[0].SignCount != 2 || store.creds[0].LastUsedAt == nil {
+		t.Fatalf("expected usage recorded: %+v", store.creds[0])
+	}
+
+	_, err = login(svc, a, "usr_2")
+	assertKind(t, err, apperrors.KindInvalidInput)
+
+	if err := svc.Delete(ctx, cred.ID); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	assertKind(t, svc.Delete(ctx, cred.ID), apperrors.KindNotFound)
+}
+
+func TestServiceCeremoniesAreSingleUseAndBound(t *testing.T) {
+	svc, _ := newTestService(t)
+	ctx := identity.WithUser(context.Background(), "usr_1", string(users.RoleUser))
+	a := passkeytest.New("localhost", "http://localhost:8080")
+	register(t, svc, ctx, a)
+
+	c, err := svc.BeginLogin(context.Background(), "usr_1")
+	if err != nil {
+		t.Fatalf("begin login: %v", err)
+	}
+	resp, _ := a.Get(c.Options)
+
+	// A second factor ceremony cannot finish a passwordless login.
+	_, err = svc.FinishLogin(context.Background(), c.ID, "", resp)
+	assertKind(t, err, apperrors.KindUnauthorized)
+	// The failed attempt used the ceremony up.
+	_, err = svc.FinishLogin(context.Background(), c.ID, "usr_1", resp)
+	assertKind(t, err, apperrors.KindUnauthorized)
+
+	// A registration ceremony belongs to the user who started it.
+	c, err = svc.BeginRegistration(ctx)
+	if err != nil {
+		t.Fatalf("begin registration: %v", err)
+	}
+	resp, _ = passkeytest.New("localhost", "http://localhost:8080").Create(c.Options)
+	other := identity.WithUser(context.Background(), "usr_2", string(users.RoleUser))
+	_, err = svc.FinishRegistration(other, c.ID, "", resp)
+	assertKind(t, err, apperrors.KindUnauthorized)
+}
+
+func TestServiceRejectsClonedAuthenticator(t *testing.T) {
+	svc, _ := newTestService(t)
+	ctx := identity.WithUser(context.Background(), "usr_1", string(users.RoleUser))
+	a := passkeytest.New("localhost", "http://localhost:8080")
+	register(t, svc, ctx, a)
+
+	clone := a.Clone()
+	if _, err := login(svc, a, ""); err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	// The clone's counter is behind the stored one.
+	_, err := login(svc, clone, "")
+	assertKind(t, err, apperrors.KindUnauthorized)
+}
+
+func assertKind(t *testing.T, err error, kind apperrors.Kind) {
+	t.Helper()
+	if err == nil {
+		t.Fatalf("expected error kind %s", kind)
+	}
+	var appErr *apperrors.Error
+	if !errors.As(err, &appErr) {
+		t.Fatalf("expected app error, got: %v", err)
+	}
+	if appErr.Kind != kind {
+		t.Fatalf("unexpected kind: %s", appErr.Kind)
+	}
+}
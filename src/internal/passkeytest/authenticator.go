@@ -0,0 +1,220 @@
+package passkeytest
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"sync"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+// Authenticator is a software WebAuthn authenticator for tests and local
+// development. It creates ES256 credentials with "none" attestation and
+// always reports the user as present and verified.
+type Authenticator struct {
+	RPID   string
+	Origin string
+
+	mu    sync.Mutex
+	creds []*credential
+}
+
+type credential struct {
+	id         []byte
+	userHandle []byte
+	key        *ecdsa.PrivateKey
+	signCount  uint32
+}
+
+func New(rpID, origin string) *Authenticator {
+	return &Authenticator{RPID: rpID, Origin: origin}
+}
+
+// Clone returns an authenticator holding copies of the same keys and sign
+// counters, like a cloned security key.
+func (a *Authenticator) Clone() *Authenticator {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	out := &Authenticator{RPID: a.RPID, Origin: a.Origin}
+	for _, c := range a.creds {
+		cp := *c
+		out.creds = append(out.creds, &cp)
+	}
+	return out
+}
+
+type descriptor struct {
+	ID string `json:"id"`
+}
+
+type creationOptions struct {
+	PublicKey struct {
+		Challenge string `json:"challenge"`
+		RP        struct {
+			ID string `json:"id"`
+		} `json:"rp"`
+		User struct {
+			ID string `json:"id"`
+		} `json:"user"`
+		ExcludeCredentials []descriptor `json:"excludeCredentials"`
+	} `json:"publicKey"`
+}
+
+type requestOptions struct {
+	PublicKey struct {
+		Challenge        string       `json:"challenge"`
+		RPID             string       `json:"rpId"`
+		AllowCredentials []descriptor `json:"allowCredentials"`
+	} `json:"publicKey"`
+}
+
+// Create answers navigator.credentials.create() for the JSON options of a
+// registration ceremony, returning the credential as JSON.
+func (a *Authenticator) Create(options []byte) ([]byte, error) {
+	var opts creationOptions
+	if err := json.Unmarshal(options, &opts); err != nil {
+		return nil, err
+	}
+	o := opts.PublicKey
+	if o.RP.ID != a.RPID {
+		return nil, errors.New("passkeytest: relying party mismatch")
+	}
+	userHandle, err := b64decode(o.User.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for _, c := range a.creds {
+		for _, d := range o.ExcludeCredentials {
+			if d.ID == b64(c.id) {
+				return nil, errors.New("passkeytest: credential already registered")
+			}
+		}
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	c := &credential{id: make([]byte, 16), userHandle: userHandle, key: key}
+	if _, err := rand.Read(c.id); err != nil {
+		return nil, err
+	}
+
+	x, y := key.PublicKey.X.FillBytes(make([]byte, 32)), key.PublicKey.Y.FillBytes(make([]byte, 32))
+	coseKey, err := cbor.Marshal(map[int]any{1: 2, 3: -7, -1: 1, -2: x, -3: y})
+	if err != nil {
+		return nil, err
+	}
+	authData := a.authData(0x45, 0) // UP, UV, AT
+	authData = append(authData, make([]byte, 16)...)
+	authData = binary.BigEndian.AppendUint16(authData, uint16(len(c.id)))
+	authData = append(authData, c.id...)
+	authData = append(authData, coseKey...)
+	attestation, err := cbor.Marshal(map[string]any{"fmt": "none", "attStmt": map[string]any{}, "authData": authData})
+	if err != nil {
+		return nil, err
+	}
+	clientData, err := a.clientData("webauthn.create", o.Challenge)
+	if err != nil {
+		return nil, err
+	}
+	a.creds = append(a.creds, c)
+
+	return json.Marshal(map[string]any{
+		"id":    b64(c.id),
+		"rawId": b64(c.id),
+		"type":  "public-key",
+		"response": map[string]any{
+			"clientDataJSON":    b64(clientData),
+			"attestationObject": b64(attestation),
+			"transports":        []string{"internal"},
+		},
+	})
+}
+
+// Get answers navigator.credentials.get() for the JSON options of a login
+// ceremony with the first allowed credential, or the first credential
+// when any is allowed.
+func (a *Authenticator) Get(options []byte) ([]byte, error) {
+	var opts requestOptions
+	if err := json.Unmarshal(options, &opts); err != nil {
+		return nil, err
+	}
+	o := opts.PublicKey
+	if o.RPID != a.RPID {
+		return nil, errors.New("passkeytest: relying party mismatch")
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	var c *credential
+	for _, cand := range a.creds {
+		if len(o.AllowCredentials) == 0 {
+			c = cand
+			break
+		}
+		for _, d := range o.AllowCredentials {
+			if d.ID == b64(cand.id) {
+				c = cand
+			}
+		}
+		if c != nil {
+			break
+		}
+	}
+	if c == nil {
+		return nil, errors.New("passkeytest: no credential")
+	}
+
+	c.signCount++
+	authData := a.authData(0x05, c.signCount) // UP, UV
+	clientData, err := a.clientData("webauthn.get", o.Challenge)
+	if err != nil {
+		return nil, err
+	}
+	hash := sha256.Sum256(clientData)
+	digest := sha256.Sum256(append(append([]byte{}, authData...), hash[:]...))
+	sig, err := ecdsa.SignASN1(rand.Reader, c.key, digest[:])
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(map[string]any{
+		"id":    b64(c.id),
+		"rawId": b64(c.id),
+		"type":  "public-key",
+		"response": map[string]any{
+			"clientDataJSON":    b64(clientData),
+			"authenticatorData": b64(authData),
+			"signature":         b64(sig),
+			"userHandle":        b64(c.userHandle),
+		},
+	})
+}
+
+func (a *Authenticator) authData(flags byte, signCount uint32) []byte {
+	rpIDHash := sha256.Sum256([]byte(a.RPID))
+	out := append(rpIDHash[:], flags)
+	return binary.BigEndian.AppendUint32(out, signCount)
+}
+
+func (a *Authenticator) clientData(typ, challenge string) ([]byte, error) {
+	return json.Marshal(map[string]any{"type": typ, "challenge": challenge, "origin": a.Origin})
+}
+
+func b64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func b64decode(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(s)
+}
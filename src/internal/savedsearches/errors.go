@@ -0,0 +1,21 @@
+package savedsearches
+
+import (
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+var ErrNotFound = errors.New("saved search not found")
+
+func IsNotFound(err error) bool {
+	return errors.Is(err, pgx.ErrNoRows) || errors.Is(err, ErrNotFound)
+}
+
+// IsDuplicateName reports whether the owner already has a search with the
+// same name.
+func IsDuplicateName(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505" && pgErr.ConstraintName == "saved_searches_owner_name_key"
+}
@@ -0,0 +1,88 @@
+package savedsearches
+
+import (
+	"time"
+
+	"github.com/PabloPavan/sniply_api/internal/snippets"
+)
+
+// Notify selects how the owner hears about new matches.
+type Notify string
+
+const (
+	NotifyNone    Notify = "none"
+	NotifyInbox   Notify = "inbox"
+	NotifyWebhook Notify = "webhook"
+)
+
+func (n Notify) Valid() bool {
+	switch n {
+	case NotifyNone, NotifyInbox, NotifyWebhook:
+		return true
+	default:
+		return false
+	}
+}
+
+// Query is the stored form of a snippets.ListInput, without paging.
+type Query struct {
+	Q          string `json:"q,omitempty"`
+	Mode       string `json:"mode,omitempty"`
+	Creator    string `json:"creator,omitempty"`
+	Language   string `json:"language,omitempty"`
+	Tag        string `json:"tag,omitempty"`
+	Visibility string `json:"visibility,omitempty"`
+}
+
+func (q Query) ListInput() snippets.ListInput {
+	return snippets.ListInput{
+		Query:      q.Q,
+		Mode:       snippets.SearchMode(q.Mode),
+		Creator:    q.Creator,
+		Language:   q.Language,
+		Tag:        q.Tag,
+		Visibility: snippets.Visibility(q.Visibility),
+	}
+}
+
+type SavedSearch struct {
+	ID      string `json:"id"`
+	OwnerID string `json:"owner_id"`
+	Name    string `json:"name"`
+	Query   Query  `json:"query"`
+	Notify  Notify `json:"notify"`
+
+	// WebhookSecret signs webhook deliveries; it is only set for webhooks.
+	WebhookURL    string `json:"webhook_url,omitempty"`
+	WebhookSecret string `json:"webhook_secret,omitempty"`
+
+	// CheckedAt is the end of the last window evaluated for new matches.
+	CheckedAt time.Time `json:"checked_at"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type Input struct {
+	Name       string
+	Query      Query
+	Notify     Notify
+	WebhookURL string
+}
+
+// Match lists the snippets created in [From, To) that match a saved search.
+// It is the webhook payload and the data of inbox notifications.
+type Match struct {
+	SavedSearchID string           `json:"saved_search_id"`
+	Name          string           `json:"name"`
+	From          time.Time        `json:"from"`
+	To            time.Time        `json:"to"`
+	Snippets      []MatchedSnippet `json:"snippets"`
+}
+
+type MatchedSnippet struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Language  string    `json:"language"`
+	CreatorID string    `json:"creator_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,187 @@
+package savedsearches
+
+import (
+	"context"
+	"time"
+
+	"github.com/PabloPavan/sniply_api/internal/db"
+	"github.com/jackc/pgx/v5"
+)
+
+type Repository struct {
+	base *db.Base
+}
+
+func NewRepository(base *db.Base) *Repository {
+	return &Repository{base: base}
+}
+
+const (
+	sqlSavedSearchColumns = `id, owner_id, name, query, notify, webhook_url, webhook_secret, checked_at, created_at, updated_at`
+
+	sqlSavedSearchInsert = `INSERT INTO saved_searches (id, owner_id, name, query, notify, webhook_url, webhook_secret, checked_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING created_at, updated_at`
+
+	sqlSavedSearchGetByID = `SELECT ` + sqlSavedSearchColumns + `
+		FROM saved_searches
+		WHERE id = $1`
+
+	sqlSavedSearchListByOwner = `SELECT ` + sqlSavedSearchColumns + `
+		FROM saved_searches
+		WHERE owner_id = $1
+		ORDER BY created_at DESC, id DESC`
+
+	sqlSavedSearchListDue = `SELECT ` + sqlSavedSearchColumns + `
+		FROM saved_searches
+		WHERE notify <> 'none' AND checked_at <= $1
+		ORDER BY checked_at ASC
+		LIMIT $2`
+
+	sqlSavedSearchUpdate = `UPDATE saved_searches
+		SET name = $1, query = $2, notify = $3, webhook_url = $4, webhook_secret = $5, updated_at = now()
+		WHERE id = $6
+		RETURNING updated_at`
+
+	sqlSavedSearchDelete = `DELETE FROM saved_searches
+		WHERE id = $1`
+
+	// sqlSavedSearchMoveCheckedAt moves the window cursor only if nobody
+	// else moved it first.
+	sqlSavedSearchMoveCheckedAt = `UPDATE saved_searches
+		SET checked_at = $3
+		WHERE id = $1 AND checked_at = $2`
+)
+
+func (r *Repository) Create(ctx context.Context, s *SavedSearch) error {
+	ctx, cancel := r.base.WithTimeout(ctx)
+	defer cancel()
+
+	return r.base.Q().QueryRow(ctx, sqlSavedSearchInsert,
+		s.ID,
+		s.OwnerID,
+		s.Name,
+		s.Query,
+		string(s.Notify),
+		s.WebhookURL,
+		s.WebhookSecret,
+		s.CheckedAt,
+	).Scan(&s.CreatedAt, &s.UpdatedAt)
+}
+
+func (r *Repository) GetByID(ctx context.Context, id string) (*SavedSearch, error) {
+	ctx, cancel := r.base.WithTimeout(ctx)
+	defer cancel()
+
+	rows, err := r.base.Q().Query(ctx, sqlSavedSearchGetByID, id)
+	if err != nil {
+		return nil, err
+	}
+	list, err := collect(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(list) == 0 {
+		return nil, ErrNotFound
+	}
+	return list[0], nil
+}
+
+func (r *Repository) ListByOwner(ctx context.Context, ownerID string) ([]*SavedSearch, error) {
+	ctx, cancel := r.base.WithTimeout(ctx)
+	defer cancel()
+
+	rows, err := r.base.Q().Query(ctx, sqlSavedSearchListByOwner, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	return collect(rows)
+}
+
+// ListDue returns searches with notifications whose last window ended at or
+// before the given time, oldest first.
+func (r *Repository) ListDue(ctx context.Context, before time.Time, limit int) ([]*SavedSearch, error) {
+	ctx, cancel := r.base.WithTimeout(ctx)
+	defer cancel()
+
+	rows, err := r.base.Q().Query(ctx, sqlSavedSearchListDue, before, limit)
+	if err != nil {
+		return nil, err
+	}
+	return collect(rows)
+}
+
+func (r *Repository) Update(ctx context.Context, s *SavedSearch) error {
+	ctx, cancel := r.base.WithTimeout(ctx)
+	defer cancel()
+
+	err := r.base.Q().QueryRow(ctx, sqlSavedSearchUpdate,
+		s.Name,
+		s.Query,
+		string(s.Notify),
+		s.WebhookURL,
+		s.WebhookSecret,
+		s.ID,
+	).Scan(&s.UpdatedAt)
+	if IsNotFound(err) {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (r *Repository) Delete(ctx context.Context, id string) error {
+	ctx, cancel := r.base.WithTimeout(ctx)
+	defer cancel()
+
+	tag, err := r.base.Q().Exec(ctx, sqlSavedSearchDelete, id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// MoveCheckedAt sets checked_at to to if it is still from. It reports false
+// when another worker moved it first.
+func (r *Repository) MoveCheckedAt(ctx context.Context, id string, from, to time.Time) (bool, error) {
+	ctx, cancel := r.base.WithTimeout(ctx)
+	defer cancel()
+
+	tag, err := r.base.Q().Exec(ctx, sqlSavedSearchMoveCheckedAt, id, from, to)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+func collect(rows pgx.Rows) ([]*SavedSearch, error) {
+	defer rows.Close()
+
+	out := make([]*SavedSearch, 0)
+	for rows.Next() {
+		var s SavedSearch
+		var notify string
+		if err := rows.Scan(
+			&s.ID,
+			&s.OwnerID,
+			&s.Name,
+			&s.Query,
+			&notify,
+			&s.WebhookURL,
+			&s.WebhookSecret,
+			&s.CheckedAt,
+			&s.CreatedAt,
+			&s.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		s.Notify = Notify(notify)
+		out = append(out, &s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
@@ -0,0 +1,262 @@
+package savedsearches
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/PabloPavan/sniply_api/internal"
+	"github.com/PabloPavan/sniply_api/internal/apperrors"
+	"github.com/PabloPavan/sniply_api/internal/identity"
+	"github.com/PabloPavan/sniply_api/internal/snippets"
+)
+
+const (
+	MaxPerUser    = 50
+	MaxNameLength = 100
+)
+
+type Store interface {
+	Create(ctx context.Context, s *SavedSearch) error
+	GetByID(ctx context.Context, id string) (*SavedSearch, error)
+	ListByOwner(ctx context.Context, ownerID string) ([]*SavedSearch, error)
+	Update(ctx context.Context, s *SavedSearch) error
+	Delete(ctx context.Context, id string) error
+	ListDue(ctx context.Context, before time.Time, limit int) ([]*SavedSearch, error)
+	MoveCheckedAt(ctx context.Context, id string, from, to time.Time) (bool, error)
+}
+
+// Searcher runs a snippet search as the user in ctx.
+type Searcher interface {
+	List(ctx context.Context, input snippets.ListInput) ([]*snippets.Snippet, error)
+}
+
+type Service struct {
+	Store       Store
+	Searcher    Searcher
+	IDGenerator func() string
+	Now         func() time.Time
+}
+
+func (s *Service) Create(ctx context.Context, input Input) (*SavedSearch, error) {
+	if s.Store == nil || s.Searcher == nil {
+		return nil, apperrors.New(apperrors.KindInternal, "saved searches store not configured")
+	}
+	ownerID, ok := identity.UserID(ctx)
+	if !ok || strings.TrimSpace(ownerID) == "" {
+		return nil, apperrors.New(apperrors.KindUnauthorized, "unauthorized")
+	}
+
+	saved := &SavedSearch{OwnerID: ownerID}
+	if err := s.apply(ctx, saved, input); err != nil {
+		return nil, err
+	}
+
+	existing, err := s.Store.ListByOwner(ctx, ownerID)
+	if err != nil {
+		return nil, apperrors.New(apperrors.KindInternal, "failed to list saved searches")
+	}
+	if len(existing) >= MaxPerUser {
+		return nil, apperrors.New(apperrors.KindConflict, "too many saved searches")
+	}
+
+	idGen := s.IDGenerator
+	if idGen == nil {
+		idGen = func() string {
+			return "srch_" + internal.RandomHex(12)
+		}
+	}
+	saved.ID = idGen()
+	// Only snippets created from now on are reported.
+	saved.CheckedAt = s.now().Truncate(time.Microsecond)
+
+	if err := s.Store.Create(ctx, saved); err != nil {
+		if IsDuplicateName(err) {
+			return nil, apperrors.New(apperrors.KindConflict, "a saved search with this name already exists")
+		}
+		return nil, apperrors.New(apperrors.KindInternal, "failed to create saved search")
+	}
+	return saved, nil
+}
+
+func (s *Service) Get(ctx context.Context, id string) (*SavedSearch, error) {
+	if s.Store == nil {
+		return nil, apperrors.New(apperrors.KindInternal, "saved searches store not configured")
+	}
+	return s.load(ctx, id)
+}
+
+func (s *Service) List(ctx context.Context) ([]*SavedSearch, error) {
+	if s.Store == nil {
+		return nil, apperrors.New(apperrors.KindInternal, "saved searches store not configured")
+	}
+	ownerID, ok := identity.UserID(ctx)
+	if !ok || strings.TrimSpace(ownerID) == "" {
+		return nil, apperrors.New(apperrors.KindUnauthorized, "unauthorized")
+	}
+
+	list, err := s.Store.ListByOwner(ctx, ownerID)
+	if err != nil {
+		return nil, apperrors.New(apperrors.KindInternal, "failed to list saved searches")
+	}
+	return list, nil
+}
+
+func (s *Service) Update(ctx context.Context, id string, input Input) (*SavedSearch, error) {
+	if s.Store == nil || s.Searcher == nil {
+		return nil, apperrors.New(apperrors.KindInternal, "saved searches store not configured")
+	}
+	saved, err := s.load(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.apply(ctx, saved, input); err != nil {
+		return nil, err
+	}
+
+	if err := s.Store.Update(ctx, saved); err != nil {
+		switch {
+		case IsNotFound(err):
+			return nil, apperrors.New(apperrors.KindNotFound, "not found")
+		case IsDuplicateName(err):
+			return nil, apperrors.New(apperrors.KindConflict, "a saved search with this name already exists")
+		}
+		return nil, apperrors.New(apperrors.KindInternal, "failed to update saved search")
+	}
+	return saved, nil
+}
+
+func (s *Service) Delete(ctx context.Context, id string) error {
+	if s.Store == nil {
+		return apperrors.New(apperrors.KindInternal, "saved searches store not configured")
+	}
+	saved, err := s.load(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if err := s.Store.Delete(ctx, saved.ID); err != nil {
+		if IsNotFound(err) {
+			return apperrors.New(apperrors.KindNotFound, "not found")
+		}
+		return apperrors.New(apperrors.KindInternal, "failed to delete saved search")
+	}
+	return nil
+}
+
+// Run executes a saved search now, with the same paging as the list
+// endpoint.
+func (s *Service) Run(ctx context.Context, id string, limit, offset int) ([]*snippets.Snippet, error) {
+	if s.Store == nil || s.Searcher == nil {
+		return nil, apperrors.New(apperrors.KindInternal, "saved searches store not configured")
+	}
+	saved, err := s.load(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	input := saved.Query.ListInput()
+	input.Limit = limit
+	input.Offset = offset
+	return s.Searcher.List(ctx, input)
+}
+
+// load returns a saved search of the requester. Other users' searches are
+// reported as missing.
+func (s *Service) load(ctx context.Context, id string) (*SavedSearch, error) {
+	ownerID, ok := identity.UserID(ctx)
+	if !ok || strings.TrimSpace(ownerID) == "" {
+		return nil, apperrors.New(apperrors.KindUnauthorized, "unauthorized")
+	}
+
+	saved, err := s.Store.GetByID(ctx, strings.TrimSpace(id))
+	if err != nil {
+		if IsNotFound(err) {
+			return nil, apperrors.New(apperrors.KindNotFound, "not found")
+		}
+		return nil, apperrors.New(apperrors.KindInternal, "failed to load saved search")
+	}
+	if saved.OwnerID != ownerID {
+		return nil, apperrors.New(apperrors.KindNotFound, "not found")
+	}
+	return saved, nil
+}
+
+// apply validates input and copies it onto saved. The query is checked by
+// running it once, so that a search the owner may not run is never stored.
+func (s *Service) apply(ctx context.Context, saved *SavedSearch, input Input) error {
+	name := strings.TrimSpace(input.Name)
+	if name == "" {
+		return apperrors.New(apperrors.KindInvalidInput, "name is required")
+	}
+	if len(name) > MaxNameLength {
+		return apperrors.New(apperrors.KindInvalidInput, "name is too long")
+	}
+
+	notify := Notify(strings.ToLower(strings.TrimSpace(string(input.Notify))))
+	if notify == "" {
+		notify = NotifyNone
+	}
+	if !notify.Valid() {
+		return apperrors.New(apperrors.KindInvalidInput, "notify must be none, inbox or webhook")
+	}
+
+	webhookURL := ""
+	if notify == NotifyWebhook {
+		var err error
+		webhookURL, err = normalizeWebhookURL(input.WebhookURL)
+		if err != nil {
+			return err
+		}
+	}
+
+	query := Query{
+		Q:          strings.TrimSpace(input.Query.Q),
+		Mode:       strings.TrimSpace(input.Query.Mode),
+		Creator:    strings.TrimSpace(input.Query.Creator),
+		Language:   strings.TrimSpace(input.Query.Language),
+		Tag:        strings.TrimSpace(input.Query.Tag),
+		Visibility: strings.TrimSpace(input.Query.Visibility),
+	}
+	check := query.ListInput()
+	check.Limit = 1
+	if _, err := s.Searcher.List(ctx, check); err != nil {
+		var appErr *apperrors.Error
+		if !errors.As(err, &appErr) || appErr.Kind != apperrors.KindNotFound {
+			return err
+		}
+	}
+
+	saved.Name = name
+	saved.Query = query
+	saved.Notify = notify
+	saved.WebhookURL = webhookURL
+	switch {
+	case notify != NotifyWebhook:
+		saved.WebhookSecret = ""
+	case saved.WebhookSecret == "":
+		saved.WebhookSecret = "whsec_" + internal.RandomHex(24)
+	}
+	return nil
+}
+
+func normalizeWebhookURL(raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return "", apperrors.New(apperrors.KindInvalidInput, "webhook_url is required for webhook notifications")
+	}
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" || u.User != nil {
+		return "", apperrors.New(apperrors.KindInvalidInput, "webhook_url must be an http or https URL")
+	}
+	return u.String(), nil
+}
+
+func (s *Service) now() time.Time {
+	if s.Now != nil {
+		return s.Now().UTC()
+	}
+	return time.Now().UTC()
+}
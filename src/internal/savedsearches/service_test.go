@@ -0,0 +1,327 @@
+package savedsearches
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PabloPavan/sniply_api/internal/apperrors"
+	"github.com/PabloPavan/sniply_api/internal/identity"
+	"github.com/PabloPavan/sniply_api/internal/notifications"
+	"github.com/PabloPavan/sniply_api/internal/snippets"
+	"github.com/PabloPavan/sniply_api/internal/users"
+)
+
+type storeStub struct {
+	searches map[string]*SavedSearch
+}
+
+func newStoreStub() *storeStub {
+	return &storeStub{searches: map[string]*SavedSearch{}}
+}
+
+func (s *storeStub) Create(ctx context.Context, saved *SavedSearch) error {
+	cp := *saved
+	s.searches[saved.ID] = &cp
+	return nil
+}
+
+func (s *storeStub) GetByID(ctx context.Context, id string) (*SavedSearch, error) {
+	saved, ok := s.searches[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	cp := *saved
+	return &cp, nil
+}
+
+func (s *storeStub) ListByOwner(ctx context.Context, ownerID string) ([]*SavedSearch, error) {
+	var out []*SavedSearch
+	for _, saved := range s.searches {
+		if saved.OwnerID == ownerID {
+			cp := *saved
+			out = append(out, &cp)
+		}
+	}
+	return out, nil
+}
+
+func (s *storeStub) Update(ctx context.Context, saved *SavedSearch) error {
+	cp := *saved
+	s.searches[saved.ID] = &cp
+	return nil
+}
+
+func (s *storeStub) Delete(ctx context.Context, id string) error {
+	delete(s.searches, id)
+	return nil
+}
+
+func (s *storeStub) ListDue(ctx context.Context, before time.Time, limit int) ([]*SavedSearch, error) {
+	var out []*SavedSearch
+	for _, saved := range s.searches {
+		if saved.Notify != NotifyNone && !saved.CheckedAt.After(before) {
+			cp := *saved
+			out = append(out, &cp)
+		}
+	}
+	return out, nil
+}
+
+func (s *storeStub) MoveCheckedAt(ctx context.Context, id string, from, to time.Time) (bool, error) {
+	saved, ok := s.searches[id]
+	if !ok || !saved.CheckedAt.Equal(from) {
+		return false, nil
+	}
+	saved.CheckedAt = to
+	return true, nil
+}
+
+// searcherStub returns the configured snippets and records the inputs and
+// requesters it was called with.
+type searcherStub struct {
+	result     []*snippets.Snippet
+	err        error
+	inputs     []snippets.ListInput
+	requesters []string
+}
+
+func (s *searcherStub) List(ctx context.Context, input snippets.ListInput) ([]*snippets.Snippet, error) {
+	userID, _ := identity.UserID(ctx)
+	s.inputs = append(s.inputs, input)
+	s.requesters = append(s.requesters, userID)
+	if s.err != nil {
+		return nil, s.err
+	}
+	if len(s.result) == 0 {
+		return nil, apperrors.New(apperrors.KindNotFound, "not found")
+	}
+	return s.result, nil
+}
+
+type usersStub struct{}
+
+func (usersStub) GetByID(ctx context.Context, id string) (*users.User, error) {
+	return &users.User{ID: id, Role: users.RoleUser}, nil
+}
+
+type inboxStub struct {
+	sent []*notifications.Notification
+}
+
+func (s *inboxStub) Notify(ctx context.Context, n *notifications.Notification) error {
+	s.sent = append(s.sent, n)
+	return nil
+}
+
+type webhookStub struct {
+	err   error
+	calls int
+}
+
+func (s *webhookStub) Send(ctx context.Context, url, secret, event string, payload []byte) error {
+	s.calls++
+	return s.err
+}
+
+func TestServiceOwnerOnly(t *testing.T) {
+	store := newStoreStub()
+	svc := &Service{Store: store, Searcher: &searcherStub{}}
+	owner := identity.WithUser(context.Background(), "usr_owner", "user")
+	other := identity.WithUser(context.Background(), "usr_other", "admin")
+
+	saved, err := svc.Create(owner, Input{Name: " go ", Query: Query{Q: "lang:go"}})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if saved.Name != "go" || saved.Notify != NotifyNone || !strings.HasPrefix(saved.ID, "srch_") {
+		t.Fatalf("unexpected saved search: %+v", saved)
+	}
+
+	if _, err := svc.Get(other, saved.ID); err == nil {
+		t.Fatalf("expected other user to be refused")
+	} else {
+		assertKind(t, err, apperrors.KindNotFound)
+	}
+	assertKind(t, svc.Delete(other, saved.ID), apperrors.KindNotFound)
+
+	list, err := svc.List(other)
+	if err != nil || len(list) != 0 {
+		t.Fatalf("expected no searches for other user, got %d (%v)", len(list), err)
+	}
+
+	_, err = svc.Create(context.Background(), Input{Name: "x"})
+	assertKind(t, err, apperrors.KindUnauthorized)
+}
+
+func TestServiceRejectsInvalidQuery(t *testing.T) {
+	searcher := &searcherStub{err: apperrors.New(apperrors.KindInvalidInput, "unknown qualifier")}
+	svc := &Service{Store: newStoreStub(), Searcher: searcher}
+	ctx := identity.WithUser(context.Background(), "usr_owner", "user")
+
+	_, err := svc.Create(ctx, Input{Name: "bad", Query: Query{Q: "created:yesterday"}})
+	assertKind(t, err, apperrors.KindInvalidInput)
+
+	_, err = svc.Create(ctx, Input{Name: "bad", Notify: "email"})
+	assertKind(t, err, apperrors.KindInvalidInput)
+}
+
+func TestServiceWebhookSettings(t *testing.T) {
+	store := newStoreStub()
+	svc := &Service{Store: store, Searcher: &searcherStub{}}
+	ctx := identity.WithUser(context.Background(), "usr_owner", "user")
+
+	_, err := svc.Create(ctx, Input{Name: "hook", Notify: NotifyWebhook})
+	assertKind(t, err, apperrors.KindInvalidInput)
+	_, err = svc.Create(ctx, Input{Name: "hook", Notify: NotifyWebhook, WebhookURL: "ftp://example.com"})
+	assertKind(t, err, apperrors.KindInvalidInput)
+	_, err = svc.Create(ctx, Input{Name: "hook", Notify: NotifyWebhook, WebhookURL: "https://user:pw@example.com"})
+	assertKind(t, err, apperrors.KindInvalidInput)
+
+	saved, err := svc.Create(ctx, Input{Name: "hook", Notify: NotifyWebhook, WebhookURL: "https://example.com/hook"})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if !strings.HasPrefix(saved.WebhookSecret, "whsec_") {
+		t.Fatalf("expected webhook secret, got %q", saved.WebhookSecret)
+	}
+
+	updated, err := svc.Update(ctx, saved.ID, Input{Name: "hook", Notify: NotifyWebhook, WebhookURL: "https://example.com/other"})
+	if err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if updated.WebhookSecret != saved.WebhookSecret {
+		t.Fatalf("expected secret to be kept")
+	}
+
+	updated, err = svc.Update(ctx, saved.ID, Input{Name: "hook", Notify: NotifyInbox})
+	if err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if updated.WebhookURL != "" || updated.WebhookSecret != "" {
+		t.Fatalf("expected webhook settings to be cleared: %+v", updated)
+	}
+}
+
+func TestWorkerDeliversNewMatches(t *testing.T) {
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	checked := now.Add(-time.Hour)
+	store := newStoreStub()
+	store.searches["srch_1"] = &SavedSearch{
+		ID: "srch_1", OwnerID: "usr_owner", Name: "go", Query: Query{Q: "lang:go"},
+		Notify: NotifyInbox, CheckedAt: checked,
+	}
+	searcher := &searcherStub{result: []*snippets.Snippet{{ID: "snp_1", Name: "main.go"}}}
+	inbox := &inboxStub{}
+	worker := &Worker{
+		Store: store, Searcher: searcher, Users: usersStub{}, Inbox: inbox,
+		Now: func() time.Time { return now },
+	}
+
+	if err := worker.Check(context.Background()); err != nil {
+		t.Fatalf("check: %v", err)
+	}
+	if len(inbox.sent) != 1 || inbox.sent[0].UserID != "usr_owner" {
+		t.Fatalf("expected one notification for the owner, got %+v", inbox.sent)
+	}
+	if searcher.requesters[0] != "usr_owner" {
+		t.Fatalf("expected search to run as the owner, got %q", searcher.requesters[0])
+	}
+	in := searcher.inputs[0]
+	if !in.CreatedAfter.Equal(checked) || !in.CreatedBefore.Equal(now.Add(-settleDelay)) {
+		t.Fatalf("unexpected window: %v - %v", in.CreatedAfter, in.CreatedBefore)
+	}
+	if !store.searches["srch_1"].CheckedAt.Equal(now.Add(-settleDelay)) {
+		t.Fatalf("expected window to be claimed")
+	}
+
+	// The search is not due again until Every has passed.
+	if err := worker.Check(context.Background()); err != nil {
+		t.Fatalf("check: %v", err)
+	}
+	if len(inbox.sent) != 1 {
+		t.Fatalf("expected no second notification, got %d", len(inbox.sent))
+	}
+}
+
+func TestWorkerReleasesWindowOnFailedWebhook(t *testing.T) {
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	checked := now.Add(-time.Hour)
+	store := newStoreStub()
+	store.searches["srch_1"] = &SavedSearch{
+		ID: "srch_1", OwnerID: "usr_owner", Name: "go", Notify: NotifyWebhook,
+		WebhookURL: "https://example.com/hook", WebhookSecret: "whsec_x", CheckedAt: checked,
+	}
+	webhooks := &webhookStub{err: errors.New("connection refused")}
+	worker := &Worker{
+		Store:    store,
+		Searcher: &searcherStub{result: []*snippets.Snippet{{ID: "snp_1"}}},
+		Users:    usersStub{},
+		Webhooks: webhooks,
+		Now:      func() time.Time { return now },
+	}
+
+	if err := worker.Check(context.Background()); err != nil {
+		t.Fatalf("check: %v", err)
+	}
+	if webhooks.calls != 1 {
+		t.Fatalf("expected one delivery attempt, got %d", webhooks.calls)
+	}
+	if !store.searches["srch_1"].CheckedAt.Equal(checked) {
+		t.Fatalf("expected window to be released, checked_at=%v", store.searches["srch_1"].CheckedAt)
+	}
+
+	webhooks.err = nil
+	if err := worker.Check(context.Background()); err != nil {
+		t.Fatalf("check: %v", err)
+	}
+	if webhooks.calls != 2 || store.searches["srch_1"].CheckedAt.Equal(checked) {
+		t.Fatalf("expected the window to be retried and claimed")
+	}
+}
+
+func TestHTTPWebhookSignsPayload(t *testing.T) {
+	var gotSig, gotEvent string
+	var gotBody []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotSig = r.Header.Get(SignatureHeader)
+		gotEvent = r.Header.Get(EventHeader)
+		gotBody, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	payload := []byte(`{"saved_search_id":"srch_1"}`)
+	if err := NewHTTPWebhook(true).Send(context.Background(), srv.URL, "whsec_x", "saved_search.match", payload); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	if string(gotBody) != string(payload) || gotEvent != "saved_search.match" {
+		t.Fatalf("unexpected delivery: %s %q", gotBody, gotEvent)
+	}
+	if gotSig != Sign("whsec_x", payload) || !strings.HasPrefix(gotSig, "sha256=") {
+		t.Fatalf("unexpected signature %q", gotSig)
+	}
+
+	if err := NewHTTPWebhook(false).Send(context.Background(), srv.URL, "whsec_x", "saved_search.match", payload); err == nil {
+		t.Fatalf("expected loopback address to be refused")
+	}
+}
+
+func assertKind(t *testing.T, err error, kind apperrors.Kind) {
+	t.Helper()
+	if err == nil {
+		t.Fatalf("expected error kind %s", kind)
+	}
+	var appErr *apperrors.Error
+	if !errors.As(err, &appErr) {
+		t.Fatalf("expected app error, got: %v", err)
+	}
+	if appErr.Kind != kind {
+		t.Fatalf("unexpected kind: %s", appErr.Kind)
+	}
+}
@@ -0,0 +1,203 @@
+package savedsearches
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/PabloPavan/sniply_api/internal/apperrors"
+	"github.com/PabloPavan/sniply_api/internal/identity"
+	"github.com/PabloPavan/sniply_api/internal/notifications"
+	"github.com/PabloPavan/sniply_api/internal/telemetry"
+	"github.com/PabloPavan/sniply_api/internal/users"
+)
+
+// maxMatches caps how many new snippets one notification lists.
+const maxMatches = 50
+
+// settleDelay keeps the newest seconds out of a window: created_at is set
+// when an insert starts, so a snippet may commit after a window that
+// covers its timestamp was evaluated.
+const settleDelay = 5 * time.Second
+
+type UserLookup interface {
+	GetByID(ctx context.Context, id string) (*users.User, error)
+}
+
+type Inbox interface {
+	Notify(ctx context.Context, n *notifications.Notification) error
+}
+
+type WebhookSender interface {
+	Send(ctx context.Context, url, secret, event string, payload []byte) error
+}
+
+// Worker evaluates saved searches with notifications in the background and
+// reports the snippets created since the previous evaluation.
+type Worker struct {
+	Store    Store
+	Searcher Searcher
+	Users    UserLookup
+	Inbox    Inbox
+	Webhooks WebhookSender
+	// Interval is how often due searches are looked for; Every is the
+	// minimum time between two evaluations of one search.
+	Interval  time.Duration
+	Every     time.Duration
+	BatchSize int
+	Now       func() time.Time
+}
+
+func (w *Worker) Run(ctx context.Context) {
+	interval := w.Interval
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := w.Check(ctx); err != nil {
+				telemetry.LogError(ctx, "saved searches check failed",
+					telemetry.LogString("event", "saved_searches.check"),
+					telemetry.LogString("error", err.Error()),
+				)
+			}
+		}
+	}
+}
+
+// Check evaluates one batch of due searches. A search whose delivery fails
+// keeps its window and is retried on a later check.
+func (w *Worker) Check(ctx context.Context) error {
+	now := w.now().Add(-settleDelay).Truncate(time.Microsecond)
+	every := w.Every
+	if every <= 0 {
+		every = 15 * time.Minute
+	}
+	batch := w.BatchSize
+	if batch <= 0 {
+		batch = 100
+	}
+
+	due, err := w.Store.ListDue(ctx, now.Add(-every), batch)
+	if err != nil {
+		return err
+	}
+	for _, saved := range due {
+		if err := w.evaluate(ctx, saved, now); err != nil {
+			telemetry.LogError(ctx, "saved search evaluation failed",
+				telemetry.LogString("event", "saved_searches.evaluate"),
+				telemetry.LogString("saved_search.id", saved.ID),
+				telemetry.LogString("user.id", saved.OwnerID),
+				telemetry.LogString("error", err.Error()),
+			)
+		}
+	}
+	return nil
+}
+
+func (w *Worker) evaluate(ctx context.Context, saved *SavedSearch, to time.Time) error {
+	from := saved.CheckedAt
+	// Claim the window first so that two API instances never report it
+	// twice.
+	claimed, err := w.Store.MoveCheckedAt(ctx, saved.ID, from, to)
+	if err != nil || !claimed {
+		return err
+	}
+
+	owner, err := w.Users.GetByID(ctx, saved.OwnerID)
+	if err != nil {
+		return w.release(ctx, saved, from, to, fmt.Errorf("load owner: %w", err))
+	}
+
+	input := saved.Query.ListInput()
+	input.CreatedAfter = from
+	input.CreatedBefore = to
+	input.Limit = maxMatches
+	list, err := w.Searcher.List(identity.WithUser(ctx, owner.ID, string(owner.Role)), input)
+	if err != nil {
+		var appErr *apperrors.Error
+		if errors.As(err, &appErr) {
+			switch appErr.Kind {
+			case apperrors.KindNotFound:
+				return nil
+			case apperrors.KindInvalidInput, apperrors.KindForbidden:
+				// The search can no longer run, e.g. its creator was deleted
+				// or the owner lost access; retrying will not help.
+				return fmt.Errorf("search skipped: %w", err)
+			}
+		}
+		return w.release(ctx, saved, from, to, err)
+	}
+	if len(list) == 0 {
+		return nil
+	}
+
+	match := Match{SavedSearchID: saved.ID, Name: saved.Name, From: from, To: to}
+	for _, sn := range list {
+		match.Snippets = append(match.Snippets, MatchedSnippet{
+			ID:        sn.ID,
+			Name:      sn.Name,
+			Language:  sn.Language,
+			CreatorID: sn.CreatorID,
+			CreatedAt: sn.CreatedAt,
+		})
+	}
+	if err := w.deliver(ctx, saved, match); err != nil {
+		return w.release(ctx, saved, from, to, err)
+	}
+	return nil
+}
+
+func (w *Worker) deliver(ctx context.Context, saved *SavedSearch, match Match) error {
+	payload, err := json.Marshal(match)
+	if err != nil {
+		return err
+	}
+
+	switch saved.Notify {
+	case NotifyInbox:
+		if w.Inbox == nil {
+			return errors.New("inbox not configured")
+		}
+		title := fmt.Sprintf("%d new snippets match %q", len(match.Snippets), saved.Name)
+		if len(match.Snippets) == 1 {
+			title = fmt.Sprintf("1 new snippet matches %q", saved.Name)
+		}
+		return w.Inbox.Notify(ctx, &notifications.Notification{
+			UserID: saved.OwnerID,
+			Kind:   notifications.KindSavedSearchMatch,
+			Title:  title,
+			Data:   payload,
+		})
+	case NotifyWebhook:
+		if w.Webhooks == nil {
+			return errors.New("webhooks not configured")
+		}
+		return w.Webhooks.Send(ctx, saved.WebhookURL, saved.WebhookSecret, notifications.KindSavedSearchMatch, payload)
+	}
+	return nil
+}
+
+// release gives the window back so a later check retries it, and returns
+// cause.
+func (w *Worker) release(ctx context.Context, saved *SavedSearch, from, to time.Time, cause error) error {
+	if _, err := w.Store.MoveCheckedAt(ctx, saved.ID, to, from); err != nil {
+		return errors.Join(cause, err)
+	}
+	return cause
+}
+
+func (w *Worker) now() time.Time {
+	if w.Now != nil {
+		return w.Now().UTC()
+	}
+	return time.Now().UTC()
+}
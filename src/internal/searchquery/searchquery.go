@@ -0,0 +1,303 @@
+package searchquery
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Query is a parsed search string such as
+//
+//	lang:go tag:http -tag:deprecated author:usr_x created:>2025-01-01 "exact phrase" router
+//
+// Text holds the free words in their original order and Phrases the quoted
+// parts. CreatedAfter is inclusive and CreatedBefore exclusive; zero means
+// unbounded.
+type Query struct {
+	Text          string    `json:"text,omitempty"`
+	Phrases       []string  `json:"phrases,omitempty"`
+	Language      string    `json:"language,omitempty"`
+	Symbols       []string  `json:"symbols,omitempty"`
+	Tags          []string  `json:"tags,omitempty"`
+	ExcludeTags   []string  `json:"exclude_tags,omitempty"`
+	Author        string    `json:"author,omitempty"`
+	Visibility    string    `json:"visibility,omitempty"`
+	CreatedAfter  time.Time `json:"created_after,omitzero"`
+	CreatedBefore time.Time `json:"created_before,omitzero"`
+}
+
+// Error reports a qualifier that could not be used.
+type Error struct {
+	Qualifier string
+	Message   string
+}
+
+func (e *Error) Error() string {
+	if e.Qualifier == "" {
+		return e.Message
+	}
+	return fmt.Sprintf("%s: %s", e.Qualifier, e.Message)
+}
+
+const dateLayout = "2006-01-02"
+
+// qualifiers maps every accepted key, aliases included, to its canonical
+// name.
+var qualifiers = map[string]string{
+	"lang":     "lang",
+	"language": "lang",
+	"symbol":   "symbol",
+	"sym":      "symbol",
+	"tag":      "tag",
+	"tags":     "tag",
+	"author":   "author",
+	"creator":  "author",
+	"is":       "is",
+	"created":  "created",
+}
+
+// Qualifiers returns the canonical qualifier names, for help texts.
+func Qualifiers() []string {
+	seen := map[string]struct{}{}
+	var out []string
+	for _, name := range qualifiers {
+		if _, ok := seen[name]; !ok {
+			seen[name] = struct{}{}
+			out = append(out, name)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Parse splits q into qualifiers, quoted phrases and free words. A word is a
+// qualifier when the part before the first colon is a known key, optionally
+// negated with "-"; anything else, URLs and C++ scopes included, is text.
+// Values may be quoted: tag:"two words".
+func Parse(q string) (Query, error) {
+	tokens, err := tokenize(q)
+	if err != nil {
+		return Query{}, err
+	}
+
+	var query Query
+	var text []string
+	for _, tok := range tokens {
+		if tok.quoted {
+			if tok.value != "" {
+				query.Phrases = append(query.Phrases, tok.value)
+			}
+			continue
+		}
+		if tok.key == "" {
+			text = append(text, tok.value)
+			continue
+		}
+		if err := query.apply(tok); err != nil {
+			return Query{}, err
+		}
+	}
+	query.Text = strings.Join(text, " ")
+	return query, nil
+}
+
+func (q *Query) apply(tok token) error {
+	name := qualifiers[strings.ToLower(tok.key)]
+	value := tok.value
+	if value == "" {
+		return &Error{Qualifier: name, Message: "a value is required, e.g. " + example(name)}
+	}
+	if tok.negated && name != "tag" {
+		return &Error{Qualifier: name, Message: "only tag can be negated"}
+	}
+
+	switch name {
+	case "lang":
+		if q.Language != "" && !strings.EqualFold(q.Language, value) {
+			return &Error{Qualifier: name, Message: "only one language can be given"}
+		}
+		q.Language = value
+	case "symbol":
+		q.Symbols = append(q.Symbols, strings.ToLower(value))
+	case "tag":
+		tag := strings.ToLower(value)
+		if tok.negated {
+			q.ExcludeTags = append(q.ExcludeTags, tag)
+		} else {
+			q.Tags = append(q.Tags, tag)
+		}
+	case "author":
+		if q.Author != "" && q.Author != value {
+			return &Error{Qualifier: name, Message: "only one author can be given"}
+		}
+		q.Author = value
+	case "is":
+		switch strings.ToLower(value) {
+		case "public", "private":
+			v := strings.ToLower(value)
+			if q.Visibility != "" && q.Visibility != v {
+				return &Error{Qualifier: name, Message: "is:public and is:private cannot be combined"}
+			}
+			q.Visibility = v
+		case "starred":
+			return &Error{Qualifier: name, Message: "starred is not supported: snippets cannot be starred yet"}
+		default:
+			return &Error{Qualifier: name, Message: fmt.Sprintf("unknown value %q, use public or private", value)}
+		}
+	case "created":
+		return q.applyCreated(value)
+	}
+	return nil
+}
+
+// applyCreated accepts >D, >=D, <D, <=D, D (that day) and D..D (both days
+// included), with dates as YYYY-MM-DD in UTC.
+func (q *Query) applyCreated(value string) error {
+	after, before := q.CreatedAfter, q.CreatedBefore
+
+	if from, to, ok := strings.Cut(value, ".."); ok {
+		start, err := parseDate(from)
+		if err != nil {
+			return err
+		}
+		end, err := parseDate(to)
+		if err != nil {
+			return err
+		}
+		if end.Before(start) {
+			return &Error{Qualifier: "created", Message: "the range ends before it starts"}
+		}
+		after, before = later(after, start), earlier(before, end.AddDate(0, 0, 1))
+	} else {
+		op := ""
+		for _, prefix := range []string{">=", "<=", ">", "<"} {
+			if strings.HasPrefix(value, prefix) {
+				op, value = prefix, value[len(prefix):]
+				break
+			}
+		}
+		day, err := parseDate(value)
+		if err != nil {
+			return err
+		}
+		switch op {
+		case ">":
+			after = later(after, day.AddDate(0, 0, 1))
+		case ">=":
+			after = later(after, day)
+		case "<":
+			before = earlier(before, day)
+		case "<=":
+			before = earlier(before, day.AddDate(0, 0, 1))
+		default:
+			after, before = later(after, day), earlier(before, day.AddDate(0, 0, 1))
+		}
+	}
+
+	if !after.IsZero() && !before.IsZero() && !after.Before(before) {
+		return &Error{Qualifier: "created", Message: "the date conditions do not overlap"}
+	}
+	q.CreatedAfter, q.CreatedBefore = after, before
+	return nil
+}
+
+func parseDate(s string) (time.Time, error) {
+	t, err := time.Parse(dateLayout, s)
+	if err != nil {
+		return time.Time{}, &Error{Qualifier: "created", Message: fmt.Sprintf("invalid date %q, use YYYY-MM-DD as in %s", s, example("created"))}
+	}
+	return t, nil
+}
+
+func later(a, b time.Time) time.Time {
+	if a.IsZero() || b.After(a) {
+		return b
+	}
+	return a
+}
+
+func earlier(a, b time.Time) time.Time {
+	if a.IsZero() || b.Before(a) {
+		return b
+	}
+	return a
+}
+
+func example(name string) string {
+	switch name {
+	case "lang":
+		return "lang:go"
+	case "symbol":
+		return "symbol:ServeHTTP"
+	case "tag":
+		return "tag:http"
+	case "author":
+		return "author:usr_123"
+	case "is":
+		return "is:public"
+	default:
+		return "created:>2025-01-01"
+	}
+}
+
+type token struct {
+	key     string
+	value   string
+	negated bool
+	quoted  bool
+}
+
+func tokenize(q string) ([]token, error) {
+	var tokens []token
+	r := []rune(q)
+	for i := 0; i < len(r); {
+		if r[i] == ' ' || r[i] == '\t' || r[i] == '\n' || r[i] == '\r' {
+			i++
+			continue
+		}
+
+		if r[i] == '"' {
+			end := indexRune(r, i+1, '"')
+			if end < 0 {
+				return nil, &Error{Message: "unterminated quote"}
+			}
+			tokens = append(tokens, token{value: strings.TrimSpace(string(r[i+1 : end])), quoted: true})
+			i = end + 1
+			continue
+		}
+
+		start := i
+		for i < len(r) && r[i] != ' ' && r[i] != '\t' && r[i] != '\n' && r[i] != '\r' && r[i] != '"' {
+			i++
+		}
+		word := string(r[start:i])
+
+		key, value, ok := strings.Cut(word, ":")
+		negated := strings.HasPrefix(key, "-")
+		if _, known := qualifiers[strings.ToLower(strings.TrimPrefix(key, "-"))]; !ok || !known {
+			tokens = append(tokens, token{value: word})
+			continue
+		}
+
+		if value == "" && i < len(r) && r[i] == '"' {
+			end := indexRune(r, i+1, '"')
+			if end < 0 {
+				return nil, &Error{Message: "unterminated quote"}
+			}
+			value = strings.TrimSpace(string(r[i+1 : end]))
+			i = end + 1
+		}
+		tokens = append(tokens, token{key: strings.TrimPrefix(key, "-"), value: value, negated: negated})
+	}
+	return tokens, nil
+}
+
+func indexRune(r []rune, from int, c rune) int {
+	for i := from; i < len(r); i++ {
+		if r[i] == c {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,79 @@
+package searchquery
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func date(s string) time.Time {
+	t, _ := time.Parse(dateLayout, s)
+	return t
+}
+
+func TestParse(t *testing.T) {
+	got, err := Parse(`  lang:Go tag:HTTP -tag:deprecated author:usr_x "exact  phrase" router created:>2025-01-01 symbol:ServeHTTP https://x.io tag:"two words"`)
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+	want := Query{
+		Text:         "router https://x.io",
+		Phrases:      []string{"exact  phrase"},
+		Language:     "Go",
+		Symbols:      []string{"servehttp"},
+		Tags:         []string{"http", "two words"},
+		ExcludeTags:  []string{"deprecated"},
+		Author:       "usr_x",
+		CreatedAfter: date("2025-01-02"),
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %+v\nwant %+v", got, want)
+	}
+}
+
+func TestParseCreated(t *testing.T) {
+	cases := map[string][2]string{
+		"created:>=2025-01-01":                    {"2025-01-01", ""},
+		"created:<2025-01-01":                     {"", "2025-01-01"},
+		"created:<=2025-01-01":                    {"", "2025-01-02"},
+		"created:2025-01-01":                      {"2025-01-01", "2025-01-02"},
+		"created:2025-01-01..2025-01-31":          {"2025-01-01", "2025-02-01"},
+		"created:>2025-01-01 created:<2025-03-01": {"2025-01-02", "2025-03-01"},
+	}
+	for q, want := range cases {
+		got, err := Parse(q)
+		if err != nil {
+			t.Fatalf("%s: %v", q, err)
+		}
+		if !got.CreatedAfter.Equal(date(want[0])) || !got.CreatedBefore.Equal(date(want[1])) {
+			t.Fatalf("%s: got %v..%v", q, got.CreatedAfter, got.CreatedBefore)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	cases := map[string]string{
+		"lang:":             "lang: a value is required",
+		"lang:go lang:rust": "only one language",
+		"is:starred":        "cannot be starred",
+		"is:archived":       "use public or private",
+		"-lang:go":          "only tag can be negated",
+		"created:yesterday": "use YYYY-MM-DD",
+		"created:>2025-02-01 created:<2025-01-01": "do not overlap",
+		"created:2025-02-01..2025-01-01":          "ends before it starts",
+		`tag:"open`:                               "unterminated quote",
+		`"open`:                                   "unterminated quote",
+	}
+	for q, msg := range cases {
+		_, err := Parse(q)
+		var qErr *Error
+		if !errors.As(err, &qErr) || !strings.Contains(qErr.Error(), msg) {
+			t.Fatalf("%q: expected error containing %q, got %v", q, msg, err)
+		}
+	}
+	if _, err := Parse("lang:go lang:GO"); err != nil {
+		t.Fatalf("repeated language should be accepted: %v", err)
+	}
+}
@@ -0,0 +1,70 @@
+package secrets
+
+import (
+	"context"
+
+	"github.com/PabloPavan/sniply_api/internal/db"
+)
+
+type Repository struct {
+	base *db.Base
+}
+
+func NewRepository(base *db.Base) *Repository {
+	return &Repository{base: base}
+}
+
+const (
+	sqlFindingsDelete = `DELETE FROM snippet_secret_findings
+		WHERE snippet_id = $1;`
+
+	sqlFindingInsert = `INSERT INTO snippet_secret_findings (snippet_id, rule_id, line, col, preview, redacted)
+		VALUES ($1, $2, $3, $4, $5, $6);`
+
+	sqlFindingsBySnippet = `SELECT rule_id, line, col, preview, redacted
+		FROM snippet_secret_findings
+		WHERE snippet_id = $1
+		ORDER BY line, col;`
+)
+
+// ReplaceForSnippet stores the findings of the latest save, dropping the
+// previous ones. The snippet repository calls it in the transaction that
+// saves the snippet, so findings never disagree with the content.
+func ReplaceForSnippet(ctx context.Context, q db.Queryer, snippetID string, findings []Finding) error {
+	if _, err := q.Exec(ctx, sqlFindingsDelete, snippetID); err != nil {
+		return err
+	}
+	for _, f := range findings {
+		if _, err := q.Exec(ctx, sqlFindingInsert, snippetID, f.RuleID, f.Line, f.Column, f.Preview, f.Redacted); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *Repository) ListForSnippet(ctx context.Context, snippetID string) ([]Finding, error) {
+	ctx, cancel := r.base.WithTimeout(ctx)
+	defer cancel()
+
+	rows, err := r.base.Q().Query(ctx, sqlFindingsBySnippet, snippetID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	findings := make([]Finding, 0)
+	for rows.Next() {
+		var f Finding
+		if err := rows.Scan(&f.RuleID, &f.Line, &f.Column, &f.Preview, &f.Redacted); err != nil {
+			return nil, err
+		}
+		f.Description = describe(f.RuleID)
+		findings = append(findings, f)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return findings, nil
+}
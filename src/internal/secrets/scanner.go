@@ -0,0 +1,226 @@
+package secrets
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+type Policy string
+
+const (
+	PolicyOff    Policy = "off"
+	PolicyWarn   Policy = "warn"
+	PolicyBlock  Policy = "block"
+	PolicyRedact Policy = "redact"
+)
+
+func ParsePolicy(s string) (Policy, error) {
+	switch p := Policy(strings.ToLower(strings.TrimSpace(s))); p {
+	case PolicyOff, PolicyWarn, PolicyBlock, PolicyRedact:
+		return p, nil
+	case "":
+		return PolicyBlock, nil
+	default:
+		return "", fmt.Errorf("invalid secret scan policy %q", s)
+	}
+}
+
+// DefaultEntropy is the minimum Shannon entropy, in bits per character, for
+// rules that only fire on random-looking values.
+const DefaultEntropy = 3.5
+
+type Rule struct {
+	ID          string
+	Description string
+	Pattern     *regexp.Regexp
+	// Group selects the submatch holding the secret; 0 means the whole match.
+	Group int
+	// MinEntropy, when set, discards matches that look like words rather
+	// than generated credentials.
+	MinEntropy float64
+}
+
+type Finding struct {
+	RuleID      string `json:"rule_id"`
+	Description string `json:"description"`
+	Line        int    `json:"line"`
+	Column      int    `json:"column"`
+	Preview     string `json:"preview"`
+	Redacted    bool   `json:"redacted,omitempty"`
+
+	start int
+	end   int
+}
+
+var DefaultRules = []Rule{
+	{
+		ID:          "aws-access-key-id",
+		Description: "AWS access key ID",
+		Pattern:     regexp.MustCompile(`\b(?:AKIA|ASIA|AGPA|AIDA|AROA|ANPA)[0-9A-Z]{16}\b`),
+	},
+	{
+		ID:          "aws-secret-access-key",
+		Description: "AWS secret access key",
+		Pattern:     regexp.MustCompile(`(?i)aws_?secret_?(?:access_?)?key["']?\s*[:=]\s*["']?([A-Za-z0-9/+=]{40})\b`),
+		Group:       1,
+	},
+	{
+		ID:          "github-token",
+		Description: "GitHub token",
+		Pattern:     regexp.MustCompile(`\b(?:gh[pousr]_[A-Za-z0-9]{36,255}|github_pat_[A-Za-z0-9_]{22,255})\b`),
+	},
+	{
+		ID:          "slack-token",
+		Description: "Slack token",
+		Pattern:     regexp.MustCompile(`\bxox[abposr]-[A-Za-z0-9-]{10,}`),
+	},
+	{
+		ID:          "slack-webhook",
+		Description: "Slack incoming webhook URL",
+		Pattern:     regexp.MustCompile(`https://hooks\.slack\.com/services/T[A-Za-z0-9_]+/B[A-Za-z0-9_]+/[A-Za-z0-9_]+`),
+	},
+	{
+		ID:          "private-key",
+		Description: "Private key",
+		Pattern:     regexp.MustCompile(`(?s)-----BEGIN [A-Z ]*PRIVATE KEY(?: BLOCK)?-----.*?(?:-----END [A-Z ]*PRIVATE KEY(?: BLOCK)?-----|\z)`),
+	},
+	{
+		ID:          "jwt",
+		Description: "JSON Web Token",
+		Pattern:     regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{8,}\.eyJ[A-Za-z0-9_-]{8,}\.[A-Za-z0-9_-]{16,}`),
+	},
+	{
+		ID:          "sniply-api-key",
+		Description: "Sniply API key",
+		Pattern:     regexp.MustCompile(`\bsk_[0-9a-f]{64}\b`),
+	},
+	{
+		ID:          "generic-secret",
+		Description: "High-entropy value assigned to a secret-like name",
+		Pattern:     regexp.MustCompile(`(?i)\b[\w.-]*(?:secret|token|passw(?:or)?d|api_?key|access_?key|auth_?key|client_?secret)[\w.-]*["']?\s*[:=]\s*["']?([A-Za-z0-9/+_=.~-]{16,})`),
+		Group:       1,
+		MinEntropy:  DefaultEntropy,
+	},
+}
+
+func describe(ruleID string) string {
+	for _, r := range DefaultRules {
+		if r.ID == ruleID {
+			return r.Description
+		}
+	}
+	return ruleID
+}
+
+type Scanner struct {
+	Rules []Rule
+}
+
+func NewScanner() *Scanner {
+	return &Scanner{Rules: DefaultRules}
+}
+
+// Scan returns the findings in content ordered by position. Overlapping
+// matches are reported once, by the first rule that found them.
+func (s *Scanner) Scan(content string) []Finding {
+	var findings []Finding
+	for _, rule := range s.Rules {
+		for _, m := range rule.Pattern.FindAllStringSubmatchIndex(content, -1) {
+			start, end := m[0], m[1]
+			if rule.Group > 0 && len(m) > 2*rule.Group+1 && m[2*rule.Group] >= 0 {
+				start, end = m[2*rule.Group], m[2*rule.Group+1]
+			}
+			if rule.MinEntropy > 0 && Entropy(content[start:end]) < rule.MinEntropy {
+				continue
+			}
+			if overlaps(findings, start, end) {
+				continue
+			}
+			line, col := position(content, start)
+			findings = append(findings, Finding{
+				RuleID:      rule.ID,
+				Description: rule.Description,
+				Line:        line,
+				Column:      col,
+				Preview:     preview(content[start:end]),
+				start:       start,
+				end:         end,
+			})
+		}
+	}
+	sort.Slice(findings, func(i, j int) bool { return findings[i].start < findings[j].start })
+	return findings
+}
+
+// Redact replaces every finding in content with a marker naming its rule.
+// findings must come from scanning the same content.
+func Redact(content string, findings []Finding) string {
+	if len(findings) == 0 {
+		return content
+	}
+	sorted := make([]Finding, len(findings))
+	copy(sorted, findings)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].start < sorted[j].start })
+
+	var b strings.Builder
+	last := 0
+	for _, f := range sorted {
+		if f.start < last || f.end > len(content) {
+			continue
+		}
+		b.WriteString(content[last:f.start])
+		b.WriteString("[REDACTED:" + f.RuleID + "]")
+		last = f.end
+	}
+	b.WriteString(content[last:])
+	return b.String()
+}
+
+// Entropy is the Shannon entropy of s in bits per character.
+func Entropy(s string) float64 {
+	if s == "" {
+		return 0
+	}
+	counts := make(map[rune]int)
+	total := 0
+	for _, r := range s {
+		counts[r]++
+		total++
+	}
+	var h float64
+	for _, c := range counts {
+		p := float64(c) / float64(total)
+		h -= p * math.Log2(p)
+	}
+	return h
+}
+
+func overlaps(findings []Finding, start, end int) bool {
+	for _, f := range findings {
+		if start < f.end && f.start < end {
+			return true
+		}
+	}
+	return false
+}
+
+func position(content string, offset int) (int, int) {
+	before := content[:offset]
+	line := strings.Count(before, "\n") + 1
+	col := offset - strings.LastIndex(before, "\n")
+	return line, col
+}
+
+// preview keeps just enough of the secret to recognise it.
+func preview(secret string) string {
+	if i := strings.IndexByte(secret, '\n'); i >= 0 {
+		secret = secret[:i]
+	}
+	if len(secret) <= 8 {
+		return strings.Repeat("*", len(secret))
+	}
+	return secret[:4] + strings.Repeat("*", 8)
+}
@@ -2,10 +2,14 @@ package session
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
+	"sort"
 	"time"
 
 	"github.com/PabloPavan/sniply_api/internal"
+	"github.com/PabloPavan/sniply_api/internal/identity"
 )
 
 var ErrNotFound = errors.New("session not found")
@@ -18,12 +22,57 @@ type Session struct {
 	CreatedAt       time.Time `json:"created_at"`
 	LastRefreshedAt time.Time `json:"last_refreshed_at"`
 	ExpiresAt       time.Time `json:"expires_at"`
+
+	// UserAgent is the client that logged in; IP and LastSeenAt follow its
+	// latest requests, updated at most every Manager.TouchEvery.
+	UserAgent  string    `json:"user_agent,omitempty"`
+	IP         string    `json:"ip,omitempty"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+
+	// Epoch is the user epoch Role was read at; see Manager.BumpEpoch.
+	Epoch int64 `json:"epoch,omitempty"`
+
+	// A pending session is a login waiting for its second factor; it does
+	// not authenticate requests. Enroll is set when the user must first set
+	// a second factor up.
+	Pending bool `json:"pending,omitempty"`
+	Enroll  bool `json:"enroll,omitempty"`
 }
 
+// DefaultPendingTTL is how long a login may wait for its second factor.
+const DefaultPendingTTL = 5 * time.Minute
+
+// DefaultTouchEvery is how stale LastSeenAt may get before a request
+// writes it back.
+const DefaultTouchEvery = time.Minute
+
+// maxUserAgent caps the stored User-Agent header.
+const maxUserAgent = 256
+
 type Store interface {
 	Set(ctx context.Context, id string, s Session, ttl time.Duration) error
+	// Update writes back an existing session and returns ErrNotFound when
+	// it is gone, so a request still in flight cannot restore a revoked
+	// session.
+	Update(ctx context.Context, id string, s Session, ttl time.Duration) error
 	Get(ctx context.Context, id string) (*Session, error)
 	Delete(ctx context.Context, id string) error
+	// ListUser returns the unexpired sessions of a user, pending ones
+	// included.
+	ListUser(ctx context.Context, userID string) ([]Session, error)
+	// UserEpoch is 0 until BumpUserEpoch first moves it forward.
+	UserEpoch(ctx context.Context, userID string) (int64, error)
+	BumpUserEpoch(ctx context.Context, userID string) error
+	// IncrAttempts atomically counts an attempt against the session id and
+	// returns the count so far; the counter lives for ttl.
+	IncrAttempts(ctx context.Context, id string, ttl time.Duration) (int64, error)
+}
+
+// PublicID identifies a session to its user without revealing the session
+// ID, which is the cookie secret.
+func PublicID(id string) string {
+	sum := sha256.Sum256([]byte(id))
+	return hex.EncodeToString(sum[:8])
 }
 
 type Manager struct {
@@ -32,6 +81,8 @@ type Manager struct {
 	MaxAge        time.Duration
 	RefreshBefore time.Duration
 	IDBytes       int
+	PendingTTL    time.Duration
+	TouchEvery    time.Duration
 }
 
 func (m *Manager) Create(ctx context.Context, userID, role string) (*Session, error) {
@@ -46,6 +97,16 @@ func (m *Manager) Create(ctx context.Context, userID, role string) (*Session, er
 
 	now := time.Now()
 	exp := now.Add(m.TTL)
+	// A session starts at the user's current epoch, so that it only reloads
+	// the user after a later change.
+	epoch, err := m.Store.UserEpoch(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	userAgent, ip := identity.Client(ctx)
+	if len(userAgent) > maxUserAgent {
+		userAgent = userAgent[:maxUserAgent]
+	}
 	s := Session{
 		ID:              "ses_" + internal.RandomHex(idBytes),
 		UserID:          userID,
@@ -54,6 +115,10 @@ func (m *Manager) Create(ctx context.Context, userID, role string) (*Session, er
 		CreatedAt:       now,
 		LastRefreshedAt: now,
 		ExpiresAt:       exp,
+		UserAgent:       userAgent,
+		IP:              ip,
+		LastSeenAt:      now,
+		Epoch:           epoch,
 	}
 
 	if err := m.Store.Set(ctx, s.ID, s, m.TTL); err != nil {
@@ -62,6 +127,51 @@ func (m *Manager) Create(ctx context.Context, userID, role string) (*Session, er
 	return &s, nil
 }
 
+// CreatePending stores a login waiting for its second factor.
+func (m *Manager) CreatePending(ctx context.Context, userID, role string, enroll bool) (*Session, error) {
+	if m.Store == nil {
+		return nil, errors.New("session store not configured")
+	}
+
+	ttl := m.PendingTTL
+	if ttl <= 0 {
+		ttl = DefaultPendingTTL
+	}
+	epoch, err := m.Store.UserEpoch(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	s := Session{
+		ID:              "pnd_" + internal.RandomHex(32),
+		UserID:          userID,
+		Role:            role,
+		CreatedAt:       now,
+		LastRefreshedAt: now,
+		ExpiresAt:       now.Add(ttl),
+		Pending:         true,
+		Enroll:          enroll,
+		Epoch:           epoch,
+	}
+
+	if err := m.Store.Set(ctx, s.ID, s, ttl); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// Save writes back a changed session, keeping its expiry.
+func (m *Manager) Save(ctx context.Context, sess *Session) error {
+	if m.Store == nil {
+		return errors.New("session store not configured")
+	}
+	ttl := time.Until(sess.ExpiresAt)
+	if ttl <= 0 {
+		return ErrNotFound
+	}
+	return m.Store.Update(ctx, sess.ID, *sess, ttl)
+}
+
 func (m *Manager) Get(ctx context.Context, id string) (*Session, error) {
 	if m.Store == nil {
 		return nil, errors.New("session store not configured")
@@ -88,6 +198,81 @@ func (m *Manager) Delete(ctx context.Context, id string) error {
 	return m.Store.Delete(ctx, id)
 }
 
+// CountAttempt counts one attempt at completing a pending session and
+// returns how many were made, this one included. Parallel requests each get
+// their own count.
+func (m *Manager) CountAttempt(ctx context.Context, sess *Session) (int, error) {
+	if m.Store == nil {
+		return 0, errors.New("session store not configured")
+	}
+	ttl := time.Until(sess.ExpiresAt)
+	if ttl <= 0 {
+		return 0, ErrNotFound
+	}
+	n, err := m.Store.IncrAttempts(ctx, sess.ID, ttl)
+	return int(n), err
+}
+
+// BumpEpoch marks what the sessions of a user know about the user, such as
+// the role, as stale; they read it again on their next request.
+func (m *Manager) BumpEpoch(ctx context.Context, userID string) error {
+	if m.Store == nil {
+		return errors.New("session store not configured")
+	}
+	return m.Store.BumpUserEpoch(ctx, userID)
+}
+
+func (m *Manager) Epoch(ctx context.Context, userID string) (int64, error) {
+	if m.Store == nil {
+		return 0, errors.New("session store not configured")
+	}
+	return m.Store.UserEpoch(ctx, userID)
+}
+
+// List returns the sessions of a user that are still valid, newest first.
+func (m *Manager) List(ctx context.Context, userID string) ([]Session, error) {
+	if m.Store == nil {
+		return nil, errors.New("session store not configured")
+	}
+	list, err := m.Store.ListUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	out := make([]Session, 0, len(list))
+	for _, sess := range list {
+		m.ensureSessionTimestamps(&sess, now)
+		if m.MaxAge > 0 && now.After(sess.CreatedAt.Add(m.MaxAge)) {
+			continue
+		}
+		out = append(out, sess)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.After(out[j].CreatedAt) })
+	return out, nil
+}
+
+// DeleteUser ends every session of a user except keepID, which may be
+// empty.
+func (m *Manager) DeleteUser(ctx context.Context, userID, keepID string) error {
+	if m.Store == nil {
+		return errors.New("session store not configured")
+	}
+	list, err := m.Store.ListUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	for _, sess := range list {
+		if sess.ID == keepID {
+			continue
+		}
+		if err := m.Store.Delete(ctx, sess.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (m *Manager) Refresh(ctx context.Context, sess *Session) (*Session, bool, error) {
 	if m.Store == nil {
 		return nil, false, errors.New("session store not configured")
@@ -106,22 +291,48 @@ func (m *Manager) Refresh(ctx context.Context, sess *Session) (*Session, bool, e
 		return nil, false, ErrNotFound
 	}
 
+	_, ip := identity.Client(ctx)
 	if m.RefreshBefore > 0 {
 		if time.Until(sess.ExpiresAt) > m.RefreshBefore {
-			return sess, false, nil
+			return sess, false, m.touch(ctx, sess, ip, now)
 		}
 	}
 
 	exp := now.Add(m.TTL)
 	sess.ExpiresAt = exp
 	sess.LastRefreshedAt = now
+	sess.LastSeenAt = now
+	if ip != "" {
+		sess.IP = ip
+	}
 
-	if err := m.Store.Set(ctx, sess.ID, *sess, m.TTL); err != nil {
+	if err := m.Store.Update(ctx, sess.ID, *sess, m.TTL); err != nil {
 		return nil, false, err
 	}
 	return sess, true, nil
 }
 
+// touch records a request on a session that is not due for a refresh,
+// writing it back only when LastSeenAt is stale or the address changed.
+func (m *Manager) touch(ctx context.Context, sess *Session, ip string, now time.Time) error {
+	every := m.TouchEvery
+	if every <= 0 {
+		every = DefaultTouchEvery
+	}
+	if now.Sub(sess.LastSeenAt) < every && (ip == "" || ip == sess.IP) {
+		return nil
+	}
+	sess.LastSeenAt = now
+	if ip != "" {
+		sess.IP = ip
+	}
+	ttl := time.Until(sess.ExpiresAt)
+	if ttl <= 0 {
+		return nil
+	}
+	return m.Store.Update(ctx, sess.ID, *sess, ttl)
+}
+
 func (m *Manager) ensureSessionTimestamps(sess *Session, now time.Time) {
 	if sess.CreatedAt.IsZero() {
 		if m.TTL > 0 && !sess.ExpiresAt.IsZero() {
@@ -136,4 +347,7 @@ func (m *Manager) ensureSessionTimestamps(sess *Session, now time.Time) {
 	if sess.LastRefreshedAt.IsZero() {
 		sess.LastRefreshedAt = sess.CreatedAt
 	}
+	if sess.LastSeenAt.IsZero() {
+		sess.LastSeenAt = sess.LastRefreshedAt
+	}
 }
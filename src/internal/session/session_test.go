@@ -0,0 +1,41 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestManagerRevokedSessionStaysRevoked(t *testing.T) {
+	store := NewMemoryStore()
+	m := &Manager{Store: store, TTL: time.Hour, RefreshBefore: 10 * time.Minute, TouchEvery: time.Minute}
+	ctx := context.Background()
+
+	for _, name := range []string{"touch", "refresh"} {
+		sess, err := m.Create(ctx, "usr_1", "user")
+		if err != nil {
+			t.Fatalf("create: %v", err)
+		}
+		// A request loaded the session, then it was revoked before the
+		// request wrote it back.
+		inFlight := *sess
+		inFlight.LastSeenAt = time.Now().Add(-time.Hour)
+		if name == "refresh" {
+			inFlight.ExpiresAt = time.Now().Add(time.Minute)
+		}
+		if err := m.Delete(ctx, sess.ID); err != nil {
+			t.Fatalf("delete: %v", err)
+		}
+
+		if _, _, err := m.Refresh(ctx, &inFlight); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("%s: expected ErrNotFound, got %v", name, err)
+		}
+		if _, err := m.Get(ctx, sess.ID); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("%s: expected the session to stay revoked, got %v", name, err)
+		}
+		if list, _ := m.List(ctx, "usr_1"); len(list) != 0 {
+			t.Fatalf("%s: expected no sessions listed, got %d", name, len(list))
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package session
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// StateStore keeps short-lived values between the steps of a login, such
+// as the OIDC state between the redirect and the callback. Take returns a
+// value at most once.
+type StateStore interface {
+	Put(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	Take(ctx context.Context, key string) ([]byte, error)
+}
+
+type MemoryStateStore struct {
+	mu    sync.Mutex
+	items map[string]stateItem
+}
+
+type stateItem struct {
+	value     []byte
+	expiresAt time.Time
+}
+
+func NewMemoryStateStore() *MemoryStateStore {
+	return &MemoryStateStore{items: make(map[string]stateItem)}
+}
+
+func (s *MemoryStateStore) Put(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for k, item := range s.items {
+		if now.After(item.expiresAt) {
+			delete(s.items, k)
+		}
+	}
+	s.items[key] = stateItem{value: value, expiresAt: now.Add(ttl)}
+	return nil
+}
+
+func (s *MemoryStateStore) Take(_ context.Context, key string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	item, ok := s.items[key]
+	delete(s.items, key)
+	if !ok || time.Now().After(item.expiresAt) {
+		return nil, ErrNotFound
+	}
+	return item.value, nil
+}
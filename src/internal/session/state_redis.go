@@ -0,0 +1,37 @@
+package session
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+type RedisStateStore struct {
+	client *redis.Client
+	prefix string
+}
+
+func NewRedisStateStore(client *redis.Client, prefix string) *RedisStateStore {
+	p := strings.TrimSpace(prefix)
+	if p == "" {
+		p = "sniply:authstate:"
+	}
+	return &RedisStateStore{client: client, prefix: p}
+}
+
+func (s *RedisStateStore) Put(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return s.client.Set(ctx, s.prefix+key, value, ttl).Err()
+}
+
+func (s *RedisStateStore) Take(ctx context.Context, key string) ([]byte, error) {
+	val, err := s.client.GetDel(ctx, s.prefix+key).Bytes()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return val, nil
+}
@@ -7,13 +7,17 @@ import (
 )
 
 type MemoryStore struct {
-	mu    sync.RWMutex
-	items map[string]Session
+	mu       sync.RWMutex
+	items    map[string]Session
+	epochs   map[string]int64
+	attempts map[string]int64
 }
 
 func NewMemoryStore() *MemoryStore {
 	return &MemoryStore{
-		items: make(map[string]Session),
+		items:    make(map[string]Session),
+		epochs:   make(map[string]int64),
+		attempts: make(map[string]int64),
 	}
 }
 
@@ -26,6 +30,18 @@ func (s *MemoryStore) Set(ctx context.Context, id string, sess Session, ttl time
 	return nil
 }
 
+func (s *MemoryStore) Update(ctx context.Context, id string, sess Session, ttl time.Duration) error {
+	_ = ctx
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.items[id]; !ok {
+		return ErrNotFound
+	}
+	s.items[id] = sess
+	return nil
+}
+
 func (s *MemoryStore) Get(ctx context.Context, id string) (*Session, error) {
 	_ = ctx
 	s.mu.RLock()
@@ -48,5 +64,45 @@ func (s *MemoryStore) Delete(ctx context.Context, id string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	delete(s.items, id)
+	delete(s.attempts, id)
+	return nil
+}
+
+func (s *MemoryStore) ListUser(ctx context.Context, userID string) ([]Session, error) {
+	_ = ctx
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := time.Now()
+	out := make([]Session, 0)
+	for _, sess := range s.items {
+		if sess.UserID == userID && !now.After(sess.ExpiresAt) {
+			out = append(out, sess)
+		}
+	}
+	return out, nil
+}
+
+func (s *MemoryStore) UserEpoch(ctx context.Context, userID string) (int64, error) {
+	_ = ctx
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.epochs[userID], nil
+}
+
+func (s *MemoryStore) BumpUserEpoch(ctx context.Context, userID string) error {
+	_ = ctx
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.epochs[userID]++
 	return nil
 }
+
+func (s *MemoryStore) IncrAttempts(ctx context.Context, id string, ttl time.Duration) (int64, error) {
+	_ = ctx
+	_ = ttl
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.attempts[id]++
+	return s.attempts[id], nil
+}
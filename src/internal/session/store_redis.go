@@ -3,6 +3,7 @@ package session
 import (
 	"context"
 	"encoding/json"
+	"strconv"
 	"strings"
 	"time"
 
@@ -26,12 +27,84 @@ func (s *RedisStore) key(id string) string {
 	return s.prefix + id
 }
 
+// userKey is a sorted set of the user's session IDs scored by expiry in
+// milliseconds. Deleted sessions are dropped from it lazily by ListUser.
+func (s *RedisStore) userKey(userID string) string {
+	return s.prefix + "user:" + userID
+}
+
+func (s *RedisStore) attemptsKey(id string) string {
+	return s.prefix + "attempts:" + id
+}
+
+// epochKey counts the changes to a user. It never expires: starting over
+// from 0 could bring an old epoch back.
+func (s *RedisStore) epochKey(userID string) string {
+	return s.prefix + "epoch:" + userID
+}
+
+// setScript stores a session, indexes it under its user and keeps the
+// index alive as long as its last session.
+var setScript = redis.NewScript(`
+redis.call("SET", KEYS[1], ARGV[1], "PX", ARGV[2])
+if #KEYS < 2 then
+  return 0
+end
+redis.call("ZADD", KEYS[2], ARGV[3], ARGV[4])
+redis.call("ZREMRANGEBYSCORE", KEYS[2], "-inf", ARGV[5])
+local last = redis.call("ZRANGE", KEYS[2], -1, -1, "WITHSCORES")
+if last[2] then
+  redis.call("PEXPIREAT", KEYS[2], last[2])
+end
+return 1
+`)
+
+// updateScript is setScript for a session that must still exist: it
+// writes nothing, the index included, once the session was deleted.
+var updateScript = redis.NewScript(`
+if not redis.call("SET", KEYS[1], ARGV[1], "PX", ARGV[2], "XX") then
+  return 0
+end
+if #KEYS < 2 then
+  return 1
+end
+redis.call("ZADD", KEYS[2], ARGV[3], ARGV[4])
+redis.call("ZREMRANGEBYSCORE", KEYS[2], "-inf", ARGV[5])
+local last = redis.call("ZRANGE", KEYS[2], -1, -1, "WITHSCORES")
+if last[2] then
+  redis.call("PEXPIREAT", KEYS[2], last[2])
+end
+return 1
+`)
+
 func (s *RedisStore) Set(ctx context.Context, id string, sess Session, ttl time.Duration) error {
-	payload, err := json.Marshal(sess)
+	_, err := s.write(ctx, setScript, id, sess, ttl)
+	return err
+}
+
+func (s *RedisStore) Update(ctx context.Context, id string, sess Session, ttl time.Duration) error {
+	written, err := s.write(ctx, updateScript, id, sess, ttl)
 	if err != nil {
 		return err
 	}
-	return s.client.Set(ctx, s.key(id), payload, ttl).Err()
+	if written == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *RedisStore) write(ctx context.Context, script *redis.Script, id string, sess Session, ttl time.Duration) (int64, error) {
+	payload, err := json.Marshal(sess)
+	if err != nil {
+		return 0, err
+	}
+	keys := []string{s.key(id)}
+	if sess.UserID != "" {
+		keys = append(keys, s.userKey(sess.UserID))
+	}
+	now := time.Now()
+	return script.Run(ctx, s.client, keys,
+		payload, ttl.Milliseconds(), now.Add(ttl).UnixMilli(), id, now.UnixMilli()).Int64()
 }
 
 func (s *RedisStore) Get(ctx context.Context, id string) (*Session, error) {
@@ -55,5 +128,76 @@ func (s *RedisStore) Get(ctx context.Context, id string) (*Session, error) {
 }
 
 func (s *RedisStore) Delete(ctx context.Context, id string) error {
-	return s.client.Del(ctx, s.key(id)).Err()
+	return s.client.Del(ctx, s.key(id), s.attemptsKey(id)).Err()
+}
+
+func (s *RedisStore) ListUser(ctx context.Context, userID string) ([]Session, error) {
+	userKey := s.userKey(userID)
+	ids, err := s.client.ZRangeByScore(ctx, userKey, &redis.ZRangeBy{
+		Min: strconv.FormatInt(time.Now().UnixMilli(), 10),
+		Max: "+inf",
+	}).Result()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]Session, 0, len(ids))
+	if len(ids) == 0 {
+		return out, nil
+	}
+
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = s.key(id)
+	}
+	vals, err := s.client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	gone := make([]any, 0)
+	now := time.Now()
+	for i, val := range vals {
+		raw, ok := val.(string)
+		if !ok {
+			gone = append(gone, ids[i])
+			continue
+		}
+		var sess Session
+		if err := json.Unmarshal([]byte(raw), &sess); err != nil {
+			return nil, err
+		}
+		if sess.UserID != userID || now.After(sess.ExpiresAt) {
+			continue
+		}
+		out = append(out, sess)
+	}
+	if len(gone) > 0 {
+		_ = s.client.ZRem(ctx, userKey, gone...).Err()
+	}
+	return out, nil
+}
+
+func (s *RedisStore) UserEpoch(ctx context.Context, userID string) (int64, error) {
+	n, err := s.client.Get(ctx, s.epochKey(userID)).Int64()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	return n, err
+}
+
+func (s *RedisStore) BumpUserEpoch(ctx context.Context, userID string) error {
+	return s.client.Incr(ctx, s.epochKey(userID)).Err()
+}
+
+func (s *RedisStore) IncrAttempts(ctx context.Context, id string, ttl time.Duration) (int64, error) {
+	var incr *redis.IntCmd
+	_, err := s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		incr = pipe.Incr(ctx, s.attemptsKey(id))
+		pipe.PExpire(ctx, s.attemptsKey(id), ttl)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return incr.Val(), nil
 }
@@ -0,0 +1,111 @@
+package snippetio
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/PabloPavan/sniply_api/internal/snippets"
+)
+
+type Format string
+
+const (
+	FormatVSCode    Format = "vscode"
+	FormatJetBrains Format = "jetbrains"
+	FormatGist      Format = "gist"
+)
+
+var ErrUnsupportedFormat = errors.New("unsupported format")
+
+func ParseFormat(s string) (Format, error) {
+	f := Format(strings.ToLower(strings.TrimSpace(s)))
+	switch f {
+	case FormatVSCode, FormatJetBrains, FormatGist:
+		return f, nil
+	default:
+		return "", fmt.Errorf("%w: %q", ErrUnsupportedFormat, s)
+	}
+}
+
+func (f Format) ContentType() string {
+	switch f {
+	case FormatJetBrains:
+		return "application/xml"
+	default:
+		return "application/json"
+	}
+}
+
+func (f Format) FileName() string {
+	switch f {
+	case FormatVSCode:
+		return "sniply.code-snippets"
+	case FormatJetBrains:
+		return "sniply.xml"
+	case FormatGist:
+		return "sniply-gists.json"
+	default:
+		return "sniply"
+	}
+}
+
+// Decode maps an editor export onto create requests. It never touches the
+// store, so callers can use it for dry-run previews.
+func Decode(f Format, r io.Reader) ([]snippets.CreateSnippetRequest, error) {
+	switch f {
+	case FormatVSCode:
+		return decodeVSCode(r)
+	case FormatJetBrains:
+		return decodeJetBrains(r)
+	case FormatGist:
+		return decodeGist(r)
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedFormat, string(f))
+	}
+}
+
+func Encode(f Format, w io.Writer, list []*snippets.Snippet) error {
+	switch f {
+	case FormatVSCode:
+		return encodeVSCode(w, list)
+	case FormatJetBrains:
+		return encodeJetBrains(w, list)
+	case FormatGist:
+		return encodeGist(w, list)
+	default:
+		return fmt.Errorf("%w: %q", ErrUnsupportedFormat, string(f))
+	}
+}
+
+func firstNonBlank(values ...string) string {
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func normalizeNewlines(s string) string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	return strings.ReplaceAll(s, "\r", "\n")
+}
+
+// slug turns a snippet name into an editor trigger such as "http-handler".
+func slug(name string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(strings.TrimSpace(name)) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			b.WriteRune(r)
+			dash = false
+		case b.Len() > 0 && !dash:
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+	return strings.TrimSuffix(b.String(), "-")
+}
@@ -0,0 +1,117 @@
+package snippetio
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/PabloPavan/sniply_api/internal/snippets"
+)
+
+type gist struct {
+	Description string              `json:"description"`
+	Public      bool                `json:"public"`
+	Files       map[string]gistFile `json:"files"`
+}
+
+type gistFile struct {
+	Filename string `json:"filename"`
+	Language string `json:"language,omitempty"`
+	Content  string `json:"content"`
+}
+
+// decodeGist accepts a single gist object or an array of gists, matching
+// what the GitHub API returns for GET /gists/{id} and GET /gists.
+func decodeGist(r io.Reader) ([]snippets.CreateSnippetRequest, error) {
+	br := bufio.NewReader(r)
+	first, err := peekNonSpace(br)
+	if err != nil {
+		return nil, fmt.Errorf("invalid gist export: %w", err)
+	}
+
+	var gists []gist
+	dec := json.NewDecoder(br)
+	if first == '[' {
+		err = dec.Decode(&gists)
+	} else {
+		var g gist
+		err = dec.Decode(&g)
+		gists = []gist{g}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid gist export: %w", err)
+	}
+
+	var out []snippets.CreateSnippetRequest
+	for _, g := range gists {
+		visibility := snippets.VisibilityPrivate
+		if g.Public {
+			visibility = snippets.VisibilityPublic
+		}
+
+		names := make([]string, 0, len(g.Files))
+		for name := range g.Files {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, key := range names {
+			f := g.Files[key]
+			filename := firstNonBlank(f.Filename, key)
+			name := filename
+			if len(g.Files) == 1 {
+				name = firstNonBlank(g.Description, filename)
+			}
+			language := strings.ToLower(strings.TrimSpace(f.Language))
+			if language == "" {
+				language = languageFromFilename(filename)
+			}
+			out = append(out, snippets.CreateSnippetRequest{
+				Name:       name,
+				Content:    normalizeNewlines(f.Content),
+				Language:   language,
+				Tags:       []string{},
+				Visibility: visibility,
+			})
+		}
+	}
+	return out, nil
+}
+
+func encodeGist(w io.Writer, list []*snippets.Snippet) error {
+	out := make([]gist, 0, len(list))
+	for _, s := range list {
+		base := slug(s.Name)
+		if base == "" {
+			base = s.ID
+		}
+		filename := base + extensionFor(s.Language)
+		out = append(out, gist{
+			Description: s.Name,
+			Public:      s.Visibility == snippets.VisibilityPublic,
+			Files: map[string]gistFile{
+				filename: {Filename: filename, Language: s.Language, Content: s.Content},
+			},
+		})
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
+
+func peekNonSpace(br *bufio.Reader) (byte, error) {
+	for {
+		b, err := br.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		if !bytes.ContainsRune([]byte(" \t\r\n"), rune(b)) {
+			return b, br.UnreadByte()
+		}
+	}
+}
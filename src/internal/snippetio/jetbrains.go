@@ -0,0 +1,98 @@
+package snippetio
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/PabloPavan/sniply_api/internal/snippets"
+)
+
+const jetbrainsGroup = "sniply"
+
+type jetbrainsTemplateSet struct {
+	XMLName   xml.Name            `xml:"templateSet"`
+	Group     string              `xml:"group,attr"`
+	Templates []jetbrainsTemplate `xml:"template"`
+}
+
+type jetbrainsTemplate struct {
+	Name             string            `xml:"name,attr"`
+	Value            string            `xml:"value,attr"`
+	Description      string            `xml:"description,attr,omitempty"`
+	ToReformat       bool              `xml:"toReformat,attr"`
+	ToShortenFQNames bool              `xml:"toShortenFQNames,attr"`
+	Context          *jetbrainsContext `xml:"context"`
+}
+
+type jetbrainsContext struct {
+	Options []jetbrainsOption `xml:"option"`
+}
+
+type jetbrainsOption struct {
+	Name  string `xml:"name,attr"`
+	Value bool   `xml:"value,attr"`
+}
+
+func decodeJetBrains(r io.Reader) ([]snippets.CreateSnippetRequest, error) {
+	var set jetbrainsTemplateSet
+	if err := xml.NewDecoder(r).Decode(&set); err != nil {
+		return nil, fmt.Errorf("invalid jetbrains template set: %w", err)
+	}
+
+	var tags []string
+	if group := strings.ToLower(strings.TrimSpace(set.Group)); group != "" {
+		tags = []string{group}
+	}
+
+	out := make([]snippets.CreateSnippetRequest, 0, len(set.Templates))
+	for _, t := range set.Templates {
+		language := ""
+		if t.Context != nil {
+			for _, opt := range t.Context.Options {
+				if opt.Value {
+					language = languageFromJetBrainsContext(opt.Name)
+					break
+				}
+			}
+		}
+		out = append(out, snippets.CreateSnippetRequest{
+			Name:     firstNonBlank(t.Name, t.Description),
+			Content:  normalizeNewlines(t.Value),
+			Language: language,
+			Tags:     append([]string{}, tags...),
+		})
+	}
+	return out, nil
+}
+
+func encodeJetBrains(w io.Writer, list []*snippets.Snippet) error {
+	set := jetbrainsTemplateSet{Group: jetbrainsGroup}
+	for _, s := range list {
+		name := slug(s.Name)
+		if name == "" {
+			name = s.ID
+		}
+		set.Templates = append(set.Templates, jetbrainsTemplate{
+			Name:             name,
+			Value:            s.Content,
+			Description:      s.Name,
+			ToShortenFQNames: true,
+			Context: &jetbrainsContext{Options: []jetbrainsOption{
+				{Name: jetbrainsContextFor(s.Language), Value: true},
+			}},
+		})
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(set); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
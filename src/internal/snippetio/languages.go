@@ -0,0 +1,112 @@
+package snippetio
+
+import (
+	"strings"
+
+	"github.com/PabloPavan/sniply_api/internal/languages"
+)
+
+// JetBrains live templates are bound to IDE contexts rather than languages.
+var jetbrainsContexts = map[string]string{
+	"bash":       "SHELL_SCRIPT",
+	"css":        "CSS",
+	"go":         "GO",
+	"html":       "HTML",
+	"java":       "JAVA_CODE",
+	"javascript": "JAVA_SCRIPT",
+	"json":       "JSON",
+	"kotlin":     "KOTLIN",
+	"php":        "PHP",
+	"python":     "Python",
+	"ruby":       "RUBY",
+	"rust":       "RUST",
+	"sql":        "SQL",
+	"typescript": "TypeScript",
+	"xml":        "XML",
+	"yaml":       "YAML",
+}
+
+var vscodeScopes = map[string]string{
+	"shellscript":     "bash",
+	"javascriptreact": "javascript",
+	"typescriptreact": "typescript",
+	"plaintext":       "txt",
+}
+
+func extensionFor(language string) string {
+	if l, ok := languages.Lookup(language); ok && len(l.Extensions) > 0 {
+		return l.Extensions[0]
+	}
+	return ".txt"
+}
+
+func languageFromFilename(name string) string {
+	if l, ok := languages.Default.ByFilename(name); ok {
+		return l.ID
+	}
+	return ""
+}
+
+// knownLanguage returns the canonical id, or "" so the service detects it.
+func knownLanguage(name string) string {
+	if l, ok := languages.Lookup(name); ok {
+		return l.ID
+	}
+	return ""
+}
+
+func languageFromVSCodeScope(scope string) string {
+	for _, part := range strings.Split(scope, ",") {
+		part = strings.ToLower(strings.TrimSpace(part))
+		if part == "" {
+			continue
+		}
+		if lang, ok := vscodeScopes[part]; ok {
+			return lang
+		}
+		return knownLanguage(part)
+	}
+	return ""
+}
+
+func vscodeScopeFor(language string) string {
+	language = strings.ToLower(strings.TrimSpace(language))
+	for scope, lang := range vscodeScopes {
+		if lang == language && scope != "javascriptreact" && scope != "typescriptreact" {
+			return scope
+		}
+	}
+	return language
+}
+
+func languageFromJetBrainsContext(name string) string {
+	upper := strings.ToUpper(strings.TrimSpace(name))
+	if upper == "" || upper == "OTHER" {
+		return ""
+	}
+	for lang, ctx := range jetbrainsContexts {
+		if strings.ToUpper(ctx) == upper {
+			return lang
+		}
+	}
+	if strings.HasPrefix(upper, "JAVA_SCRIPT") || strings.HasPrefix(upper, "JS_") {
+		return "javascript"
+	}
+	if strings.HasPrefix(upper, "JAVA_") {
+		return "java"
+	}
+	if strings.HasPrefix(upper, "GO_") {
+		return "go"
+	}
+	if strings.HasPrefix(upper, "PYTHON") {
+		return "python"
+	}
+	return knownLanguage(upper)
+}
+
+func jetbrainsContextFor(language string) string {
+	if ctx, ok := jetbrainsContexts[strings.ToLower(strings.TrimSpace(language))]; ok {
+		return ctx
+	}
+	return "OTHER"
+}
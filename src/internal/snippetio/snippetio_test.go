@@ -0,0 +1,141 @@
+package snippetio
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/PabloPavan/sniply_api/internal/snippets"
+)
+
+func TestDecodeVSCode(t *testing.T) {
+	src := `{
+		"Print to console": {
+			"prefix": ["log", "console"],
+			"body": ["console.log('$1');", "$2"],
+			"description": "Log output to console",
+			"scope": "javascript,typescript"
+		},
+		"Shell shebang": {
+			"prefix": "",
+			"body": "#!/usr/bin/env bash",
+			"description": "Bash header",
+			"scope": "shellscript"
+		}
+	}`
+
+	reqs, err := Decode(FormatVSCode, strings.NewReader(src))
+	if err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+	if len(reqs) != 2 {
+		t.Fatalf("unexpected count: %d", len(reqs))
+	}
+	if reqs[0].Name != "log" || reqs[0].Language != "javascript" {
+		t.Fatalf("unexpected first snippet: %+v", reqs[0])
+	}
+	if reqs[0].Content != "console.log('$1');\n$2" {
+		t.Fatalf("unexpected body: %q", reqs[0].Content)
+	}
+	if reqs[1].Name != "Bash header" || reqs[1].Language != "bash" {
+		t.Fatalf("unexpected second snippet: %+v", reqs[1])
+	}
+}
+
+func TestDecodeJetBrains(t *testing.T) {
+	src := `<templateSet group="Go Helpers">
+  <template name="iferr" value="if err != nil {&#10;  return $END$&#10;}" description="Return on error" toReformat="true" toShortenFQNames="true">
+    <context>
+      <option name="GO_STATEMENT" value="false" />
+      <option name="GO" value="true" />
+    </context>
+  </template>
+</templateSet>`
+
+	reqs, err := Decode(FormatJetBrains, strings.NewReader(src))
+	if err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+	if len(reqs) != 1 {
+		t.Fatalf("unexpected count: %d", len(reqs))
+	}
+	got := reqs[0]
+	if got.Name != "iferr" || got.Language != "go" {
+		t.Fatalf("unexpected snippet: %+v", got)
+	}
+	if got.Content != "if err != nil {\n  return $END$\n}" {
+		t.Fatalf("unexpected content: %q", got.Content)
+	}
+	if len(got.Tags) != 1 || got.Tags[0] != "go helpers" {
+		t.Fatalf("unexpected tags: %v", got.Tags)
+	}
+}
+
+func TestDecodeGist(t *testing.T) {
+	single := `{"description": "retry helper", "public": true, "files": {"retry.go": {"filename": "retry.go", "language": "Go", "content": "package retry"}}}`
+	reqs, err := Decode(FormatGist, strings.NewReader(single))
+	if err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+	if len(reqs) != 1 || reqs[0].Name != "retry helper" || reqs[0].Language != "go" {
+		t.Fatalf("unexpected single gist: %+v", reqs)
+	}
+	if reqs[0].Visibility != snippets.VisibilityPublic {
+		t.Fatalf("unexpected visibility: %s", reqs[0].Visibility)
+	}
+
+	many := ` [{"description": "", "public": false, "files": {
+		"b.py": {"filename": "b.py", "content": "print(1)"},
+		"a.sql": {"filename": "a.sql", "content": "select 1"}
+	}}]`
+	reqs, err = Decode(FormatGist, strings.NewReader(many))
+	if err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+	if len(reqs) != 2 {
+		t.Fatalf("unexpected count: %d", len(reqs))
+	}
+	if reqs[0].Name != "a.sql" || reqs[0].Language != "sql" || reqs[1].Language != "python" {
+		t.Fatalf("unexpected gist files: %+v", reqs)
+	}
+	if reqs[0].Visibility != snippets.VisibilityPrivate {
+		t.Fatalf("unexpected visibility: %s", reqs[0].Visibility)
+	}
+}
+
+func TestDecodeInvalid(t *testing.T) {
+	if _, err := Decode(FormatVSCode, strings.NewReader(`[1,2]`)); err == nil {
+		t.Fatal("expected error for invalid vscode file")
+	}
+	if _, err := ParseFormat("sublime"); err == nil {
+		t.Fatal("expected error for unsupported format")
+	}
+}
+
+func TestEncodeRoundTrip(t *testing.T) {
+	list := []*snippets.Snippet{
+		{ID: "snp_1", Name: "HTTP Handler", Content: "func h() {\n}", Language: "go", Visibility: snippets.VisibilityPublic},
+		{ID: "snp_2", Name: "HTTP Handler", Content: "echo hi", Language: "bash", Visibility: snippets.VisibilityPrivate},
+	}
+
+	for _, f := range []Format{FormatVSCode, FormatJetBrains, FormatGist} {
+		var buf bytes.Buffer
+		if err := Encode(f, &buf, list); err != nil {
+			t.Fatalf("%s encode error: %v", f, err)
+		}
+		reqs, err := Decode(f, &buf)
+		if err != nil {
+			t.Fatalf("%s decode error: %v", f, err)
+		}
+		if len(reqs) != len(list) {
+			t.Fatalf("%s: unexpected count %d", f, len(reqs))
+		}
+		languages := map[string]string{}
+		for _, req := range reqs {
+			languages[req.Content] = req.Language
+		}
+		if languages["func h() {\n}"] != "go" || languages["echo hi"] != "bash" {
+			t.Fatalf("%s: languages not preserved: %v", f, languages)
+		}
+	}
+}
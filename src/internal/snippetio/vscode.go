@@ -0,0 +1,93 @@
+package snippetio
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/PabloPavan/sniply_api/internal/snippets"
+)
+
+type vscodeSnippet struct {
+	Prefix      stringList `json:"prefix"`
+	Body        stringList `json:"body"`
+	Description string     `json:"description,omitempty"`
+	Scope       string     `json:"scope,omitempty"`
+}
+
+// stringList accepts both "x" and ["x", "y"], which VS Code allows for
+// prefix and body.
+type stringList []string
+
+func (l *stringList) UnmarshalJSON(data []byte) error {
+	var one string
+	if err := json.Unmarshal(data, &one); err == nil {
+		*l = stringList{one}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(data, &many); err != nil {
+		return errors.New("expected string or array of strings")
+	}
+	*l = many
+	return nil
+}
+
+func decodeVSCode(r io.Reader) ([]snippets.CreateSnippetRequest, error) {
+	var doc map[string]vscodeSnippet
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("invalid vscode snippets file: %w", err)
+	}
+
+	keys := make([]string, 0, len(doc))
+	for k := range doc {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	out := make([]snippets.CreateSnippetRequest, 0, len(doc))
+	for _, key := range keys {
+		item := doc[key]
+		prefix := ""
+		if len(item.Prefix) > 0 {
+			prefix = item.Prefix[0]
+		}
+		out = append(out, snippets.CreateSnippetRequest{
+			Name:     firstNonBlank(prefix, item.Description, key),
+			Content:  normalizeNewlines(strings.Join(item.Body, "\n")),
+			Language: languageFromVSCodeScope(item.Scope),
+			Tags:     []string{},
+		})
+	}
+	return out, nil
+}
+
+func encodeVSCode(w io.Writer, list []*snippets.Snippet) error {
+	doc := make(map[string]vscodeSnippet, len(list))
+	for _, s := range list {
+		key := s.Name
+		for n := 2; ; n++ {
+			if _, taken := doc[key]; !taken {
+				break
+			}
+			key = fmt.Sprintf("%s (%d)", s.Name, n)
+		}
+		prefix := slug(s.Name)
+		if prefix == "" {
+			prefix = s.ID
+		}
+		doc[key] = vscodeSnippet{
+			Prefix:      stringList{prefix},
+			Body:        strings.Split(s.Content, "\n"),
+			Description: s.Name,
+			Scope:       vscodeScopeFor(s.Language),
+		}
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(doc)
+}
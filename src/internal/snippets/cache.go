@@ -12,3 +12,18 @@ type Cache interface {
 	GetList(ctx context.Context, key string) ([]*Snippet, bool, error)
 	SetList(ctx context.Context, key string, snippets []*Snippet, ttl time.Duration) error
 }
+
+type RenderCache interface {
+	GetRender(ctx context.Context, key string) ([]byte, bool, error)
+	SetRender(ctx context.Context, key string, data []byte, ttl time.Duration) error
+}
+
+type FacetCache interface {
+	GetFacets(ctx context.Context, key string) (*Facets, bool, error)
+	SetFacets(ctx context.Context, key string, facets *Facets, ttl time.Duration) error
+}
+
+type RelatedCache interface {
+	GetRelated(ctx context.Context, key string) ([]RelatedSnippet, bool, error)
+	SetRelated(ctx context.Context, key string, related []RelatedSnippet, ttl time.Duration) error
+}
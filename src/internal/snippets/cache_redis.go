@@ -30,6 +30,18 @@ func (c *RedisCache) keyList(key string) string {
 	return c.prefix + "snippet:list:" + key
 }
 
+func (c *RedisCache) keyRender(key string) string {
+	return c.prefix + "render:" + key
+}
+
+func (c *RedisCache) keyFacets(key string) string {
+	return c.prefix + "snippet:facets:" + key
+}
+
+func (c *RedisCache) keyRelated(key string) string {
+	return c.prefix + "related:" + key
+}
+
 func (c *RedisCache) GetByID(ctx context.Context, id string) (*Snippet, bool, error) {
 	val, err := c.client.Get(ctx, c.keyByID(id)).Result()
 	if err != nil {
@@ -81,3 +93,66 @@ func (c *RedisCache) SetList(ctx context.Context, key string, snippets []*Snippe
 	}
 	return c.client.Set(ctx, c.keyList(key), payload, ttl).Err()
 }
+
+func (c *RedisCache) GetRender(ctx context.Context, key string) ([]byte, bool, error) {
+	val, err := c.client.Get(ctx, c.keyRender(key)).Bytes()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+	return val, true, nil
+}
+
+func (c *RedisCache) SetRender(ctx context.Context, key string, data []byte, ttl time.Duration) error {
+	return c.client.Set(ctx, c.keyRender(key), data, ttl).Err()
+}
+
+func (c *RedisCache) GetRelated(ctx context.Context, key string) ([]RelatedSnippet, bool, error) {
+	val, err := c.client.Get(ctx, c.keyRelated(key)).Result()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+
+	var out []RelatedSnippet
+	if err := json.Unmarshal([]byte(val), &out); err != nil {
+		return nil, false, err
+	}
+	return out, true, nil
+}
+
+func (c *RedisCache) SetRelated(ctx context.Context, key string, related []RelatedSnippet, ttl time.Duration) error {
+	payload, err := json.Marshal(related)
+	if err != nil {
+		return err
+	}
+	return c.client.Set(ctx, c.keyRelated(key), payload, ttl).Err()
+}
+
+func (c *RedisCache) GetFacets(ctx context.Context, key string) (*Facets, bool, error) {
+	val, err := c.client.Get(ctx, c.keyFacets(key)).Result()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+
+	var out Facets
+	if err := json.Unmarshal([]byte(val), &out); err != nil {
+		return nil, false, err
+	}
+	return &out, true, nil
+}
+
+func (c *RedisCache) SetFacets(ctx context.Context, key string, facets *Facets, ttl time.Duration) error {
+	payload, err := json.Marshal(facets)
+	if err != nil {
+		return err
+	}
+	return c.client.Set(ctx, c.keyFacets(key), payload, ttl).Err()
+}
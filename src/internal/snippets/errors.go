@@ -8,7 +8,9 @@ import (
 )
 
 var (
-	ErrNotFound = errors.New("snippet not found")
+	ErrNotFound      = errors.New("snippet not found")
+	ErrSearchTimeout = errors.New("search timed out")
+	ErrInvalidRegex  = errors.New("invalid regular expression")
 )
 
 func IsNotFound(err error) bool {
@@ -34,3 +36,18 @@ func IsUniqueViolationID(err error) bool {
 
 	return false
 }
+
+// searchError maps Postgres errors raised by a regex search.
+func searchError(err error) error {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return err
+	}
+	switch pgErr.Code {
+	case "57014": // query_canceled, raised by statement_timeout
+		return ErrSearchTimeout
+	case "2201B": // invalid_regular_expression
+		return ErrInvalidRegex
+	}
+	return err
+}
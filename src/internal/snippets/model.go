@@ -1,6 +1,14 @@
 package snippets
 
-import "time"
+import (
+	"time"
+
+	"github.com/PabloPavan/sniply_api/internal/highlight"
+	"github.com/PabloPavan/sniply_api/internal/languages"
+	"github.com/PabloPavan/sniply_api/internal/secrets"
+	"github.com/PabloPavan/sniply_api/internal/symbols"
+	"github.com/PabloPavan/sniply_api/internal/templates"
+)
 
 type Visibility string
 
@@ -17,26 +25,172 @@ type Snippet struct {
 	Tags       []string   `json:"tags"`
 	Visibility Visibility `json:"visibility"`
 
+	Placeholders []templates.Placeholder `json:"placeholders"`
+
+	// SyntaxValid records whether the saved content parsed. It is null for
+	// languages without a validator.
+	SyntaxValid *bool `json:"syntax_valid"`
+
+	// Symbols are the definitions found in the content when it was saved.
+	Symbols []symbols.Symbol `json:"symbols"`
+
 	CreatorID string `json:"creator_id"`
 
+	// Revision starts at 1 and is bumped on every update.
+	Revision  int       `json:"revision"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
+
+	// Excerpt is set when only a line range of the content was requested.
+	Excerpt *Excerpt `json:"excerpt,omitempty"`
+
+	// Detection is set on create/update when the language was detected.
+	Detection *languages.Detection `json:"language_detection,omitempty"`
+	// SecretFindings is set on create/update when the content had secrets.
+	// Create and Update store them with the snippet, replacing older ones.
+	SecretFindings []secrets.Finding `json:"secret_findings,omitempty"`
 }
 
 type CreateSnippetRequest struct {
-	Name       string
-	Content    string
-	Language   string
-	Tags       []string
-	Visibility Visibility
+	Name         string
+	Content      string
+	Language     string
+	Tags         []string
+	Visibility   Visibility
+	Placeholders []templates.Placeholder
+	// ValidateSyntax rejects content that does not parse instead of saving
+	// it with syntax_valid=false.
+	ValidateSyntax bool
+}
+
+// Revision is the content of a snippet as it was saved at one point.
+type Revision struct {
+	SnippetID string
+	Revision  int
+	Name      string
+	Content   string
+	Language  string
+	CreatedAt time.Time
+}
+
+// Selection picks a line range and, optionally, a pinned revision of a
+// snippet. Zero values mean the whole content of the latest revision.
+type Selection struct {
+	Lines    highlight.Range
+	Revision int
+}
+
+// Excerpt describes the slice of content returned for a line range. From and
+// To are line numbers in the full content.
+type Excerpt struct {
+	From       int    `json:"from"`
+	To         int    `json:"to"`
+	TotalLines int    `json:"total_lines"`
+	Permalink  string `json:"permalink"`
+}
+
+// RelatedSnippet is a lightweight view of a snippet similar to another one.
+// Score is between 0 and 1.
+type RelatedSnippet struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Language   string     `json:"language"`
+	Tags       []string   `json:"tags"`
+	Visibility Visibility `json:"visibility"`
+	CreatorID  string     `json:"creator_id"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+	Score      float64    `json:"score"`
+}
+
+type RelatedFilter struct {
+	SnippetID   string
+	RequesterID string
+	Limit       int
+}
+
+type LanguageStat struct {
+	languages.Language
+	Count int `json:"count"`
+}
+
+type TagCount struct {
+	Tag   string `json:"tag"`
+	Count int    `json:"count"`
+}
+
+type TagFilter struct {
+	Prefix      string
+	RequesterID string
+	Limit       int
+}
+
+// SearchMode selects how SnippetFilter.Query is matched.
+type SearchMode string
+
+const (
+	// SearchFTS matches words in the name, content and tags, or a similar
+	// name. It is the default.
+	SearchFTS SearchMode = "fts"
+	// SearchPhrase matches the query verbatim, punctuation included.
+	SearchPhrase SearchMode = "phrase"
+	// SearchRegex matches a POSIX regular expression against the content.
+	SearchRegex SearchMode = "regex"
+)
+
+func (m SearchMode) Valid() bool {
+	switch m {
+	case SearchFTS, SearchPhrase, SearchRegex:
+		return true
+	default:
+		return false
+	}
 }
 
 type SnippetFilter struct {
-	Query      string // full-text or simple substring search
-	Creator    string
-	Language   string
-	Tags       []string
-	Visibility Visibility
-	Limit      int
-	Offset     int
+	Query string // full-text or simple substring search
+	Mode  SearchMode
+	// Phrases must each appear verbatim, whatever the mode.
+	Phrases  []string
+	Creator  string
+	Language string
+	// Tags must all be set on the snippet; ExcludeTags none of them.
+	Tags        []string
+	ExcludeTags []string
+	// Symbols must all be among the snippet's symbol names or name parts.
+	Symbols []string
+	// CreatedAfter is inclusive and CreatedBefore exclusive.
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+	Visibility    Visibility
+	Limit         int
+	Offset        int
+}
+
+// Facet names accepted by Service.Facets.
+const (
+	FacetLanguage = "language"
+	FacetTags     = "tags"
+	FacetCreator  = "creator"
+)
+
+type FacetCount struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+// Facets counts the snippets matching a filter. Only requested facets are
+// set; each lists its most frequent values first.
+type Facets struct {
+	Total    int          `json:"total"`
+	Language []FacetCount `json:"language,omitzero"`
+	Tags     []FacetCount `json:"tags,omitzero"`
+	Creator  []FacetCount `json:"creator,omitzero"`
+}
+
+type FacetFilter struct {
+	// Filter selects the snippets; its Limit and Offset are ignored.
+	Filter SnippetFilter
+	Facets []string
+	// Limit caps the values returned per facet.
+	Limit int
 }
@@ -2,68 +2,185 @@ package snippets
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/PabloPavan/sniply_api/internal/db"
+	"github.com/PabloPavan/sniply_api/internal/secrets"
+	"github.com/PabloPavan/sniply_api/internal/symbols"
+	"github.com/jackc/pgx/v5"
 )
 
 type Repository struct {
 	base *db.Base
+	// RegexTimeout bounds regex searches with a statement timeout.
+	RegexTimeout time.Duration
 }
 
 func NewRepository(base *db.Base) *Repository {
-	return &Repository{base: base}
+	return &Repository{base: base, RegexTimeout: 2 * time.Second}
 }
 
 const (
-	sqlSnippetInsert = `INSERT INTO snippets (id, name, content, language, tags, visibility, creator_id)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
-		RETURNING created_at, updated_at;`
+	sqlSnippetInsert = `INSERT INTO snippets (id, name, content, language, tags, placeholders, syntax_valid, symbols, symbol_names, search_terms, visibility, creator_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		RETURNING revision, created_at, updated_at;`
 
-	sqlSnippetSelectByID = `SELECT id, name, content, language, tags, visibility, creator_id, created_at, updated_at
+	sqlSnippetSelectByID = `SELECT id, name, content, language, tags, placeholders, syntax_valid, symbols, visibility, creator_id, revision, created_at, updated_at
 		FROM snippets
 		WHERE id = $1
 		LIMIT 1;`
 
-	sqlSnippetListBase = `SELECT id, name, content, language, tags, visibility, creator_id, created_at, updated_at
+	sqlSnippetListBase = `SELECT id, name, content, language, tags, placeholders, syntax_valid, symbols, visibility, creator_id, revision, created_at, updated_at
 		FROM snippets
 		WHERE %s
 		ORDER BY created_at DESC
 		LIMIT $%d OFFSET $%d;`
 
+	// sqlFacetsBase counts the matching snippets once and each requested
+	// facet from the same materialized set.
+	sqlFacetsBase = `WITH m AS MATERIALIZED (
+			SELECT language, tags, creator_id
+			FROM snippets
+			WHERE %s
+		)
+		%s;`
+
+	sqlFacetPart = `(SELECT %[1]s, %[2]s, count(*) FROM %[3]s GROUP BY 2 ORDER BY 3 DESC, 2 LIMIT $%[4]d)`
+
 	sqlSnippetUpdate = `UPDATE snippets
-		SET name = $1, content = $2, language = $3, tags = $4, visibility = $5, updated_at = now()
-		WHERE id = $6
-		RETURNING updated_at;`
+		SET name = $1, content = $2, language = $3, tags = $4, placeholders = $5, syntax_valid = $6,
+			symbols = $7, symbol_names = $8, search_terms = $9, symbols_pending = false, visibility = $10,
+			revision = revision + 1, updated_at = now()
+		WHERE id = $11
+		RETURNING revision, created_at, updated_at;`
+
+	sqlSnippetListBatch = `SELECT id, name, content, language, tags, placeholders, syntax_valid, symbols, visibility, creator_id, revision, created_at, updated_at
+		FROM snippets
+		WHERE id > $1
+		ORDER BY id
+		LIMIT $2;`
+
+	sqlSnippetListSymbolsPending = `SELECT id, name, content, language, tags, placeholders, syntax_valid, symbols, visibility, creator_id, revision, created_at, updated_at
+		FROM snippets
+		WHERE symbols_pending AND id > $1
+		ORDER BY id
+		LIMIT $2;`
+
+	sqlSnippetUpdateSearch = `UPDATE snippets
+		SET symbols = $2, symbol_names = $3, search_terms = $4, symbols_pending = false
+		WHERE id = $1
+			AND (symbols_pending OR symbols IS DISTINCT FROM $2 OR symbol_names IS DISTINCT FROM $3 OR search_terms IS DISTINCT FROM $4);`
+
+	sqlRevisionInsert = `INSERT INTO snippet_revisions (snippet_id, revision, name, content, language, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6);`
+
+	sqlRevisionSelect = `SELECT snippet_id, revision, name, content, language, created_at
+		FROM snippet_revisions
+		WHERE snippet_id = $1 AND revision = $2;`
+
+	sqlSetStatementTimeout = `SELECT set_config('statement_timeout', $1, true);`
 
 	sqlSnippetDelete = `DELETE FROM snippets 
 		WHERE id = $1;`
+
+	sqlSnippetCountByLanguage = `SELECT language, count(*)
+		FROM snippets
+		WHERE visibility = 'public' OR creator_id = $1
+		GROUP BY language;`
+
+	// sqlSnippetRelated scores candidates sharing tags, a similar name or
+	// words with the source snippet. The source words are turned into an OR
+	// query; websearch_to_tsquery never fails on odd lexemes.
+	sqlSnippetRelated = `WITH src AS (
+			SELECT id, name, language, tags,
+				websearch_to_tsquery('simple', array_to_string((tsvector_to_array(search_tsv))[1:64], ' or ')) AS q
+			FROM snippets
+			WHERE id = $1
+		)
+		SELECT c.id, c.name, c.language, c.tags, c.visibility, c.creator_id, c.updated_at,
+			(
+				0.40 * coalesce((SELECT count(*) FROM unnest(c.tags) AS t(tag) WHERE t.tag = ANY(src.tags))::float8 / nullif(cardinality(src.tags), 0), 0)
+				+ 0.15 * (c.language = src.language)::int
+				+ 0.25 * similarity(c.name, src.name)
+				+ 0.20 * least(ts_rank(c.search_tsv, src.q) * 10, 1)
+			)::float8 AS score
+		FROM snippets c, src
+		WHERE c.id <> src.id
+			AND (c.visibility = 'public' OR c.creator_id = $2)
+			AND (c.tags && src.tags OR c.name % src.name OR c.search_tsv @@ src.q)
+		ORDER BY score DESC, c.updated_at DESC
+		LIMIT $3;`
+
+	sqlSnippetListRelated = `SELECT id, name, language, tags, visibility, creator_id, updated_at
+		FROM snippets
+		WHERE id = ANY($1)
+			AND (visibility = 'public' OR creator_id = $2);`
+
+	sqlTagList = `SELECT t.tag, count(*) AS uses
+		FROM snippets s, unnest(s.tags) AS t(tag)
+		WHERE (s.visibility = 'public' OR s.creator_id = $1)
+			AND t.tag LIKE $2 ESCAPE '\'
+		GROUP BY t.tag
+		ORDER BY uses DESC, t.tag ASC
+		LIMIT $3;`
+
+	sqlTagLockOwned = `SELECT id
+		FROM snippets
+		WHERE creator_id = $1 AND tags && $2
+		FOR UPDATE;`
+
+	sqlTagMergeOwned = `UPDATE snippets
+		SET tags = ARRAY(
+				SELECT x.tag
+				FROM (
+					SELECT CASE WHEN u.tag = ANY($2) THEN $3 ELSE u.tag END AS tag, min(u.ord) AS ord
+					FROM unnest(tags) WITH ORDINALITY AS u(tag, ord)
+					GROUP BY 1
+				) x
+				ORDER BY x.ord
+			),
+			updated_at = now()
+		WHERE id = ANY($1);`
 )
 
 func (r *Repository) Create(ctx context.Context, s *Snippet) error {
-	ctx, cancel := r.base.WithTimeout(ctx)
-	defer cancel()
-
-	err := r.base.Q().QueryRow(ctx, sqlSnippetInsert,
-		s.ID,
-		s.Name,
-		s.Content,
-		s.Language,
-		s.Tags,
-		string(s.Visibility),
-		s.CreatorID,
-	).Scan(&s.CreatedAt, &s.UpdatedAt)
-
-	if IsNotFound(err) {
-		return ErrNotFound
+	placeholders, err := json.Marshal(s.Placeholders)
+	if err != nil {
+		return err
 	}
-
+	index, err := newSymbolIndex(s)
 	if err != nil {
 		return err
 	}
 
-	return nil
+	return r.base.WithTx(ctx, func(ctx context.Context, tx pgx.Tx) error {
+		err := tx.QueryRow(ctx, sqlSnippetInsert,
+			s.ID,
+			s.Name,
+			s.Content,
+			s.Language,
+			s.Tags,
+			placeholders,
+			s.SyntaxValid,
+			index.symbols,
+			index.names,
+			index.terms,
+			string(s.Visibility),
+			s.CreatorID,
+		).Scan(&s.Revision, &s.CreatedAt, &s.UpdatedAt)
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.Exec(ctx, sqlRevisionInsert, s.ID, s.Revision, s.Name, s.Content, s.Language, s.UpdatedAt); err != nil {
+			return err
+		}
+		return secrets.ReplaceForSnippet(ctx, tx, s.ID, s.SecretFindings)
+	})
 }
 
 func (r *Repository) GetByID(ctx context.Context, id string) (*Snippet, error) {
@@ -79,8 +196,12 @@ func (r *Repository) GetByID(ctx context.Context, id string) (*Snippet, error) {
 		&s.Content,
 		&s.Language,
 		&s.Tags,
+		&s.Placeholders,
+		&s.SyntaxValid,
+		&s.Symbols,
 		&visibility,
 		&s.CreatorID,
+		&s.Revision,
 		&s.CreatedAt,
 		&s.UpdatedAt,
 	)
@@ -98,6 +219,117 @@ func (r *Repository) GetByID(ctx context.Context, id string) (*Snippet, error) {
 }
 
 func (r *Repository) List(ctx context.Context, f SnippetFilter) ([]*Snippet, error) {
+	where, args := listConditions(f)
+	limitPos := len(args) + 1
+	offsetPos := len(args) + 2
+	args = append(args, f.Limit, f.Offset)
+
+	query := fmt.Sprintf(sqlSnippetListBase, strings.Join(where, " AND "), limitPos, offsetPos)
+
+	var snippets []*Snippet
+	err := r.search(ctx, f, query, args, func(rows pgx.Rows) error {
+		var err error
+		snippets, err = collectSnippets(rows, f.Limit)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return snippets, nil
+}
+
+// Facets counts the snippets matching f.Filter by language, tag and
+// creator in one query.
+func (r *Repository) Facets(ctx context.Context, f FacetFilter) (*Facets, error) {
+	where, args := listConditions(f.Filter)
+	limitPos := len(args) + 1
+	args = append(args, f.Limit)
+
+	parts := []string{`(SELECT 'total', '', count(*) FROM m)`}
+	for _, facet := range f.Facets {
+		switch facet {
+		case FacetLanguage:
+			parts = append(parts, fmt.Sprintf(sqlFacetPart, "'language'", "language", "m", limitPos))
+		case FacetTags:
+			parts = append(parts, fmt.Sprintf(sqlFacetPart, "'tags'", "t.tag", "m, unnest(m.tags) AS t(tag)", limitPos))
+		case FacetCreator:
+			parts = append(parts, fmt.Sprintf(sqlFacetPart, "'creator'", "creator_id", "m", limitPos))
+		}
+	}
+	query := fmt.Sprintf(sqlFacetsBase, strings.Join(where, " AND "), strings.Join(parts, "\n\t\tUNION ALL "))
+
+	out := &Facets{}
+	for _, facet := range f.Facets {
+		switch facet {
+		case FacetLanguage:
+			out.Language = []FacetCount{}
+		case FacetTags:
+			out.Tags = []FacetCount{}
+		case FacetCreator:
+			out.Creator = []FacetCount{}
+		}
+	}
+	err := r.search(ctx, f.Filter, query, args, func(rows pgx.Rows) error {
+		defer rows.Close()
+		for rows.Next() {
+			var facet string
+			var c FacetCount
+			if err := rows.Scan(&facet, &c.Value, &c.Count); err != nil {
+				return err
+			}
+			switch facet {
+			case "total":
+				out.Total = c.Count
+			case FacetLanguage:
+				out.Language = append(out.Language, c)
+			case FacetTags:
+				out.Tags = append(out.Tags, c)
+			case FacetCreator:
+				out.Creator = append(out.Creator, c)
+			}
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// search runs a query built from listConditions. Regex searches run in a
+// transaction with a statement timeout, since a pathological pattern must
+// not hold a connection for long.
+func (r *Repository) search(ctx context.Context, f SnippetFilter, query string, args []any, collect func(pgx.Rows) error) error {
+	if f.Mode == SearchRegex && f.Query != "" {
+		err := r.base.WithTx(ctx, func(ctx context.Context, tx pgx.Tx) error {
+			if _, err := tx.Exec(ctx, sqlSetStatementTimeout, strconv.FormatInt(r.RegexTimeout.Milliseconds(), 10)); err != nil {
+				return err
+			}
+			rows, err := tx.Query(ctx, query, args...)
+			if err != nil {
+				return err
+			}
+			return collect(rows)
+		})
+		if err != nil {
+			return searchError(err)
+		}
+		return nil
+	}
+
+	ctx, cancel := r.base.WithTimeout(ctx)
+	defer cancel()
+
+	rows, err := r.base.Q().Query(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	return collect(rows)
+}
+
+// listConditions returns the WHERE conditions of f and their arguments,
+// numbered from $1.
+func listConditions(f SnippetFilter) ([]string, []any) {
 	where := []string{"1=1"}
 	args := make([]any, 0, 8)
 	argPos := 1
@@ -113,39 +345,66 @@ func (r *Repository) List(ctx context.Context, f SnippetFilter) ([]*Snippet, err
 		argPos++
 	}
 	if f.Query != "" {
-		where = append(where, fmt.Sprintf("((search_tsv @@ plainto_tsquery('simple', $%d)) OR (name %% $%d) OR (similarity(name, $%d) > 0.25))", argPos, argPos, argPos))
+		switch f.Mode {
+		case SearchRegex:
+			// Uses idx_snippets_content_trgm to narrow candidates first.
+			where = append(where, fmt.Sprintf("content ~ $%d", argPos))
+		case SearchPhrase:
+			where = append(where, phraseClause(argPos))
+		default:
+			where = append(where, fmt.Sprintf("((search_tsv @@ plainto_tsquery('simple', $%d)) OR (name %% $%d) OR (similarity(name, $%d) > 0.25))", argPos, argPos, argPos))
+		}
 		qstr := strings.TrimSpace(f.Query)
 		args = append(args, qstr)
 		argPos += 1
 	}
+	for _, phrase := range f.Phrases {
+		where = append(where, phraseClause(argPos))
+		args = append(args, phrase)
+		argPos++
+	}
+	if len(f.Symbols) > 0 {
+		where = append(where, fmt.Sprintf("symbol_names @> $%d", argPos))
+		args = append(args, f.Symbols)
+		argPos++
+	}
 	if len(f.Tags) > 0 {
-		where = append(where, fmt.Sprintf("tags && $%d", argPos))
+		where = append(where, fmt.Sprintf("tags @> $%d", argPos))
 		args = append(args, f.Tags)
 		argPos++
 	}
+	if len(f.ExcludeTags) > 0 {
+		where = append(where, fmt.Sprintf("NOT (tags && $%d)", argPos))
+		args = append(args, f.ExcludeTags)
+		argPos++
+	}
+	if !f.CreatedAfter.IsZero() {
+		where = append(where, fmt.Sprintf("created_at >= $%d", argPos))
+		args = append(args, f.CreatedAfter)
+		argPos++
+	}
+	if !f.CreatedBefore.IsZero() {
+		where = append(where, fmt.Sprintf("created_at < $%d", argPos))
+		args = append(args, f.CreatedBefore)
+		argPos++
+	}
 
 	if f.Visibility != "" {
 		where = append(where, fmt.Sprintf("visibility = $%d", argPos))
 		args = append(args, string(f.Visibility))
-		argPos++
 	}
 
-	limit := f.Limit
-	offset := f.Offset
-
-	limitPos := argPos
-	offsetPos := argPos + 1
-	args = append(args, limit, offset)
-
-	query := fmt.Sprintf(sqlSnippetListBase, strings.Join(where, " AND "), limitPos, offsetPos)
+	return where, args
+}
 
-	ctx, cancel := r.base.WithTimeout(ctx)
-	defer cancel()
+// phraseClause matches the text in argument n verbatim, ignoring case. The
+// tsquery narrows candidates through the FTS index unless the phrase is only
+// punctuation; strpos then checks it exactly.
+func phraseClause(n int) string {
+	return fmt.Sprintf("((numnode(phraseto_tsquery('simple', $%[1]d)) = 0 OR search_tsv @@ phraseto_tsquery('simple', $%[1]d)) AND (strpos(lower(name), lower($%[1]d)) > 0 OR strpos(lower(content), lower($%[1]d)) > 0))", n)
+}
 
-	rows, err := r.base.Q().Query(ctx, query, args...)
-	if err != nil {
-		return nil, err
-	}
+func collectSnippets(rows pgx.Rows, limit int) ([]*Snippet, error) {
 	defer rows.Close()
 
 	snippets := make([]*Snippet, 0, min(limit, 128))
@@ -158,8 +417,12 @@ func (r *Repository) List(ctx context.Context, f SnippetFilter) ([]*Snippet, err
 			&s.Content,
 			&s.Language,
 			&s.Tags,
+			&s.Placeholders,
+			&s.SyntaxValid,
+			&s.Symbols,
 			&visibility,
 			&s.CreatorID,
+			&s.Revision,
 			&s.CreatedAt,
 			&s.UpdatedAt,
 		); err != nil {
@@ -169,7 +432,7 @@ func (r *Repository) List(ctx context.Context, f SnippetFilter) ([]*Snippet, err
 		snippets = append(snippets, &s)
 	}
 
-	if err = rows.Err(); err != nil {
+	if err := rows.Err(); err != nil {
 		return nil, err
 	}
 
@@ -177,28 +440,156 @@ func (r *Repository) List(ctx context.Context, f SnippetFilter) ([]*Snippet, err
 }
 
 func (r *Repository) Update(ctx context.Context, s *Snippet) error {
+	placeholders, err := json.Marshal(s.Placeholders)
+	if err != nil {
+		return err
+	}
+	index, err := newSymbolIndex(s)
+	if err != nil {
+		return err
+	}
+
+	return r.base.WithTx(ctx, func(ctx context.Context, tx pgx.Tx) error {
+		err := tx.QueryRow(ctx, sqlSnippetUpdate,
+			s.Name,
+			s.Content,
+			s.Language,
+			s.Tags,
+			placeholders,
+			s.SyntaxValid,
+			index.symbols,
+			index.names,
+			index.terms,
+			string(s.Visibility),
+			s.ID,
+		).Scan(&s.Revision, &s.CreatedAt, &s.UpdatedAt)
+		if IsNotFound(err) {
+			return ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.Exec(ctx, sqlRevisionInsert, s.ID, s.Revision, s.Name, s.Content, s.Language, s.UpdatedAt); err != nil {
+			return err
+		}
+		return secrets.ReplaceForSnippet(ctx, tx, s.ID, s.SecretFindings)
+	})
+}
+
+// ListBatch returns up to limit snippets with an id greater than afterID,
+// in id order, to walk the whole table.
+func (r *Repository) ListBatch(ctx context.Context, afterID string, limit int) ([]*Snippet, error) {
+	ctx, cancel := r.base.WithTimeout(ctx)
+	defer cancel()
+
+	rows, err := r.base.Q().Query(ctx, sqlSnippetListBatch, afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+	return collectSnippets(rows, limit)
+}
+
+// UpdateSearchColumns rewrites the columns derived from s.Symbols and the
+// content, without bumping the revision. Unchanged rows are not written.
+func (r *Repository) UpdateSearchColumns(ctx context.Context, s *Snippet) error {
+	index, err := newSymbolIndex(s)
+	if err != nil {
+		return err
+	}
 
 	ctx, cancel := r.base.WithTimeout(ctx)
 	defer cancel()
 
-	err := r.base.Q().QueryRow(ctx, sqlSnippetUpdate,
-		s.Name,
-		s.Content,
-		s.Language,
-		s.Tags,
-		string(s.Visibility),
-		s.ID,
-	).Scan(&s.UpdatedAt)
+	_, err = r.base.Q().Exec(ctx, sqlSnippetUpdateSearch, s.ID, index.symbols, index.names, index.terms)
+	return err
+}
+
+// BackfillSymbols extracts the symbols of rows saved before symbol
+// extraction existed and writes their search columns. It returns how many
+// rows were updated; rows already indexed are skipped, so it is cheap to run
+// on every start.
+func (r *Repository) BackfillSymbols(ctx context.Context) (int, error) {
+	n := 0
+	after := ""
+	for {
+		page, err := r.listSymbolsPending(ctx, after, reindexBatch)
+		if err != nil {
+			return n, err
+		}
+		for _, s := range page {
+			s.Symbols = symbols.Extract(s.Language, s.Content)
+			if err := r.UpdateSearchColumns(ctx, s); err != nil {
+				return n, fmt.Errorf("backfill %s: %w", s.ID, err)
+			}
+			n++
+		}
+		if len(page) < reindexBatch {
+			return n, nil
+		}
+		after = page[len(page)-1].ID
+	}
+}
+
+func (r *Repository) listSymbolsPending(ctx context.Context, afterID string, limit int) ([]*Snippet, error) {
+	ctx, cancel := r.base.WithTimeout(ctx)
+	defer cancel()
+
+	rows, err := r.base.Q().Query(ctx, sqlSnippetListSymbolsPending, afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+	return collectSnippets(rows, limit)
+}
+
+// symbolIndex holds the columns derived from a snippet for symbol: lookups
+// and code-aware full-text search.
+type symbolIndex struct {
+	symbols []byte
+	names   []string
+	terms   string
+}
+
+func newSymbolIndex(s *Snippet) (symbolIndex, error) {
+	list := s.Symbols
+	if list == nil {
+		list = []symbols.Symbol{}
+	}
+	raw, err := json.Marshal(list)
+	if err != nil {
+		return symbolIndex{}, err
+	}
+	return symbolIndex{
+		symbols: raw,
+		names:   symbols.Names(list),
+		terms:   strings.Join(symbols.Terms(s.Name+"\n"+s.Content), " "),
+	}, nil
+}
+
+// GetRevision loads one saved revision of a snippet.
+func (r *Repository) GetRevision(ctx context.Context, id string, revision int) (*Revision, error) {
+	ctx, cancel := r.base.WithTimeout(ctx)
+	defer cancel()
+
+	var rev Revision
+	err := r.base.Q().QueryRow(ctx, sqlRevisionSelect, id, revision).Scan(
+		&rev.SnippetID,
+		&rev.Revision,
+		&rev.Name,
+		&rev.Content,
+		&rev.Language,
+		&rev.CreatedAt,
+	)
 
 	if IsNotFound(err) {
-		return ErrNotFound
+		return nil, ErrNotFound
 	}
 
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	return nil
+	return &rev, nil
 }
 
 func (r *Repository) Delete(ctx context.Context, id string) error {
@@ -217,3 +608,137 @@ func (r *Repository) Delete(ctx context.Context, id string) error {
 
 	return nil
 }
+
+func (r *Repository) CountByLanguage(ctx context.Context, requesterID string) (map[string]int, error) {
+	ctx, cancel := r.base.WithTimeout(ctx)
+	defer cancel()
+
+	rows, err := r.base.Q().Query(ctx, sqlSnippetCountByLanguage, requesterID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int)
+	for rows.Next() {
+		var language string
+		var count int
+		if err := rows.Scan(&language, &count); err != nil {
+			return nil, err
+		}
+		counts[language] = count
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return counts, nil
+}
+
+func (r *Repository) Related(ctx context.Context, f RelatedFilter) ([]RelatedSnippet, error) {
+	ctx, cancel := r.base.WithTimeout(ctx)
+	defer cancel()
+
+	rows, err := r.base.Q().Query(ctx, sqlSnippetRelated, f.SnippetID, f.RequesterID, f.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]RelatedSnippet, 0, f.Limit)
+	for rows.Next() {
+		var rs RelatedSnippet
+		var visibility string
+		if err := rows.Scan(&rs.ID, &rs.Name, &rs.Language, &rs.Tags, &visibility, &rs.CreatorID, &rs.UpdatedAt, &rs.Score); err != nil {
+			return nil, err
+		}
+		rs.Visibility = Visibility(visibility)
+		out = append(out, rs)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *Repository) ListRelated(ctx context.Context, ids []string, requesterID string) ([]RelatedSnippet, error) {
+	ctx, cancel := r.base.WithTimeout(ctx)
+	defer cancel()
+
+	rows, err := r.base.Q().Query(ctx, sqlSnippetListRelated, ids, requesterID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]RelatedSnippet, 0, len(ids))
+	for rows.Next() {
+		var rs RelatedSnippet
+		var visibility string
+		if err := rows.Scan(&rs.ID, &rs.Name, &rs.Language, &rs.Tags, &visibility, &rs.CreatorID, &rs.UpdatedAt); err != nil {
+			return nil, err
+		}
+		rs.Visibility = Visibility(visibility)
+		out = append(out, rs)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *Repository) ListTags(ctx context.Context, f TagFilter) ([]TagCount, error) {
+	ctx, cancel := r.base.WithTimeout(ctx)
+	defer cancel()
+
+	pattern := likeEscaper.Replace(f.Prefix) + "%"
+	rows, err := r.base.Q().Query(ctx, sqlTagList, f.RequesterID, pattern, f.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := make([]TagCount, 0, min(f.Limit, 128))
+	for rows.Next() {
+		var tc TagCount
+		if err := rows.Scan(&tc.Tag, &tc.Count); err != nil {
+			return nil, err
+		}
+		tags = append(tags, tc)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return tags, nil
+}
+
+// MergeTags replaces every source tag with target on the owner's snippets and
+// returns the ids of the snippets that changed.
+func (r *Repository) MergeTags(ctx context.Context, ownerID string, sources []string, target string) ([]string, error) {
+	var ids []string
+	err := r.base.WithTx(ctx, func(ctx context.Context, tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, sqlTagLockOwned, ownerID, sources)
+		if err != nil {
+			return err
+		}
+		ids, err = pgx.CollectRows(rows, pgx.RowTo[string])
+		if err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+
+		_, err = tx.Exec(ctx, sqlTagMergeOwned, ids, sources, target)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
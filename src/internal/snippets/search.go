@@ -0,0 +1,51 @@
+package snippets
+
+import (
+	"context"
+	"time"
+
+	"github.com/PabloPavan/sniply_api/internal/telemetry"
+)
+
+// SearchIndex answers snippet list queries. The service feeds it every
+// saved or deleted snippet; Rebuild replaces its content with what fill
+// indexes, e.g. every snippet in the store.
+type SearchIndex interface {
+	Index(ctx context.Context, s *Snippet) error
+	Delete(ctx context.Context, id string) error
+	Query(ctx context.Context, f SnippetFilter) ([]*Snippet, error)
+	Rebuild(ctx context.Context, fill func(ctx context.Context, into SearchIndex) error) error
+}
+
+// reindexBatch is how many snippets a rebuild loads from the store at once.
+const reindexBatch = 500
+
+// IndexRefresher rebuilds the search index periodically, picking up changes
+// that bypass the service, such as snippets deleted with their owner or
+// saved by another API instance.
+type IndexRefresher struct {
+	Service  *Service
+	Interval time.Duration
+}
+
+func (r *IndexRefresher) Run(ctx context.Context) {
+	if r.Interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(r.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := r.Service.RebuildIndex(ctx); err != nil {
+				telemetry.LogError(ctx, "search index rebuild failed",
+					telemetry.LogString("event", "snippets.index.rebuild"),
+					telemetry.LogString("error", err.Error()),
+				)
+			}
+		}
+	}
+}
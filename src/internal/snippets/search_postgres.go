@@ -0,0 +1,39 @@
+package snippets
+
+import "context"
+
+// PostgresIndex searches the snippets table itself. The search columns are
+// written with each row, so Index and Delete have nothing to do; Rebuild
+// recomputes them for rows saved by older versions.
+type PostgresIndex struct {
+	repo *Repository
+}
+
+func NewPostgresIndex(repo *Repository) *PostgresIndex {
+	return &PostgresIndex{repo: repo}
+}
+
+func (p *PostgresIndex) Index(ctx context.Context, s *Snippet) error {
+	return nil
+}
+
+func (p *PostgresIndex) Delete(ctx context.Context, id string) error {
+	return nil
+}
+
+func (p *PostgresIndex) Query(ctx context.Context, f SnippetFilter) ([]*Snippet, error) {
+	return p.repo.List(ctx, f)
+}
+
+func (p *PostgresIndex) Rebuild(ctx context.Context, fill func(ctx context.Context, into SearchIndex) error) error {
+	return fill(ctx, postgresBackfill{p})
+}
+
+// postgresBackfill writes the search columns of every snippet it is given.
+type postgresBackfill struct {
+	*PostgresIndex
+}
+
+func (b postgresBackfill) Index(ctx context.Context, s *Snippet) error {
+	return b.repo.UpdateSearchColumns(ctx, s)
+}
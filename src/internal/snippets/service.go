@@ -2,7 +2,10 @@ package snippets
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"net/url"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
@@ -10,7 +13,15 @@ import (
 
 	"github.com/PabloPavan/sniply_api/internal"
 	"github.com/PabloPavan/sniply_api/internal/apperrors"
+	"github.com/PabloPavan/sniply_api/internal/codeformat"
+	"github.com/PabloPavan/sniply_api/internal/highlight"
 	"github.com/PabloPavan/sniply_api/internal/identity"
+	"github.com/PabloPavan/sniply_api/internal/languages"
+	"github.com/PabloPavan/sniply_api/internal/searchquery"
+	"github.com/PabloPavan/sniply_api/internal/secrets"
+	"github.com/PabloPavan/sniply_api/internal/symbols"
+	"github.com/PabloPavan/sniply_api/internal/telemetry"
+	"github.com/PabloPavan/sniply_api/internal/templates"
 	"github.com/PabloPavan/sniply_api/internal/users"
 )
 
@@ -20,35 +31,111 @@ type Store interface {
 	List(ctx context.Context, f SnippetFilter) ([]*Snippet, error)
 	Update(ctx context.Context, s *Snippet) error
 	Delete(ctx context.Context, id string) error
+	CountByLanguage(ctx context.Context, requesterID string) (map[string]int, error)
+	ListTags(ctx context.Context, f TagFilter) ([]TagCount, error)
+	MergeTags(ctx context.Context, ownerID string, sources []string, target string) ([]string, error)
+	GetRevision(ctx context.Context, id string, revision int) (*Revision, error)
+	Related(ctx context.Context, f RelatedFilter) ([]RelatedSnippet, error)
+	ListRelated(ctx context.Context, ids []string, requesterID string) ([]RelatedSnippet, error)
+	ListBatch(ctx context.Context, afterID string, limit int) ([]*Snippet, error)
+	Facets(ctx context.Context, f FacetFilter) (*Facets, error)
 }
 
 type UserLookup interface {
 	GetByID(ctx context.Context, id string) (*users.User, error)
 }
 
+type LanguageDetector interface {
+	Detect(in languages.Input) (languages.Detection, bool)
+}
+
+type SecretScanner interface {
+	Scan(content string) []secrets.Finding
+}
+
+type FindingStore interface {
+	ListForSnippet(ctx context.Context, snippetID string) ([]secrets.Finding, error)
+}
+
 type Service struct {
-	Store        Store
-	Users        UserLookup
-	Cache        Cache
-	CacheTTL     time.Duration
-	ListCacheTTL time.Duration
-	IDGenerator  func() string
+	Store           Store
+	Users           UserLookup
+	Languages       LanguageDetector
+	Cache           Cache
+	CacheTTL        time.Duration
+	ListCacheTTL    time.Duration
+	RenderCache     RenderCache
+	RenderCacheTTL  time.Duration
+	RelatedCache    RelatedCache
+	RelatedCacheTTL time.Duration
+	// FacetCache stores facet counts for ListCacheTTL.
+	FacetCache   FacetCache
+	Secrets      SecretScanner
+	SecretPolicy secrets.Policy
+	Findings     FindingStore
+	// Search answers List queries; without it the store is queried.
+	Search      SearchIndex
+	IDGenerator func() string
 }
 
+const (
+	maxRegexLength    = 256
+	defaultFacetLimit = 10
+	maxFacetLimit     = 50
+)
+
 type ListInput struct {
 	Query      string
+	Mode       SearchMode
 	Creator    string
 	Language   string
 	Tag        string
 	Visibility Visibility
-	Limit      int
-	Offset     int
+	// CreatedAfter (inclusive) and CreatedBefore (exclusive) narrow any
+	// created: qualifier in Query.
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+	Limit         int
+	Offset        int
 }
 
 func (s *Service) Create(ctx context.Context, req CreateSnippetRequest) (*Snippet, error) {
 	if s.Store == nil {
 		return nil, apperrors.New(apperrors.KindInternal, "snippets store not configured")
 	}
+	snippet, err := s.prepare(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	idGen := s.IDGenerator
+	if idGen == nil {
+		idGen = func() string {
+			return "snp_" + internal.RandomHex(12)
+		}
+	}
+	snippet.ID = idGen()
+
+	if err := s.Store.Create(ctx, snippet); err != nil {
+		if IsUniqueViolationID(err) {
+			return nil, apperrors.New(apperrors.KindConflict, "snippet already exists")
+		}
+		return nil, apperrors.New(apperrors.KindInternal, "failed to create snippet")
+	}
+
+	s.indexSnippet(ctx, snippet)
+
+	return snippet, nil
+}
+
+// Preview runs the same checks and normalization as Create without storing
+// anything, so callers can show what a create would produce. The returned
+// snippet has no ID.
+func (s *Service) Preview(ctx context.Context, req CreateSnippetRequest) (*Snippet, error) {
+	return s.prepare(ctx, req)
+}
+
+func (s *Service) prepare(ctx context.Context, req CreateSnippetRequest) (*Snippet, error) {
 	creatorID, ok := identity.UserID(ctx)
 	if !ok || strings.TrimSpace(creatorID) == "" {
 		return nil, apperrors.New(apperrors.KindUnauthorized, "unauthorized")
@@ -60,46 +147,50 @@ func (s *Service) Create(ctx context.Context, req CreateSnippetRequest) (*Snippe
 	if name == "" || content == "" {
 		return nil, apperrors.New(apperrors.KindInvalidInput, "name and content are required")
 	}
-	if language == "" {
-		language = "txt"
-	}
-	tags := req.Tags
-	if tags == nil {
-		tags = []string{}
+	language, detection, err := s.resolveLanguage(name, content, language)
+	if err != nil {
+		return nil, err
 	}
+	tags := NormalizeTags(req.Tags)
 	visibility := req.Visibility
 	if visibility == "" {
 		visibility = VisibilityPrivate
 	}
-
-	idGen := s.IDGenerator
-	if idGen == nil {
-		idGen = func() string {
-			return "snp_" + internal.RandomHex(12)
-		}
+	placeholders := req.Placeholders
+	if placeholders == nil {
+		placeholders = []templates.Placeholder{}
 	}
-
-	snippet := &Snippet{
-		ID:         idGen(),
-		Name:       name,
-		Content:    content,
-		Language:   language,
-		Tags:       tags,
-		Visibility: visibility,
-		CreatorID:  creatorID,
+	content, findings, err := s.scanSecrets(content, visibility)
+	if err != nil {
+		return nil, err
 	}
-
-	if err := s.Store.Create(ctx, snippet); err != nil {
-		if IsUniqueViolationID(err) {
-			return nil, apperrors.New(apperrors.KindConflict, "snippet already exists")
-		}
-		return nil, apperrors.New(apperrors.KindInternal, "failed to create snippet")
+	if err := templates.ValidateDefinitions(content, placeholders); err != nil {
+		return nil, templateError(err, "invalid placeholders")
+	}
+	syntaxValid, err := checkSyntax(language, content, req.ValidateSyntax)
+	if err != nil {
+		return nil, err
 	}
 
-	return snippet, nil
+	return &Snippet{
+		Name:         name,
+		Content:      content,
+		Language:     language,
+		Tags:         tags,
+		Visibility:   visibility,
+		Placeholders: placeholders,
+		SyntaxValid:  syntaxValid,
+		Symbols:      symbols.Extract(language, content),
+		CreatorID:    creatorID,
+		Detection:    detection,
+
+		SecretFindings: findings,
+	}, nil
 }
 
-func (s *Service) GetByID(ctx context.Context, id string) (*Snippet, error) {
+// GetVisible loads a snippet the requester is allowed to read: any public
+// snippet, or a private one they own. Admins can read every snippet.
+func (s *Service) GetVisible(ctx context.Context, id string) (*Snippet, error) {
 	if s.Store == nil {
 		return nil, apperrors.New(apperrors.KindInternal, "snippets store not configured")
 	}
@@ -109,7 +200,7 @@ func (s *Service) GetByID(ctx context.Context, id string) (*Snippet, error) {
 	}
 
 	if s.Cache != nil {
-		if cached, ok, err := s.Cache.GetByID(ctx, id); err == nil && ok {
+		if cached, ok, err := s.Cache.GetByID(ctx, id); err == nil && ok && canView(ctx, cached) {
 			return cached, nil
 		}
 	}
@@ -121,37 +212,465 @@ func (s *Service) GetByID(ctx context.Context, id string) (*Snippet, error) {
 		}
 		return nil, apperrors.New(apperrors.KindInternal, "failed to load snippet")
 	}
-	if snippet.Visibility != VisibilityPublic {
+	if !canView(ctx, snippet) {
 		return nil, apperrors.New(apperrors.KindNotFound, "not found")
 	}
 
-	if s.Cache != nil && s.CacheTTL > 0 {
+	if s.Cache != nil && s.CacheTTL > 0 && snippet.Visibility == VisibilityPublic {
 		_ = s.Cache.SetByID(ctx, snippet, s.CacheTTL)
 	}
 
 	return snippet, nil
 }
 
+// Render expands the snippet placeholders with the given values.
+func (s *Service) Render(ctx context.Context, id string, values map[string]string) (string, error) {
+	snippet, err := s.GetVisible(ctx, id)
+	if err != nil {
+		return "", err
+	}
+
+	out, err := templates.Render(snippet.Content, snippet.Placeholders, values)
+	if err != nil {
+		return "", templateError(err, "invalid variables")
+	}
+	return out, nil
+}
+
+// View loads a snippet the requester can read, optionally pinned to an older
+// revision and cut down to a line range. Older revisions are only shown to
+// the owner and admins: they may predate the snippet being made public.
+func (s *Service) View(ctx context.Context, id string, sel Selection) (*Snippet, error) {
+	snippet, err := s.GetVisible(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	out := *snippet
+
+	if sel.Revision < 0 {
+		return nil, apperrors.New(apperrors.KindInvalidInput, "invalid revision")
+	}
+	if sel.Revision > 0 && sel.Revision != out.Revision {
+		if !isOwnerOrAdmin(ctx, snippet) {
+			return nil, apperrors.New(apperrors.KindNotFound, "revision not found")
+		}
+		rev, err := s.Store.GetRevision(ctx, out.ID, sel.Revision)
+		if err != nil {
+			if IsNotFound(err) {
+				return nil, apperrors.New(apperrors.KindNotFound, "revision not found")
+			}
+			return nil, apperrors.New(apperrors.KindInternal, "failed to load revision")
+		}
+		out.Name = rev.Name
+		out.Content = rev.Content
+		out.Language = rev.Language
+		out.Revision = rev.Revision
+		out.UpdatedAt = rev.CreatedAt
+		out.SyntaxValid = nil
+	}
+
+	if !sel.Lines.IsZero() {
+		total := strings.Count(strings.TrimSuffix(out.Content, "\n"), "\n") + 1
+		content, lines, err := highlight.SliceLines(out.Content, sel.Lines)
+		if err != nil {
+			return nil, apperrors.New(apperrors.KindInvalidInput, err.Error())
+		}
+		out.Content = content
+		out.Excerpt = &Excerpt{
+			From:       lines.From,
+			To:         lines.To,
+			TotalLines: total,
+			Permalink:  Permalink(out.ID, out.Revision, lines),
+		}
+	}
+
+	return &out, nil
+}
+
+// Permalink is the stable API path of a line range in one revision.
+func Permalink(id string, revision int, lines highlight.Range) string {
+	q := url.Values{}
+	if !lines.IsZero() {
+		q.Set("lines", lines.String())
+	}
+	if revision > 0 {
+		q.Set("rev", strconv.Itoa(revision))
+	}
+	path := "/v1/snippets/" + url.PathEscape(id)
+	if len(q) == 0 {
+		return path
+	}
+	return path + "?" + q.Encode()
+}
+
+// Highlight renders the snippet with syntax highlighting. The output is keyed
+// by the snippet revision so updates never serve stale markup. A non-zero
+// revision renders that revision instead of the latest one.
+func (s *Service) Highlight(ctx context.Context, id string, revision int, opts highlight.Options) ([]byte, highlight.Options, error) {
+	opts, err := opts.Normalize()
+	if err != nil {
+		return nil, opts, apperrors.New(apperrors.KindInvalidInput, err.Error())
+	}
+
+	snippet, err := s.View(ctx, id, Selection{Revision: revision})
+	if err != nil {
+		return nil, opts, err
+	}
+
+	key := snippet.ID + ":" + strconv.Itoa(snippet.Revision) + ":" + strconv.FormatInt(snippet.UpdatedAt.UnixNano(), 10) + ":" + opts.Key()
+	if s.RenderCache != nil {
+		if cached, ok, err := s.RenderCache.GetRender(ctx, key); err == nil && ok {
+			return cached, opts, nil
+		}
+	}
+
+	out, err := highlight.Render(snippet.Content, snippet.Language, opts)
+	if err != nil {
+		if errors.Is(err, highlight.ErrInvalidRange) {
+			return nil, opts, apperrors.New(apperrors.KindInvalidInput, err.Error())
+		}
+		return nil, opts, apperrors.New(apperrors.KindInternal, "failed to render snippet")
+	}
+
+	if s.RenderCache != nil && s.RenderCacheTTL > 0 {
+		_ = s.RenderCache.SetRender(ctx, key, out, s.RenderCacheTTL)
+	}
+
+	return out, opts, nil
+}
+
+// Related lists snippets similar to the given one that the requester can
+// see, best match first. Results are cached per snippet revision and
+// requester.
+func (s *Service) Related(ctx context.Context, id string, limit int) ([]RelatedSnippet, error) {
+	snippet, err := s.GetVisible(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	requesterID, _ := identity.UserID(ctx)
+
+	if limit <= 0 {
+		limit = 10
+	}
+	limit = min(limit, 50)
+
+	key := snippet.ID + ":" + strconv.Itoa(snippet.Revision) + ":" + requesterID + ":" + strconv.Itoa(limit)
+	if s.RelatedCache != nil {
+		if cached, ok, err := s.RelatedCache.GetRelated(ctx, key); err == nil && ok {
+			if related, err := s.reloadRelated(ctx, cached, requesterID); err == nil {
+				return related, nil
+			}
+		}
+	}
+
+	related, err := s.Store.Related(ctx, RelatedFilter{
+		SnippetID:   snippet.ID,
+		RequesterID: requesterID,
+		Limit:       limit,
+	})
+	if err != nil {
+		return nil, apperrors.New(apperrors.KindInternal, "failed to load related snippets")
+	}
+
+	if s.RelatedCache != nil && s.RelatedCacheTTL > 0 {
+		ranked := make([]RelatedSnippet, len(related))
+		for i, rs := range related {
+			ranked[i] = RelatedSnippet{ID: rs.ID, Score: rs.Score}
+		}
+		_ = s.RelatedCache.SetRelated(ctx, key, ranked, s.RelatedCacheTTL)
+	}
+
+	return related, nil
+}
+
+// reloadRelated turns cached ids and scores back into results. Candidates are
+// reloaded with the visibility check, so ones made private or deleted since
+// the ranking was cached are dropped.
+func (s *Service) reloadRelated(ctx context.Context, ranked []RelatedSnippet, requesterID string) ([]RelatedSnippet, error) {
+	if len(ranked) == 0 {
+		return []RelatedSnippet{}, nil
+	}
+	ids := make([]string, len(ranked))
+	for i, rs := range ranked {
+		ids[i] = rs.ID
+	}
+	list, err := s.Store.ListRelated(ctx, ids, requesterID)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[string]RelatedSnippet, len(list))
+	for _, rs := range list {
+		byID[rs.ID] = rs
+	}
+
+	out := make([]RelatedSnippet, 0, len(ranked))
+	for _, cached := range ranked {
+		rs, ok := byID[cached.ID]
+		if !ok {
+			continue
+		}
+		rs.Score = cached.Score
+		out = append(out, rs)
+	}
+	return out, nil
+}
+
+// LanguageStats lists the language registry with the number of snippets the
+// requester can see in each language.
+func (s *Service) LanguageStats(ctx context.Context) ([]LanguageStat, error) {
+	if s.Store == nil {
+		return nil, apperrors.New(apperrors.KindInternal, "snippets store not configured")
+	}
+	requesterID, _ := identity.UserID(ctx)
+
+	counts, err := s.Store.CountByLanguage(ctx, requesterID)
+	if err != nil {
+		return nil, apperrors.New(apperrors.KindInternal, "failed to count languages")
+	}
+
+	list := languages.Default.List()
+	out := make([]LanguageStat, 0, len(list))
+	for _, l := range list {
+		out = append(out, LanguageStat{Language: l, Count: counts[l.ID]})
+	}
+	return out, nil
+}
+
+// Tags lists tags used on snippets visible to the requester, most used first.
+func (s *Service) Tags(ctx context.Context, prefix string, limit int) ([]TagCount, error) {
+	if s.Store == nil {
+		return nil, apperrors.New(apperrors.KindInternal, "snippets store not configured")
+	}
+	requesterID, _ := identity.UserID(ctx)
+
+	if limit <= 0 {
+		limit = 50
+	}
+	limit = min(limit, 500)
+
+	tags, err := s.Store.ListTags(ctx, TagFilter{
+		Prefix:      strings.ToLower(strings.TrimSpace(prefix)),
+		RequesterID: requesterID,
+		Limit:       limit,
+	})
+	if err != nil {
+		return nil, apperrors.New(apperrors.KindInternal, "failed to list tags")
+	}
+	return tags, nil
+}
+
+// RenameTag renames a tag across the requester's snippets. Renaming onto a
+// tag that already exists merges the two.
+func (s *Service) RenameTag(ctx context.Context, from, to string) (int, error) {
+	return s.MergeTags(ctx, []string{from}, to)
+}
+
+// MergeTags replaces every source tag with target across the requester's
+// snippets and returns how many snippets changed.
+func (s *Service) MergeTags(ctx context.Context, sources []string, target string) (int, error) {
+	if s.Store == nil {
+		return 0, apperrors.New(apperrors.KindInternal, "snippets store not configured")
+	}
+	requesterID, ok := identity.UserID(ctx)
+	if !ok || strings.TrimSpace(requesterID) == "" {
+		return 0, apperrors.New(apperrors.KindUnauthorized, "unauthorized")
+	}
+
+	target = strings.ToLower(strings.TrimSpace(target))
+	if target == "" {
+		return 0, apperrors.New(apperrors.KindInvalidInput, "target tag is required")
+	}
+	from := make([]string, 0, len(sources))
+	for _, tag := range NormalizeTags(sources) {
+		if tag != target {
+			from = append(from, tag)
+		}
+	}
+	if len(from) == 0 {
+		return 0, apperrors.New(apperrors.KindInvalidInput, "source tags are required")
+	}
+
+	ids, err := s.Store.MergeTags(ctx, requesterID, from, target)
+	if err != nil {
+		return 0, apperrors.New(apperrors.KindInternal, "failed to update tags")
+	}
+
+	if s.Cache != nil {
+		for _, id := range ids {
+			_ = s.Cache.DeleteByID(ctx, id)
+		}
+	}
+	if s.Search != nil {
+		for _, id := range ids {
+			if snippet, err := s.Store.GetByID(ctx, id); err == nil {
+				s.indexSnippet(ctx, snippet)
+			}
+		}
+	}
+
+	return len(ids), nil
+}
+
 func (s *Service) List(ctx context.Context, input ListInput) ([]*Snippet, error) {
 	if s.Store == nil {
 		return nil, apperrors.New(apperrors.KindInternal, "snippets store not configured")
 	}
+	filter, err := s.listFilter(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.Cache != nil && filter.Visibility == VisibilityPublic {
+		cacheKey := listCacheKey(filter)
+		if cached, ok, err := s.Cache.GetList(ctx, cacheKey); err == nil && ok {
+			return cached, nil
+		}
+	}
+
+	list, err := s.query(ctx, filter)
+	if err != nil {
+		return nil, searchAppError(err, "failed to list snippets")
+	}
+	if len(list) == 0 {
+		return nil, apperrors.New(apperrors.KindNotFound, "not found any snippets")
+	}
+
+	if s.Cache != nil && filter.Visibility == VisibilityPublic && s.ListCacheTTL > 0 {
+		cacheKey := listCacheKey(filter)
+		_ = s.Cache.SetList(ctx, cacheKey, list, s.ListCacheTTL)
+	}
+
+	return list, nil
+}
+
+// Facets counts the snippets List would return for input by language, tag
+// and creator. Empty facets means all of them; limit caps the values per
+// facet.
+func (s *Service) Facets(ctx context.Context, input ListInput, facets []string, limit int) (*Facets, error) {
+	if s.Store == nil {
+		return nil, apperrors.New(apperrors.KindInternal, "snippets store not configured")
+	}
+
+	names := make([]string, 0, 3)
+	for _, name := range facets {
+		name = strings.ToLower(strings.TrimSpace(name))
+		switch name {
+		case "":
+			continue
+		case FacetLanguage, FacetTags, FacetCreator:
+			if !slices.Contains(names, name) {
+				names = append(names, name)
+			}
+		default:
+			return nil, apperrors.New(apperrors.KindInvalidInput, "unknown facet: "+name)
+		}
+	}
+	if len(names) == 0 {
+		names = []string{FacetLanguage, FacetTags, FacetCreator}
+	}
+	if limit <= 0 {
+		limit = defaultFacetLimit
+	}
+	limit = min(limit, maxFacetLimit)
+
+	filter, err := s.listFilter(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+	filter.Limit, filter.Offset = 0, 0
+	f := FacetFilter{Filter: filter, Facets: names, Limit: limit}
+
+	cacheKey := ""
+	if s.FacetCache != nil && filter.Visibility == VisibilityPublic {
+		cacheKey = facetCacheKey(f)
+		if cached, ok, err := s.FacetCache.GetFacets(ctx, cacheKey); err == nil && ok {
+			return cached, nil
+		}
+	}
+
+	out, err := s.Store.Facets(ctx, f)
+	if err != nil {
+		return nil, searchAppError(err, "failed to count facets")
+	}
 
+	if cacheKey != "" && s.ListCacheTTL > 0 {
+		_ = s.FacetCache.SetFacets(ctx, cacheKey, out, s.ListCacheTTL)
+	}
+	return out, nil
+}
+
+// listFilter validates a list input and turns it into a store filter,
+// applying the visibility rules of List.
+func (s *Service) listFilter(ctx context.Context, input ListInput) (SnippetFilter, error) {
 	input.Query = strings.TrimSpace(input.Query)
+	input.Mode = SearchMode(strings.ToLower(strings.TrimSpace(string(input.Mode))))
+	if input.Mode == "" {
+		input.Mode = SearchFTS
+	}
+	if !input.Mode.Valid() {
+		return SnippetFilter{}, apperrors.New(apperrors.KindInvalidInput, "mode must be fts, phrase or regex")
+	}
+	if input.Mode == SearchRegex && len(input.Query) > maxRegexLength {
+		return SnippetFilter{}, apperrors.New(apperrors.KindInvalidInput, "regular expression is too long")
+	}
 	input.Creator = strings.TrimSpace(input.Creator)
 	input.Language = strings.TrimSpace(input.Language)
-	input.Tag = strings.TrimSpace(input.Tag)
+	if input.Language != "" {
+		input.Language = languages.Normalize(input.Language)
+	}
+
+	input.Tag = strings.ToLower(strings.TrimSpace(input.Tag))
+	var tags []string
+	if input.Tag != "" {
+		tags = []string{input.Tag}
+	}
+
+	// Qualifiers are only read in fts mode; phrases and patterns are
+	// matched as written.
+	var parsed searchquery.Query
+	if input.Mode == SearchFTS && input.Query != "" {
+		var err error
+		parsed, err = searchquery.Parse(input.Query)
+		if err != nil {
+			return SnippetFilter{}, apperrors.New(apperrors.KindInvalidInput, err.Error())
+		}
+		input.Query = parsed.Text
+		if parsed.Language != "" {
+			lang := languages.Normalize(parsed.Language)
+			if input.Language != "" && input.Language != lang {
+				return SnippetFilter{}, apperrors.New(apperrors.KindInvalidInput, "lang: conflicts with the language parameter")
+			}
+			input.Language = lang
+		}
+		if parsed.Author != "" {
+			if input.Creator != "" && input.Creator != parsed.Author {
+				return SnippetFilter{}, apperrors.New(apperrors.KindInvalidInput, "author: conflicts with the creator parameter")
+			}
+			input.Creator = parsed.Author
+		}
+		if parsed.Visibility != "" {
+			v := Visibility(parsed.Visibility)
+			if input.Visibility != "" && input.Visibility != v {
+				return SnippetFilter{}, apperrors.New(apperrors.KindInvalidInput, "is: conflicts with the visibility parameter")
+			}
+			input.Visibility = v
+		}
+		tags = NormalizeTags(append(tags, parsed.Tags...))
+		if len(tags) == 0 {
+			tags = nil
+		}
+	}
 
 	if input.Creator != "" {
 		if s.Users == nil {
-			return nil, apperrors.New(apperrors.KindInternal, "users store not configured")
+			return SnippetFilter{}, apperrors.New(apperrors.KindInternal, "users store not configured")
 		}
 		_, err := s.Users.GetByID(ctx, input.Creator)
 		if err != nil {
 			if users.IsNotFound(err) {
-				return nil, apperrors.New(apperrors.KindInvalidInput, "creator not found")
+				return SnippetFilter{}, apperrors.New(apperrors.KindInvalidInput, "creator not found")
 			}
-			return nil, apperrors.New(apperrors.KindInternal, "failed to load creator")
+			return SnippetFilter{}, apperrors.New(apperrors.KindInternal, "failed to load creator")
 		}
 	}
 
@@ -161,14 +680,14 @@ func (s *Service) List(ctx context.Context, input ListInput) ([]*Snippet, error)
 	}
 	if visibility == VisibilityPrivate {
 		if input.Creator == "" {
-			return nil, apperrors.New(apperrors.KindInvalidInput, "creator is required")
+			return SnippetFilter{}, apperrors.New(apperrors.KindInvalidInput, "creator is required")
 		}
 		requesterID, ok := identity.UserID(ctx)
 		if !ok || strings.TrimSpace(requesterID) == "" {
-			return nil, apperrors.New(apperrors.KindUnauthorized, "unauthorized")
+			return SnippetFilter{}, apperrors.New(apperrors.KindUnauthorized, "unauthorized")
 		}
 		if !identity.IsAdmin(ctx) && requesterID != input.Creator {
-			return nil, apperrors.New(apperrors.KindForbidden, "forbidden")
+			return SnippetFilter{}, apperrors.New(apperrors.KindForbidden, "forbidden")
 		}
 	}
 
@@ -181,42 +700,74 @@ func (s *Service) List(ctx context.Context, input ListInput) ([]*Snippet, error)
 		offset = input.Offset
 	}
 
-	var tags []string
-	if input.Tag != "" {
-		tags = []string{input.Tag}
-	}
-
 	filter := SnippetFilter{
-		Query:      input.Query,
-		Creator:    input.Creator,
-		Language:   input.Language,
-		Tags:       tags,
-		Visibility: visibility,
-		Limit:      limit,
-		Offset:     offset,
+		Query:         input.Query,
+		Mode:          input.Mode,
+		Phrases:       parsed.Phrases,
+		Creator:       input.Creator,
+		Language:      input.Language,
+		Tags:          tags,
+		ExcludeTags:   NormalizeTags(parsed.ExcludeTags),
+		Symbols:       parsed.Symbols,
+		CreatedAfter:  parsed.CreatedAfter,
+		CreatedBefore: parsed.CreatedBefore,
+		Visibility:    visibility,
+		Limit:         limit,
+		Offset:        offset,
+	}
+	if len(filter.ExcludeTags) == 0 {
+		filter.ExcludeTags = nil
+	}
+	if !input.CreatedAfter.IsZero() && input.CreatedAfter.After(filter.CreatedAfter) {
+		filter.CreatedAfter = input.CreatedAfter
+	}
+	if !input.CreatedBefore.IsZero() && (filter.CreatedBefore.IsZero() || input.CreatedBefore.Before(filter.CreatedBefore)) {
+		filter.CreatedBefore = input.CreatedBefore
 	}
 
-	if s.Cache != nil && visibility == VisibilityPublic {
-		cacheKey := listCacheKey(filter)
-		if cached, ok, err := s.Cache.GetList(ctx, cacheKey); err == nil && ok {
-			return cached, nil
-		}
+	return filter, nil
+}
+
+// searchAppError maps a store or index search error; msg describes any
+// other failure.
+func searchAppError(err error, msg string) error {
+	switch {
+	case errors.Is(err, ErrSearchTimeout):
+		return apperrors.New(apperrors.KindInvalidInput, "search timed out, use a more specific pattern")
+	case errors.Is(err, ErrInvalidRegex):
+		return apperrors.New(apperrors.KindInvalidInput, "invalid regular expression")
 	}
+	return apperrors.New(apperrors.KindInternal, msg)
+}
 
-	list, err := s.Store.List(ctx, filter)
-	if err != nil {
-		return nil, apperrors.New(apperrors.KindInternal, "failed to list snippets")
+// ListOwned returns every snippet of the requester, public and private,
+// paging through the store so exports are not capped by the list limit.
+func (s *Service) ListOwned(ctx context.Context) ([]*Snippet, error) {
+	if s.Store == nil {
+		return nil, apperrors.New(apperrors.KindInternal, "snippets store not configured")
 	}
-	if len(list) == 0 {
-		return nil, apperrors.New(apperrors.KindNotFound, "not found any snippets")
+	requesterID, ok := identity.UserID(ctx)
+	if !ok || strings.TrimSpace(requesterID) == "" {
+		return nil, apperrors.New(apperrors.KindUnauthorized, "unauthorized")
 	}
 
-	if s.Cache != nil && visibility == VisibilityPublic && s.ListCacheTTL > 0 {
-		cacheKey := listCacheKey(filter)
-		_ = s.Cache.SetList(ctx, cacheKey, list, s.ListCacheTTL)
+	const pageSize = 1000
+	out := make([]*Snippet, 0)
+	for offset := 0; ; offset += pageSize {
+		page, err := s.Store.List(ctx, SnippetFilter{
+			Creator: requesterID,
+			Limit:   pageSize,
+			Offset:  offset,
+		})
+		if err != nil {
+			return nil, apperrors.New(apperrors.KindInternal, "failed to list snippets")
+		}
+		out = append(out, page...)
+		if len(page) < pageSize {
+			break
+		}
 	}
-
-	return list, nil
+	return out, nil
 }
 
 func (s *Service) Update(ctx context.Context, id string, req CreateSnippetRequest) (*Snippet, error) {
@@ -232,32 +783,62 @@ func (s *Service) Update(ctx context.Context, id string, req CreateSnippetReques
 		return nil, apperrors.New(apperrors.KindInvalidInput, "id is required")
 	}
 
+	current, err := s.Store.GetByID(ctx, id)
+	if err != nil {
+		if IsNotFound(err) {
+			return nil, apperrors.New(apperrors.KindNotFound, "not found")
+		}
+		return nil, apperrors.New(apperrors.KindInternal, "failed to load snippet")
+	}
+	if !isOwnerOrAdmin(ctx, current) {
+		return nil, apperrors.New(apperrors.KindNotFound, "not found")
+	}
+
 	name := strings.TrimSpace(req.Name)
 	content := strings.TrimSpace(req.Content)
 	language := strings.TrimSpace(req.Language)
 	if name == "" || content == "" {
 		return nil, apperrors.New(apperrors.KindInvalidInput, "name and content are required")
 	}
-	if language == "" {
-		language = "txt"
-	}
-	tags := req.Tags
-	if tags == nil {
-		tags = []string{}
+	language, detection, err := s.resolveLanguage(name, content, language)
+	if err != nil {
+		return nil, err
 	}
+	tags := NormalizeTags(req.Tags)
 	visibility := req.Visibility
 	if visibility == "" {
 		visibility = VisibilityPrivate
 	}
+	placeholders := req.Placeholders
+	if placeholders == nil {
+		placeholders = []templates.Placeholder{}
+	}
+	content, findings, err := s.scanSecrets(content, visibility)
+	if err != nil {
+		return nil, err
+	}
+	if err := templates.ValidateDefinitions(content, placeholders); err != nil {
+		return nil, templateError(err, "invalid placeholders")
+	}
+	syntaxValid, err := checkSyntax(language, content, req.ValidateSyntax)
+	if err != nil {
+		return nil, err
+	}
 
 	snippet := &Snippet{
-		ID:         id,
-		Name:       name,
-		Content:    content,
-		Language:   language,
-		Tags:       tags,
-		Visibility: visibility,
-		CreatorID:  requesterID,
+		ID:           id,
+		Name:         name,
+		Content:      content,
+		Language:     language,
+		Tags:         tags,
+		Visibility:   visibility,
+		Placeholders: placeholders,
+		SyntaxValid:  syntaxValid,
+		Symbols:      symbols.Extract(language, content),
+		CreatorID:    current.CreatorID,
+		Detection:    detection,
+
+		SecretFindings: findings,
 	}
 
 	if err := s.Store.Update(ctx, snippet); err != nil {
@@ -270,6 +851,7 @@ func (s *Service) Update(ctx context.Context, id string, req CreateSnippetReques
 	if s.Cache != nil {
 		_ = s.Cache.DeleteByID(ctx, id)
 	}
+	s.indexSnippet(ctx, snippet)
 
 	return snippet, nil
 }
@@ -308,14 +890,323 @@ func (s *Service) Delete(ctx context.Context, id string) error {
 	if s.Cache != nil {
 		_ = s.Cache.DeleteByID(ctx, id)
 	}
+	if s.Search != nil {
+		if err := s.Search.Delete(ctx, id); err != nil {
+			telemetry.LogError(ctx, "search index delete failed",
+				telemetry.LogString("event", "snippets.index.delete"),
+				telemetry.LogString("snippet.id", id),
+				telemetry.LogString("error", err.Error()),
+			)
+		}
+	}
 
 	return nil
 }
 
+// Format rewrites the snippet content in the canonical layout for its
+// language. With dryRun the formatted snippet is returned without saving.
+func (s *Service) Format(ctx context.Context, id string, dryRun bool) (*Snippet, error) {
+	if s.Store == nil {
+		return nil, apperrors.New(apperrors.KindInternal, "snippets store not configured")
+	}
+	requesterID, ok := identity.UserID(ctx)
+	if !ok || strings.TrimSpace(requesterID) == "" {
+		return nil, apperrors.New(apperrors.KindUnauthorized, "unauthorized")
+	}
+	id = strings.TrimSpace(id)
+	if id == "" {
+		return nil, apperrors.New(apperrors.KindInvalidInput, "id is required")
+	}
+
+	snippet, err := s.Store.GetByID(ctx, id)
+	if err != nil {
+		if IsNotFound(err) {
+			return nil, apperrors.New(apperrors.KindNotFound, "not found")
+		}
+		return nil, apperrors.New(apperrors.KindInternal, "failed to load snippet")
+	}
+	if snippet.CreatorID != requesterID && !identity.IsAdmin(ctx) {
+		return nil, apperrors.New(apperrors.KindNotFound, "not found")
+	}
+	if !codeformat.CanFormat(snippet.Language) {
+		return nil, apperrors.New(apperrors.KindInvalidInput, "formatting is not supported for "+snippet.Language)
+	}
+
+	formatted, err := codeformat.Format(snippet.Language, snippet.Content)
+	if err != nil {
+		return nil, syntaxError(err)
+	}
+	// A save like any other: the formatted content goes through the same
+	// secret policy and placeholder checks as Update.
+	formatted, findings, err := s.scanSecrets(formatted, snippet.Visibility)
+	if err != nil {
+		return nil, err
+	}
+	if err := templates.ValidateDefinitions(formatted, snippet.Placeholders); err != nil {
+		return nil, templateError(err, "invalid placeholders")
+	}
+	valid := true
+	snippet.Content = formatted
+	snippet.SyntaxValid = &valid
+	snippet.Symbols = symbols.Extract(snippet.Language, formatted)
+	snippet.SecretFindings = findings
+	if dryRun {
+		return snippet, nil
+	}
+
+	if err := s.Store.Update(ctx, snippet); err != nil {
+		if IsNotFound(err) {
+			return nil, apperrors.New(apperrors.KindNotFound, "not found")
+		}
+		return nil, apperrors.New(apperrors.KindInternal, "failed to update snippet")
+	}
+
+	if s.Cache != nil {
+		_ = s.Cache.DeleteByID(ctx, id)
+	}
+	s.indexSnippet(ctx, snippet)
+
+	return snippet, nil
+}
+
+// Reindex rebuilds the search index from the store. Admin only.
+func (s *Service) Reindex(ctx context.Context) (int, error) {
+	if _, ok := identity.UserID(ctx); !ok {
+		return 0, apperrors.New(apperrors.KindUnauthorized, "unauthorized")
+	}
+	if !identity.IsAdmin(ctx) {
+		return 0, apperrors.New(apperrors.KindForbidden, "forbidden")
+	}
+	n, err := s.RebuildIndex(ctx)
+	if err != nil {
+		var appErr *apperrors.Error
+		if errors.As(err, &appErr) {
+			return 0, err
+		}
+		return 0, apperrors.New(apperrors.KindInternal, "failed to rebuild search index")
+	}
+	return n, nil
+}
+
+// RebuildIndex feeds every stored snippet to a fresh search index and
+// returns how many were indexed. Symbols are extracted again, so snippets
+// saved before an extractor change are brought up to date.
+func (s *Service) RebuildIndex(ctx context.Context) (int, error) {
+	if s.Store == nil || s.Search == nil {
+		return 0, apperrors.New(apperrors.KindInternal, "search index not configured")
+	}
+
+	n := 0
+	err := s.Search.Rebuild(ctx, func(ctx context.Context, into SearchIndex) error {
+		after := ""
+		for {
+			page, err := s.Store.ListBatch(ctx, after, reindexBatch)
+			if err != nil {
+				return err
+			}
+			for _, snippet := range page {
+				snippet.Symbols = symbols.Extract(snippet.Language, snippet.Content)
+				if err := into.Index(ctx, snippet); err != nil {
+					return fmt.Errorf("index %s: %w", snippet.ID, err)
+				}
+				n++
+			}
+			if len(page) < reindexBatch {
+				return nil
+			}
+			after = page[len(page)-1].ID
+		}
+	})
+	if err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// query runs a list filter against the search index, or the store when no
+// index is configured.
+func (s *Service) query(ctx context.Context, f SnippetFilter) ([]*Snippet, error) {
+	if s.Search != nil {
+		return s.Search.Query(ctx, f)
+	}
+	return s.Store.List(ctx, f)
+}
+
+// indexSnippet feeds a saved snippet to the search index. The write has
+// already succeeded, so a failure is only logged; the next rebuild repairs
+// the index.
+func (s *Service) indexSnippet(ctx context.Context, snippet *Snippet) {
+	if s.Search == nil {
+		return
+	}
+	if err := s.Search.Index(ctx, snippet); err != nil {
+		telemetry.LogError(ctx, "search index update failed",
+			telemetry.LogString("event", "snippets.index.update"),
+			telemetry.LogString("snippet.id", snippet.ID),
+			telemetry.LogString("error", err.Error()),
+		)
+	}
+}
+
+// checkSyntax parses content when the language has a validator. Invalid
+// content is only rejected in strict mode; otherwise it is flagged.
+func checkSyntax(language, content string, strict bool) (*bool, error) {
+	if !codeformat.Supported(language) {
+		return nil, nil
+	}
+	err := codeformat.Validate(language, content)
+	if err != nil && strict {
+		return nil, syntaxError(err)
+	}
+	valid := err == nil
+	return &valid, nil
+}
+
+func syntaxError(err error) error {
+	var fmtErr *codeformat.Error
+	if !errors.As(err, &fmtErr) {
+		return apperrors.New(apperrors.KindInvalidInput, "invalid syntax")
+	}
+	return apperrors.Validation("invalid syntax", []apperrors.FieldError{{
+		Field:   "content",
+		Code:    "syntax",
+		Message: fmtErr.Error(),
+	}})
+}
+
+// scanSecrets applies the secret policy to content. Blocking only applies to
+// public snippets; private ones are saved with a warning.
+func (s *Service) scanSecrets(content string, visibility Visibility) (string, []secrets.Finding, error) {
+	if s.Secrets == nil || s.SecretPolicy == secrets.PolicyOff {
+		return content, nil, nil
+	}
+	findings := s.Secrets.Scan(content)
+	if len(findings) == 0 {
+		return content, nil, nil
+	}
+
+	switch s.SecretPolicy {
+	case secrets.PolicyRedact:
+		content = secrets.Redact(content, findings)
+		for i := range findings {
+			findings[i].Redacted = true
+		}
+	case secrets.PolicyWarn:
+	default:
+		if visibility == VisibilityPublic {
+			fields := make([]apperrors.FieldError, 0, len(findings))
+			for _, f := range findings {
+				fields = append(fields, apperrors.FieldError{
+					Field:   "content",
+					Code:    f.RuleID,
+					Message: fmt.Sprintf("%s at line %d, column %d", f.Description, f.Line, f.Column),
+				})
+			}
+			return "", nil, apperrors.Validation("public snippets cannot contain secrets", fields)
+		}
+	}
+	return content, findings, nil
+}
+
+// SecretFindings returns the findings stored for the snippet's last save.
+// Only the owner and admins can see them.
+func (s *Service) SecretFindings(ctx context.Context, id string) ([]secrets.Finding, error) {
+	if s.Store == nil || s.Findings == nil {
+		return nil, apperrors.New(apperrors.KindInternal, "secret findings not configured")
+	}
+	requesterID, ok := identity.UserID(ctx)
+	if !ok || strings.TrimSpace(requesterID) == "" {
+		return nil, apperrors.New(apperrors.KindUnauthorized, "unauthorized")
+	}
+
+	snippet, err := s.Store.GetByID(ctx, strings.TrimSpace(id))
+	if err != nil {
+		if IsNotFound(err) {
+			return nil, apperrors.New(apperrors.KindNotFound, "not found")
+		}
+		return nil, apperrors.New(apperrors.KindInternal, "failed to load snippet")
+	}
+	if snippet.CreatorID != requesterID && !identity.IsAdmin(ctx) {
+		return nil, apperrors.New(apperrors.KindNotFound, "not found")
+	}
+
+	findings, err := s.Findings.ListForSnippet(ctx, snippet.ID)
+	if err != nil {
+		return nil, apperrors.New(apperrors.KindInternal, "failed to load secret findings")
+	}
+	return findings, nil
+}
+
+// NormalizeTags lowercases and trims tags, dropping blanks and duplicates
+// while keeping the original order.
+func NormalizeTags(tags []string) []string {
+	out := make([]string, 0, len(tags))
+	seen := make(map[string]struct{}, len(tags))
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" {
+			continue
+		}
+		if _, ok := seen[tag]; ok {
+			continue
+		}
+		seen[tag] = struct{}{}
+		out = append(out, tag)
+	}
+	return out
+}
+
+// resolveLanguage normalizes aliases to their canonical id. A blank or "auto"
+// language is detected from the content, using the name as a file name hint.
+func (s *Service) resolveLanguage(name, content, language string) (string, *languages.Detection, error) {
+	if language != "" && !strings.EqualFold(language, "auto") {
+		l, ok := languages.Lookup(language)
+		if !ok {
+			return "", nil, apperrors.New(apperrors.KindInvalidInput, "unsupported language")
+		}
+		return l.ID, nil, nil
+	}
+	if s.Languages != nil {
+		if det, ok := s.Languages.Detect(languages.Input{Content: content, Filename: name}); ok {
+			return det.Language, &det, nil
+		}
+	}
+	return languages.Fallback, nil, nil
+}
+
+func canView(ctx context.Context, snippet *Snippet) bool {
+	if snippet.Visibility == VisibilityPublic || identity.IsAdmin(ctx) {
+		return true
+	}
+	requesterID, ok := identity.UserID(ctx)
+	return ok && requesterID != "" && requesterID == snippet.CreatorID
+}
+
+func isOwnerOrAdmin(ctx context.Context, snippet *Snippet) bool {
+	if identity.IsAdmin(ctx) {
+		return true
+	}
+	requesterID, ok := identity.UserID(ctx)
+	return ok && requesterID != "" && requesterID == snippet.CreatorID
+}
+
+func templateError(err error, msg string) error {
+	var tErr *templates.Error
+	if !errors.As(err, &tErr) {
+		return apperrors.New(apperrors.KindInvalidInput, msg)
+	}
+	fields := make([]apperrors.FieldError, 0, len(tErr.Issues))
+	for _, issue := range tErr.Issues {
+		fields = append(fields, apperrors.FieldError{Field: issue.Field, Code: issue.Code, Message: issue.Message})
+	}
+	return apperrors.Validation(msg, fields)
+}
+
 func listCacheKey(f SnippetFilter) string {
 	v := url.Values{}
 	if f.Query != "" {
 		v.Set("q", f.Query)
+		v.Set("mode", string(f.Mode))
 	}
 	if f.Creator != "" {
 		v.Set("creator", f.Creator)
@@ -328,6 +1219,23 @@ func listCacheKey(f SnippetFilter) string {
 		sort.Strings(tags)
 		v.Set("tags", strings.Join(tags, ","))
 	}
+	if len(f.Phrases) > 0 {
+		v["phrase"] = f.Phrases
+	}
+	if len(f.ExcludeTags) > 0 {
+		tags := append([]string(nil), f.ExcludeTags...)
+		sort.Strings(tags)
+		v.Set("exclude_tags", strings.Join(tags, ","))
+	}
+	if len(f.Symbols) > 0 {
+		v["symbol"] = f.Symbols
+	}
+	if !f.CreatedAfter.IsZero() {
+		v.Set("created_after", f.CreatedAfter.Format(time.RFC3339))
+	}
+	if !f.CreatedBefore.IsZero() {
+		v.Set("created_before", f.CreatedBefore.Format(time.RFC3339))
+	}
 	if f.Visibility != "" {
 		v.Set("visibility", string(f.Visibility))
 	}
@@ -339,3 +1247,9 @@ func listCacheKey(f SnippetFilter) string {
 	}
 	return v.Encode()
 }
+
+func facetCacheKey(f FacetFilter) string {
+	facets := append([]string(nil), f.Facets...)
+	sort.Strings(facets)
+	return listCacheKey(f.Filter) + "&facets=" + strings.Join(facets, ",") + "&facet_limit=" + strconv.Itoa(f.Limit)
+}
@@ -0,0 +1,80 @@
+package symbols
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strings"
+)
+
+// extractGo parses content as a Go file. Snippets often leave out the
+// package clause, so one is added when missing; content that still does not
+// parse falls back to the lexer.
+func extractGo(content string) []Symbol {
+	offset := 0
+	src := content
+	if !goPackageRe.MatchString(content) {
+		src = "package snippet\n" + content
+		offset = 1
+	}
+
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", src, parser.SkipObjectResolution)
+	if err != nil {
+		return extractLexer("go", content)
+	}
+
+	var out []Symbol
+	line := func(pos token.Pos) int { return fset.Position(pos).Line - offset }
+	for _, decl := range file.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			s := Symbol{Name: d.Name.Name, Kind: "func", Line: line(d.Name.Pos())}
+			if d.Recv != nil && len(d.Recv.List) > 0 {
+				s.Kind = "method"
+				s.Container = receiverName(d.Recv.List[0].Type)
+			}
+			out = append(out, s)
+		case *ast.GenDecl:
+			for _, spec := range d.Specs {
+				switch sp := spec.(type) {
+				case *ast.TypeSpec:
+					kind := "type"
+					switch sp.Type.(type) {
+					case *ast.StructType:
+						kind = "struct"
+					case *ast.InterfaceType:
+						kind = "interface"
+					}
+					out = append(out, Symbol{Name: sp.Name.Name, Kind: kind, Line: line(sp.Name.Pos())})
+				case *ast.ValueSpec:
+					kind := strings.ToLower(d.Tok.String())
+					for _, name := range sp.Names {
+						if name.Name == "_" {
+							continue
+						}
+						out = append(out, Symbol{Name: name.Name, Kind: kind, Line: line(name.Pos())})
+					}
+				}
+			}
+		}
+	}
+	return out
+}
+
+func receiverName(expr ast.Expr) string {
+	for {
+		switch e := expr.(type) {
+		case *ast.StarExpr:
+			expr = e.X
+		case *ast.IndexExpr:
+			expr = e.X
+		case *ast.IndexListExpr:
+			expr = e.X
+		case *ast.Ident:
+			return e.Name
+		default:
+			return ""
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package symbols
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	identRe     = regexp.MustCompile(`[A-Za-z_$][\w$]*(?:(?:\.|::|->)[A-Za-z_$][\w$]*)*`)
+	goPackageRe = regexp.MustCompile(`(?m)^\s*package\s+\w+`)
+)
+
+// rule matches one definition per line. The pattern has a "name" group and
+// either a "kind" group or a fixed kind; an optional "type" group must not
+// be a keyword.
+type rule struct {
+	kind string
+	re   *regexp.Regexp
+}
+
+func def(kind, pattern string) rule {
+	return rule{kind: kind, re: regexp.MustCompile(pattern)}
+}
+
+var (
+	cFamilyTypes = def("", `\b(?P<kind>class|interface|enum|struct|record|trait|object|protocol|union)\s+(?P<name>[A-Za-z_]\w*)`)
+	// cFamilyMethod matches "modifiers Type name(" at the start of a line
+	// that does not end a statement.
+	cFamilyMethod = def("method", `^\s*(?:(?:public|private|protected|internal|static|final|abstract|override|virtual|async|synchronized|extern|inline|const|unsafe)\s+)*(?P<type>[A-Za-z_][\w<>\[\],.?*&:]*)\s+[*&]?(?P<name>[A-Za-z_][\w~]*)\s*\([^;]*$`)
+)
+
+var lexers = map[string][]rule{
+	"go": {
+		def("func", `^func\s+(?:\([^)]*\)\s*)?(?P<name>[A-Za-z_]\w*)`),
+		def("type", `^type\s+(?P<name>[A-Za-z_]\w*)`),
+	},
+	"python": {
+		def("func", `^\s*(?:async\s+)?def\s+(?P<name>[A-Za-z_]\w*)`),
+		def("class", `^\s*class\s+(?P<name>[A-Za-z_]\w*)`),
+	},
+	"javascript": jsRules,
+	"typescript": append([]rule{
+		def("", `\b(?P<kind>interface|enum)\s+(?P<name>[A-Za-z_$][\w$]*)`),
+		def("type", `^\s*(?:export\s+)?type\s+(?P<name>[A-Za-z_$][\w$]*)\s*(?:<[^>]*>)?\s*=`),
+	}, jsRules...),
+	"rust": {
+		def("func", `\bfn\s+(?P<name>[A-Za-z_]\w*)`),
+		def("", `\b(?P<kind>struct|enum|trait|union|mod)\s+(?P<name>[A-Za-z_]\w*)`),
+		def("type", `^\s*(?:pub(?:\([^)]*\))?\s+)?type\s+(?P<name>[A-Za-z_]\w*)`),
+	},
+	"ruby": {
+		def("func", `^\s*def\s+(?:self\.)?(?P<name>[A-Za-z_]\w*[?!=]?)`),
+		def("", `^\s*(?P<kind>class|module)\s+(?P<name>[A-Z]\w*(?:::\w+)*)`),
+	},
+	"php": {
+		def("func", `\bfunction\s+&?(?P<name>[A-Za-z_]\w*)`),
+		def("", `\b(?P<kind>class|interface|trait|enum)\s+(?P<name>[A-Za-z_]\w*)`),
+	},
+	"java":   {cFamilyTypes, cFamilyMethod},
+	"csharp": {cFamilyTypes, cFamilyMethod},
+	"dart":   {cFamilyTypes, cFamilyMethod},
+	"kotlin": {
+		def("func", `\bfun\s+(?:<[^>]*>\s*)?(?:[\w.]+\.)?(?P<name>[A-Za-z_]\w*)`),
+		cFamilyTypes,
+	},
+	"scala": {
+		def("func", `\bdef\s+(?P<name>[A-Za-z_]\w*)`),
+		cFamilyTypes,
+	},
+	"swift": {
+		def("func", `\bfunc\s+(?P<name>[A-Za-z_]\w*)`),
+		cFamilyTypes,
+	},
+	"c": {
+		def("", `^\s*(?:typedef\s+)?(?P<kind>struct|enum|union)\s+(?P<name>[A-Za-z_]\w*)`),
+		def("macro", `^\s*#\s*define\s+(?P<name>[A-Za-z_]\w*)`),
+		def("func", `^[A-Za-z_][\w\s*]*?[\s*](?P<name>[A-Za-z_]\w*)\s*\([^;]*$`),
+	},
+	"cpp": {
+		cFamilyTypes,
+		def("macro", `^\s*#\s*define\s+(?P<name>[A-Za-z_]\w*)`),
+		def("func", `^[A-Za-z_][\w\s*&:<>,]*?[\s*&](?P<name>[A-Za-z_][\w:~]*)\s*\([^;]*$`),
+	},
+	"bash": {
+		def("func", `^\s*(?:function\s+)?(?P<name>[A-Za-z_][\w-]*)\s*\(\s*\)`),
+		def("func", `^\s*function\s+(?P<name>[A-Za-z_][\w-]*)`),
+	},
+	"lua": {
+		def("func", `\bfunction\s+(?P<name>[A-Za-z_][\w.:]*)`),
+	},
+	"elixir": {
+		def("func", `^\s*defp?\s+(?P<name>[a-z_]\w*[?!]?)`),
+		def("module", `^\s*defmodule\s+(?P<name>[A-Z][\w.]*)`),
+	},
+	"perl": {
+		def("func", `^\s*sub\s+(?P<name>[A-Za-z_]\w*)`),
+		def("module", `^\s*package\s+(?P<name>[A-Za-z_][\w:]*)`),
+	},
+	"sql": {
+		def("", `(?i)\bcreate\s+(?:or\s+replace\s+)?(?:temp(?:orary)?\s+|unique\s+|materialized\s+)?(?P<kind>table|view|function|procedure|index|type|trigger|sequence)\s+(?:if\s+not\s+exists\s+)?(?P<name>[\w."]+)`),
+	},
+	"protobuf": {
+		def("", `^\s*(?P<kind>message|enum|service)\s+(?P<name>[A-Za-z_]\w*)`),
+		def("rpc", `^\s*rpc\s+(?P<name>[A-Za-z_]\w*)`),
+	},
+	"graphql": {
+		def("", `^\s*(?:extend\s+)?(?P<kind>type|interface|enum|input|union|scalar|query|mutation|fragment)\s+(?P<name>[A-Za-z_]\w*)`),
+	},
+}
+
+var jsRules = []rule{
+	def("func", `\bfunction\s*\*?\s*(?P<name>[A-Za-z_$][\w$]*)`),
+	def("class", `\bclass\s+(?P<name>[A-Za-z_$][\w$]*)`),
+	def("func", `^\s*(?:export\s+)?(?:const|let|var)\s+(?P<name>[A-Za-z_$][\w$]*)\s*=\s*(?:async\s*)?(?:function\b|\([^)]*\)\s*=>|[A-Za-z_$][\w$]*\s*=>)`),
+}
+
+// keywords are never symbol names; they show up when a call or a control
+// statement looks like a C-style definition.
+var keywords = map[string]struct{}{
+	"if": {}, "else": {}, "for": {}, "while": {}, "switch": {}, "return": {}, "new": {},
+	"catch": {}, "throw": {}, "sizeof": {}, "case": {}, "do": {}, "typeof": {}, "await": {},
+}
+
+func extractLexer(language, content string) []Symbol {
+	rules, ok := lexers[language]
+	if !ok {
+		return nil
+	}
+
+	var out []Symbol
+	for i, line := range strings.Split(content, "\n") {
+		for _, r := range rules {
+			m := r.re.FindStringSubmatch(line)
+			if m == nil {
+				continue
+			}
+			s := Symbol{Kind: r.kind, Line: i + 1}
+			skip := false
+			for j, group := range r.re.SubexpNames() {
+				switch group {
+				case "name":
+					s.Name = strings.Trim(m[j], `"`)
+					_, kw := keywords[s.Name]
+					skip = skip || kw || s.Name == ""
+				case "kind":
+					s.Kind = strings.ToLower(m[j])
+				case "type":
+					_, kw := keywords[m[j]]
+					skip = skip || kw
+				}
+			}
+			if skip {
+				continue
+			}
+			out = append(out, s)
+		}
+	}
+	return out
+}
@@ -0,0 +1,157 @@
+package symbols
+
+import (
+	"sort"
+	"strings"
+)
+
+// MaxSymbols caps how many symbols are kept per snippet.
+const MaxSymbols = 500
+
+// maxTerms caps the identifier parts added to the search index per snippet.
+const maxTerms = 2000
+
+// Symbol is a named definition found in a snippet. Container is the type a
+// method belongs to, when known. Line is 1-based.
+type Symbol struct {
+	Name      string `json:"name"`
+	Kind      string `json:"kind"`
+	Container string `json:"container,omitempty"`
+	Line      int    `json:"line"`
+}
+
+// Extract returns the definitions in content. Go is parsed with go/parser;
+// other languages use line-based lexers. Unsupported languages yield none.
+func Extract(language, content string) []Symbol {
+	var out []Symbol
+	if language == "go" {
+		out = extractGo(content)
+	} else {
+		out = extractLexer(language, content)
+	}
+	if len(out) == 0 {
+		return []Symbol{}
+	}
+
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Line < out[j].Line })
+	seen := make(map[Symbol]struct{}, len(out))
+	uniq := out[:0]
+	for _, s := range out {
+		key := Symbol{Name: s.Name, Kind: s.Kind, Container: s.Container}
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+		uniq = append(uniq, s)
+		if len(uniq) == MaxSymbols {
+			break
+		}
+	}
+	return uniq
+}
+
+// Names returns the lowercased lookup keys for symbols: each name, its
+// qualified Container.Name form and the parts of both.
+func Names(list []Symbol) []string {
+	set := newTermSet(0)
+	for _, s := range list {
+		set.add(strings.ToLower(s.Name))
+		set.addParts(s.Name)
+		if s.Container != "" {
+			set.add(strings.ToLower(s.Container + "." + s.Name))
+		}
+	}
+	return set.list
+}
+
+// Terms returns the parts of the compound identifiers in content
+// (camelCase, snake_case, dotted paths), so that a search for "request"
+// finds parseHTTPRequest.
+func Terms(content string) []string {
+	set := newTermSet(maxTerms)
+	for _, ident := range identRe.FindAllString(content, -1) {
+		parts := Split(ident)
+		if len(parts) < 2 {
+			continue
+		}
+		for _, p := range parts {
+			if !set.add(p) {
+				return set.list
+			}
+		}
+	}
+	return set.list
+}
+
+// Split breaks an identifier into lowercased words on separators and case
+// changes: "parseHTTPRequest" gives parse, http, request and
+// "net/http.Client" gives net, http, client. Single letters are dropped.
+func Split(ident string) []string {
+	var parts []string
+	for _, field := range strings.FieldsFunc(ident, func(r rune) bool {
+		return !isLetter(r) && !isDigit(r)
+	}) {
+		parts = append(parts, splitCase(field)...)
+	}
+
+	out := parts[:0]
+	for _, p := range parts {
+		if len(p) > 1 {
+			out = append(out, strings.ToLower(p))
+		}
+	}
+	return out
+}
+
+func splitCase(s string) []string {
+	r := []rune(s)
+	var parts []string
+	start := 0
+	for i := 1; i < len(r); i++ {
+		prev, cur := r[i-1], r[i]
+		boundary := isUpper(cur) && (isLower(prev) || isDigit(prev)) ||
+			isUpper(cur) && isUpper(prev) && i+1 < len(r) && isLower(r[i+1])
+		if boundary {
+			parts = append(parts, string(r[start:i]))
+			start = i
+		}
+	}
+	return append(parts, string(r[start:]))
+}
+
+func isUpper(r rune) bool  { return r >= 'A' && r <= 'Z' }
+func isLower(r rune) bool  { return r >= 'a' && r <= 'z' }
+func isDigit(r rune) bool  { return r >= '0' && r <= '9' }
+func isLetter(r rune) bool { return isUpper(r) || isLower(r) }
+
+type termSet struct {
+	seen  map[string]struct{}
+	list  []string
+	limit int
+}
+
+func newTermSet(limit int) *termSet {
+	return &termSet{seen: map[string]struct{}{}, list: []string{}, limit: limit}
+}
+
+// add records term and reports whether there is room for more.
+func (t *termSet) add(term string) bool {
+	if term == "" {
+		return true
+	}
+	if _, ok := t.seen[term]; !ok {
+		t.seen[term] = struct{}{}
+		t.list = append(t.list, term)
+	}
+	return t.limit == 0 || len(t.list) < t.limit
+}
+
+func (t *termSet) addParts(ident string) {
+	parts := Split(ident)
+	if len(parts) < 2 {
+		return
+	}
+	for _, p := range parts {
+		t.add(p)
+	}
+}
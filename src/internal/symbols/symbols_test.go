@@ -0,0 +1,90 @@
+package symbols
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplit(t *testing.T) {
+	cases := map[string][]string{
+		"parseHTTPRequest":   {"parse", "http", "request"},
+		"snake_case_name":    {"snake", "case", "name"},
+		"net/http.Client":    {"net", "http", "client"},
+		"std::vector":        {"std", "vector"},
+		"XMLToJSON2":         {"xml", "to", "json2"},
+		"base64Encode":       {"base64", "encode"},
+		"x":                  {},
+		"getX":               {"get"},
+		"__init__":           {"init"},
+		"MAX_RETRY_ATTEMPTS": {"max", "retry", "attempts"},
+	}
+	for in, want := range cases {
+		got := Split(in)
+		if len(got) == 0 && len(want) == 0 {
+			continue
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("%s: got %v, want %v", in, got, want)
+		}
+	}
+}
+
+func TestExtractGo(t *testing.T) {
+	src := "import \"net/http\"\n\ntype Server struct{}\n\nfunc (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {}\n\nconst maxBody = 1 << 20\n\nfunc NewServer() *Server { return &Server{} }\n"
+	got := Extract("go", src)
+	want := []Symbol{
+		{Name: "Server", Kind: "struct", Line: 3},
+		{Name: "ServeHTTP", Kind: "method", Container: "Server", Line: 5},
+		{Name: "maxBody", Kind: "const", Line: 7},
+		{Name: "NewServer", Kind: "func", Line: 9},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected symbols:\n%+v\nwant:\n%+v", got, want)
+	}
+
+	// Fragments that do not parse still get the lexer.
+	got = Extract("go", "func handle(w http.ResponseWriter) {\n\tif x {\n")
+	if len(got) != 1 || got[0].Name != "handle" || got[0].Kind != "func" {
+		t.Fatalf("unexpected fallback symbols: %+v", got)
+	}
+}
+
+func TestExtractLexers(t *testing.T) {
+	cases := []struct {
+		lang    string
+		content string
+		want    []string
+	}{
+		{"python", "class UserRepo:\n    async def fetch_user(self, id):\n        return get(id)\n", []string{"class UserRepo", "func fetch_user"}},
+		{"typescript", "export interface Props {}\nexport const useAuth = () => {}\nfunction render() {}\n", []string{"interface Props", "func useAuth", "func render"}},
+		{"java", "public class Cache {\n  public String get(String key) {\n    return lookup(key);\n  }\n}\n", []string{"class Cache", "method get"}},
+		{"rust", "pub struct Point { x: i32 }\nimpl Point {\n    pub fn norm(&self) -> f64 {\n        if x (\n", []string{"struct Point", "func norm"}},
+		{"sql", "CREATE TABLE IF NOT EXISTS users (id int);\ncreate or replace function touch() returns trigger", []string{"table users", "function touch"}},
+		{"txt", "def nothing()", nil},
+	}
+	for _, tc := range cases {
+		t.Run(tc.lang, func(t *testing.T) {
+			var got []string
+			for _, s := range Extract(tc.lang, tc.content) {
+				got = append(got, s.Kind+" "+s.Name)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestNamesAndTerms(t *testing.T) {
+	names := Names([]Symbol{{Name: "ServeHTTP", Kind: "method", Container: "Server"}, {Name: "run", Kind: "func"}})
+	want := []string{"servehttp", "serve", "http", "server.servehttp", "run"}
+	if !reflect.DeepEqual(names, want) {
+		t.Fatalf("got %v, want %v", names, want)
+	}
+
+	terms := Terms("resp, err := http.DefaultClient.Do(req)\nlog_level = 1")
+	want = []string{"http", "default", "client", "do", "log", "level"}
+	if !reflect.DeepEqual(terms, want) {
+		t.Fatalf("got %v, want %v", terms, want)
+	}
+}
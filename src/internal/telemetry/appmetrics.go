@@ -79,7 +79,8 @@ func InitAppMetrics(serviceName string, db *pgxpool.Pool, redisClient *redis.Cli
 		var sessionsVal int64
 		if redisClient != nil && sessionPrefix != "" {
 			redisCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
-			sessionsVal = countRedisKeys(redisCtx, redisClient, sessionPrefix+"*")
+			// Only logged in sessions, not pending logins or user indexes.
+			sessionsVal = countRedisKeys(redisCtx, redisClient, sessionPrefix+"ses_*")
 			cancel()
 		}
 		o.ObserveInt64(sessionsActive, sessionsVal)
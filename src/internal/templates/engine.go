@@ -0,0 +1,169 @@
+package templates
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Template is parsed snippet content. The engine only substitutes values:
+// there are no functions, pipelines or conditionals, so rendering user
+// supplied content cannot reach anything on the server.
+type Template struct {
+	nodes []node
+}
+
+type node struct {
+	text string
+	ref  string
+}
+
+type Issue struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+type Error struct {
+	Issues []Issue
+}
+
+func (e *Error) Error() string {
+	if e == nil || len(e.Issues) == 0 {
+		return "invalid template"
+	}
+	return e.Issues[0].Field + ": " + e.Issues[0].Message
+}
+
+// Parse splits content into literal text and placeholder references.
+// Anything that does not look like a reference, such as ${1} tab stops or
+// shell expansions like ${HOME:-/tmp}, is kept as literal text.
+func Parse(content string) *Template {
+	t := &Template{}
+	var lit strings.Builder
+	flush := func() {
+		if lit.Len() > 0 {
+			t.nodes = append(t.nodes, node{text: lit.String()})
+			lit.Reset()
+		}
+	}
+
+	for i := 0; i < len(content); {
+		if content[i] == '\\' && strings.HasPrefix(content[i+1:], "${") {
+			if end := strings.IndexByte(content[i+3:], '}'); end >= 0 {
+				if _, ok := parseRef(content[i+3 : i+3+end]); ok {
+					lit.WriteString(content[i+1 : i+3+end+1])
+					i += 3 + end + 1
+					continue
+				}
+			}
+		}
+		if strings.HasPrefix(content[i:], "${") {
+			if end := strings.IndexByte(content[i+2:], '}'); end >= 0 {
+				if name, ok := parseRef(content[i+2 : i+2+end]); ok {
+					flush()
+					t.nodes = append(t.nodes, node{ref: name, text: content[i : i+2+end+1]})
+					i += 2 + end + 1
+					continue
+				}
+			}
+		}
+		lit.WriteByte(content[i])
+		i++
+	}
+	flush()
+	return t
+}
+
+// parseRef accepts "name", "env:NAME" and the tab-stop form "1:name".
+func parseRef(body string) (string, bool) {
+	if n := leadingDigits(body); n > 0 {
+		if n == len(body) || body[n] != ':' {
+			return "", false
+		}
+		body = body[n+1:]
+	}
+	if !nameRe.MatchString(body) {
+		return "", false
+	}
+	return body, true
+}
+
+func leadingDigits(s string) int {
+	n := 0
+	for n < len(s) && s[n] >= '0' && s[n] <= '9' {
+		n++
+	}
+	return n
+}
+
+func (t *Template) refs() []string {
+	var out []string
+	for _, n := range t.nodes {
+		if n.ref != "" {
+			out = append(out, n.ref)
+		}
+	}
+	return out
+}
+
+// Render expands declared placeholders with the given values. References to
+// names that are not declared are left untouched.
+func Render(content string, defs []Placeholder, values map[string]string) (string, error) {
+	if len(values) > MaxPlaceholders {
+		return "", &Error{Issues: []Issue{{Field: "variables", Code: "too_many", Message: fmt.Sprintf("at most %d variables are allowed", MaxPlaceholders)}}}
+	}
+	declared := make(map[string]Placeholder, len(defs))
+	for _, p := range defs {
+		declared[p.Name] = p
+	}
+
+	var issues []Issue
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if _, ok := declared[name]; !ok {
+			issues = append(issues, Issue{Field: "variables." + name, Code: "unknown", Message: fmt.Sprintf("snippet has no placeholder %q", name)})
+		}
+	}
+
+	resolved := make(map[string]string, len(defs))
+	for _, p := range defs {
+		value, ok := values[p.Name]
+		if !ok {
+			if p.Default == nil {
+				issues = append(issues, Issue{Field: "variables." + p.Name, Code: "required", Message: fmt.Sprintf("%q is required", p.Name)})
+				continue
+			}
+			value = *p.Default
+		}
+		if len(value) > MaxValueLength {
+			issues = append(issues, Issue{Field: "variables." + p.Name, Code: "too_long", Message: fmt.Sprintf("%q is longer than %d bytes", p.Name, MaxValueLength)})
+			continue
+		}
+		if err := p.check(value); err != nil {
+			issues = append(issues, Issue{Field: "variables." + p.Name, Code: "invalid", Message: fmt.Sprintf("%q %s", p.Name, err.Error())})
+			continue
+		}
+		resolved[p.Name] = value
+	}
+	if len(issues) > 0 {
+		return "", &Error{Issues: issues}
+	}
+
+	var b strings.Builder
+	for _, n := range Parse(content).nodes {
+		text := n.text
+		if value, ok := resolved[n.ref]; ok && n.ref != "" {
+			text = value
+		}
+		if b.Len()+len(text) > MaxRenderedSize {
+			return "", &Error{Issues: []Issue{{Field: "variables", Code: "too_large", Message: fmt.Sprintf("rendered content would exceed %d bytes", MaxRenderedSize)}}}
+		}
+		b.WriteString(text)
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,137 @@
+package templates
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+type Type string
+
+const (
+	TypeString Type = "string"
+	TypeInt    Type = "int"
+	TypeNumber Type = "number"
+	TypeBool   Type = "bool"
+	TypeEnum   Type = "enum"
+)
+
+const (
+	MaxPlaceholders       = 50
+	maxDescriptionLength  = 200
+	maxDefaultValueLength = 1000
+	// MaxValueLength bounds a value passed to Render and MaxRenderedSize
+	// what Render returns, as one value can be referenced many times.
+	MaxValueLength  = 10000
+	MaxRenderedSize = 1 << 20
+)
+
+var nameRe = regexp.MustCompile(`^(env:)?[A-Za-z_][A-Za-z0-9_]{0,63}$`)
+
+// Placeholder describes a variable referenced from snippet content as
+// ${name}, ${1:name} or ${env:NAME}.
+type Placeholder struct {
+	Name        string   `json:"name"`
+	Type        Type     `json:"type,omitempty"`
+	Default     *string  `json:"default,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Options     []string `json:"options,omitempty"`
+}
+
+func (p Placeholder) kind() Type {
+	if p.Type == "" {
+		return TypeString
+	}
+	return p.Type
+}
+
+func (p Placeholder) check(value string) error {
+	switch p.kind() {
+	case TypeString:
+		return nil
+	case TypeInt:
+		if _, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64); err != nil {
+			return fmt.Errorf("must be an integer")
+		}
+	case TypeNumber:
+		if _, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err != nil {
+			return fmt.Errorf("must be a number")
+		}
+	case TypeBool:
+		if _, err := strconv.ParseBool(strings.TrimSpace(value)); err != nil {
+			return fmt.Errorf("must be true or false")
+		}
+	case TypeEnum:
+		if !slices.Contains(p.Options, value) {
+			return fmt.Errorf("must be one of: %s", strings.Join(p.Options, ", "))
+		}
+	default:
+		return fmt.Errorf("unsupported type %q", p.Type)
+	}
+	return nil
+}
+
+// ValidateDefinitions checks placeholder declarations against the content
+// they are stored with.
+func ValidateDefinitions(content string, defs []Placeholder) error {
+	var issues []Issue
+	if len(defs) > MaxPlaceholders {
+		issues = append(issues, Issue{Field: "placeholders", Code: "too_many", Message: fmt.Sprintf("at most %d placeholders are allowed", MaxPlaceholders)})
+		return &Error{Issues: issues}
+	}
+
+	referenced := map[string]bool{}
+	for _, ref := range Parse(content).refs() {
+		referenced[ref] = true
+	}
+
+	seen := map[string]bool{}
+	for i, p := range defs {
+		field := fmt.Sprintf("placeholders[%d]", i)
+		if !nameRe.MatchString(p.Name) {
+			issues = append(issues, Issue{Field: field + ".name", Code: "invalid", Message: "name must be an identifier, optionally prefixed with env:"})
+			continue
+		}
+		if seen[p.Name] {
+			issues = append(issues, Issue{Field: field + ".name", Code: "duplicate", Message: fmt.Sprintf("placeholder %q is declared more than once", p.Name)})
+			continue
+		}
+		seen[p.Name] = true
+
+		if !referenced[p.Name] {
+			issues = append(issues, Issue{Field: field + ".name", Code: "unused", Message: fmt.Sprintf("placeholder %q is not referenced in content", p.Name)})
+		}
+		if len(p.Description) > maxDescriptionLength {
+			issues = append(issues, Issue{Field: field + ".description", Code: "too_long", Message: "description is too long"})
+		}
+
+		switch p.kind() {
+		case TypeString, TypeInt, TypeNumber, TypeBool:
+			if len(p.Options) > 0 {
+				issues = append(issues, Issue{Field: field + ".options", Code: "invalid", Message: "options are only allowed for enum placeholders"})
+			}
+		case TypeEnum:
+			if len(p.Options) == 0 {
+				issues = append(issues, Issue{Field: field + ".options", Code: "required", Message: "enum placeholders need options"})
+			}
+		default:
+			issues = append(issues, Issue{Field: field + ".type", Code: "invalid", Message: "type must be one of string, int, number, bool, enum"})
+			continue
+		}
+
+		if p.Default != nil {
+			if len(*p.Default) > maxDefaultValueLength {
+				issues = append(issues, Issue{Field: field + ".default", Code: "too_long", Message: "default is too long"})
+			} else if err := p.check(*p.Default); err != nil {
+				issues = append(issues, Issue{Field: field + ".default", Code: "invalid", Message: "default " + err.Error()})
+			}
+		}
+	}
+
+	if len(issues) > 0 {
+		return &Error{Issues: issues}
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package templates
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func strPtr(s string) *string { return &s }
+
+func TestRender(t *testing.T) {
+	content := "curl -H 'Authorization: ${env:TOKEN}' ${1:base_url}/users?limit=${limit}\necho ${HOME} \\${limit}"
+	defs := []Placeholder{
+		{Name: "base_url"},
+		{Name: "limit", Type: TypeInt, Default: strPtr("10")},
+		{Name: "env:TOKEN"},
+	}
+
+	got, err := Render(content, defs, map[string]string{
+		"base_url":  "https://api.local",
+		"env:TOKEN": "Bearer x",
+	})
+	if err != nil {
+		t.Fatalf("render error: %v", err)
+	}
+	want := "curl -H 'Authorization: Bearer x' https://api.local/users?limit=10\necho ${HOME} ${limit}"
+	if got != want {
+		t.Fatalf("unexpected output:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestRenderValidation(t *testing.T) {
+	defs := []Placeholder{
+		{Name: "port", Type: TypeInt},
+		{Name: "mode", Type: TypeEnum, Options: []string{"dev", "prod"}},
+		{Name: "name"},
+	}
+
+	_, err := Render("${port} ${mode} ${name}", defs, map[string]string{
+		"port":  "eighty",
+		"mode":  "staging",
+		"extra": "x",
+	})
+	var tErr *Error
+	if !errors.As(err, &tErr) {
+		t.Fatalf("expected template error, got %v", err)
+	}
+
+	codes := map[string]string{}
+	for _, issue := range tErr.Issues {
+		codes[issue.Field] = issue.Code
+	}
+	want := map[string]string{
+		"variables.extra": "unknown",
+		"variables.port":  "invalid",
+		"variables.mode":  "invalid",
+		"variables.name":  "required",
+	}
+	for field, code := range want {
+		if codes[field] != code {
+			t.Fatalf("expected %s for %s, got %v", code, field, codes)
+		}
+	}
+}
+
+func TestRenderLimits(t *testing.T) {
+	defs := []Placeholder{{Name: "x"}}
+	issueCode := func(err error) string {
+		t.Helper()
+		var tErr *Error
+		if !errors.As(err, &tErr) || len(tErr.Issues) != 1 {
+			t.Fatalf("expected one template issue, got %v", err)
+		}
+		return tErr.Issues[0].Code
+	}
+
+	values := map[string]string{}
+	for i := 0; i <= MaxPlaceholders; i++ {
+		values[fmt.Sprintf("v%d", i)] = "1"
+	}
+	_, err := Render("${x}", defs, values)
+	if code := issueCode(err); code != "too_many" {
+		t.Fatalf("expected too_many, got %s", code)
+	}
+
+	_, err = Render("${x}", defs, map[string]string{"x": strings.Repeat("a", MaxValueLength+1)})
+	if code := issueCode(err); code != "too_long" {
+		t.Fatalf("expected too_long, got %s", code)
+	}
+
+	// Each value fits, but repeating it does not.
+	content := strings.Repeat("${x}", MaxRenderedSize/MaxValueLength+1)
+	_, err = Render(content, defs, map[string]string{"x": strings.Repeat("a", MaxValueLength)})
+	if code := issueCode(err); code != "too_large" {
+		t.Fatalf("expected too_large, got %s", code)
+	}
+}
+
+func TestValidateDefinitions(t *testing.T) {
+	if err := ValidateDefinitions("${a} ${b}", []Placeholder{{Name: "a"}, {Name: "b", Type: TypeBool, Default: strPtr("true")}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	err := ValidateDefinitions("${a}", []Placeholder{
+		{Name: "a"},
+		{Name: "a"},
+		{Name: "missing"},
+		{Name: "1bad"},
+		{Name: "e", Type: TypeEnum},
+	})
+	var tErr *Error
+	if !errors.As(err, &tErr) {
+		t.Fatalf("expected template error, got %v", err)
+	}
+	if len(tErr.Issues) != 5 {
+		t.Fatalf("unexpected issues: %+v", tErr.Issues)
+	}
+}
@@ -0,0 +1,13 @@
+package twofactor
+
+import (
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var ErrNotFound = errors.New("two-factor secret not found")
+
+func IsNotFound(err error) bool {
+	return errors.Is(err, pgx.ErrNoRows) || errors.Is(err, ErrNotFound)
+}
@@ -0,0 +1,41 @@
+package twofactor
+
+import (
+	"time"
+
+	"github.com/PabloPavan/sniply_api/internal/users"
+)
+
+// TOTP is a user's authenticator app secret. It counts as enabled once a
+// code generated from it was confirmed.
+type TOTP struct {
+	UserID      string
+	Secret      string
+	ConfirmedAt *time.Time
+	// LastStep is the time step of the last accepted code.
+	LastStep  int64
+	CreatedAt time.Time
+}
+
+func (t *TOTP) Enabled() bool {
+	return t != nil && t.ConfirmedAt != nil
+}
+
+type Status struct {
+	Enabled           bool `json:"enabled"`
+	Required          bool `json:"required"`
+	RecoveryCodesLeft int  `json:"recovery_codes_left"`
+}
+
+// Enrollment is what an authenticator app needs to add an account.
+type Enrollment struct {
+	Secret string
+	URI    string
+	QRCode []byte // PNG
+}
+
+// Policy tells whether users of a role must use two-factor authentication.
+type Policy struct {
+	Role     users.UserRole `json:"role"`
+	Required bool           `json:"required"`
+}
@@ -0,0 +1,215 @@
+package twofactor
+
+import (
+	"context"
+
+	"github.com/PabloPavan/sniply_api/internal/db"
+	"github.com/PabloPavan/sniply_api/internal/users"
+	"github.com/jackc/pgx/v5"
+)
+
+type Repository struct {
+	base *db.Base
+}
+
+func NewRepository(base *db.Base) *Repository {
+	return &Repository{base: base}
+}
+
+const (
+	sqlTOTPGet = `SELECT user_id, secret, confirmed_at, last_step, created_at
+		FROM user_totp
+		WHERE user_id = $1`
+
+	// sqlTOTPSavePending starts over an enrollment, but never replaces a
+	// confirmed secret.
+	sqlTOTPSavePending = `INSERT INTO user_totp (user_id, secret)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE
+		SET secret = EXCLUDED.secret, last_step = 0, created_at = now()
+		WHERE user_totp.confirmed_at IS NULL`
+
+	sqlTOTPConfirm = `UPDATE user_totp
+		SET confirmed_at = now(), last_step = $2
+		WHERE user_id = $1 AND confirmed_at IS NULL`
+
+	// sqlTOTPUseStep accepts each time step once.
+	sqlTOTPUseStep = `UPDATE user_totp
+		SET last_step = $2
+		WHERE user_id = $1 AND confirmed_at IS NOT NULL AND last_step < $2`
+
+	sqlTOTPDelete = `DELETE FROM user_totp
+		WHERE user_id = $1`
+
+	sqlRecoveryCodesDelete = `DELETE FROM user_recovery_codes
+		WHERE user_id = $1`
+
+	sqlRecoveryCodeInsert = `INSERT INTO user_recovery_codes (user_id, code_hash)
+		VALUES ($1, $2)`
+
+	sqlRecoveryCodeUse = `UPDATE user_recovery_codes
+		SET used_at = now()
+		WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL`
+
+	sqlRecoveryCodesCount = `SELECT count(*)
+		FROM user_recovery_codes
+		WHERE user_id = $1 AND used_at IS NULL`
+
+	sqlPoliciesList = `SELECT role, required
+		FROM two_factor_policies
+		ORDER BY role`
+
+	sqlPolicyGet = `SELECT required
+		FROM two_factor_policies
+		WHERE role = $1`
+
+	sqlPolicyUpsert = `INSERT INTO two_factor_policies (role, required)
+		VALUES ($1, $2)
+		ON CONFLICT (role) DO UPDATE
+		SET required = EXCLUDED.required, updated_at = now()`
+)
+
+func (r *Repository) GetTOTP(ctx context.Context, userID string) (*TOTP, error) {
+	ctx, cancel := r.base.WithTimeout(ctx)
+	defer cancel()
+
+	var t TOTP
+	err := r.base.Q().QueryRow(ctx, sqlTOTPGet, userID).Scan(&t.UserID, &t.Secret, &t.ConfirmedAt, &t.LastStep, &t.CreatedAt)
+	if IsNotFound(err) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// SavePending stores the secret of an enrollment in progress. It reports
+// false if the user already has a confirmed secret.
+func (r *Repository) SavePending(ctx context.Context, userID, secret string) (bool, error) {
+	ctx, cancel := r.base.WithTimeout(ctx)
+	defer cancel()
+
+	tag, err := r.base.Q().Exec(ctx, sqlTOTPSavePending, userID, secret)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// Confirm enables the pending secret and replaces the recovery codes.
+func (r *Repository) Confirm(ctx context.Context, userID string, step int64, codeHashes []string) error {
+	return r.base.WithTx(ctx, func(ctx context.Context, tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx, sqlTOTPConfirm, userID, step)
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return ErrNotFound
+		}
+		return replaceRecoveryCodes(ctx, tx, userID, codeHashes)
+	})
+}
+
+func (r *Repository) UseStep(ctx context.Context, userID string, step int64) (bool, error) {
+	ctx, cancel := r.base.WithTimeout(ctx)
+	defer cancel()
+
+	tag, err := r.base.Q().Exec(ctx, sqlTOTPUseStep, userID, step)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// Delete removes the secret and the recovery codes.
+func (r *Repository) Delete(ctx context.Context, userID string) error {
+	return r.base.WithTx(ctx, func(ctx context.Context, tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, sqlRecoveryCodesDelete, userID); err != nil {
+			return err
+		}
+		_, err := tx.Exec(ctx, sqlTOTPDelete, userID)
+		return err
+	})
+}
+
+func (r *Repository) ReplaceRecoveryCodes(ctx context.Context, userID string, codeHashes []string) error {
+	return r.base.WithTx(ctx, func(ctx context.Context, tx pgx.Tx) error {
+		return replaceRecoveryCodes(ctx, tx, userID, codeHashes)
+	})
+}
+
+func replaceRecoveryCodes(ctx context.Context, tx pgx.Tx, userID string, codeHashes []string) error {
+	if _, err := tx.Exec(ctx, sqlRecoveryCodesDelete, userID); err != nil {
+		return err
+	}
+	for _, h := range codeHashes {
+		if _, err := tx.Exec(ctx, sqlRecoveryCodeInsert, userID, h); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UseRecoveryCode marks an unused code as used, reporting whether there
+// was one.
+func (r *Repository) UseRecoveryCode(ctx context.Context, userID, codeHash string) (bool, error) {
+	ctx, cancel := r.base.WithTimeout(ctx)
+	defer cancel()
+
+	tag, err := r.base.Q().Exec(ctx, sqlRecoveryCodeUse, userID, codeHash)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+func (r *Repository) CountRecoveryCodes(ctx context.Context, userID string) (int, error) {
+	ctx, cancel := r.base.WithTimeout(ctx)
+	defer cancel()
+
+	var n int
+	err := r.base.Q().QueryRow(ctx, sqlRecoveryCodesCount, userID).Scan(&n)
+	return n, err
+}
+
+func (r *Repository) ListPolicies(ctx context.Context) ([]Policy, error) {
+	ctx, cancel := r.base.WithTimeout(ctx)
+	defer cancel()
+
+	rows, err := r.base.Q().Query(ctx, sqlPoliciesList)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]Policy, 0)
+	for rows.Next() {
+		var p Policy
+		if err := rows.Scan(&p.Role, &p.Required); err != nil {
+			return nil, err
+		}
+		out = append(out, p)
+	}
+	return out, rows.Err()
+}
+
+func (r *Repository) Required(ctx context.Context, role users.UserRole) (bool, error) {
+	ctx, cancel := r.base.WithTimeout(ctx)
+	defer cancel()
+
+	var required bool
+	err := r.base.Q().QueryRow(ctx, sqlPolicyGet, role).Scan(&required)
+	if IsNotFound(err) {
+		return false, nil
+	}
+	return required, err
+}
+
+func (r *Repository) SetPolicy(ctx context.Context, p Policy) error {
+	ctx, cancel := r.base.WithTimeout(ctx)
+	defer cancel()
+
+	_, err := r.base.Q().Exec(ctx, sqlPolicyUpsert, p.Role, p.Required)
+	return err
+}
@@ -0,0 +1,300 @@
+package twofactor
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/PabloPavan/sniply_api/internal/apperrors"
+	"github.com/PabloPavan/sniply_api/internal/identity"
+	"github.com/PabloPavan/sniply_api/internal/users"
+	"github.com/skip2/go-qrcode"
+)
+
+const DefaultIssuer = "Sniply"
+
+type Store interface {
+	GetTOTP(ctx context.Context, userID string) (*TOTP, error)
+	SavePending(ctx context.Context, userID, secret string) (bool, error)
+	Confirm(ctx context.Context, userID string, step int64, codeHashes []string) error
+	UseStep(ctx context.Context, userID string, step int64) (bool, error)
+	Delete(ctx context.Context, userID string) error
+	ReplaceRecoveryCodes(ctx context.Context, userID string, codeHashes []string) error
+	UseRecoveryCode(ctx context.Context, userID, codeHash string) (bool, error)
+	CountRecoveryCodes(ctx context.Context, userID string) (int, error)
+	ListPolicies(ctx context.Context) ([]Policy, error)
+	Required(ctx context.Context, role users.UserRole) (bool, error)
+	SetPolicy(ctx context.Context, p Policy) error
+}
+
+type UserLookup interface {
+	GetByID(ctx context.Context, id string) (*users.User, error)
+}
+
+// Service manages TOTP second factors. The methods taking a user ID serve
+// logins in progress, before there is an identity in the context.
+type Service struct {
+	Store Store
+	Users UserLookup
+	// Issuer names the account in authenticator apps.
+	Issuer string
+	Now    func() time.Time
+}
+
+func (s *Service) now() time.Time {
+	if s.Now != nil {
+		return s.Now()
+	}
+	return time.Now()
+}
+
+func (s *Service) user(ctx context.Context) (string, users.UserRole, error) {
+	if s.Store == nil {
+		return "", "", apperrors.New(apperrors.KindInternal, "two-factor store not configured")
+	}
+	userID, ok := identity.UserID(ctx)
+	if !ok || strings.TrimSpace(userID) == "" {
+		return "", "", apperrors.New(apperrors.KindUnauthorized, "unauthorized")
+	}
+	role, _ := identity.Role(ctx)
+	return userID, users.UserRole(role), nil
+}
+
+func (s *Service) Status(ctx context.Context) (Status, error) {
+	userID, role, err := s.user(ctx)
+	if err != nil {
+		return Status{}, err
+	}
+	enabled, required, err := s.Requirement(ctx, userID, role)
+	if err != nil {
+		return Status{}, err
+	}
+	st := Status{Enabled: enabled, Required: required}
+	if enabled {
+		if st.RecoveryCodesLeft, err = s.Store.CountRecoveryCodes(ctx, userID); err != nil {
+			return Status{}, apperrors.New(apperrors.KindInternal, "failed to count recovery codes")
+		}
+	}
+	return st, nil
+}
+
+func (s *Service) Enroll(ctx context.Context) (*Enrollment, error) {
+	userID, _, err := s.user(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.EnrollUser(ctx, userID)
+}
+
+func (s *Service) Confirm(ctx context.Context, code string) ([]string, error) {
+	userID, _, err := s.user(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.ConfirmUser(ctx, userID, code)
+}
+
+// Disable turns two-factor authentication off, unless the user's role
+// requires it. It takes a current code to prove the factor is at hand.
+func (s *Service) Disable(ctx context.Context, code string) error {
+	userID, role, err := s.user(ctx)
+	if err != nil {
+		return err
+	}
+	required, err := s.Store.Required(ctx, role)
+	if err != nil {
+		return apperrors.New(apperrors.KindInternal, "failed to load two-factor policy")
+	}
+	if required {
+		return apperrors.New(apperrors.KindForbidden, "two-factor authentication is required for your role")
+	}
+	if err := s.VerifyUser(ctx, userID, code); err != nil {
+		return err
+	}
+	if err := s.Store.Delete(ctx, userID); err != nil {
+		return apperrors.New(apperrors.KindInternal, "failed to disable two-factor authentication")
+	}
+	return nil
+}
+
+// RegenerateRecoveryCodes replaces every recovery code of the user.
+func (s *Service) RegenerateRecoveryCodes(ctx context.Context, code string) ([]string, error) {
+	userID, _, err := s.user(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.VerifyUser(ctx, userID, code); err != nil {
+		return nil, err
+	}
+	codes, hashes := newRecoveryCodes()
+	if err := s.Store.ReplaceRecoveryCodes(ctx, userID, hashes); err != nil {
+		return nil, apperrors.New(apperrors.KindInternal, "failed to save recovery codes")
+	}
+	return codes, nil
+}
+
+// Policies returns the policy of every role; roles never configured do not
+// require two-factor authentication.
+func (s *Service) Policies(ctx context.Context) ([]Policy, error) {
+	if _, _, err := s.user(ctx); err != nil {
+		return nil, err
+	}
+	if !identity.IsAdmin(ctx) {
+		return nil, apperrors.New(apperrors.KindForbidden, "forbidden")
+	}
+	stored, err := s.Store.ListPolicies(ctx)
+	if err != nil {
+		return nil, apperrors.New(apperrors.KindInternal, "failed to list two-factor policies")
+	}
+	out := []Policy{{Role: users.RoleUser}, {Role: users.RoleAdmin}}
+	for i := range out {
+		for _, p := range stored {
+			if p.Role == out[i].Role {
+				out[i].Required = p.Required
+			}
+		}
+	}
+	return out, nil
+}
+
+// SetPolicy applies from the next login of each user of the role.
+func (s *Service) SetPolicy(ctx context.Context, p Policy) error {
+	if _, _, err := s.user(ctx); err != nil {
+		return err
+	}
+	if !identity.IsAdmin(ctx) {
+		return apperrors.New(apperrors.KindForbidden, "forbidden")
+	}
+	if !p.Role.Valid() {
+		return apperrors.New(apperrors.KindInvalidInput, "invalid role")
+	}
+	if err := s.Store.SetPolicy(ctx, p); err != nil {
+		return apperrors.New(apperrors.KindInternal, "failed to save two-factor policy")
+	}
+	return nil
+}
+
+// Requirement reports whether the user has two-factor authentication
+// enabled and whether their role requires it.
+func (s *Service) Requirement(ctx context.Context, userID string, role users.UserRole) (bool, bool, error) {
+	if s.Store == nil {
+		return false, false, apperrors.New(apperrors.KindInternal, "two-factor store not configured")
+	}
+	t, err := s.Store.GetTOTP(ctx, userID)
+	if err != nil && !IsNotFound(err) {
+		return false, false, apperrors.New(apperrors.KindInternal, "failed to load two-factor settings")
+	}
+	required, err := s.Store.Required(ctx, role)
+	if err != nil {
+		return false, false, apperrors.New(apperrors.KindInternal, "failed to load two-factor policy")
+	}
+	return t.Enabled(), required, nil
+}
+
+// EnrollUser creates a new secret for the user. It is not enabled until
+// ConfirmUser gets a code generated from it.
+func (s *Service) EnrollUser(ctx context.Context, userID string) (*Enrollment, error) {
+	if s.Store == nil || s.Users == nil {
+		return nil, apperrors.New(apperrors.KindInternal, "two-factor store not configured")
+	}
+	u, err := s.Users.GetByID(ctx, userID)
+	if err != nil {
+		if users.IsNotFound(err) {
+			return nil, apperrors.New(apperrors.KindNotFound, "user not found")
+		}
+		return nil, apperrors.New(apperrors.KindInternal, "failed to load user")
+	}
+
+	secret := newSecret()
+	saved, err := s.Store.SavePending(ctx, userID, secret)
+	if err != nil {
+		return nil, apperrors.New(apperrors.KindInternal, "failed to save two-factor secret")
+	}
+	if !saved {
+		return nil, apperrors.New(apperrors.KindConflict, "two-factor authentication is already enabled")
+	}
+
+	issuer := s.Issuer
+	if issuer == "" {
+		issuer = DefaultIssuer
+	}
+	e := &Enrollment{Secret: secret, URI: uri(issuer, u.Email, secret)}
+	if e.QRCode, err = qrcode.Encode(e.URI, qrcode.Medium, 256); err != nil {
+		return nil, apperrors.New(apperrors.KindInternal, "failed to render qr code")
+	}
+	return e, nil
+}
+
+// ConfirmUser enables the enrolled secret if code was generated from it and
+// returns fresh recovery codes, shown only this once.
+func (s *Service) ConfirmUser(ctx context.Context, userID, code string) ([]string, error) {
+	if s.Store == nil {
+		return nil, apperrors.New(apperrors.KindInternal, "two-factor store not configured")
+	}
+	t, err := s.Store.GetTOTP(ctx, userID)
+	if err != nil {
+		if IsNotFound(err) {
+			return nil, apperrors.New(apperrors.KindInvalidInput, "no two-factor enrollment in progress")
+		}
+		return nil, apperrors.New(apperrors.KindInternal, "failed to load two-factor settings")
+	}
+	if t.Enabled() {
+		return nil, apperrors.New(apperrors.KindConflict, "two-factor authentication is already enabled")
+	}
+	step, ok := verify(t.Secret, strings.TrimSpace(code), s.now(), t.LastStep)
+	if !ok {
+		return nil, apperrors.New(apperrors.KindUnauthorized, "invalid code")
+	}
+
+	codes, hashes := newRecoveryCodes()
+	if err := s.Store.Confirm(ctx, userID, step, hashes); err != nil {
+		if IsNotFound(err) {
+			return nil, apperrors.New(apperrors.KindConflict, "two-factor authentication is already enabled")
+		}
+		return nil, apperrors.New(apperrors.KindInternal, "failed to enable two-factor authentication")
+	}
+	return codes, nil
+}
+
+// VerifyUser checks a TOTP code or an unused recovery code. Either works
+// once.
+func (s *Service) VerifyUser(ctx context.Context, userID, code string) error {
+	if s.Store == nil {
+		return apperrors.New(apperrors.KindInternal, "two-factor store not configured")
+	}
+	code = strings.TrimSpace(code)
+	if code == "" {
+		return apperrors.New(apperrors.KindInvalidInput, "code is required")
+	}
+	t, err := s.Store.GetTOTP(ctx, userID)
+	if err != nil && !IsNotFound(err) {
+		return apperrors.New(apperrors.KindInternal, "failed to load two-factor settings")
+	}
+	if !t.Enabled() {
+		return apperrors.New(apperrors.KindInvalidInput, "two-factor authentication is not enabled")
+	}
+
+	if isTOTPCode(code) {
+		step, ok := verify(t.Secret, code, s.now(), t.LastStep)
+		if !ok {
+			return apperrors.New(apperrors.KindUnauthorized, "invalid code")
+		}
+		used, err := s.Store.UseStep(ctx, userID, step)
+		if err != nil {
+			return apperrors.New(apperrors.KindInternal, "failed to verify code")
+		}
+		if !used {
+			return apperrors.New(apperrors.KindUnauthorized, "invalid code")
+		}
+		return nil
+	}
+
+	used, err := s.Store.UseRecoveryCode(ctx, userID, hashRecoveryCode(code))
+	if err != nil {
+		return apperrors.New(apperrors.KindInternal, "failed to verify code")
+	}
+	if !used {
+		return apperrors.New(apperrors.KindUnauthorized, "invalid code")
+	}
+	return nil
+}
@@ -0,0 +1,239 @@
+package twofactor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PabloPavan/sniply_api/internal/apperrors"
+	"github.com/PabloPavan/sniply_api/internal/identity"
+	"github.com/PabloPavan/sniply_api/internal/users"
+)
+
+type storeStub struct {
+	totp     map[string]*TOTP
+	codes    map[string]map[string]bool // user -> hash -> used
+	policies map[users.UserRole]bool
+}
+
+func newStoreStub() *storeStub {
+	return &storeStub{
+		totp:     make(map[string]*TOTP),
+		codes:    make(map[string]map[string]bool),
+		policies: make(map[users.UserRole]bool),
+	}
+}
+
+func (s *storeStub) GetTOTP(ctx context.Context, userID string) (*TOTP, error) {
+	t, ok := s.totp[userID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	cp := *t
+	return &cp, nil
+}
+
+func (s *storeStub) SavePending(ctx context.Context, userID, secret string) (bool, error) {
+	if s.totp[userID].Enabled() {
+		return false, nil
+	}
+	s.totp[userID] = &TOTP{UserID: userID, Secret: secret}
+	return true, nil
+}
+
+func (s *storeStub) Confirm(ctx context.Context, userID string, step int64, codeHashes []string) error {
+	t, ok := s.totp[userID]
+	if !ok || t.Enabled() {
+		return ErrNotFound
+	}
+	now := time.Now()
+	t.ConfirmedAt, t.LastStep = &now, step
+	return s.ReplaceRecoveryCodes(ctx, userID, codeHashes)
+}
+
+func (s *storeStub) UseStep(ctx context.Context, userID string, step int64) (bool, error) {
+	t, ok := s.totp[userID]
+	if !ok || !t.Enabled() || t.LastStep >= step {
+		return false, nil
+	}
+	t.LastStep = step
+	return true, nil
+}
+
+func (s *storeStub) Delete(ctx context.Context, userID string) error {
+	delete(s.totp, userID)
+	delete(s.codes, userID)
+	return nil
+}
+
+func (s *storeStub) ReplaceRecoveryCodes(ctx context.Context, userID string, codeHashes []string) error {
+	s.codes[userID] = make(map[string]bool)
+	for _, h := range codeHashes {
+		s.codes[userID][h] = false
+	}
+	return nil
+}
+
+func (s *storeStub) UseRecoveryCode(ctx context.Context, userID, codeHash string) (bool, error) {
+	used, ok := s.codes[userID][codeHash]
+	if !ok || used {
+		return false, nil
+	}
+	s.codes[userID][codeHash] = true
+	return true, nil
+}
+
+func (s *storeStub) CountRecoveryCodes(ctx context.Context, userID string) (int, error) {
+	n := 0
+	for _, used := range s.codes[userID] {
+		if !used {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (s *storeStub) ListPolicies(ctx context.Context) ([]Policy, error) {
+	out := make([]Policy, 0)
+	for role, required := range s.policies {
+		out = append(out, Policy{Role: role, Required: required})
+	}
+	return out, nil
+}
+
+func (s *storeStub) Required(ctx context.Context, role users.UserRole) (bool, error) {
+	return s.policies[role], nil
+}
+
+func (s *storeStub) SetPolicy(ctx context.Context, p Policy) error {
+	s.policies[p.Role] = p.Required
+	return nil
+}
+
+type userLookupStub struct{}
+
+func (userLookupStub) GetByID(ctx context.Context, id string) (*users.User, error) {
+	return &users.User{ID: id, Email: id + "@local"}, nil
+}
+
+func TestCodeMatchesRFC6238(t *testing.T) {
+	// RFC 6238 appendix B, SHA1 secret "12345678901234567890", truncated to
+	// six digits.
+	secret := b32.EncodeToString([]byte("12345678901234567890"))
+	for unix, want := range map[int64]string{59: "287082", 1111111109: "081804", 2000000000: "279037"} {
+		got, err := code(secret, step(time.Unix(unix, 0)))
+		if err != nil || got != want {
+			t.Fatalf("code at %d: expected %s, got %s (%v)", unix, want, got, err)
+		}
+	}
+}
+
+func TestServiceEnrollConfirmVerify(t *testing.T) {
+	store := newStoreStub()
+	now := time.Unix(1_700_000_000, 0)
+	svc := &Service{Store: store, Users: userLookupStub{}, Now: func() time.Time { return now }}
+	ctx := identity.WithUser(context.Background(), "usr_1", string(users.RoleUser))
+
+	e, err := svc.Enroll(ctx)
+	if err != nil {
+		t.Fatalf("enroll: %v", err)
+	}
+	if !strings.HasPrefix(e.URI, "otpauth://totp/Sniply:usr_1@local?") || !strings.Contains(e.URI, "secret="+e.Secret) {
+		t.Fatalf("unexpected uri: %s", e.URI)
+	}
+	if !bytes.HasPrefix(e.QRCode, []byte("\x89PNG")) {
+		t.Fatal("expected a png qr code")
+	}
+	if err := svc.VerifyUser(ctx, "usr_1", "123456"); !isKind(err, apperrors.KindInvalidInput) {
+		t.Fatalf("expected an unconfirmed secret to be unusable, got %v", err)
+	}
+
+	_, err = svc.Confirm(ctx, "000000")
+	assertKind(t, err, apperrors.KindUnauthorized)
+	current, _ := code(e.Secret, step(now))
+	recovery, err := svc.Confirm(ctx, current)
+	if err != nil {
+		t.Fatalf("confirm: %v", err)
+	}
+	if len(recovery) != recoveryCodeCount {
+		t.Fatalf("expected %d recovery codes, got %d", recoveryCodeCount, len(recovery))
+	}
+	_, err = svc.Enroll(ctx)
+	assertKind(t, err, apperrors.KindConflict)
+
+	// The confirming code cannot be replayed; the next period's can be used
+	// once, even a little early.
+	assertKind(t, svc.VerifyUser(ctx, "usr_1", current), apperrors.KindUnauthorized)
+	next, _ := code(e.Secret, step(now)+1)
+	if err := svc.VerifyUser(ctx, "usr_1", next); err != nil {
+		t.Fatalf("verify next code: %v", err)
+	}
+	assertKind(t, svc.VerifyUser(ctx, "usr_1", next), apperrors.KindUnauthorized)
+
+	if err := svc.VerifyUser(ctx, "usr_1", strings.ToUpper(recovery[0])); err != nil {
+		t.Fatalf("verify recovery code: %v", err)
+	}
+	assertKind(t, svc.VerifyUser(ctx, "usr_1", recovery[0]), apperrors.KindUnauthorized)
+
+	st, err := svc.Status(ctx)
+	if err != nil || !st.Enabled || st.RecoveryCodesLeft != recoveryCodeCount-1 {
+		t.Fatalf("unexpected status: %+v (%v)", st, err)
+	}
+
+	if err := svc.Disable(ctx, recovery[1]); err != nil {
+		t.Fatalf("disable: %v", err)
+	}
+	if st, _ := svc.Status(ctx); st.Enabled {
+		t.Fatal("expected two-factor authentication disabled")
+	}
+}
+
+func TestServicePolicies(t *testing.T) {
+	store := newStoreStub()
+	svc := &Service{Store: store, Users: userLookupStub{}}
+	userCtx := identity.WithUser(context.Background(), "usr_1", string(users.RoleUser))
+	adminCtx := identity.WithUser(context.Background(), "usr_2", string(users.RoleAdmin))
+
+	assertKind(t, svc.SetPolicy(userCtx, Policy{Role: users.RoleAdmin, Required: true}), apperrors.KindForbidden)
+	assertKind(t, svc.SetPolicy(adminCtx, Policy{Role: "owner", Required: true}), apperrors.KindInvalidInput)
+	if err := svc.SetPolicy(adminCtx, Policy{Role: users.RoleAdmin, Required: true}); err != nil {
+		t.Fatalf("set policy: %v", err)
+	}
+
+	list, err := svc.Policies(adminCtx)
+	if err != nil || len(list) != 2 || list[0].Required || !list[1].Required {
+		t.Fatalf("unexpected policies: %+v (%v)", list, err)
+	}
+
+	enabled, required, err := svc.Requirement(context.Background(), "usr_2", users.RoleAdmin)
+	if err != nil || enabled || !required {
+		t.Fatalf("expected a required, not enabled factor: %v %v %v", enabled, required, err)
+	}
+
+	// Admins cannot turn off what their role requires.
+	now := time.Now()
+	store.totp["usr_2"] = &TOTP{UserID: "usr_2", Secret: newSecret(), ConfirmedAt: &now}
+	assertKind(t, svc.Disable(adminCtx, "123456"), apperrors.KindForbidden)
+}
+
+func isKind(err error, kind apperrors.Kind) bool {
+	var appErr *apperrors.Error
+	return errors.As(err, &appErr) && appErr.Kind == kind
+}
+
+func assertKind(t *testing.T, err error, kind apperrors.Kind) {
+	t.Helper()
+	if err == nil {
+		t.Fatalf("expected error kind %s", kind)
+	}
+	var appErr *apperrors.Error
+	if !errors.As(err, &appErr) {
+		t.Fatalf("expected app error, got: %v", err)
+	}
+	if appErr.Kind != kind {
+		t.Fatalf("unexpected kind: %s", appErr.Kind)
+	}
+}
@@ -0,0 +1,128 @@
+package twofactor
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// TOTP parameters (RFC 6238) understood by every authenticator app.
+const (
+	digits      = 6
+	period      = 30 * time.Second
+	secretBytes = 20
+	// skew accepts codes from this many periods before or after now, for
+	// clock drift and slow typists.
+	skew = 1
+
+	recoveryCodeCount = 10
+)
+
+var b32 = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+func newSecret() string {
+	b := make([]byte, secretBytes)
+	_, _ = rand.Read(b)
+	return b32.EncodeToString(b)
+}
+
+// code returns the TOTP code of a base32 secret for a time step.
+func code(secret string, step int64) (string, error) {
+	key, err := b32.DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		return "", err
+	}
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(step))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	n := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", digits, n%1_000_000), nil
+}
+
+// GenerateCode returns the code an authenticator app shows at t.
+func GenerateCode(secret string, t time.Time) (string, error) {
+	return code(secret, step(t))
+}
+
+func step(t time.Time) int64 {
+	return t.Unix() / int64(period/time.Second)
+}
+
+// verify returns the time step matching code around now. Steps up to
+// lastUsed were already used and are refused, so a code works only once.
+func verify(secret, input string, now time.Time, lastUsed int64) (int64, bool) {
+	current := step(now)
+	for s := current - skew; s <= current+skew; s++ {
+		if s <= lastUsed {
+			continue
+		}
+		want, err := code(secret, s)
+		if err != nil {
+			return 0, false
+		}
+		if subtle.ConstantTimeCompare([]byte(want), []byte(input)) == 1 {
+			return s, true
+		}
+	}
+	return 0, false
+}
+
+// uri is the otpauth:// URI authenticator apps import, usually from a QR
+// code.
+func uri(issuer, account, secret string) string {
+	label := url.PathEscape(issuer) + ":" + url.PathEscape(account)
+	q := url.Values{
+		"secret":    {secret},
+		"issuer":    {issuer},
+		"algorithm": {"SHA1"},
+		"digits":    {fmt.Sprint(digits)},
+		"period":    {fmt.Sprint(int(period / time.Second))},
+	}
+	return "otpauth://totp/" + label + "?" + q.Encode()
+}
+
+func isTOTPCode(input string) bool {
+	if len(input) != digits {
+		return false
+	}
+	for _, r := range input {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// newRecoveryCodes returns one-time codes like "3f9a1-c07b2" and their
+// hashes, which are all that is stored.
+func newRecoveryCodes() ([]string, []string) {
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+	for range recoveryCodeCount {
+		b := make([]byte, 5)
+		_, _ = rand.Read(b)
+		raw := hex.EncodeToString(b)
+		codes = append(codes, raw[:5]+"-"+raw[5:])
+		hashes = append(hashes, hashRecoveryCode(raw))
+	}
+	return codes, hashes
+}
+
+func hashRecoveryCode(input string) string {
+	normalized := strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(input))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
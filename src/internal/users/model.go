@@ -8,6 +8,8 @@ type User struct {
 	PasswordHash string    `json:"-"`
 	Role         UserRole  `json:"role"`
 	CreatedAt    time.Time `json:"created_at"`
+	// EmailVerifiedAt is nil until the user confirms the current email.
+	EmailVerifiedAt *time.Time `json:"email_verified_at,omitempty"`
 }
 
 type CreateUserRequest struct {
@@ -27,6 +29,8 @@ type UserResponse struct {
 	Email     string    `json:"email"`
 	Role      UserRole  `json:"role,omitempty"`
 	CreatedAt time.Time `json:"created_at,omitempty"`
+	// EmailVerifiedAt is only set on the caller's own user.
+	EmailVerifiedAt *time.Time `json:"email_verified_at,omitempty"`
 }
 
 type UserFilter struct {
@@ -34,3 +38,11 @@ type UserFilter struct {
 	Limit  int
 	Offset int
 }
+
+// Identity links a user to an account at an external identity provider.
+type Identity struct {
+	Provider string
+	Subject  string
+	UserID   string
+	Email    string
+}
@@ -6,6 +6,7 @@ import (
 	"strings"
 
 	"github.com/PabloPavan/sniply_api/internal/db"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
 )
 
@@ -21,17 +22,17 @@ const (
 	sqlUserInsert = `INSERT INTO users (id, email, password_hash)
 		VALUES ($1, $2, $3)`
 
-	sqlUserList = `SELECT id, email, password_hash, role, created_at
+	sqlUserList = `SELECT id, email, password_hash, role, created_at, email_verified_at
 		FROM users
 		WHERE email ILIKE $1
 		ORDER BY created_at DESC
 		LIMIT $2 OFFSET $3`
 
-	sqlUserGetByEmail = `SELECT id, email, password_hash, role, created_at
+	sqlUserGetByEmail = `SELECT id, email, password_hash, role, created_at, email_verified_at
 		FROM users
 		WHERE email = $1`
 
-	sqlUserGetByID = `SELECT id, email, password_hash, role, created_at
+	sqlUserGetByID = `SELECT id, email, password_hash, role, created_at, email_verified_at
 		FROM users
 		WHERE id = $1`
 
@@ -41,6 +42,23 @@ const (
 
 	sqlUserDelete = `DELETE FROM users 
 		WHERE id = $1`
+
+	sqlUserInsertWithRole = `INSERT INTO users (id, email, password_hash, role, email_verified_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING created_at`
+
+	sqlIdentityInsert = `INSERT INTO user_identities (provider, subject, user_id, email)
+		VALUES ($1, $2, $3, $4)`
+
+	sqlUserGetByIdentity = `WITH i AS (
+			UPDATE user_identities
+			SET last_login_at = now()
+			WHERE provider = $1 AND subject = $2
+			RETURNING user_id
+		)
+		SELECT u.id, u.email, u.password_hash, u.role, u.created_at, u.email_verified_at
+		FROM users u
+		JOIN i ON i.user_id = u.id`
 )
 
 func (r *Repository) Create(ctx context.Context, u *User) error {
@@ -60,7 +78,7 @@ func (r *Repository) GetByEmail(ctx context.Context, email string) (User, error)
 
 	var u User
 	err := r.base.Q().QueryRow(ctx, sqlUserGetByEmail, email).Scan(
-		&u.ID, &u.Email, &u.PasswordHash, &u.Role, &u.CreatedAt,
+		&u.ID, &u.Email, &u.PasswordHash, &u.Role, &u.CreatedAt, &u.EmailVerifiedAt,
 	)
 	if IsNotFound(err) {
 		return User{}, ErrNotFound
@@ -83,6 +101,7 @@ func (r *Repository) GetByID(ctx context.Context, id string) (*User, error) {
 		&u.PasswordHash,
 		&u.Role,
 		&u.CreatedAt,
+		&u.EmailVerifiedAt,
 	)
 
 	if IsNotFound(err) {
@@ -117,7 +136,7 @@ func (r *Repository) List(ctx context.Context, f UserFilter) ([]*User, error) {
 	var out []*User
 	for rows.Next() {
 		var u User
-		if err := rows.Scan(&u.ID, &u.Email, &u.PasswordHash, &u.Role, &u.CreatedAt); err != nil {
+		if err := rows.Scan(&u.ID, &u.Email, &u.PasswordHash, &u.Role, &u.CreatedAt, &u.EmailVerifiedAt); err != nil {
 			return nil, err
 		}
 		out = append(out, &u)
@@ -129,14 +148,16 @@ func (r *Repository) List(ctx context.Context, f UserFilter) ([]*User, error) {
 }
 
 func (r *Repository) Update(ctx context.Context, u *UpdateUserRequest) error {
-	set := make([]string, 0, 4)
+	set := make([]string, 0, 5)
 	args := make([]any, 0, 5)
 
 	args = append(args, u.ID)
 	argPos := 2
 
 	if u.Email != "" {
-		set = append(set, "email = $"+strconv.Itoa(argPos))
+		// A new address is unverified; the right side sees the old email.
+		set = append(set, "email = $"+strconv.Itoa(argPos),
+			"email_verified_at = CASE WHEN email = $"+strconv.Itoa(argPos)+" THEN email_verified_at END")
 		args = append(args, u.Email)
 		argPos++
 	}
@@ -186,3 +207,41 @@ func (r *Repository) Delete(ctx context.Context, id string) error {
 	}
 	return nil
 }
+
+// GetByIdentity returns the user linked to an identity provider account and
+// records the login on the link.
+func (r *Repository) GetByIdentity(ctx context.Context, provider, subject string) (User, error) {
+	ctx, cancel := r.base.WithTimeout(ctx)
+	defer cancel()
+
+	var u User
+	err := r.base.Q().QueryRow(ctx, sqlUserGetByIdentity, provider, subject).Scan(
+		&u.ID, &u.Email, &u.PasswordHash, &u.Role, &u.CreatedAt, &u.EmailVerifiedAt,
+	)
+	if IsNotFound(err) {
+		return User{}, ErrNotFound
+	}
+	if err != nil {
+		return User{}, err
+	}
+	return u, nil
+}
+
+func (r *Repository) LinkIdentity(ctx context.Context, id Identity) error {
+	ctx, cancel := r.base.WithTimeout(ctx)
+	defer cancel()
+
+	_, err := r.base.Q().Exec(ctx, sqlIdentityInsert, id.Provider, id.Subject, id.UserID, id.Email)
+	return err
+}
+
+// CreateWithIdentity creates u, with its role, already linked to id.
+func (r *Repository) CreateWithIdentity(ctx context.Context, u *User, id Identity) error {
+	return r.base.WithTx(ctx, func(ctx context.Context, tx pgx.Tx) error {
+		if err := tx.QueryRow(ctx, sqlUserInsertWithRole, u.ID, u.Email, u.PasswordHash, u.Role, u.EmailVerifiedAt).Scan(&u.CreatedAt); err != nil {
+			return err
+		}
+		_, err := tx.Exec(ctx, sqlIdentityInsert, id.Provider, id.Subject, u.ID, id.Email)
+		return err
+	})
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/PabloPavan/sniply_api/internal"
 	"github.com/PabloPavan/sniply_api/internal/apperrors"
 	"github.com/PabloPavan/sniply_api/internal/identity"
+	"github.com/PabloPavan/sniply_api/internal/telemetry"
 )
 
 type Store interface {
@@ -18,10 +19,28 @@ type Store interface {
 	Delete(ctx context.Context, id string) error
 }
 
+// EmailVerifier mails a user a link confirming email.
+type EmailVerifier interface {
+	SendVerification(ctx context.Context, userID, email string) error
+}
+
+// SessionManager ends the sessions of a user, except keepID, or makes them
+// read the user again.
+type SessionManager interface {
+	DeleteUser(ctx context.Context, userID, keepID string) error
+	BumpEpoch(ctx context.Context, userID string) error
+}
+
 type Service struct {
 	Store          Store
 	PasswordHasher func(plain string) (string, error)
 	IDGenerator    func() string
+	// Verifier, when set, is asked to verify the email of new users and
+	// changed emails.
+	Verifier EmailVerifier
+	// Sessions, when set, are revoked when a user's password changes or the
+	// user is deleted, and take a changed role on their next request.
+	Sessions SessionManager
 }
 
 type UpdateUserInput struct {
@@ -67,6 +86,7 @@ func (s *Service) Create(ctx context.Context, req CreateUserRequest) (*User, err
 		}
 		return nil, apperrors.New(apperrors.KindInternal, "failed to create user")
 	}
+	s.sendVerification(ctx, u.ID, u.Email)
 
 	return u, nil
 }
@@ -212,10 +232,52 @@ func (s *Service) updateWithTarget(ctx context.Context, requesterID string, isAd
 		}
 		return apperrors.New(apperrors.KindInternal, "internal error")
 	}
+	if req.Email != "" {
+		s.sendVerification(ctx, targetID, req.Email)
+	}
+	if req.PasswordHash != "" {
+		// Users changing their own password stay logged in here.
+		keepID := ""
+		if requesterID == targetID {
+			keepID, _ = identity.SessionID(ctx)
+		}
+		if err := s.revokeSessions(ctx, targetID, keepID); err != nil {
+			return err
+		}
+	}
+	if req.Role.Valid() && s.Sessions != nil {
+		if err := s.Sessions.BumpEpoch(ctx, targetID); err != nil {
+			return apperrors.New(apperrors.KindInternal, "failed to update sessions")
+		}
+	}
 
 	return nil
 }
 
+func (s *Service) revokeSessions(ctx context.Context, userID, keepID string) error {
+	if s.Sessions == nil {
+		return nil
+	}
+	if err := s.Sessions.DeleteUser(ctx, userID, keepID); err != nil {
+		return apperrors.New(apperrors.KindInternal, "failed to revoke sessions")
+	}
+	return nil
+}
+
+// sendVerification is best effort: the user can ask for another link.
+func (s *Service) sendVerification(ctx context.Context, userID, email string) {
+	if s.Verifier == nil {
+		return
+	}
+	if err := s.Verifier.SendVerification(ctx, userID, email); err != nil {
+		telemetry.LogError(ctx, "email verification failed",
+			telemetry.LogString("event", "user.verification"),
+			telemetry.LogString("user.id", userID),
+			telemetry.LogString("error", err.Error()),
+		)
+	}
+}
+
 func (s *Service) DeleteSelf(ctx context.Context) error {
 	if s.Store == nil {
 		return apperrors.New(apperrors.KindInternal, "users store not configured")
@@ -252,5 +314,5 @@ func (s *Service) deleteWithTarget(ctx context.Context, requesterID string, isAd
 		}
 		return apperrors.New(apperrors.KindInternal, "failed to delete user")
 	}
-	return nil
+	return s.revokeSessions(ctx, targetID, "")
 }
@@ -95,6 +95,100 @@ func TestServiceCreateUser(t *testing.T) {
 	}
 }
 
+type verifierStub struct {
+	sent []string
+	err  error
+}
+
+func (v *verifierStub) SendVerification(ctx context.Context, userID, email string) error {
+	v.sent = append(v.sent, userID+" "+email)
+	return v.err
+}
+
+func TestServiceAsksToVerifyNewEmails(t *testing.T) {
+	verifier := &verifierStub{err: errors.New("outbox down")}
+	svc := &Service{
+		Store:          &storeStub{},
+		PasswordHasher: func(plain string) (string, error) { return "hash", nil },
+		IDGenerator:    func() string { return "usr_1" },
+		Verifier:       verifier,
+	}
+
+	// A failed send does not fail the signup.
+	if _, err := svc.Create(context.Background(), CreateUserRequest{Email: "A@Local", Password: "secret"}); err != nil {
+		t.Fatalf("create user error: %v", err)
+	}
+
+	ctx := identity.WithUser(context.Background(), "usr_1", "member")
+	password := "other"
+	if err := svc.UpdateSelf(ctx, UpdateUserInput{Password: &password}); err != nil {
+		t.Fatalf("update error: %v", err)
+	}
+	email := "B@Local"
+	if err := svc.UpdateSelf(ctx, UpdateUserInput{Email: &email}); err != nil {
+		t.Fatalf("update error: %v", err)
+	}
+	if len(verifier.sent) != 2 || verifier.sent[0] != "usr_1 a@local" || verifier.sent[1] != "usr_1 b@local" {
+		t.Fatalf("unexpected verifications: %v", verifier.sent)
+	}
+}
+
+type revokerStub struct {
+	calls []string
+}
+
+func (r *revokerStub) DeleteUser(ctx context.Context, userID, keepID string) error {
+	r.calls = append(r.calls, userID+" keep:"+keepID)
+	return nil
+}
+
+func (r *revokerStub) BumpEpoch(ctx context.Context, userID string) error {
+	r.calls = append(r.calls, userID+" epoch")
+	return nil
+}
+
+func TestServiceRevokesSessions(t *testing.T) {
+	revoker := &revokerStub{}
+	svc := &Service{
+		Store:          &storeStub{},
+		PasswordHasher: func(plain string) (string, error) { return "hash", nil },
+		Sessions:       revoker,
+	}
+	self := identity.WithSession(identity.WithUser(context.Background(), "usr_1", "member"), "ses_1")
+	admin := identity.WithSession(identity.WithUser(context.Background(), "usr_2", "admin"), "ses_2")
+
+	email, password, role := "new@local", "other", "user"
+	steps := []struct {
+		ctx    context.Context
+		target string
+		input  UpdateUserInput
+	}{
+		{self, "usr_1", UpdateUserInput{Email: &email}},
+		{self, "usr_1", UpdateUserInput{Password: &password}},
+		{admin, "usr_1", UpdateUserInput{Password: &password}},
+		{admin, "usr_1", UpdateUserInput{Role: &role}},
+	}
+	for _, step := range steps {
+		if err := svc.UpdateByID(step.ctx, step.target, step.input); err != nil {
+			t.Fatalf("update error: %v", err)
+		}
+	}
+	if err := svc.DeleteByID(admin, "usr_1"); err != nil {
+		t.Fatalf("delete error: %v", err)
+	}
+
+	// A role change keeps the sessions but makes them read the role again.
+	want := []string{"usr_1 keep:ses_1", "usr_1 keep:", "usr_1 epoch", "usr_1 keep:"}
+	if len(revoker.calls) != len(want) {
+		t.Fatalf("unexpected revocations: %v", revoker.calls)
+	}
+	for i := range want {
+		if revoker.calls[i] != want[i] {
+			t.Fatalf("unexpected revocations: %v", revoker.calls)
+		}
+	}
+}
+
 func TestServiceListRequiresAdmin(t *testing.T) {
 	store := &storeStub{}
 	svc := &Service{Store: store}
@@ -0,0 +1,2 @@
+ALTER TABLE snippets
+  ADD COLUMN IF NOT EXISTS placeholders JSONB NOT NULL DEFAULT '[]'::jsonb;
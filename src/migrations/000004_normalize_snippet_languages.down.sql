@@ -0,0 +1,5 @@
+UPDATE snippets
+SET language = language_legacy
+WHERE language_legacy IS NOT NULL;
+
+ALTER TABLE snippets DROP COLUMN IF EXISTS language_legacy;
@@ -0,0 +1,88 @@
+-- Map case variants and aliases onto the ids of the built-in language
+-- registry (internal/languages). Anything still unknown becomes 'txt'.
+-- language_legacy keeps every value this rewrites, so nothing is lost and
+-- the down migration can put it back. The lists below must match the
+-- registry; languages_test.go checks them.
+ALTER TABLE snippets ADD COLUMN IF NOT EXISTS language_legacy TEXT;
+
+UPDATE snippets
+SET language_legacy = language
+WHERE language_legacy IS NULL
+  AND language NOT IN ('bash', 'c', 'clojure', 'cpp', 'csharp', 'css', 'dart', 'diff', 'dockerfile', 'elixir', 'erlang', 'go', 'graphql', 'haskell', 'hcl', 'html', 'ini', 'java', 'javascript', 'json', 'kotlin', 'lua', 'makefile', 'markdown', 'perl', 'php', 'powershell', 'protobuf', 'python', 'r', 'ruby', 'rust', 'scala', 'sql', 'swift', 'toml', 'txt', 'typescript', 'xml', 'yaml');
+
+UPDATE snippets
+SET language = lower(btrim(language))
+WHERE language <> lower(btrim(language));
+
+WITH aliases (alias, id) AS (
+  VALUES
+  ('sh', 'bash'),
+  ('shell', 'bash'),
+  ('shellscript', 'bash'),
+  ('zsh', 'bash'),
+  ('clj', 'clojure'),
+  ('c++', 'cpp'),
+  ('cxx', 'cpp'),
+  ('c#', 'csharp'),
+  ('cs', 'csharp'),
+  ('patch', 'diff'),
+  ('udiff', 'diff'),
+  ('docker', 'dockerfile'),
+  ('ex', 'elixir'),
+  ('exs', 'elixir'),
+  ('erl', 'erlang'),
+  ('golang', 'go'),
+  ('gql', 'graphql'),
+  ('hs', 'haskell'),
+  ('terraform', 'hcl'),
+  ('tf', 'hcl'),
+  ('htm', 'html'),
+  ('xhtml', 'html'),
+  ('cfg', 'ini'),
+  ('dosini', 'ini'),
+  ('js', 'javascript'),
+  ('node', 'javascript'),
+  ('nodejs', 'javascript'),
+  ('jsx', 'javascript'),
+  ('javascriptreact', 'javascript'),
+  ('jsonc', 'json'),
+  ('kt', 'kotlin'),
+  ('make', 'makefile'),
+  ('mf', 'makefile'),
+  ('md', 'markdown'),
+  ('pl', 'perl'),
+  ('ps1', 'powershell'),
+  ('pwsh', 'powershell'),
+  ('posh', 'powershell'),
+  ('proto', 'protobuf'),
+  ('py', 'python'),
+  ('python3', 'python'),
+  ('py3', 'python'),
+  ('rlang', 'r'),
+  ('rb', 'ruby'),
+  ('rs', 'rust'),
+  ('postgresql', 'sql'),
+  ('postgres', 'sql'),
+  ('mysql', 'sql'),
+  ('plpgsql', 'sql'),
+  ('sqlite', 'sql'),
+  ('text', 'txt'),
+  ('plaintext', 'txt'),
+  ('plain', 'txt'),
+  ('ts', 'typescript'),
+  ('tsx', 'typescript'),
+  ('typescriptreact', 'typescript'),
+  ('svg', 'xml'),
+  ('xsd', 'xml'),
+  ('xsl', 'xml'),
+  ('yml', 'yaml')
+)
+UPDATE snippets s
+SET language = a.id
+FROM aliases a
+WHERE s.language = a.alias;
+
+UPDATE snippets
+SET language = 'txt'
+WHERE language_legacy IS NOT NULL
+  AND language NOT IN ('bash', 'c', 'clojure', 'cpp', 'csharp', 'css', 'dart', 'diff', 'dockerfile', 'elixir', 'erlang', 'go', 'graphql', 'haskell', 'hcl', 'html', 'ini', 'java', 'javascript', 'json', 'kotlin', 'lua', 'makefile', 'markdown', 'perl', 'php', 'powershell', 'protobuf', 'python', 'r', 'ruby', 'rust', 'scala', 'sql', 'swift', 'toml', 'txt', 'typescript', 'xml', 'yaml');
@@ -0,0 +1,20 @@
+-- Tags are stored lowercased, trimmed and without duplicates.
+UPDATE snippets
+SET tags = ARRAY(
+  SELECT x.tag
+  FROM (
+    SELECT lower(btrim(u.tag)) AS tag, min(u.ord) AS ord
+    FROM unnest(tags) WITH ORDINALITY AS u(tag, ord)
+    WHERE btrim(u.tag) <> ''
+    GROUP BY 1
+  ) x
+  ORDER BY x.ord
+)
+WHERE EXISTS (
+  SELECT 1 FROM unnest(tags) AS u(tag)
+  WHERE u.tag <> lower(btrim(u.tag)) OR btrim(u.tag) = ''
+)
+OR cardinality(tags) <> (SELECT count(DISTINCT u.tag) FROM unnest(tags) AS u(tag));
+
+CREATE INDEX IF NOT EXISTS idx_snippets_tags
+  ON snippets USING GIN (tags);
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS snippet_secret_findings (
+  snippet_id  TEXT NOT NULL REFERENCES snippets(id) ON DELETE CASCADE,
+  rule_id     TEXT NOT NULL,
+  line        INTEGER NOT NULL,
+  col         INTEGER NOT NULL,
+  preview     TEXT NOT NULL DEFAULT '',
+  redacted    BOOLEAN NOT NULL DEFAULT false,
+  created_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_snippet_secret_findings_snippet
+  ON snippet_secret_findings (snippet_id);
@@ -0,0 +1 @@
+ALTER TABLE snippets ADD COLUMN IF NOT EXISTS syntax_valid BOOLEAN;
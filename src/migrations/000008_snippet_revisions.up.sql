@@ -0,0 +1,17 @@
+ALTER TABLE snippets
+  ADD COLUMN IF NOT EXISTS revision INTEGER NOT NULL DEFAULT 1;
+
+CREATE TABLE IF NOT EXISTS snippet_revisions (
+  snippet_id  TEXT NOT NULL REFERENCES snippets(id) ON DELETE CASCADE,
+  revision    INTEGER NOT NULL,
+  name        TEXT NOT NULL,
+  content     TEXT NOT NULL,
+  language    TEXT NOT NULL,
+  created_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+  PRIMARY KEY (snippet_id, revision)
+);
+
+INSERT INTO snippet_revisions (snippet_id, revision, name, content, language, created_at)
+SELECT id, revision, name, content, language, updated_at
+FROM snippets
+ON CONFLICT DO NOTHING;
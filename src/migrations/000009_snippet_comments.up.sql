@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS snippet_comments (
+  id          TEXT PRIMARY KEY,
+  snippet_id  TEXT NOT NULL REFERENCES snippets(id) ON DELETE CASCADE,
+  parent_id   TEXT REFERENCES snippet_comments(id) ON DELETE CASCADE,
+  author_id   TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  body        TEXT NOT NULL,
+  line_start  INTEGER,
+  line_end    INTEGER,
+  revision    INTEGER,
+  created_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+  updated_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+  CHECK (line_start IS NULL OR (line_start >= 1 AND line_end >= line_start AND revision IS NOT NULL))
+);
+
+CREATE INDEX IF NOT EXISTS idx_snippet_comments_snippet_created
+  ON snippet_comments (snippet_id, created_at);
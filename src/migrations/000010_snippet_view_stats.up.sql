@@ -0,0 +1,11 @@
+-- Hourly view and raw download counts, flushed from Redis in batches.
+CREATE TABLE IF NOT EXISTS snippet_view_stats (
+  snippet_id     TEXT NOT NULL REFERENCES snippets(id) ON DELETE CASCADE,
+  bucket         TIMESTAMPTZ NOT NULL,
+  views          BIGINT NOT NULL DEFAULT 0,
+  raw_downloads  BIGINT NOT NULL DEFAULT 0,
+  PRIMARY KEY (snippet_id, bucket)
+);
+
+CREATE INDEX IF NOT EXISTS idx_snippet_view_stats_bucket
+  ON snippet_view_stats (bucket);
@@ -0,0 +1,3 @@
+-- Trigram index so regex and substring searches on content can skip most rows.
+CREATE INDEX IF NOT EXISTS idx_snippets_content_trgm
+  ON snippets USING GIN (content gin_trgm_ops);
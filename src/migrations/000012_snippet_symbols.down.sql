@@ -0,0 +1,19 @@
+ALTER TABLE snippets DROP COLUMN IF EXISTS search_tsv;
+ALTER TABLE snippets
+  ADD COLUMN search_tsv TSVECTOR GENERATED ALWAYS AS (
+    to_tsvector(
+      'simple',
+      coalesce(name,'') || ' ' ||
+      coalesce(content,'')
+    ) || array_to_tsvector(tags)
+  ) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_snippets_fts
+  ON snippets USING GIN (search_tsv);
+
+DROP INDEX IF EXISTS idx_snippets_symbol_names;
+
+ALTER TABLE snippets
+  DROP COLUMN IF EXISTS search_terms,
+  DROP COLUMN IF EXISTS symbol_names,
+  DROP COLUMN IF EXISTS symbols;
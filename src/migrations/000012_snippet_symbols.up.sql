@@ -0,0 +1,27 @@
+-- Definitions extracted from the content on save (internal/symbols).
+-- symbol_names holds their lowercased names and name parts for symbol:
+-- lookups; search_terms holds the parts of compound identifiers so that
+-- full-text search can match words inside camelCase and snake_case names.
+ALTER TABLE snippets
+  ADD COLUMN IF NOT EXISTS symbols JSONB NOT NULL DEFAULT '[]'::jsonb,
+  ADD COLUMN IF NOT EXISTS symbol_names TEXT[] NOT NULL DEFAULT '{}',
+  ADD COLUMN IF NOT EXISTS search_terms TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idx_snippets_symbol_names
+  ON snippets USING GIN (symbol_names);
+
+-- Generated columns cannot be altered, so search_tsv is rebuilt with the
+-- identifier parts. Dropping it also drops idx_snippets_fts.
+ALTER TABLE snippets DROP COLUMN IF EXISTS search_tsv;
+ALTER TABLE snippets
+  ADD COLUMN search_tsv TSVECTOR GENERATED ALWAYS AS (
+    to_tsvector(
+      'simple',
+      coalesce(name,'') || ' ' ||
+      coalesce(content,'') || ' ' ||
+      coalesce(search_terms,'')
+    ) || array_to_tsvector(tags)
+  ) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_snippets_fts
+  ON snippets USING GIN (search_tsv);
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_saved_searches_due;
+DROP INDEX IF EXISTS idx_saved_searches_owner_created;
+DROP TABLE IF EXISTS saved_searches;
+
+DROP INDEX IF EXISTS idx_notifications_user_unread;
+DROP INDEX IF EXISTS idx_notifications_user_created;
+DROP TABLE IF EXISTS notifications;
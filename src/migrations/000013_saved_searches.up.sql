@@ -0,0 +1,39 @@
+CREATE TABLE IF NOT EXISTS notifications (
+  id          TEXT PRIMARY KEY,
+  user_id     TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  kind        TEXT NOT NULL,
+  title       TEXT NOT NULL,
+  body        TEXT NOT NULL DEFAULT '',
+  data        JSONB NOT NULL DEFAULT '{}'::jsonb,
+  read_at     TIMESTAMPTZ,
+  created_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_notifications_user_created
+  ON notifications (user_id, created_at DESC);
+
+CREATE INDEX IF NOT EXISTS idx_notifications_user_unread
+  ON notifications (user_id)
+  WHERE read_at IS NULL;
+
+-- checked_at is the end of the last window evaluated for new matches.
+CREATE TABLE IF NOT EXISTS saved_searches (
+  id              TEXT PRIMARY KEY,
+  owner_id        TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  name            TEXT NOT NULL,
+  query           JSONB NOT NULL DEFAULT '{}'::jsonb,
+  notify          TEXT NOT NULL DEFAULT 'none' CHECK (notify IN ('none', 'inbox', 'webhook')),
+  webhook_url     TEXT NOT NULL DEFAULT '',
+  webhook_secret  TEXT NOT NULL DEFAULT '',
+  checked_at      TIMESTAMPTZ NOT NULL DEFAULT now(),
+  created_at      TIMESTAMPTZ NOT NULL DEFAULT now(),
+  updated_at      TIMESTAMPTZ NOT NULL DEFAULT now(),
+  CONSTRAINT saved_searches_owner_name_key UNIQUE (owner_id, name)
+);
+
+CREATE INDEX IF NOT EXISTS idx_saved_searches_owner_created
+  ON saved_searches (owner_id, created_at DESC);
+
+CREATE INDEX IF NOT EXISTS idx_saved_searches_due
+  ON saved_searches (checked_at)
+  WHERE notify <> 'none';
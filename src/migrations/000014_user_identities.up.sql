@@ -0,0 +1,13 @@
+-- Accounts at external identity providers, keyed by the provider's subject.
+CREATE TABLE IF NOT EXISTS user_identities (
+  provider       TEXT NOT NULL,
+  subject        TEXT NOT NULL,
+  user_id        TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  email          TEXT NOT NULL DEFAULT '',
+  created_at     TIMESTAMPTZ NOT NULL DEFAULT now(),
+  last_login_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+  PRIMARY KEY (provider, subject)
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_identities_user
+  ON user_identities (user_id);
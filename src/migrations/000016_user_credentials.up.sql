@@ -0,0 +1,19 @@
+-- WebAuthn public key credentials (passkeys and security keys). id is the
+-- base64url credential ID chosen by the authenticator.
+CREATE TABLE IF NOT EXISTS user_credentials (
+  id                TEXT PRIMARY KEY,
+  user_id           TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  name              TEXT NOT NULL DEFAULT '',
+  public_key        BYTEA NOT NULL,
+  attestation_type  TEXT NOT NULL DEFAULT '',
+  aaguid            BYTEA,
+  sign_count        BIGINT NOT NULL DEFAULT 0,
+  transports        TEXT[] NOT NULL DEFAULT '{}',
+  backup_eligible   BOOLEAN NOT NULL DEFAULT false,
+  backup_state      BOOLEAN NOT NULL DEFAULT false,
+  created_at        TIMESTAMPTZ NOT NULL DEFAULT now(),
+  last_used_at      TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_credentials_user
+  ON user_credentials (user_id);
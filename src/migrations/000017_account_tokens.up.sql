@@ -0,0 +1,35 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS email_verified_at TIMESTAMPTZ;
+
+-- Single-use tokens mailed to users. Only a SHA-256 of the token is kept;
+-- email is the address a verification token was sent to.
+CREATE TABLE IF NOT EXISTS user_tokens (
+  token_hash  TEXT PRIMARY KEY,
+  user_id     TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  purpose     TEXT NOT NULL CHECK (purpose IN ('password_reset', 'email_verification')),
+  email       TEXT NOT NULL,
+  expires_at  TIMESTAMPTZ NOT NULL,
+  used_at     TIMESTAMPTZ,
+  created_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_tokens_user
+  ON user_tokens (user_id, purpose);
+
+-- Transactional outbox: mail is inserted in the same transaction as the
+-- change that triggers it and delivered by a background worker.
+CREATE TABLE IF NOT EXISTS mail_outbox (
+  id               BIGSERIAL PRIMARY KEY,
+  recipient        TEXT NOT NULL,
+  subject          TEXT NOT NULL,
+  body             TEXT NOT NULL,
+  attempts         INT NOT NULL DEFAULT 0,
+  next_attempt_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+  last_error       TEXT NOT NULL DEFAULT '',
+  sent_at          TIMESTAMPTZ,
+  failed_at        TIMESTAMPTZ,
+  created_at       TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_mail_outbox_pending
+  ON mail_outbox (next_attempt_at)
+  WHERE sent_at IS NULL AND failed_at IS NULL;
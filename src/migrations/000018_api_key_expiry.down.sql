@@ -0,0 +1,4 @@
+ALTER TABLE api_keys
+  DROP COLUMN IF EXISTS last_used_ip,
+  DROP COLUMN IF EXISTS last_used_at,
+  DROP COLUMN IF EXISTS expires_at;
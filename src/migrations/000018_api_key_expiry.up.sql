@@ -0,0 +1,4 @@
+ALTER TABLE api_keys
+  ADD COLUMN IF NOT EXISTS expires_at   TIMESTAMPTZ,
+  ADD COLUMN IF NOT EXISTS last_used_at TIMESTAMPTZ,
+  ADD COLUMN IF NOT EXISTS last_used_ip TEXT NOT NULL DEFAULT '';
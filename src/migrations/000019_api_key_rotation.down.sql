@@ -0,0 +1,3 @@
+ALTER TABLE api_keys
+  DROP COLUMN IF EXISTS lifetime_seconds,
+  DROP COLUMN IF EXISTS replaced_by;
@@ -0,0 +1,10 @@
+-- replaced_by marks a rotated key, which cannot be rotated again.
+-- lifetime_seconds is the lifetime a key was created with; a rotation
+-- shortens expires_at, so successors take their lifetime from here.
+ALTER TABLE api_keys
+  ADD COLUMN IF NOT EXISTS replaced_by      TEXT REFERENCES api_keys(id) ON DELETE SET NULL,
+  ADD COLUMN IF NOT EXISTS lifetime_seconds BIGINT;
+
+UPDATE api_keys
+SET lifetime_seconds = GREATEST(1, round(extract(epoch FROM expires_at - created_at)))::bigint
+WHERE expires_at IS NOT NULL AND lifetime_seconds IS NULL;
@@ -0,0 +1,5 @@
+-- Delivered and abandoned mail no longer keeps its body, which can hold a
+-- single-use token. Clear the rows written before that.
+UPDATE mail_outbox
+SET body = ''
+WHERE (sent_at IS NOT NULL OR failed_at IS NOT NULL) AND body <> '';
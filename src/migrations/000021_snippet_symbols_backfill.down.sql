@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_snippets_symbols_pending;
+
+ALTER TABLE snippets DROP COLUMN IF EXISTS symbols_pending;
@@ -0,0 +1,10 @@
+-- Rows saved before 000012 still hold the empty symbol defaults. Mark them
+-- so the API can fill in their symbols once at startup; rows written from
+-- now on start out indexed.
+ALTER TABLE snippets
+  ADD COLUMN IF NOT EXISTS symbols_pending BOOLEAN NOT NULL DEFAULT true;
+ALTER TABLE snippets
+  ALTER COLUMN symbols_pending SET DEFAULT false;
+
+CREATE INDEX IF NOT EXISTS idx_snippets_symbols_pending
+  ON snippets (id) WHERE symbols_pending;